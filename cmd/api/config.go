@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/clickhouse"
+	"github.com/colby/snip/internal/directory"
+	"github.com/colby/snip/internal/interstitial"
+	"github.com/colby/snip/internal/metering"
+	"github.com/colby/snip/internal/outbound"
+	"github.com/colby/snip/internal/secrets"
+	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/internal/urlscan"
+)
+
+// Config holds server configuration.
+type Config struct {
+	Port       string
+	BaseURL    string
+	LogLevel   string
+	CodeLength int
+	ReadOnly   bool // reject create/update/delete; hot-reloadable
+
+	DataDir              string  // keeps data in an embedded database here; empty keeps it in memory
+	DataCompactFreeRatio float64 // free fraction of the data file that triggers compaction at startup; 0 disables
+
+	CodeLengthGrowRate float64 // collisions per create that lengthen codes; 0 disables growth
+	SettingsFile       string  // where a grown code length is kept across restarts
+
+	CaseInsensitiveCodes bool // single-case codes that resolve in any case
+	DedupeDestinations   bool // return the existing link when a destination is shortened again
+
+	DeleteGraceDays int // days deleted links stay restorable; 0 deletes at once
+
+	ClickDedupeSeconds   int  // repeat clicks by a visitor within this window aren't counted; 0 counts all
+	NotFoundCacheSeconds int  // how long unknown codes are answered from memory; 0 disables
+	ClickRecordTimeoutMS int  // bounds writing each click in the background
+	CountPrefetches      bool // count link preview bots and prefetches as clicks
+	HonorDoNotTrack      bool // store DNT / Sec-GPC visitors' click events anonymously
+
+	ResolveRedirects bool // store the final URL of a destination's redirect chain
+	ResolveMaxHops   int
+
+	ShortenerDomains []string // overrides the built-in shortener list when set
+	ShortenerPolicy  string   // reject, resolve or allow links to other shorteners
+
+	VirusTotalAPIKey        string // enables background URL scanning when set
+	VirusTotalMinDetections int
+
+	PhishingWarnScore  int // 0 disables phishing warnings
+	PhishingBlockScore int // 0 disables phishing blocking
+
+	ThumbnailEndpoint string // screenshot service URL with {url}; enables thumbnails when set
+	ThumbnailDir      string
+
+	InterstitialBrand   string
+	InterstitialSeconds int
+
+	PublicDirectory bool // serves the links marked public at /directory
+	DirectoryTitle  string
+
+	ClickHouseURL           string // stores click events in ClickHouse when set
+	ClickHouseDatabase      string
+	ClickHouseTable         string
+	ClickHouseUser          string
+	ClickHousePassword      string
+	ClickHouseBatchSize     int
+	ClickHouseFlushInterval int // seconds
+
+	WebhookURL          string   // receives milestone notifications when set
+	WebhookSecret       string   // signs webhook bodies when set
+	WebhookAllowPrivate bool     // lets the webhook URL resolve to a private address
+	WebhookEvents       []string // event types to deliver; defaults to milestones
+	Milestones          []int64  // click counts that trigger milestone notifications
+
+	StripeAPIKey           string // reports usage to Stripe when set
+	StripeRedirectsItem    string // metered subscription item billed per redirect
+	StripeLinksCreatedItem string // metered subscription item billed per link created
+	MeteringFlushSeconds   int
+
+	MetricsAddr string // listen address for /debug/vars, e.g. "127.0.0.1:9090"; empty disables it
+
+	RequireAPIKeys bool // require an API key on the /api endpoints; keys are issued under /api/admin
+
+	JWTSecret   string // signs user tokens and enables accounts when set; at least 32 bytes
+	JWTTTLHours int    // how long a user token is valid
+
+	AdminToken      string         // Bearer token for /api/admin endpoints; empty disables them
+	AdminNetworks   []netip.Prefix // ranges allowed to reach /api/admin; empty allows any
+	AdminClientCert bool           // require a verified TLS client certificate for /api/admin
+
+	TrustedProxies []netip.Prefix // proxies whose X-Forwarded-For and X-Real-IP are believed
+
+	TLSCertFile     string // serves HTTPS when set, with TLSKeyFile
+	TLSKeyFile      string
+	TLSClientCAFile string // CA bundle that client certificates are verified against
+
+	LiveFeedToken   string   // shared token for GET /api/ws; empty disables the feed
+	LiveFeedOrigins []string // extra browser origins allowed to open the feed
+
+	AccessLogFile       string // path, "stdout" or "stderr"; empty disables the access log
+	AccessLogFormat     string // json or combined
+	AccessLogMaxSizeMB  int
+	AccessLogMaxBackups int
+
+	SentryDSN         string // enables error reporting to Sentry when set
+	SentryEnvironment string
+	SentryRelease     string
+}
+
+// loadConfig reads configuration from environment variables. If CONFIG_FILE
+// names a KEY=VALUE file, its entries take precedence over the environment;
+// that file is re-read on SIGHUP.
+func loadConfig() (Config, error) {
+	src := configSource{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		src = values
+	}
+	if err := src.resolveSecrets(); err != nil {
+		return Config{}, err
+	}
+
+	adminNetworks, err := parseNetworks(src.get("ADMIN_ALLOWED_CIDRS", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ADMIN_ALLOWED_CIDRS: %w", err)
+	}
+
+	trustedProxies, err := parseNetworks(src.get("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	return Config{
+		Port:       src.get("PORT", "8080"),
+		BaseURL:    src.get("BASE_URL", "http://localhost:8080"),
+		LogLevel:   src.get("LOG_LEVEL", "info"),
+		CodeLength: src.getInt("CODE_LENGTH", 7),
+		ReadOnly:   src.getBool("READ_ONLY", false),
+
+		DataDir:              src.get("DATA_DIR", ""),
+		DataCompactFreeRatio: src.getFloat("DATA_COMPACT_FREE_RATIO", 0.5),
+
+		CodeLengthGrowRate: src.getFloat("CODE_LENGTH_GROW_RATE", 0),
+		SettingsFile:       src.get("SETTINGS_FILE", ""),
+
+		CaseInsensitiveCodes: src.getBool("CASE_INSENSITIVE_CODES", false),
+		DedupeDestinations:   src.getBool("DEDUPE_DESTINATIONS", false),
+
+		DeleteGraceDays: src.getInt("DELETE_GRACE_DAYS", 0),
+
+		ClickDedupeSeconds:   src.getInt("CLICK_DEDUPE_SECONDS", 0),
+		NotFoundCacheSeconds: src.getInt("NOT_FOUND_CACHE_SECONDS", 0),
+		ClickRecordTimeoutMS: src.getInt("CLICK_RECORD_TIMEOUT_MS", int(service.DefaultAsyncClickTimeout/time.Millisecond)),
+		CountPrefetches:      src.getBool("COUNT_PREFETCHES", false),
+		HonorDoNotTrack:      src.getBool("HONOR_DO_NOT_TRACK", false),
+
+		ResolveRedirects: src.getBool("RESOLVE_REDIRECTS", false),
+		ResolveMaxHops:   src.getInt("RESOLVE_MAX_HOPS", outbound.DefaultMaxHops),
+
+		ShortenerDomains: splitList(src.get("SHORTENER_DOMAINS", "")),
+		ShortenerPolicy:  src.get("SHORTENER_POLICY", "reject"),
+
+		VirusTotalAPIKey:        src.get("VIRUSTOTAL_API_KEY", ""),
+		VirusTotalMinDetections: src.getInt("VIRUSTOTAL_MIN_DETECTIONS", urlscan.DefaultMinDetections),
+
+		PhishingWarnScore:  src.getInt("PHISHING_WARN_SCORE", 3),
+		PhishingBlockScore: src.getInt("PHISHING_BLOCK_SCORE", 6),
+
+		ThumbnailEndpoint: src.get("THUMBNAIL_ENDPOINT", ""),
+		ThumbnailDir:      src.get("THUMBNAIL_DIR", "thumbnails"),
+
+		InterstitialBrand:   src.get("INTERSTITIAL_BRAND", interstitial.DefaultBrand),
+		InterstitialSeconds: src.getInt("INTERSTITIAL_SECONDS", interstitial.DefaultSeconds),
+
+		PublicDirectory: src.getBool("PUBLIC_DIRECTORY", false),
+		DirectoryTitle:  src.get("DIRECTORY_TITLE", directory.DefaultTitle),
+
+		ClickHouseURL:           src.get("CLICKHOUSE_URL", ""),
+		ClickHouseDatabase:      src.get("CLICKHOUSE_DATABASE", ""),
+		ClickHouseTable:         src.get("CLICKHOUSE_TABLE", clickhouse.DefaultTable),
+		ClickHouseUser:          src.get("CLICKHOUSE_USER", ""),
+		ClickHousePassword:      src.get("CLICKHOUSE_PASSWORD", ""),
+		ClickHouseBatchSize:     src.getInt("CLICKHOUSE_BATCH_SIZE", clickhouse.DefaultBatchSize),
+		ClickHouseFlushInterval: src.getInt("CLICKHOUSE_FLUSH_INTERVAL", int(clickhouse.DefaultFlushInterval/time.Second)),
+
+		WebhookURL:          src.get("WEBHOOK_URL", ""),
+		WebhookSecret:       src.get("WEBHOOK_SECRET", ""),
+		WebhookAllowPrivate: src.getBool("WEBHOOK_ALLOW_PRIVATE", false),
+		WebhookEvents:       splitList(src.get("WEBHOOK_EVENTS", "")),
+		Milestones:          parseMilestones(src.get("MILESTONES", "")),
+
+		StripeAPIKey:           src.get("STRIPE_API_KEY", ""),
+		StripeRedirectsItem:    src.get("STRIPE_REDIRECTS_ITEM", ""),
+		StripeLinksCreatedItem: src.get("STRIPE_LINKS_CREATED_ITEM", ""),
+		MeteringFlushSeconds:   src.getInt("METERING_FLUSH_SECONDS", int(metering.DefaultFlushInterval/time.Second)),
+
+		MetricsAddr: src.get("METRICS_ADDR", ""),
+
+		RequireAPIKeys: src.getBool("REQUIRE_API_KEYS", false),
+
+		JWTSecret:   src.get("JWT_SECRET", ""),
+		JWTTTLHours: src.getInt("JWT_TTL_HOURS", int(auth.DefaultTokenTTL/time.Hour)),
+
+		AdminToken:      src.get("ADMIN_TOKEN", ""),
+		AdminNetworks:   adminNetworks,
+		AdminClientCert: src.getBool("ADMIN_REQUIRE_CLIENT_CERT", false),
+
+		TrustedProxies: trustedProxies,
+
+		TLSCertFile:     src.get("TLS_CERT_FILE", ""),
+		TLSKeyFile:      src.get("TLS_KEY_FILE", ""),
+		TLSClientCAFile: src.get("TLS_CLIENT_CA_FILE", ""),
+
+		LiveFeedToken:   src.get("LIVE_FEED_TOKEN", ""),
+		LiveFeedOrigins: splitList(src.get("LIVE_FEED_ORIGINS", "")),
+
+		AccessLogFile:       src.get("ACCESS_LOG_FILE", ""),
+		AccessLogFormat:     src.get("ACCESS_LOG_FORMAT", "json"),
+		AccessLogMaxSizeMB:  src.getInt("ACCESS_LOG_MAX_SIZE_MB", 100),
+		AccessLogMaxBackups: src.getInt("ACCESS_LOG_MAX_BACKUPS", 5),
+
+		SentryDSN:         src.get("SENTRY_DSN", ""),
+		SentryEnvironment: src.get("SENTRY_ENVIRONMENT", "production"),
+		SentryRelease:     src.get("SENTRY_RELEASE", ""),
+	}, nil
+}
+
+// reloadConfig re-reads configuration and applies the settings that can
+// change at runtime. resolver is used for shortener links when the
+// policy becomes "resolve". It returns the configuration now in effect;
+// on error the current configuration is kept.
+func reloadConfig(logger *slog.Logger, current Config, logLevel *slog.LevelVar, linkService *service.LinkService, resolver service.DestinationResolver) Config {
+	next, err := loadConfig()
+	if err != nil {
+		logger.Error("config reload failed, keeping current settings", "error", err)
+		return current
+	}
+
+	// Only these settings are hot-reloadable; everything else is wired
+	// into long-lived components at startup.
+	applied := current
+	if next.LogLevel != current.LogLevel {
+		logLevel.Set(parseLogLevel(next.LogLevel))
+		logger.Info("log level changed", "from", current.LogLevel, "to", next.LogLevel)
+	}
+	applied.LogLevel = next.LogLevel
+	if next.ReadOnly != current.ReadOnly {
+		linkService.SetReadOnly(next.ReadOnly)
+		logger.Info("read-only mode changed", "read_only", next.ReadOnly)
+	}
+	applied.ReadOnly = next.ReadOnly
+	if !slices.Equal(next.ShortenerDomains, current.ShortenerDomains) || next.ShortenerPolicy != current.ShortenerPolicy {
+		if next.ShortenerPolicy != "resolve" {
+			resolver = nil
+		}
+		linkService.SetShortenerPolicy(next.ShortenerDomains, next.ShortenerPolicy == "allow", resolver)
+		logger.Info("shortener settings changed", "policy", next.ShortenerPolicy, "domains", next.ShortenerDomains)
+	}
+	applied.ShortenerDomains = next.ShortenerDomains
+	applied.ShortenerPolicy = next.ShortenerPolicy
+
+	if pending := changedFields(applied, next); len(pending) > 0 {
+		logger.Warn("config reloaded; some changed settings only take effect after a restart", "settings", pending)
+	} else {
+		logger.Info("config reloaded")
+	}
+	return applied
+}
+
+// changedFields returns the names of the Config fields that differ
+// between a and b.
+func changedFields(a, b Config) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var changed []string
+	for i := range va.NumField() {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, va.Type().Field(i).Name)
+		}
+	}
+	return changed
+}
+
+// configSource resolves settings from a config file, falling back to the environment.
+type configSource map[string]string
+
+// get returns the value for key or a default when unset.
+func (s configSource) get(key, defaultValue string) string {
+	if value, ok := s[key]; ok && value != "" {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// secretResolver resolves secret references in settings. It's created on
+// first use, so deployments without references never load AWS config.
+var secretResolver = sync.OnceValues(func() (*secrets.Resolver, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config for secrets: %w", err)
+	}
+	return secrets.New(secrets.Config{Sources: secrets.AWSSources(cfg)}), nil
+})
+
+// secretsTimeout bounds resolving all secret references at load time.
+const secretsTimeout = 10 * time.Second
+
+// resolveSecrets replaces settings whose value is a secret reference, from
+// the config file or the environment, with the secret itself. Secrets
+// are cached, so a reload within the cache TTL doesn't fetch them again.
+func (s configSource) resolveSecrets() error {
+	refs := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, _ := strings.Cut(entry, "="); secrets.IsReference(value) {
+			refs[key] = value
+		}
+	}
+	for key, value := range s {
+		if value == "" {
+			continue
+		}
+		delete(refs, key) // the file's value wins over the environment's
+		if secrets.IsReference(value) {
+			refs[key] = value
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	resolver, err := secretResolver()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+
+	for key, ref := range refs {
+		value, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", key, err)
+		}
+		s[key] = value
+	}
+	return nil
+}
+
+// getInt returns an integer value or a default if unset or invalid.
+func (s configSource) getInt(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(s.get(key, "")); err == nil {
+		return n
+	}
+	return defaultValue
+}
+
+// getFloat returns a float value or a default if unset or invalid.
+func (s configSource) getFloat(key string, defaultValue float64) float64 {
+	if f, err := strconv.ParseFloat(s.get(key, ""), 64); err == nil {
+		return f
+	}
+	return defaultValue
+}
+
+// getBool returns a boolean value or a default if unset or invalid.
+func (s configSource) getBool(key string, defaultValue bool) bool {
+	if b, err := strconv.ParseBool(s.get(key, "")); err == nil {
+		return b
+	}
+	return defaultValue
+}
+
+// readConfigFile parses a dotenv-style file: KEY=VALUE lines, blank lines
+// and # comments ignored, optional surrounding quotes stripped.
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening config file: %w", err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config file %s:%d: expected KEY=VALUE", path, lineNo)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return values, nil
+}
+
+// splitList parses a comma-separated environment value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNetworks parses a comma-separated list of CIDR ranges. A bare
+// address is taken as a range of one.
+func parseNetworks(value string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, item := range splitList(value) {
+		if addr, err := netip.ParseAddr(item); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		network, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
+
+// parseMilestones parses a comma-separated list of click counts. Invalid
+// entries are skipped; an empty list yields nil, so the service defaults
+// apply.
+func parseMilestones(value string) []int64 {
+	var milestones []int64
+	for _, item := range splitList(value) {
+		if n, err := strconv.ParseInt(item, 10, 64); err == nil && n > 0 {
+			milestones = append(milestones, n)
+		}
+	}
+	return milestones
+}
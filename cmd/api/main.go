@@ -3,17 +3,39 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // stats time zones mustn't depend on the host having zoneinfo
 
+	"github.com/colby/snip/internal/accesslog"
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/boltstore"
+	"github.com/colby/snip/internal/clickhouse"
+	"github.com/colby/snip/internal/directory"
+	"github.com/colby/snip/internal/errreport"
+	"github.com/colby/snip/internal/events"
 	"github.com/colby/snip/internal/handler"
+	"github.com/colby/snip/internal/interstitial"
+	"github.com/colby/snip/internal/metering"
+	"github.com/colby/snip/internal/metrics"
+	"github.com/colby/snip/internal/outbound"
 	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/seed"
 	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/internal/thumbnail"
+	"github.com/colby/snip/internal/tracecontext"
+	"github.com/colby/snip/internal/urlscan"
+	"github.com/colby/snip/internal/webhook"
 )
 
 func main() {
@@ -24,122 +46,506 @@ func main() {
 }
 
 func run() error {
-	// Configuration (will be from environment variables later)
-	cfg := Config{
-		Port:       getEnv("PORT", "8080"),
-		BaseURL:    getEnv("BASE_URL", "http://localhost:8080"),
-		LogLevel:   getEnv("LOG_LEVEL", "info"),
-		CodeLength: 7,
+	seedFile := flag.String("seed", "", "YAML fixtures file to preload into the in-memory store")
+	flag.Parse()
+
+	// Configuration from environment variables, optionally overridden by CONFIG_FILE
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
 	}
 
-	// Setup structured logging
-	logger := setupLogger(cfg.LogLevel)
+	// Setup structured logging; the level can be changed at runtime via SIGHUP
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(parseLogLevel(cfg.LogLevel))
+	logger := setupLogger(logLevel)
 
 	logger.Info("starting snip server",
 		"port", cfg.Port,
 		"base_url", cfg.BaseURL,
+		"read_only", cfg.ReadOnly,
+	)
+
+	// Optional access log sink, separate from application logs
+	accessLog, closeAccessLog, err := setupAccessLog(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAccessLog()
+
+	// Error reporting for 5xx responses and recovered panics
+	var reporter errreport.Reporter = errreport.Nop{}
+	if cfg.SentryDSN != "" {
+		sentry, err := errreport.NewSentry(errreport.SentryConfig{
+			DSN:         cfg.SentryDSN,
+			Environment: cfg.SentryEnvironment,
+			Release:     cfg.SentryRelease,
+		})
+		if err != nil {
+			return fmt.Errorf("configuring sentry: %w", err)
+		}
+		defer sentry.Close()
+		reporter = sentry
+	}
+
+	// Initialize repositories: in memory, or in an embedded database file
+	// when a data directory is configured
+	var (
+		linkRepo        repository.LinkRepository        = repository.NewMemoryLinkRepository()
+		clickRepo       repository.ClickRepository       = repository.NewMemoryClickRepository()
+		prefixRepo      repository.PrefixRepository      = repository.NewMemoryPrefixRepository()
+		templateRepo    repository.TemplateRepository    = repository.NewMemoryTemplateRepository()
+		aliasRepo       repository.LinkAliasRepository   = repository.NewMemoryLinkAliasRepository()
+		idempotencyRepo repository.IdempotencyRepository = repository.NewMemoryIdempotencyRepository()
+		apiKeyRepo      repository.APIKeyRepository      = repository.NewMemoryAPIKeyRepository()
+		userRepo        repository.UserRepository        = repository.NewMemoryUserRepository()
+		rollupRepo      repository.StatsRollupRepository = repository.NewMemoryStatsRollupRepository()
+		settings        repository.SettingsRepository
 	)
+	if cfg.DataDir != "" {
+		store, err := boltstore.Open(boltstore.Config{
+			Dir:              cfg.DataDir,
+			CompactFreeRatio: cfg.DataCompactFreeRatio,
+			Logger:           logger,
+		})
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		linkRepo, clickRepo, prefixRepo = store.Links(), store.Clicks(), store.Prefixes()
+		templateRepo, aliasRepo, rollupRepo, settings = store.Templates(), store.Aliases(), store.Rollups(), store.Settings()
+		idempotencyRepo, apiKeyRepo, userRepo = store.Idempotency(), store.APIKeys(), store.Users()
+		logger.Info("storing data on disk", "dir", cfg.DataDir)
+	}
+
+	// Optional ClickHouse click store for analytics on heavy traffic
+	var clickHouse *clickhouse.ClickRepository
+	if cfg.ClickHouseURL != "" {
+		clickHouse = clickhouse.NewClickRepository(clickhouse.Config{
+			URL:           cfg.ClickHouseURL,
+			Database:      cfg.ClickHouseDatabase,
+			Table:         cfg.ClickHouseTable,
+			Username:      cfg.ClickHouseUser,
+			Password:      cfg.ClickHousePassword,
+			BatchSize:     cfg.ClickHouseBatchSize,
+			FlushInterval: time.Duration(cfg.ClickHouseFlushInterval) * time.Second,
+			Logger:        logger,
+		})
+		clickRepo = clickHouse
+		logger.Info("storing click events in clickhouse", "url", cfg.ClickHouseURL)
+	}
+
+	// Optional usage metering for billing
+	var meter metering.Meter
+	var stripe *metering.Stripe
+	if cfg.StripeAPIKey != "" {
+		items := make(map[string]string)
+		if cfg.StripeRedirectsItem != "" {
+			items[metering.MetricRedirects] = cfg.StripeRedirectsItem
+		}
+		if cfg.StripeLinksCreatedItem != "" {
+			items[metering.MetricLinksCreated] = cfg.StripeLinksCreatedItem
+		}
+		var err error
+		stripe, err = metering.NewStripe(metering.StripeConfig{
+			APIKey:            cfg.StripeAPIKey,
+			SubscriptionItems: items,
+			FlushInterval:     time.Duration(cfg.MeteringFlushSeconds) * time.Second,
+			Logger:            logger,
+		})
+		if err != nil {
+			return fmt.Errorf("configuring Stripe metering: %w", err)
+		}
+		meter = stripe
+		logger.Info("reporting usage to Stripe", "metrics", len(items))
+	}
+
+	if *seedFile != "" {
+		fixtures, err := seed.LoadFile(*seedFile)
+		if err != nil {
+			return err
+		}
+		if err := seed.Apply(context.Background(), fixtures, linkRepo, clickRepo, time.Now()); err != nil {
+			return err
+		}
+		logger.Info("loaded seed data", "file", *seedFile, "links", len(fixtures.Links))
+	}
+
+	// Event bus feeding live consumers (WebSocket dashboard, webhooks)
+	bus := events.NewBus()
+
+	// Optional webhook for milestone notifications and other events
+	var notifier *webhook.Notifier
+	if cfg.WebhookURL != "" {
+		notifier = webhook.New(webhook.Config{
+			URL:          cfg.WebhookURL,
+			Secret:       cfg.WebhookSecret,
+			AllowPrivate: cfg.WebhookAllowPrivate,
+			Events:       cfg.WebhookEvents,
+			Logger:       logger,
+		})
+		notifier.Subscribe(bus)
+		logger.Info("delivering events to webhook", "url", cfg.WebhookURL)
+	}
+
+	// Settings the service changes itself, such as a grown code length
+	if cfg.SettingsFile != "" {
+		settings = repository.NewFileSettingsRepository(cfg.SettingsFile)
+	}
+
+	// Outbound requests to destinations: optional redirect resolution at
+	// create time, and reachability checks for links created with verify
+	outboundResolver := outbound.NewResolver(outbound.NewClient(outbound.ClientConfig{}), cfg.ResolveMaxHops)
+	var resolver, shortenerResolver service.DestinationResolver
+	if cfg.ResolveRedirects {
+		resolver = outboundResolver
+	}
+	if cfg.ShortenerPolicy == "resolve" {
+		shortenerResolver = outboundResolver
+	}
 
-	// Initialize repositories (in-memory for now, will be DynamoDB later)
-	linkRepo := repository.NewMemoryLinkRepository()
-	clickRepo := repository.NewMemoryClickRepository()
+	// Optional background reputation scanning of destinations
+	var scanner service.URLScanner
+	if cfg.VirusTotalAPIKey != "" {
+		scanner = urlscan.NewVirusTotal(urlscan.VirusTotalConfig{
+			APIKey:        cfg.VirusTotalAPIKey,
+			MinDetections: cfg.VirusTotalMinDetections,
+		})
+	}
+
+	// Optional destination thumbnails for dashboards
+	var thumbnailCapturer service.ThumbnailCapturer
+	var thumbnails repository.ThumbnailRepository
+	if cfg.ThumbnailEndpoint != "" {
+		thumbnailCapturer = thumbnail.NewHTTP(thumbnail.HTTPConfig{Endpoint: cfg.ThumbnailEndpoint})
+		thumbnails = repository.NewDirThumbnailRepository(cfg.ThumbnailDir)
+	}
 
 	// Initialize service
 	linkService := service.NewLinkService(linkRepo, clickRepo, service.LinkServiceConfig{
-		BaseURL:    cfg.BaseURL,
-		CodeLength: cfg.CodeLength,
-		MaxRetries: 5,
+		BaseURL:              cfg.BaseURL,
+		CodeLength:           cfg.CodeLength,
+		MaxRetries:           5,
+		ReadOnly:             cfg.ReadOnly,
+		CodeLengthGrowRate:   cfg.CodeLengthGrowRate,
+		Settings:             settings,
+		Prefixes:             prefixRepo,
+		Templates:            templateRepo,
+		Aliases:              aliasRepo,
+		Idempotency:          idempotencyRepo,
+		Resolver:             resolver,
+		ShortenerDomains:     cfg.ShortenerDomains,
+		AllowShorteners:      cfg.ShortenerPolicy == "allow",
+		ShortenerResolver:    shortenerResolver,
+		Verifier:             outboundResolver,
+		Rollups:              rollupRepo,
+		Scanner:              scanner,
+		PhishingWarnScore:    cfg.PhishingWarnScore,
+		PhishingBlockScore:   cfg.PhishingBlockScore,
+		ThumbnailCapturer:    thumbnailCapturer,
+		Thumbnails:           thumbnails,
+		CaseInsensitiveCodes: cfg.CaseInsensitiveCodes,
+		DedupeDestinations:   cfg.DedupeDestinations,
+		Milestones:           cfg.Milestones,
+		DeleteGracePeriod:    time.Duration(cfg.DeleteGraceDays) * 24 * time.Hour,
+		ClickDedupeWindow:    time.Duration(cfg.ClickDedupeSeconds) * time.Second,
+		NotFoundCacheTTL:     time.Duration(cfg.NotFoundCacheSeconds) * time.Second,
+		ClickRecorder:        service.AsyncClickRecorder{Timeout: time.Duration(cfg.ClickRecordTimeoutMS) * time.Millisecond},
+		CountPrefetches:      cfg.CountPrefetches,
+		HonorDoNotTrack:      cfg.HonorDoNotTrack,
+		Events:               bus,
+		Meter:                meter,
+		Logger:               logger,
 	})
 
+	// Optional API keys on the management API, issued through the admin
+	// endpoints
+	var apiKeys *auth.Keys
+	if cfg.RequireAPIKeys {
+		if cfg.AdminToken == "" {
+			return errors.New("REQUIRE_API_KEYS needs ADMIN_TOKEN, since keys are issued through the admin endpoints")
+		}
+		apiKeys = auth.New(apiKeyRepo)
+		logger.Info("API keys required on /api endpoints")
+	}
+
+	// Optional user accounts, signing in for tokens the /api endpoints
+	// then require
+	var users *auth.Users
+	if cfg.JWTSecret != "" {
+		users, err = auth.NewUsers(userRepo, auth.UsersConfig{
+			Secret:   []byte(cfg.JWTSecret),
+			TokenTTL: time.Duration(cfg.JWTTTLHours) * time.Hour,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid JWT_SECRET: %w", err)
+		}
+		logger.Info("user accounts enabled; tokens required on /api endpoints")
+	}
+
 	// Initialize handlers
-	h := handler.New(linkService, logger)
+	h := handler.New(linkService, logger, handler.Config{
+		ErrorReporter:   reporter,
+		APIKeys:         apiKeys,
+		Users:           users,
+		AdminToken:      cfg.AdminToken,
+		AdminNetworks:   cfg.AdminNetworks,
+		AdminClientCert: cfg.AdminClientCert,
+		TrustedProxies:  cfg.TrustedProxies,
+		Interstitial: interstitial.Config{
+			Brand:   cfg.InterstitialBrand,
+			Seconds: cfg.InterstitialSeconds,
+		},
+		PublicDirectory: cfg.PublicDirectory,
+		Directory:       directory.Config{Title: cfg.DirectoryTitle},
+	})
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	h.RegisterRoutes(mux)
+	if err := linkService.CheckBaseURL(); err != nil {
+		return fmt.Errorf("invalid BASE_URL: %w", err)
+	}
+
+	// The live feed is only exposed when a token is configured
+	if cfg.LiveFeedToken != "" {
+		mux.Handle("GET /api/ws", handler.NewLiveFeed(bus, cfg.LiveFeedToken, cfg.LiveFeedOrigins, logger))
+	}
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      loggingMiddleware(logger, mux),
+		Handler:      tracecontext.Middleware(loggingMiddleware(logger, accessLog, h.Recover(mux))),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	tlsConfig, err := setupTLS(cfg)
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = tlsConfig
+
+	// Metrics get their own listener so they can stay off the public port
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /debug/vars", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		logger.Info("serving metrics", "addr", cfg.MetricsAddr)
+	}
+
+	// Deleted links past their grace period and expired idempotency keys
+	// are purged in the background
+	stopPurges := make(chan struct{})
+	defer close(stopPurges)
+	if cfg.DeleteGraceDays > 0 {
+		go purgePeriodically("deleted links", linkService.PurgeDeletedLinks, logger, stopPurges)
+	}
+	go purgePeriodically("idempotency keys", linkService.PurgeIdempotencyKeys, logger, stopPurges)
 
 	// Graceful shutdown
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
+	if metricsServer != nil {
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("metrics: %w", err)
+			}
+		}()
+	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, reloading configuration on SIGHUP
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	select {
-	case err := <-errCh:
-		return fmt.Errorf("server error: %w", err)
-	case sig := <-quit:
-		logger.Info("received shutdown signal", "signal", sig)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+wait:
+	for {
+		select {
+		case err := <-errCh:
+			return fmt.Errorf("server error: %w", err)
+		case <-hup:
+			cfg = reloadConfig(logger, cfg, logLevel, linkService, outboundResolver)
+		case sig := <-quit:
+			logger.Info("received shutdown signal", "signal", sig)
+			break wait
+		}
 	}
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown error: %w", err)
 	}
 
+	// Clicks recorded by in-flight redirects are flushed last
+	if clickHouse != nil {
+		if err := clickHouse.Close(ctx); err != nil {
+			logger.Error("failed to flush click events to clickhouse", "error", err)
+		}
+	}
+	if stripe != nil {
+		if err := stripe.Close(ctx); err != nil {
+			logger.Error("failed to report remaining usage to Stripe", "error", err)
+		}
+	}
+	if notifier != nil {
+		if err := notifier.Close(ctx); err != nil {
+			logger.Error("failed to deliver pending webhook events", "error", err)
+		}
+	}
+
 	logger.Info("server stopped gracefully")
 	return nil
 }
 
-// Config holds server configuration.
-type Config struct {
-	Port       string
-	BaseURL    string
-	LogLevel   string
-	CodeLength int
-}
+// purgeInterval is how often soft-deleted links and expired idempotency
+// keys are checked for purging.
+const purgeInterval = time.Hour
 
-// getEnv returns the value of an environment variable or a default.
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// purgePeriodically runs purge every purgeInterval until stop is closed.
+// what names the purged records in logs, e.g. "deleted links".
+func purgePeriodically(what string, purge func(context.Context) (int, error), logger *slog.Logger, stop <-chan struct{}) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		removed, err := purge(context.Background())
+		if err != nil {
+			logger.Error("failed to purge "+what, "error", err)
+			continue
+		}
+		if removed > 0 {
+			logger.Info("purged "+what, "removed", removed)
+		}
 	}
-	return defaultValue
 }
 
-// setupLogger creates a structured logger with the specified level.
-func setupLogger(level string) *slog.Logger {
-	var logLevel slog.Level
+// parseLogLevel maps a LOG_LEVEL value to a slog level, defaulting to info.
+func parseLogLevel(level string) slog.Level {
 	switch level {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
+		return slog.LevelError
 	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	}
+}
 
+// setupLogger creates a structured logger whose level follows level.
+func setupLogger(level slog.Leveler) *slog.Logger {
 	opts := &slog.HandlerOptions{
-		Level: logLevel,
+		Level: level,
 	}
 
-	// Use JSON handler for structured logs (better for production/observability)
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	// Use JSON handler for structured logs (better for production/observability),
+	// tagging records with the request's trace ID when one is in context
+	handler := tracecontext.NewLogHandler(slog.NewJSONHandler(os.Stdout, opts))
 	return slog.New(handler)
 }
 
-// loggingMiddleware logs HTTP requests.
-func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+// setupAccessLog opens the configured access log sink. It returns a nil
+// logger when access logging is disabled, plus a cleanup function that is
+// always safe to call.
+func setupAccessLog(cfg Config) (*accesslog.Logger, func(), error) {
+	noop := func() {}
+	if cfg.AccessLogFile == "" {
+		return nil, noop, nil
+	}
+
+	format, err := accesslog.ParseFormat(cfg.AccessLogFormat)
+	if err != nil {
+		return nil, noop, err
+	}
+
+	var w io.Writer
+	closeFn := noop
+	switch cfg.AccessLogFile {
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		rf, err := accesslog.OpenRotatingFile(cfg.AccessLogFile, int64(cfg.AccessLogMaxSizeMB)<<20, cfg.AccessLogMaxBackups)
+		if err != nil {
+			return nil, noop, err
+		}
+		w = rf
+		closeFn = func() { _ = rf.Close() }
+	}
+
+	return accesslog.New(w, format), closeFn, nil
+}
+
+// setupTLS builds the server's TLS settings. It returns nil when HTTPS
+// isn't configured. With a client CA, certificates are verified when
+// presented but not demanded, so public redirects still work; the admin
+// endpoints can then insist on one.
+func setupTLS(cfg Config) (*tls.Config, error) {
+	if cfg.TLSCertFile == "" {
+		if cfg.AdminClientCert {
+			return nil, errors.New("ADMIN_REQUIRE_CLIENT_CERT needs TLS_CERT_FILE and TLS_CLIENT_CA_FILE")
+		}
+		return nil, nil
+	}
+	if cfg.TLSKeyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE needs TLS_KEY_FILE")
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSClientCAFile == "" {
+		if cfg.AdminClientCert {
+			return nil, errors.New("ADMIN_REQUIRE_CLIENT_CERT needs TLS_CLIENT_CA_FILE")
+		}
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading TLS_CLIENT_CA_FILE: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("TLS_CLIENT_CA_FILE contains no certificates")
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
+
+// loggingMiddleware logs HTTP requests to the application logger and, when
+// configured, to the dedicated access log.
+func loggingMiddleware(logger *slog.Logger, accessLog *accesslog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -150,23 +556,57 @@ func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		logger.Info("http request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", wrapped.statusCode,
-			"duration_ms", duration.Milliseconds(),
-			"user_agent", r.UserAgent(),
+		// LogAttrs skips boxing each value into an interface, on every request
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Int64("duration_ms", duration.Milliseconds()),
+			slog.String("user_agent", r.UserAgent()),
 		)
+
+		if accessLog != nil {
+			tc, _ := tracecontext.FromContext(r.Context())
+			err := accessLog.Log(accesslog.Entry{
+				Time:       start,
+				RemoteAddr: r.RemoteAddr,
+				Method:     r.Method,
+				Path:       r.URL.RequestURI(),
+				Proto:      r.Proto,
+				Status:     wrapped.statusCode,
+				Bytes:      wrapped.bytes,
+				Duration:   duration,
+				Referrer:   r.Referer(),
+				UserAgent:  r.UserAgent(),
+				TraceID:    tc.TraceID,
+			})
+			if err != nil {
+				logger.WarnContext(r.Context(), "failed to write access log", "error", err)
+			}
+		}
 	})
 }
 
-// responseWriter wraps http.ResponseWriter to capture the status code.
+// responseWriter wraps http.ResponseWriter to capture the status code and body size.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer so http.ResponseController and
+// WebSocket upgrades can reach the Hijacker/Flusher implementations.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
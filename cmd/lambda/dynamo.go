@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/colby/snip/internal/model"
@@ -22,24 +28,88 @@ type DynamoLinkRepository struct {
 
 // NewDynamoLinkRepository creates a new DynamoDB-backed link repository.
 func NewDynamoLinkRepository(tableName string) *DynamoLinkRepository {
-	cfg, err := config.LoadDefaultConfig(context.Background())
+	return &DynamoLinkRepository{
+		client:    dynamoClient(),
+		tableName: tableName,
+	}
+}
+
+// awsConfig is the AWS config shared by every client in the function,
+// loaded on first use. Loading resolves the region and credential chain,
+// which is a noticeable share of a cold start, so it's done once.
+var awsConfig = sync.OnceValue(loadAWSConfig)
+
+// dynamoClient is the DynamoDB client shared by all repositories, created
+// on first use. Clients are safe for concurrent use and pool connections,
+// so one client lets the repositories reuse warm connections.
+var dynamoClient = sync.OnceValue(newDynamoClient)
+
+// loadAWSConfig loads the default AWS config.
+//
+// DYNAMODB_ENDPOINT points the client at DynamoDB Local or LocalStack
+// (e.g., http://localhost:8000). DYNAMODB_ACCESS_KEY_ID and
+// DYNAMODB_SECRET_ACCESS_KEY supply static credentials; when an endpoint
+// override is set without them, dummy credentials are used since local
+// emulators don't validate them.
+func loadAWSConfig() aws.Config {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	accessKey := os.Getenv("DYNAMODB_ACCESS_KEY_ID")
+	secretKey := os.Getenv("DYNAMODB_SECRET_ACCESS_KEY")
+
+	var opts []func(*config.LoadOptions) error
+	if endpoint != "" {
+		// Emulators accept any region, but the SDK still requires one
+		opts = append(opts, config.WithDefaultRegion("us-east-1"))
+		if accessKey == "" {
+			accessKey, secretKey = "local", "local"
+		}
+	}
+	if accessKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		panic(fmt.Sprintf("failed to load AWS config: %v", err))
 	}
+	return cfg
+}
 
-	return &DynamoLinkRepository{
-		client:    dynamodb.NewFromConfig(cfg),
-		tableName: tableName,
-	}
+// newDynamoClient creates a DynamoDB client from the shared AWS config.
+func newDynamoClient() *dynamodb.Client {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	return dynamodb.NewFromConfig(awsConfig(), func(o *dynamodb.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 }
 
 // Create stores a new link in DynamoDB.
 func (r *DynamoLinkRepository) Create(ctx context.Context, link *model.Link) error {
 	item := map[string]types.AttributeValue{
-		"short_code":   &types.AttributeValueMemberS{Value: link.ShortCode},
-		"original_url": &types.AttributeValueMemberS{Value: link.OriginalURL},
-		"created_at":   &types.AttributeValueMemberS{Value: link.CreatedAt.Format(time.RFC3339)},
-		"click_count":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", link.ClickCount)},
+		"short_code":        &types.AttributeValueMemberS{Value: link.ShortCode},
+		"id":                &types.AttributeValueMemberS{Value: link.ID},
+		"original_url":      &types.AttributeValueMemberS{Value: link.OriginalURL},
+		"url_hash":          &types.AttributeValueMemberS{Value: repository.DestinationKey(link.OriginalURL)},
+		"created_at":        &types.AttributeValueMemberS{Value: link.CreatedAt.Format(time.RFC3339)},
+		"click_count":       &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", link.ClickCount)},
+		"pinned":            &types.AttributeValueMemberBOOL{Value: link.Pinned},
+		"notes":             &types.AttributeValueMemberS{Value: link.Notes},
+		"wildcard":          &types.AttributeValueMemberBOOL{Value: link.Wildcard},
+		"disabled":          &types.AttributeValueMemberBOOL{Value: link.Disabled},
+		"scan_status":       &types.AttributeValueMemberS{Value: link.ScanStatus},
+		"interstitial":      &types.AttributeValueMemberBOOL{Value: link.Interstitial},
+		"notify_milestones": &types.AttributeValueMemberBOOL{Value: link.NotifyMilestones},
+		"allowed_referrers": stringList(link.AllowedReferrers),
+		"public":            &types.AttributeValueMemberBOOL{Value: link.Public},
+		"title":             &types.AttributeValueMemberS{Value: link.Title},
+		"version":           &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", link.Version)},
+	}
+	if link.OwnerID != "" {
+		item["owner_id"] = &types.AttributeValueMemberS{Value: link.OwnerID}
 	}
 
 	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
@@ -85,12 +155,93 @@ func (r *DynamoLinkRepository) GetByShortCode(ctx context.Context, shortCode str
 	return link, nil
 }
 
+// warmupKey is a short code no link can have (codes and aliases can't
+// start with an underscore), read to warm the client without matching
+// anything.
+const warmupKey = "_warmup"
+
+// Warm makes one cheap read so credentials are resolved and a connection
+// to DynamoDB is open before the first request needs them. It only needs
+// the GetItem permission the repository already has.
+func (r *DynamoLinkRepository) Warm(ctx context.Context) error {
+	_, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"short_code": &types.AttributeValueMemberS{Value: warmupKey},
+		},
+		ProjectionExpression: aws.String("short_code"),
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb get item: %w", err)
+	}
+	return nil
+}
+
+// batchGetLimit is the most keys DynamoDB accepts in one BatchGetItem call.
+const batchGetLimit = 100
+
+// GetByShortCodes retrieves several links with BatchGetItem, 100 keys per
+// call, retrying any keys DynamoDB leaves unprocessed.
+func (r *DynamoLinkRepository) GetByShortCodes(ctx context.Context, shortCodes []string) (map[string]*model.Link, error) {
+	result := make(map[string]*model.Link, len(shortCodes))
+
+	seen := make(map[string]bool, len(shortCodes))
+	var keys []map[string]types.AttributeValue
+	for _, code := range shortCodes {
+		if seen[code] {
+			continue // BatchGetItem rejects duplicate keys
+		}
+		seen[code] = true
+		keys = append(keys, map[string]types.AttributeValue{
+			"short_code": &types.AttributeValueMemberS{Value: code},
+		})
+	}
+
+	for len(keys) > 0 {
+		n := min(len(keys), batchGetLimit)
+		pending := map[string]types.KeysAndAttributes{
+			r.tableName: {Keys: keys[:n]},
+		}
+		keys = keys[n:]
+
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt > 0 {
+				// Unprocessed keys mean we're being throttled; back off
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Duration(attempt) * 50 * time.Millisecond):
+				}
+			}
+
+			out, err := r.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, fmt.Errorf("dynamodb batch get item: %w", err)
+			}
+
+			for _, item := range out.Responses[r.tableName] {
+				link, err := itemToLink(item)
+				if err != nil {
+					return nil, fmt.Errorf("parsing link: %w", err)
+				}
+				result[link.ShortCode] = link
+			}
+			pending = out.UnprocessedKeys
+		}
+	}
+
+	return result, nil
+}
+
 // itemToLink converts a DynamoDB item to a Link model.
 func itemToLink(item map[string]types.AttributeValue) (*model.Link, error) {
 	link := &model.Link{}
 
 	if v, ok := item["short_code"].(*types.AttributeValueMemberS); ok {
 		link.ShortCode = v.Value
+		link.ID = v.Value // items written before links had their own ID
+	}
+	if v, ok := item["id"].(*types.AttributeValueMemberS); ok && v.Value != "" {
 		link.ID = v.Value
 	}
 
@@ -112,12 +263,345 @@ func itemToLink(item map[string]types.AttributeValue) (*model.Link, error) {
 		link.ClickCount = count
 	}
 
+	if v, ok := item["pinned"].(*types.AttributeValueMemberBOOL); ok {
+		link.Pinned = v.Value
+	}
+
+	if v, ok := item["notes"].(*types.AttributeValueMemberS); ok {
+		link.Notes = v.Value
+	}
+
+	if v, ok := item["wildcard"].(*types.AttributeValueMemberBOOL); ok {
+		link.Wildcard = v.Value
+	}
+
+	if v, ok := item["disabled"].(*types.AttributeValueMemberBOOL); ok {
+		link.Disabled = v.Value
+	}
+
+	if v, ok := item["scan_status"].(*types.AttributeValueMemberS); ok {
+		link.ScanStatus = v.Value
+	}
+
+	if v, ok := item["interstitial"].(*types.AttributeValueMemberBOOL); ok {
+		link.Interstitial = v.Value
+	}
+
+	if v, ok := item["notify_milestones"].(*types.AttributeValueMemberBOOL); ok {
+		link.NotifyMilestones = v.Value
+	}
+
+	if v, ok := item["public"].(*types.AttributeValueMemberBOOL); ok {
+		link.Public = v.Value
+	}
+	if v, ok := item["title"].(*types.AttributeValueMemberS); ok {
+		link.Title = v.Value
+	}
+	if v, ok := item["owner_id"].(*types.AttributeValueMemberS); ok {
+		link.OwnerID = v.Value
+	}
+
+	if v, ok := item["allowed_referrers"].(*types.AttributeValueMemberL); ok {
+		for _, host := range v.Value {
+			if s, ok := host.(*types.AttributeValueMemberS); ok {
+				link.AllowedReferrers = append(link.AllowedReferrers, s.Value)
+			}
+		}
+	}
+
+	// Restored links keep an empty deleted_at
+	if v, ok := item["deleted_at"].(*types.AttributeValueMemberS); ok && v.Value != "" {
+		t, err := time.Parse(time.RFC3339, v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing deleted_at: %w", err)
+		}
+		link.DeletedAt = &t
+	}
+
+	// Items written before versioning have no version attribute and read as 0
+	if v, ok := item["version"].(*types.AttributeValueMemberN); ok {
+		var version int64
+		_, _ = fmt.Sscanf(v.Value, "%d", &version)
+		link.Version = version
+	}
+
 	return link, nil
 }
 
-// IncrementClickCount atomically increments the click count for a link.
-func (r *DynamoLinkRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+// Update replaces the mutable fields of an existing link if its stored
+// version still matches link.Version.
+func (r *DynamoLinkRepository) Update(ctx context.Context, link *model.Link) error {
+	key := map[string]types.AttributeValue{
+		"short_code": &types.AttributeValueMemberS{Value: link.ShortCode},
+	}
+
 	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           &r.tableName,
+		Key:                 key,
+		UpdateExpression:    aws.String("SET original_url = :url, url_hash = :url_hash, pinned = :pinned, notes = :notes, disabled = :disabled, scan_status = :scan_status, interstitial = :interstitial, notify_milestones = :notify_milestones, allowed_referrers = :allowed_referrers, public = :public, title = :title, deleted_at = :deleted_at, version = :next"),
+		ConditionExpression: aws.String(versionCondition(link.Version)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":url":               &types.AttributeValueMemberS{Value: link.OriginalURL},
+			":url_hash":          &types.AttributeValueMemberS{Value: repository.DestinationKey(link.OriginalURL)},
+			":pinned":            &types.AttributeValueMemberBOOL{Value: link.Pinned},
+			":notes":             &types.AttributeValueMemberS{Value: link.Notes},
+			":disabled":          &types.AttributeValueMemberBOOL{Value: link.Disabled},
+			":scan_status":       &types.AttributeValueMemberS{Value: link.ScanStatus},
+			":interstitial":      &types.AttributeValueMemberBOOL{Value: link.Interstitial},
+			":notify_milestones": &types.AttributeValueMemberBOOL{Value: link.NotifyMilestones},
+			":allowed_referrers": stringList(link.AllowedReferrers),
+			":public":            &types.AttributeValueMemberBOOL{Value: link.Public},
+			":title":             &types.AttributeValueMemberS{Value: link.Title},
+			":deleted_at":        &types.AttributeValueMemberS{Value: formatDeletedAt(link.DeletedAt)},
+			":expected":          &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", link.Version)},
+			":next":              &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", link.Version+1)},
+		},
+	})
+
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if ok := errors.As(err, &condErr); ok {
+			return r.conditionFailure(ctx, link.ShortCode)
+		}
+		return fmt.Errorf("dynamodb update item: %w", err)
+	}
+
+	link.Version++
+	return nil
+}
+
+// stringList encodes strings as a DynamoDB list. Unlike a string set, a
+// list may be empty.
+func stringList(values []string) *types.AttributeValueMemberL {
+	list := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, len(values))}
+	for i, v := range values {
+		list.Value[i] = &types.AttributeValueMemberS{Value: v}
+	}
+	return list
+}
+
+// formatDeletedAt encodes a link's deletion time, or "" for a live link.
+func formatDeletedAt(deletedAt *time.Time) string {
+	if deletedAt == nil {
+		return ""
+	}
+	return deletedAt.UTC().Format(time.RFC3339)
+}
+
+// List returns one page of a table scan, so links come in DynamoDB's
+// hash order. The cursor is the short code of the last link scanned;
+// limit 0 lets DynamoDB pick the page size (up to 1 MB of items).
+func (r *DynamoLinkRepository) List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+	return r.scanPage(ctx, &dynamodb.ScanInput{TableName: &r.tableName}, cursor, limit)
+}
+
+// ownerIndex is the link table's global secondary index on owner_id,
+// sorted by created_at and projecting all attributes. Links without an
+// owner aren't in it.
+const ownerIndex = "owner_id-index"
+
+// ListByOwner queries ownerIndex for a page of the links owned by
+// ownerID, oldest first, so it reads only that owner's links. The cursor
+// is the created_at and short code of the last link, joined by '/'.
+func (r *DynamoLinkRepository) ListByOwner(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	return r.ownerPage(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String(ownerIndex),
+		KeyConditionExpression: aws.String("owner_id = :owner_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner_id": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	}, ownerID, cursor, limit)
+}
+
+// ListPinned is List filtered to pinned links. With an ownerID it's
+// ListByOwner filtered to pinned links, reading only that owner's links;
+// without one it scans the whole table. Either way the filter applies
+// after limit, so pages may come back short, or empty with a cursor to go
+// on from.
+func (r *DynamoLinkRepository) ListPinned(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	values := map[string]types.AttributeValue{
+		":pinned": &types.AttributeValueMemberBOOL{Value: true},
+	}
+	if ownerID == "" {
+		return r.scanPage(ctx, &dynamodb.ScanInput{
+			TableName:                 &r.tableName,
+			FilterExpression:          aws.String("pinned = :pinned"),
+			ExpressionAttributeValues: values,
+		}, cursor, limit)
+	}
+	values[":owner_id"] = &types.AttributeValueMemberS{Value: ownerID}
+	return r.ownerPage(ctx, &dynamodb.QueryInput{
+		TableName:                 &r.tableName,
+		IndexName:                 aws.String(ownerIndex),
+		KeyConditionExpression:    aws.String("owner_id = :owner_id"),
+		FilterExpression:          aws.String("pinned = :pinned"),
+		ExpressionAttributeValues: values,
+	}, ownerID, cursor, limit)
+}
+
+// ownerPage runs one page of input, a query on ownerIndex for ownerID,
+// starting after the link at cursor.
+func (r *DynamoLinkRepository) ownerPage(ctx context.Context, input *dynamodb.QueryInput, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	if cursor != "" {
+		createdAt, shortCode, ok := strings.Cut(cursor, "/")
+		if !ok {
+			return nil, "", fmt.Errorf("invalid owner cursor %q", cursor)
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"owner_id":   &types.AttributeValueMemberS{Value: ownerID},
+			"created_at": &types.AttributeValueMemberS{Value: createdAt},
+			"short_code": &types.AttributeValueMemberS{Value: shortCode},
+		}
+	}
+
+	out, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("dynamodb query owner links: %w", err)
+	}
+
+	links := make([]*model.Link, 0, len(out.Items))
+	for _, item := range out.Items {
+		link, err := itemToLink(item)
+		if err != nil {
+			return nil, "", err
+		}
+		links = append(links, link)
+	}
+
+	next := ""
+	createdAt, _ := out.LastEvaluatedKey["created_at"].(*types.AttributeValueMemberS)
+	shortCode, _ := out.LastEvaluatedKey["short_code"].(*types.AttributeValueMemberS)
+	if createdAt != nil && shortCode != nil {
+		next = createdAt.Value + "/" + shortCode.Value
+	}
+	return links, next, nil
+}
+
+// scanPage runs one page of input, starting after the link at cursor.
+func (r *DynamoLinkRepository) scanPage(ctx context.Context, input *dynamodb.ScanInput, cursor string, limit int) ([]*model.Link, string, error) {
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	if cursor != "" {
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"short_code": &types.AttributeValueMemberS{Value: cursor},
+		}
+	}
+
+	out, err := r.client.Scan(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("dynamodb scan links: %w", err)
+	}
+
+	links := make([]*model.Link, 0, len(out.Items))
+	for _, item := range out.Items {
+		link, err := itemToLink(item)
+		if err != nil {
+			return nil, "", err
+		}
+		links = append(links, link)
+	}
+
+	next := ""
+	if v, ok := out.LastEvaluatedKey["short_code"].(*types.AttributeValueMemberS); ok {
+		next = v.Value
+	}
+	return links, next, nil
+}
+
+// DeletedBefore returns soft-deleted links deleted before cutoff, scanning
+// the whole link table. RFC 3339 UTC times compare correctly as strings.
+func (r *DynamoLinkRepository) DeletedBefore(ctx context.Context, cutoff time.Time) ([]*model.Link, error) {
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName:        &r.tableName,
+		FilterExpression: aws.String("deleted_at > :empty AND deleted_at < :cutoff"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":empty":  &types.AttributeValueMemberS{Value: ""},
+			":cutoff": &types.AttributeValueMemberS{Value: cutoff.UTC().Format(time.RFC3339)},
+		},
+	})
+
+	var links []*model.Link
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dynamodb scan links: %w", err)
+		}
+		for _, item := range out.Items {
+			link, err := itemToLink(item)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+// destinationIndex is the link table's global secondary index on
+// url_hash, projecting all attributes.
+const destinationIndex = "url_hash-index"
+
+// GetByDestination queries destinationIndex for the links that redirect to
+// originalURL. Links written before url_hash was stored aren't in the
+// index until they're next updated.
+func (r *DynamoLinkRepository) GetByDestination(ctx context.Context, originalURL string) ([]*model.Link, error) {
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String(destinationIndex),
+		KeyConditionExpression: aws.String("url_hash = :url_hash"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":url_hash": &types.AttributeValueMemberS{Value: repository.DestinationKey(originalURL)},
+		},
+	})
+
+	links := []*model.Link{}
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dynamodb query destination: %w", err)
+		}
+		for _, item := range out.Items {
+			link, err := itemToLink(item)
+			if err != nil {
+				return nil, err
+			}
+			// Index reads are eventually consistent and may lag an update
+			if link.OriginalURL == originalURL {
+				links = append(links, link)
+			}
+		}
+	}
+	return links, nil
+}
+
+// versionCondition builds a condition expression requiring the item to exist
+// with the version bound to :expected. Version 0 also matches items written
+// before versioning, which have no version attribute.
+func versionCondition(expected int64) string {
+	if expected == 0 {
+		return "attribute_exists(short_code) AND (attribute_not_exists(version) OR version = :expected)"
+	}
+	return "attribute_exists(short_code) AND version = :expected"
+}
+
+// conditionFailure tells apart the two reasons a conditional write on a
+// link can fail: the item is gone, or its version moved on.
+func (r *DynamoLinkRepository) conditionFailure(ctx context.Context, shortCode string) error {
+	if _, err := r.GetByShortCode(ctx, shortCode); err != nil {
+		return err
+	}
+	return repository.ErrConflict
+}
+
+// IncrementClickCount atomically increments the click count for a link
+// and returns the new count.
+func (r *DynamoLinkRepository) IncrementClickCount(ctx context.Context, shortCode string) (int64, error) {
+	out, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: &r.tableName,
 		Key: map[string]types.AttributeValue{
 			"short_code": &types.AttributeValueMemberS{Value: shortCode},
@@ -126,29 +610,68 @@ func (r *DynamoLinkRepository) IncrementClickCount(ctx context.Context, shortCod
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":inc": &types.AttributeValueMemberN{Value: "1"},
 		},
+		ReturnValues: types.ReturnValueUpdatedNew,
 	})
 
 	if err != nil {
-		return fmt.Errorf("dynamodb update item: %w", err)
+		return 0, fmt.Errorf("dynamodb update item: %w", err)
 	}
 
+	var count int64
+	if v, ok := out.Attributes["click_count"].(*types.AttributeValueMemberN); ok {
+		_, _ = fmt.Sscanf(v.Value, "%d", &count)
+	}
+	return count, nil
+}
+
+// AddClickCount atomically adds delta to a link's click count.
+func (r *DynamoLinkRepository) AddClickCount(ctx context.Context, shortCode string, delta int64) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"short_code": &types.AttributeValueMemberS{Value: shortCode},
+		},
+		UpdateExpression:    aws.String("ADD click_count :delta"),
+		ConditionExpression: aws.String("attribute_exists(short_code)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":delta": &types.AttributeValueMemberN{Value: fmt.Sprint(delta)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return repository.ErrNotFound
+		}
+		return fmt.Errorf("dynamodb update item: %w", err)
+	}
 	return nil
 }
 
 // Delete removes a link by its short code.
-func (r *DynamoLinkRepository) Delete(ctx context.Context, shortCode string) error {
-	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+func (r *DynamoLinkRepository) Delete(ctx context.Context, shortCode string, expectedVersion int64) error {
+	input := &dynamodb.DeleteItemInput{
 		TableName: &r.tableName,
 		Key: map[string]types.AttributeValue{
 			"short_code": &types.AttributeValueMemberS{Value: shortCode},
 		},
 		ConditionExpression: aws.String("attribute_exists(short_code)"),
-	})
+	}
+	if expectedVersion != 0 {
+		input.ConditionExpression = aws.String(versionCondition(expectedVersion))
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":expected": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expectedVersion)},
+		}
+	}
+
+	_, err := r.client.DeleteItem(ctx, input)
 
 	if err != nil {
 		var condErr *types.ConditionalCheckFailedException
 		if ok := errors.As(err, &condErr); ok {
-			return repository.ErrNotFound
+			if expectedVersion == 0 {
+				return repository.ErrNotFound
+			}
+			return r.conditionFailure(ctx, shortCode)
 		}
 		return fmt.Errorf("dynamodb delete item: %w", err)
 	}
@@ -157,37 +680,414 @@ func (r *DynamoLinkRepository) Delete(ctx context.Context, shortCode string) err
 }
 
 // DynamoClickRepository implements repository.ClickRepository using DynamoDB.
+// Click events live in their own table, partitioned by link_id and sorted
+// by id; IDs are ULIDs, so sort order is click order.
 type DynamoClickRepository struct {
 	client    *dynamodb.Client
 	tableName string
 }
 
 // NewDynamoClickRepository creates a new DynamoDB-backed click repository.
+// With an empty tableName, individual events are not kept: only the link's
+// click count is tracked and GetByLinkID returns nothing.
 func NewDynamoClickRepository(tableName string) *DynamoClickRepository {
-	cfg, err := config.LoadDefaultConfig(context.Background())
-	if err != nil {
-		panic(fmt.Sprintf("failed to load AWS config: %v", err))
-	}
-
 	return &DynamoClickRepository{
-		client:    dynamodb.NewFromConfig(cfg),
+		client:    dynamoClient(),
 		tableName: tableName,
 	}
 }
 
-// Record stores a click event (simplified - just logs for now).
+// errInvalidCursor is returned for cursors that weren't issued by GetPage
+// for the same link.
+var errInvalidCursor = errors.New("invalid click cursor")
+
+// Record stores a click event.
 func (r *DynamoClickRepository) Record(ctx context.Context, event *model.ClickEvent) error {
-	// For now, we only track click counts (handled by IncrementClickCount).
-	// Detailed click events would require a separate table or composite key.
-	logger.Debug("click recorded",
-		"link_id", event.LinkID,
-		"referrer", event.Referrer,
-	)
+	if r.tableName == "" {
+		return nil
+	}
+
+	item := map[string]types.AttributeValue{
+		"link_id":    &types.AttributeValueMemberS{Value: event.LinkID},
+		"id":         &types.AttributeValueMemberS{Value: event.ID},
+		"clicked_at": &types.AttributeValueMemberS{Value: event.ClickedAt.Format(time.RFC3339Nano)},
+	}
+	for name, value := range map[string]string{
+		"referrer":   event.Referrer,
+		"user_agent": event.UserAgent,
+		"ip_address": event.IPAddress,
+		"source":     event.Source,
+	} {
+		if value != "" {
+			item[name] = &types.AttributeValueMemberS{Value: value}
+		}
+	}
+	if event.Deduped {
+		item["deduped"] = &types.AttributeValueMemberBOOL{Value: true}
+	}
+	if event.Prefetch {
+		item["prefetch"] = &types.AttributeValueMemberBOOL{Value: true}
+	}
+
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb put click: %w", err)
+	}
 	return nil
 }
 
-// GetByLinkID retrieves click events for a link (not implemented yet).
+// GetByLinkID retrieves click events for a link, most recent first, up to
+// limit (0 means all).
 func (r *DynamoClickRepository) GetByLinkID(ctx context.Context, linkID string, limit int) ([]model.ClickEvent, error) {
-	// TODO: Implement when we add analytics features
-	return []model.ClickEvent{}, nil
+	events := []model.ClickEvent{}
+	cursor := ""
+	for {
+		pageSize := 0
+		if limit > 0 {
+			pageSize = limit - len(events)
+		}
+
+		page, next, err := r.GetPage(ctx, linkID, cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+
+		if next == "" || (limit > 0 && len(events) >= limit) {
+			return events, nil
+		}
+		cursor = next
+	}
+}
+
+// GetPage returns one page of a link's click events, most recent first,
+// and an opaque cursor for the next page ("" when there are no more). An
+// empty cursor starts from the most recent click; limit 0 lets DynamoDB
+// pick the page size (up to 1 MB of items).
+func (r *DynamoClickRepository) GetPage(ctx context.Context, linkID, cursor string, limit int) ([]model.ClickEvent, string, error) {
+	if r.tableName == "" {
+		return []model.ClickEvent{}, "", nil
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: aws.String("link_id = :link_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":link_id": &types.AttributeValueMemberS{Value: linkID},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	if cursor != "" {
+		start, err := decodeClickCursor(linkID, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = start
+	}
+
+	out, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("dynamodb query clicks: %w", err)
+	}
+
+	events := make([]model.ClickEvent, 0, len(out.Items))
+	for _, item := range out.Items {
+		events = append(events, itemToClick(item))
+	}
+
+	next := ""
+	if len(out.LastEvaluatedKey) > 0 {
+		next = encodeClickCursor(out.LastEvaluatedKey)
+	}
+	return events, next, nil
+}
+
+// GetBetween returns every link's click events clicked in [from, to). It
+// scans the whole click table, so it's meant for maintenance sweeps only.
+func (r *DynamoClickRepository) GetBetween(ctx context.Context, from, to time.Time) ([]model.ClickEvent, error) {
+	events := []model.ClickEvent{}
+	err := r.scan(ctx, "", func(event model.ClickEvent) error {
+		if !event.ClickedAt.Before(from) && event.ClickedAt.Before(to) {
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// batchWriteLimit is the most requests DynamoDB accepts in one
+// BatchWriteItem call.
+const batchWriteLimit = 25
+
+// DeleteBefore removes click events clicked before cutoff, scanning the
+// whole click table and deleting 25 items per BatchWriteItem call.
+func (r *DynamoClickRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	var deletes []types.WriteRequest
+	removed := 0
+	flush := func() error {
+		if err := r.batchWrite(ctx, deletes); err != nil {
+			return err
+		}
+		removed += len(deletes)
+		deletes = deletes[:0]
+		return nil
+	}
+
+	err := r.scan(ctx, "link_id, id, clicked_at", func(event model.ClickEvent) error {
+		if !event.ClickedAt.Before(cutoff) {
+			return nil
+		}
+		deletes = append(deletes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"link_id": &types.AttributeValueMemberS{Value: event.LinkID},
+				"id":      &types.AttributeValueMemberS{Value: event.ID},
+			},
+		}})
+		if len(deletes) == batchWriteLimit {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && len(deletes) > 0 {
+		err = flush()
+	}
+	return removed, err
+}
+
+// scan calls fn for every click event in the table, optionally reading
+// only the attributes in projection.
+func (r *DynamoClickRepository) scan(ctx context.Context, projection string, fn func(model.ClickEvent) error) error {
+	if r.tableName == "" {
+		return nil
+	}
+
+	input := &dynamodb.ScanInput{TableName: &r.tableName}
+	if projection != "" {
+		input.ProjectionExpression = aws.String(projection)
+	}
+
+	paginator := dynamodb.NewScanPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("dynamodb scan clicks: %w", err)
+		}
+		for _, item := range out.Items {
+			if err := fn(itemToClick(item)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// batchWrite runs up to 25 write requests, retrying any DynamoDB leaves
+// unprocessed.
+func (r *DynamoClickRepository) batchWrite(ctx context.Context, requests []types.WriteRequest) error {
+	pending := map[string][]types.WriteRequest{r.tableName: requests}
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			// Unprocessed items mean we're being throttled; back off
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 50 * time.Millisecond):
+			}
+		}
+
+		out, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("dynamodb batch write item: %w", err)
+		}
+		pending = out.UnprocessedItems
+	}
+	return nil
+}
+
+// clickCursor is the JSON form of a click table key, base64url-encoded to
+// make the cursor opaque.
+type clickCursor struct {
+	LinkID string `json:"l"`
+	ID     string `json:"i"`
+}
+
+func encodeClickCursor(key map[string]types.AttributeValue) string {
+	var c clickCursor
+	if v, ok := key["link_id"].(*types.AttributeValueMemberS); ok {
+		c.LinkID = v.Value
+	}
+	if v, ok := key["id"].(*types.AttributeValueMemberS); ok {
+		c.ID = v.Value
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeClickCursor(linkID, cursor string) (map[string]types.AttributeValue, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c clickCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.LinkID != linkID || c.ID == "" {
+		return nil, errInvalidCursor
+	}
+	return map[string]types.AttributeValue{
+		"link_id": &types.AttributeValueMemberS{Value: c.LinkID},
+		"id":      &types.AttributeValueMemberS{Value: c.ID},
+	}, nil
+}
+
+// itemToClick converts a DynamoDB item to a ClickEvent.
+func itemToClick(item map[string]types.AttributeValue) model.ClickEvent {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+
+	event := model.ClickEvent{
+		ID:        str("id"),
+		LinkID:    str("link_id"),
+		Referrer:  str("referrer"),
+		UserAgent: str("user_agent"),
+		IPAddress: str("ip_address"),
+		Source:    str("source"),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, str("clicked_at")); err == nil {
+		event.ClickedAt = t
+	}
+	if v, ok := item["deduped"].(*types.AttributeValueMemberBOOL); ok {
+		event.Deduped = v.Value
+	}
+	if v, ok := item["prefetch"].(*types.AttributeValueMemberBOOL); ok {
+		event.Prefetch = v.Value
+	}
+	return event
+}
+
+// DynamoStatsRollupRepository implements repository.StatsRollupRepository
+// using DynamoDB. Each item is one link's day (partition link_id, sort key
+// day), with a clicks counter and one src_<source> counter per source,
+// all updated with atomic ADDs.
+type DynamoStatsRollupRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// rollupSourcePrefix prefixes per-source counter attributes.
+const rollupSourcePrefix = "src_"
+
+// NewDynamoStatsRollupRepository creates a new DynamoDB-backed rollup repository.
+func NewDynamoStatsRollupRepository(tableName string) *DynamoStatsRollupRepository {
+	return &DynamoStatsRollupRepository{
+		client:    dynamoClient(),
+		tableName: tableName,
+	}
+}
+
+// AddClick counts one click from source on day.
+func (r *DynamoStatsRollupRepository) AddClick(ctx context.Context, linkID, day, source string) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"link_id": &types.AttributeValueMemberS{Value: linkID},
+			"day":     &types.AttributeValueMemberS{Value: day},
+		},
+		UpdateExpression: aws.String("ADD clicks :one, #source :one"),
+		ExpressionAttributeNames: map[string]string{
+			"#source": rollupSourcePrefix + source,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb update rollup: %w", err)
+	}
+	return nil
+}
+
+// GetRange returns a link's aggregates between from and to inclusive.
+func (r *DynamoStatsRollupRepository) GetRange(ctx context.Context, linkID, from, to string) ([]model.DailyClicks, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: aws.String("link_id = :link_id AND #day BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#day": "day", // reserved word
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":link_id": &types.AttributeValueMemberS{Value: linkID},
+			":from":    &types.AttributeValueMemberS{Value: from},
+			":to":      &types.AttributeValueMemberS{Value: to},
+		},
+	}
+
+	days := []model.DailyClicks{}
+	paginator := dynamodb.NewQueryPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dynamodb query rollups: %w", err)
+		}
+		for _, item := range out.Items {
+			days = append(days, itemToDailyClicks(item))
+		}
+	}
+	return days, nil
+}
+
+// SetDay replaces a link's aggregate for rollup.Date, dropping source
+// counters that aren't in rollup.
+func (r *DynamoStatsRollupRepository) SetDay(ctx context.Context, linkID string, rollup model.DailyClicks) error {
+	item := map[string]types.AttributeValue{
+		"link_id": &types.AttributeValueMemberS{Value: linkID},
+		"day":     &types.AttributeValueMemberS{Value: rollup.Date},
+		"clicks":  &types.AttributeValueMemberN{Value: fmt.Sprint(rollup.Clicks)},
+	}
+	for source, clicks := range rollup.BySource {
+		item[rollupSourcePrefix+source] = &types.AttributeValueMemberN{Value: fmt.Sprint(clicks)}
+	}
+
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb put rollup: %w", err)
+	}
+	return nil
+}
+
+// itemToDailyClicks converts a DynamoDB rollup item to DailyClicks.
+func itemToDailyClicks(item map[string]types.AttributeValue) model.DailyClicks {
+	var rollup model.DailyClicks
+	if v, ok := item["day"].(*types.AttributeValueMemberS); ok {
+		rollup.Date = v.Value
+	}
+	for name, value := range item {
+		n, ok := value.(*types.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		var count int64
+		_, _ = fmt.Sscanf(n.Value, "%d", &count)
+		switch {
+		case name == "clicks":
+			rollup.Clicks = count
+		case strings.HasPrefix(name, rollupSourcePrefix):
+			if rollup.BySource == nil {
+				rollup.BySource = make(map[string]int64)
+			}
+			rollup.BySource[strings.TrimPrefix(name, rollupSourcePrefix)] = count
+		}
+	}
+	return rollup
 }
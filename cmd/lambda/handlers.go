@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/colby/snip/internal/etag"
+	"github.com/colby/snip/internal/fieldmask"
+	"github.com/colby/snip/internal/i18n"
 	"github.com/colby/snip/internal/model"
 	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/internal/tracecontext"
+	"github.com/colby/snip/pkg/apierror"
 )
 
 func handleRequest(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	logger.Info("received request",
+	// API Gateway lower-cases HTTP/2 header names
+	tc := tracecontext.FromHeaders(event.Headers["traceparent"], event.Headers["tracestate"])
+	ctx = tracecontext.WithContext(ctx, tc)
+	ctx = i18n.WithLanguage(ctx, i18n.Negotiate(event.Headers["accept-language"]))
+
+	logger.InfoContext(ctx, "received request",
 		"method", event.RequestContext.HTTP.Method,
 		"path", event.RawPath,
 		"rawQueryString", event.RawQueryString,
@@ -29,20 +43,62 @@ func handleRequest(ctx context.Context, event events.APIGatewayV2HTTPRequest) (e
 	case method == "POST" && path == "/api/links":
 		return handleCreateLink(ctx, event)
 
+	case method == "GET" && path == "/api/links":
+		return handleListLinks(ctx, event)
+
+	case method == "POST" && path == "/api/stats/batch":
+		return handleGetStatsBatch(ctx, event)
+
+	case method == "GET" && strings.HasPrefix(path, "/api/links/") && strings.HasSuffix(path, "/stats/daily"):
+		code := extractCodeFromStatsPath(strings.TrimSuffix(path, "/daily"))
+		return handleGetClickTimeseries(ctx, code, event)
+
 	case method == "GET" && strings.HasPrefix(path, "/api/links/") && strings.HasSuffix(path, "/stats"):
 		code := extractCodeFromStatsPath(path)
-		return handleGetStats(ctx, code)
+		return handleGetStats(ctx, code, event)
+
+	case method == "GET" && strings.HasPrefix(path, "/api/links/") && strings.HasSuffix(path, "/qr"):
+		code := strings.TrimSuffix(strings.TrimPrefix(path, "/api/links/"), "/qr")
+		return handleGetQRCode(ctx, code, event)
+
+	case method == "GET" && strings.HasPrefix(path, "/api/links/"):
+		code := strings.TrimPrefix(path, "/api/links/")
+		return handleGetLink(ctx, code, event)
+
+	case method == "POST" && path == "/api/aliases/suggest":
+		return handleSuggestAliases(ctx, event)
+
+	case (method == "POST" || method == "DELETE") && strings.HasPrefix(path, "/api/links/") && strings.HasSuffix(path, "/pin"):
+		code := strings.TrimSuffix(strings.TrimPrefix(path, "/api/links/"), "/pin")
+		pinned := method == "POST"
+		return applyPatch(ctx, code, service.LinkPatch{Pinned: &pinned}, event)
+
+	case method == "POST" && strings.HasPrefix(path, "/api/links/") && strings.HasSuffix(path, "/restore"):
+		code := strings.TrimSuffix(strings.TrimPrefix(path, "/api/links/"), "/restore")
+		return handleRestoreLink(ctx, code)
+
+	case method == "PATCH" && strings.HasPrefix(path, "/api/links/"):
+		code := strings.TrimPrefix(path, "/api/links/")
+		return handleUpdateLink(ctx, code, event)
 
 	case method == "DELETE" && strings.HasPrefix(path, "/api/links/"):
 		code := strings.TrimPrefix(path, "/api/links/")
-		return handleDeleteLink(ctx, code)
+		return handleDeleteLink(ctx, code, event)
+
+	case method == "GET" && strings.HasPrefix(path, "/api/aliases/") && strings.HasSuffix(path, "/availability"):
+		alias := strings.TrimSuffix(strings.TrimPrefix(path, "/api/aliases/"), "/availability")
+		return handleCheckAlias(ctx, alias)
+
+	case method == "POST" && adminToken != "" && strings.HasPrefix(path, "/api/admin/links/") && strings.HasSuffix(path, "/recount"):
+		code := strings.TrimSuffix(strings.TrimPrefix(path, "/api/admin/links/"), "/recount")
+		return handleRecountClicks(ctx, code, event)
 
 	case method == "GET" && len(path) > 1:
-		code := strings.TrimPrefix(path, "/")
-		return handleRedirect(ctx, code, event)
+		code, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		return handleRedirect(ctx, code, rest, event)
 
 	default:
-		return jsonResponse(http.StatusNotFound, map[string]string{"error": "not found"})
+		return errorResponse(ctx, http.StatusNotFound, apierror.CodeNotFound)
 	}
 }
 
@@ -57,72 +113,342 @@ func handleHealth() (events.APIGatewayV2HTTPResponse, error) {
 }
 
 func handleCreateLink(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	var req model.CreateLinkRequest
-	if err := json.Unmarshal([]byte(event.Body), &req); err != nil {
-		return jsonResponse(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	body := []byte(event.Body)
+	if event.IsBase64Encoded {
+		// API Gateway base64-encodes bodies it doesn't consider text, such as forms
+		decoded, err := base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		}
+		body = decoded
 	}
 
-	resp, err := linkService.CreateLink(ctx, req.URL)
+	req, err := service.ParseCreateLinkRequest(event.Headers["content-type"], body)
 	if err != nil {
-		switch err {
-		case service.ErrEmptyURL:
-			return jsonResponse(http.StatusBadRequest, map[string]string{"error": "url is required"})
-		case service.ErrInvalidURL:
-			return jsonResponse(http.StatusBadRequest, map[string]string{"error": "invalid url format"})
+		return apiErrorResponse(ctx, http.StatusBadRequest, err)
+	}
+
+	resp, err := links.CreateLink(ctx, req)
+	if err != nil {
+		switch {
+		case err == service.ErrEmptyURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeURLRequired)
+		case err == service.ErrInvalidURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidURL)
+		case err == service.ErrShortenerURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeShortenerURL)
+		case err == service.ErrSuspiciousURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeSuspiciousURL)
+		case err == service.ErrDeadURL:
+			return errorResponse(ctx, http.StatusUnprocessableEntity, apierror.CodeDeadURL)
+		case err == service.ErrInvalidAlias:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidAlias)
+		case err == service.ErrReservedAlias:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeReservedAlias)
+		case err == service.ErrAliasTaken:
+			return errorResponse(ctx, http.StatusConflict, apierror.CodeAliasTaken)
+		case err == service.ErrReadOnly:
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReadOnly)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			return apiErrorResponse(ctx, http.StatusBadRequest, err)
 		default:
-			logger.Error("failed to create link", "error", err)
-			return jsonResponse(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			logger.ErrorContext(ctx, "failed to create link", "error", err)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
 		}
 	}
 
+	if resp.Existing {
+		return jsonResponse(http.StatusOK, resp)
+	}
 	return jsonResponse(http.StatusCreated, resp)
 }
 
-func handleRedirect(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+// purpose returns the request's Sec-Purpose header, falling back to the
+// older Purpose (and X-Purpose) headers some browsers send on prefetches.
+func purpose(headers map[string]string) string {
+	for _, name := range []string{"sec-purpose", "purpose", "x-purpose"} {
+		if value := headers[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func handleRedirect(ctx context.Context, code, rest string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	metadata := service.ClickMetadata{
 		Referrer:  event.Headers["referer"],
 		UserAgent: event.Headers["user-agent"],
 		IPAddress: event.RequestContext.HTTP.SourceIP,
+		Source:    event.QueryStringParameters["src"],
+		Purpose:   purpose(event.Headers),
+
+		DoNotTrack: event.Headers["dnt"] == "1" || event.Headers["sec-gpc"] == "1",
 	}
 
-	redirectURL, err := linkService.Redirect(ctx, code, metadata)
+	target, err := links.ResolveRedirect(ctx, code, rest, metadata)
 	if err != nil {
 		if err == service.ErrLinkNotFound {
-			return jsonResponse(http.StatusNotFound, map[string]string{"error": "link not found"})
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
 		}
-		logger.Error("failed to redirect", "code", code, "error", err)
-		return jsonResponse(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		if err == service.ErrLinkDisabled {
+			return errorResponse(ctx, http.StatusGone, apierror.CodeLinkDisabled)
+		}
+		if err == service.ErrReferrerBlocked {
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReferrerBlocked)
+		}
+		logger.ErrorContext(ctx, "failed to redirect", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
 	}
 
+	if target.Interstitial {
+		var page strings.Builder
+		if err := interstitialPage.Render(&page, target.URL); err != nil {
+			logger.ErrorContext(ctx, "failed to render interstitial", "code", code, "error", err)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+		}
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: http.StatusOK,
+			Headers: map[string]string{
+				"Content-Type":  "text/html; charset=utf-8",
+				"Cache-Control": "no-store",
+			},
+			Body: page.String(),
+		}, nil
+	}
+
+	headers := map[string]string{
+		"Location": target.URL,
+	}
+	if target.Private {
+		headers["Cache-Control"] = "no-store"
+	}
 	return events.APIGatewayV2HTTPResponse{
 		StatusCode: http.StatusMovedPermanently,
-		Headers: map[string]string{
-			"Location": redirectURL,
-		},
+		Headers:    headers,
 	}, nil
 }
 
-func handleGetStats(ctx context.Context, code string) (events.APIGatewayV2HTTPResponse, error) {
-	stats, err := linkService.GetStats(ctx, code)
+func handleGetLink(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	link, err := links.GetLink(ctx, code)
+	if err != nil {
+		if err == service.ErrLinkNotFound {
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		}
+		logger.ErrorContext(ctx, "failed to get link", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return fieldsResponse(ctx, link, link.Version, event)
+}
+
+func handleGetStats(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	stats, err := links.GetStats(ctx, code)
 	if err != nil {
 		if err == service.ErrLinkNotFound {
-			return jsonResponse(http.StatusNotFound, map[string]string{"error": "link not found"})
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		}
+		logger.ErrorContext(ctx, "failed to get stats", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return fieldsResponse(ctx, stats, stats.Version, event)
+}
+
+func handleGetClickTimeseries(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	query := event.QueryStringParameters
+	series, err := links.ClickTimeseries(ctx, code, query["from"], query["to"], query["tz"])
+	if err != nil {
+		switch {
+		case err == service.ErrLinkNotFound:
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			return apiErrorResponse(ctx, http.StatusBadRequest, err)
+		}
+		logger.ErrorContext(ctx, "failed to get click timeseries", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return jsonResponse(http.StatusOK, series)
+}
+
+func handleListLinks(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	query := event.QueryStringParameters
+	resp, err := links.ListLinks(ctx, query["cursor"], query["limit"], query["pinned"])
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			return apiErrorResponse(ctx, http.StatusBadRequest, err)
+		}
+		logger.ErrorContext(ctx, "failed to list links", "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return jsonResponse(http.StatusOK, resp)
+}
+
+// handleGetQRCode serves a link's QR code. API Gateway only passes binary
+// bodies through base64 encoded, so PNGs are sent that way.
+func handleGetQRCode(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	query := event.QueryStringParameters
+	qr, err := links.QRCode(ctx, code, query["format"], query["size"], query["level"])
+	if err != nil {
+		switch {
+		case err == service.ErrLinkNotFound:
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			return apiErrorResponse(ctx, http.StatusBadRequest, err)
+		default:
+			logger.ErrorContext(ctx, "failed to render QR code", "error", err, "code", code)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+		}
+	}
+
+	resp := events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":           qr.ContentType,
+			"X-Content-Type-Options": "nosniff",
+			"Cache-Control":          "private, max-age=86400",
+		},
+		Body: string(qr.Data),
+	}
+	if qr.ContentType != "image/svg+xml" {
+		resp.Body = base64.StdEncoding.EncodeToString(qr.Data)
+		resp.IsBase64Encoded = true
+	}
+	return resp, nil
+}
+
+func handleGetStatsBatch(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	var req model.BatchStatsRequest
+	if err := json.Unmarshal([]byte(event.Body), &req); err != nil {
+		return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidRequest)
+	}
+
+	resp, err := links.GetStatsBatch(ctx, req.ShortCodes)
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			return apiErrorResponse(ctx, http.StatusBadRequest, err)
+		}
+		logger.ErrorContext(ctx, "failed to get batch stats", "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return jsonResponse(http.StatusOK, resp)
+}
+
+func handleUpdateLink(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	if ct := event.Headers["content-type"]; ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			return errorResponse(ctx, http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMedia)
+		}
+	}
+
+	patch, err := service.ParseLinkPatch([]byte(event.Body))
+	if err != nil {
+		return apiErrorResponse(ctx, http.StatusBadRequest, err)
+	}
+
+	return applyPatch(ctx, code, patch, event)
+}
+
+// applyPatch updates a link, honoring If-Match, and returns the new record.
+func applyPatch(ctx context.Context, code string, patch service.LinkPatch, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	version, err := etag.ParseIfMatch(event.Headers["if-match"])
+	if err != nil {
+		return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidRequest)
+	}
+
+	link, err := links.UpdateLink(ctx, code, patch, version)
+	if err != nil {
+		switch {
+		case err == service.ErrLinkNotFound:
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case err == service.ErrReadOnly:
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReadOnly)
+		case err == service.ErrVersionConflict:
+			return errorResponse(ctx, http.StatusPreconditionFailed, apierror.CodeVersionConflict)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			return apiErrorResponse(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			logger.ErrorContext(ctx, "failed to update link", "code", code, "error", err)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+		}
+	}
+
+	return versionedResponse(link, link.Version)
+}
+
+func handleRestoreLink(ctx context.Context, code string) (events.APIGatewayV2HTTPResponse, error) {
+	link, err := links.RestoreLink(ctx, code)
+	if err != nil {
+		switch {
+		case err == service.ErrLinkNotFound:
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case err == service.ErrReadOnly:
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReadOnly)
+		case err == service.ErrVersionConflict:
+			return errorResponse(ctx, http.StatusConflict, apierror.CodeVersionConflict)
+		default:
+			logger.ErrorContext(ctx, "failed to restore link", "code", code, "error", err)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+		}
+	}
+
+	return versionedResponse(link, link.Version)
+}
+
+func handleCheckAlias(ctx context.Context, alias string) (events.APIGatewayV2HTTPResponse, error) {
+	result, err := links.CheckAlias(ctx, alias)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to check alias", "alias", alias, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return jsonResponse(http.StatusOK, result)
+}
+
+func handleSuggestAliases(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	var req model.SuggestAliasesRequest
+	if err := json.Unmarshal([]byte(event.Body), &req); err != nil {
+		return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidRequest)
+	}
+
+	suggestions, err := links.SuggestAliases(ctx, req.URL, req.Count)
+	if err != nil {
+		switch err {
+		case service.ErrEmptyURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeURLRequired)
+		case service.ErrInvalidURL:
+			return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidURL)
+		default:
+			logger.ErrorContext(ctx, "failed to suggest aliases", "error", err)
+			return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
 		}
-		logger.Error("failed to get stats", "code", code, "error", err)
-		return jsonResponse(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
 	}
 
-	return jsonResponse(http.StatusOK, stats)
+	return jsonResponse(http.StatusOK, model.SuggestAliasesResponse{Suggestions: suggestions})
 }
 
-func handleDeleteLink(ctx context.Context, code string) (events.APIGatewayV2HTTPResponse, error) {
-	err := linkService.DeleteLink(ctx, code)
+func handleDeleteLink(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	version, err := etag.ParseIfMatch(event.Headers["if-match"])
+	if err != nil {
+		return errorResponse(ctx, http.StatusBadRequest, apierror.CodeInvalidRequest)
+	}
+
+	err = links.DeleteLink(ctx, code, version)
 	if err != nil {
 		if err == service.ErrLinkNotFound {
-			return jsonResponse(http.StatusNotFound, map[string]string{"error": "link not found"})
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		}
+		if err == service.ErrReadOnly {
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReadOnly)
+		}
+		if err == service.ErrVersionConflict {
+			return errorResponse(ctx, http.StatusPreconditionFailed, apierror.CodeVersionConflict)
 		}
-		logger.Error("failed to delete link", "code", code, "error", err)
-		return jsonResponse(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		logger.ErrorContext(ctx, "failed to delete link", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
 	}
 
 	return events.APIGatewayV2HTTPResponse{
@@ -130,12 +456,75 @@ func handleDeleteLink(ctx context.Context, code string) (events.APIGatewayV2HTTP
 	}, nil
 }
 
+func handleRecountClicks(ctx context.Context, code string, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	provided, ok := strings.CutPrefix(event.Headers["authorization"], "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+		return errorResponse(ctx, http.StatusUnauthorized, apierror.CodeUnauthorized)
+	}
+
+	recount, err := links.RecountClicks(ctx, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			return errorResponse(ctx, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			return errorResponse(ctx, http.StatusForbidden, apierror.CodeReadOnly)
+		}
+		logger.ErrorContext(ctx, "failed to recount clicks", "code", code, "error", err)
+		return errorResponse(ctx, http.StatusInternalServerError, apierror.CodeInternal)
+	}
+
+	return jsonResponse(http.StatusOK, recount)
+}
+
+// versionedResponse returns body with the link version as the ETag.
+func versionedResponse(body any, version int64) (events.APIGatewayV2HTTPResponse, error) {
+	resp, err := jsonResponse(http.StatusOK, body)
+	if resp.Headers != nil {
+		resp.Headers["ETag"] = etag.Format(version)
+	}
+	return resp, err
+}
+
+// fieldsResponse is versionedResponse pruned to the ?fields= parameter.
+func fieldsResponse(ctx context.Context, body any, version int64, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	pruned, err := fieldmask.Apply(body, event.QueryStringParameters["fields"])
+	if err != nil {
+		return apiErrorResponse(ctx, http.StatusBadRequest, err)
+	}
+	return versionedResponse(pruned, version)
+}
+
+// errorResponse builds a JSON error body carrying a stable error code and a
+// message in the request's negotiated language.
+func errorResponse(ctx context.Context, status int, code string) (events.APIGatewayV2HTTPResponse, error) {
+	return apiErrorResponse(ctx, status, apierror.New(code, ""))
+}
+
+// apiErrorResponse is errorResponse for an error value, keeping any
+// field-level details.
+func apiErrorResponse(ctx context.Context, status int, err error) (events.APIGatewayV2HTTPResponse, error) {
+	lang := i18n.Language(ctx)
+	code := apierror.CodeOf(err)
+	body := apierror.New(code, i18n.Message(lang, code))
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		body.Fields = apiErr.Fields
+	}
+
+	resp, respErr := jsonResponse(status, body)
+	if resp.Headers != nil {
+		resp.Headers["Content-Language"] = lang
+	}
+	return resp, respErr
+}
+
 func jsonResponse(status int, body any) (events.APIGatewayV2HTTPResponse, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return events.APIGatewayV2HTTPResponse{
 			StatusCode: http.StatusInternalServerError,
-			Body:       `{"error": "internal server error"}`,
+			Body:       `{"error": "internal server error", "code": "internal_error"}`,
 		}, nil
 	}
 
@@ -2,15 +2,34 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // stats time zones mustn't depend on the host having zoneinfo
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/colby/snip/internal/interstitial"
+	"github.com/colby/snip/internal/outbound"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/secrets"
 	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/internal/tracecontext"
 )
 
 var linkService *service.LinkService
+
+// links is what the router serves: linkService, seen through the
+// interface so the router only depends on the request-serving methods.
+var links service.Links
 var logger *slog.Logger
+var interstitialPage *interstitial.Renderer
+
+// adminToken enables the /api/admin endpoints; empty leaves them off.
+var adminToken string
 
 func init() {
 	// Setup logger
@@ -27,31 +46,186 @@ func init() {
 		level = slog.LevelInfo
 	}
 
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	logger = slog.New(tracecontext.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+
+	// Settings may be references to secrets in SSM or Secrets Manager
+	if err := resolveSecretEnv(); err != nil {
+		logger.Error("failed to resolve secrets", "error", err)
+		os.Exit(1)
+	}
 
 	// Get config from environment
 	tableName := os.Getenv("DYNAMODB_TABLE")
 	baseURL := os.Getenv("BASE_URL")
+	readOnly := os.Getenv("READ_ONLY") == "true"
+	codeLength, _ := strconv.Atoi(os.Getenv("CODE_LENGTH")) // 0 falls back to the default
+	caseInsensitive := os.Getenv("CASE_INSENSITIVE_CODES") == "true"
+	dedupeDestinations := os.Getenv("DEDUPE_DESTINATIONS") == "true"
+
+	shortenerPolicy := os.Getenv("SHORTENER_POLICY") // reject (default), resolve or allow
+	var shortenerDomains []string
+	for _, domain := range strings.Split(os.Getenv("SHORTENER_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			shortenerDomains = append(shortenerDomains, domain)
+		}
+	}
+
+	var resolver, shortenerResolver service.DestinationResolver
+	maxHops, _ := strconv.Atoi(os.Getenv("RESOLVE_MAX_HOPS")) // 0 falls back to the default
+	outboundResolver := outbound.NewResolver(outbound.NewClient(outbound.ClientConfig{}), maxHops)
+	if os.Getenv("RESOLVE_REDIRECTS") == "true" {
+		resolver = outboundResolver
+	}
+	if shortenerPolicy == "resolve" {
+		shortenerResolver = outboundResolver
+	}
+
+	// Phishing heuristic thresholds; unset keeps the API server's defaults
+	phishingWarn, phishingBlock := 3, 6
+	if v, err := strconv.Atoi(os.Getenv("PHISHING_WARN_SCORE")); err == nil {
+		phishingWarn = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHISHING_BLOCK_SCORE")); err == nil {
+		phishingBlock = v
+	}
+
+	interstitialSeconds, _ := strconv.Atoi(os.Getenv("INTERSTITIAL_SECONDS")) // 0 falls back to the default
+	interstitialPage = interstitial.New(interstitial.Config{
+		Brand:   os.Getenv("INTERSTITIAL_BRAND"),
+		Seconds: interstitialSeconds,
+	})
 
 	if tableName == "" {
 		logger.Error("DYNAMODB_TABLE environment variable is required")
 		os.Exit(1)
 	}
 
+	// Recounting needs stored click events, so admin endpoints stay off
+	// without a click table
+	if os.Getenv("CLICKS_TABLE") != "" {
+		adminToken = os.Getenv("ADMIN_TOKEN")
+	}
+
 	// Initialize repository
 	linkRepo := NewDynamoLinkRepository(tableName)
-	clickRepo := NewDynamoClickRepository(tableName)
+	clickRepo := NewDynamoClickRepository(os.Getenv("CLICKS_TABLE")) // optional; counts only when unset
+
+	// Optional per-day click rollups; stats scan click events without them
+	var rollups repository.StatsRollupRepository
+	if table := os.Getenv("ROLLUPS_TABLE"); table != "" {
+		rollups = NewDynamoStatsRollupRepository(table)
+	}
+
+	// The runtime freezes the process once a response is returned, so a
+	// click recorded in the background could be lost; redirects wait for
+	// it instead, up to CLICK_RECORD_TIMEOUT_MS
+	var clickRecorder service.ClickRecorder
+	timeoutMS, _ := strconv.Atoi(os.Getenv("CLICK_RECORD_TIMEOUT_MS")) // 0 falls back to the default
+	if os.Getenv("CLICK_RECORDING") == "async" {
+		clickRecorder = service.AsyncClickRecorder{Timeout: time.Duration(timeoutMS) * time.Millisecond}
+	} else {
+		clickRecorder = service.BoundedClickRecorder{Timeout: time.Duration(timeoutMS) * time.Millisecond}
+	}
+
+	// Deleted links are kept for DELETE_GRACE_DAYS, then purged by the
+	// "deleted" maintenance sweep
+	graceDays, _ := strconv.Atoi(os.Getenv("DELETE_GRACE_DAYS"))
+	dedupeSeconds, _ := strconv.Atoi(os.Getenv("CLICK_DEDUPE_SECONDS"))
+	notFoundSeconds, _ := strconv.Atoi(os.Getenv("NOT_FOUND_CACHE_SECONDS"))
 
 	// Initialize service
 	linkService = service.NewLinkService(linkRepo, clickRepo, service.LinkServiceConfig{
-		BaseURL:    baseURL,
-		CodeLength: 7,
-		MaxRetries: 5,
+		BaseURL:              baseURL,
+		CodeLength:           codeLength,
+		MaxRetries:           5,
+		ReadOnly:             readOnly,
+		CaseInsensitiveCodes: caseInsensitive,
+		DedupeDestinations:   dedupeDestinations,
+		Resolver:             resolver,
+		ShortenerDomains:     shortenerDomains,
+		AllowShorteners:      shortenerPolicy == "allow",
+		ShortenerResolver:    shortenerResolver,
+		Verifier:             outboundResolver,
+		Rollups:              rollups,
+		ClickRecorder:        clickRecorder,
+		DeleteGracePeriod:    time.Duration(graceDays) * 24 * time.Hour,
+		ClickDedupeWindow:    time.Duration(dedupeSeconds) * time.Second,
+		NotFoundCacheTTL:     time.Duration(notFoundSeconds) * time.Second,
+		CountPrefetches:      os.Getenv("COUNT_PREFETCHES") == "true",
+		HonorDoNotTrack:      os.Getenv("HONOR_DO_NOT_TRACK") == "true",
+		PhishingWarnScore:    phishingWarn,
+		PhishingBlockScore:   phishingBlock,
+		Logger:               logger,
 	})
 
-	logger.Info("lambda initialized", "table", tableName, "base_url", baseURL)
+	links = linkService
+
+	// Provisioned concurrency runs init ahead of traffic, so the time spent
+	// warming the DynamoDB client there never reaches a request. On-demand
+	// cold starts skip it unless PRELOAD asks for it.
+	if os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE") == "provisioned-concurrency" || os.Getenv("PRELOAD") == "true" {
+		preload(linkRepo)
+	}
+
+	// The router's own paths ("api", "health") are among the built-in
+	// reserved aliases
+	if err := linkService.CheckBaseURL(); err != nil {
+		logger.Error("invalid BASE_URL", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("lambda initialized", "table", tableName, "base_url", baseURL, "read_only", readOnly)
+}
+
+// resolveSecretEnv replaces environment variables holding secret
+// references with the secrets they refer to, so the rest of init reads
+// plain values. Execution environments are recycled regularly, which
+// picks up rotated secrets.
+func resolveSecretEnv() error {
+	ctx, cancel := context.WithTimeout(context.Background(), preloadTimeout)
+	defer cancel()
+
+	var resolver *secrets.Resolver
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !secrets.IsReference(value) {
+			continue
+		}
+		if resolver == nil {
+			resolver = secrets.New(secrets.Config{Sources: secrets.AWSSources(awsConfig())})
+		}
+		secret, err := resolver.Resolve(ctx, value)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", key, err)
+		}
+		os.Setenv(key, secret)
+	}
+	return nil
+}
+
+// preloadTimeout bounds warming so a slow dependency can't stall init.
+const preloadTimeout = 3 * time.Second
+
+// preload warms the shared AWS clients. Failures are only logged: the
+// first request will retry whatever didn't happen here.
+func preload(linkRepo *DynamoLinkRepository) {
+	ctx, cancel := context.WithTimeout(context.Background(), preloadTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := linkRepo.Warm(ctx); err != nil {
+		logger.Warn("preload failed", "error", err)
+		return
+	}
+	logger.Info("preloaded dynamodb client", "duration_ms", time.Since(start).Milliseconds())
 }
 
 func main() {
+	// The same binary serves API Gateway requests and, deployed with
+	// HANDLER_MODE=maintenance, scheduled sweeps
+	if os.Getenv("HANDLER_MODE") == "maintenance" {
+		lambda.Start(handleMaintenance)
+		return
+	}
 	lambda.Start(handleRequest)
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/colby/snip/internal/service"
+)
+
+// maintenanceEvent is the input of a maintenance invocation. EventBridge
+// schedules can send a constant input naming the sweeps to run, e.g.
+// {"sweeps": ["retention"]}; a plain scheduled event runs them all.
+type maintenanceEvent struct {
+	Sweeps []string `json:"sweeps"`
+}
+
+// sweeps are the maintenance tasks, by name, in the order they run.
+var sweeps = []struct {
+	name string
+	run  func(ctx context.Context) error
+}{
+	{"rollups", sweepRollups},
+	{"retention", sweepRetention},
+	{"deleted", sweepDeletedLinks},
+}
+
+// handleMaintenance runs the requested sweeps when the function is deployed
+// with HANDLER_MODE=maintenance. Every requested sweep runs even if an
+// earlier one fails; the invocation fails if any did.
+func handleMaintenance(ctx context.Context, event maintenanceEvent) error {
+	requested := make(map[string]bool, len(event.Sweeps))
+	for _, name := range event.Sweeps {
+		requested[name] = true
+	}
+
+	var errs []error
+	for _, sweep := range sweeps {
+		if len(requested) > 0 && !requested[sweep.name] {
+			continue
+		}
+		delete(requested, sweep.name)
+
+		start := time.Now()
+		if err := sweep.run(ctx); err != nil {
+			logger.ErrorContext(ctx, "sweep failed", "sweep", sweep.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", sweep.name, err))
+			continue
+		}
+		logger.InfoContext(ctx, "sweep finished", "sweep", sweep.name, "duration_ms", time.Since(start).Milliseconds())
+	}
+	for name := range requested {
+		errs = append(errs, fmt.Errorf("unknown sweep %q", name))
+	}
+	return errors.Join(errs...)
+}
+
+// sweepRollups rebuilds yesterday's rollups from click events, correcting
+// counts lost to failed updates while clicks were recorded. It's skipped
+// when ROLLUPS_TABLE or CLICKS_TABLE is unset.
+func sweepRollups(ctx context.Context) error {
+	// Without a click table there are no events to rebuild from
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	updated := 0
+	err := service.ErrSweepUnsupported
+	if os.Getenv("CLICKS_TABLE") != "" {
+		updated, err = linkService.RebuildRollups(ctx, yesterday)
+	}
+	if errors.Is(err, service.ErrSweepUnsupported) {
+		logger.InfoContext(ctx, "skipping rollup rebuild: needs ROLLUPS_TABLE and CLICKS_TABLE")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "rebuilt rollups", "day", yesterday.Format(time.DateOnly), "links", updated)
+	return nil
+}
+
+// sweepRetention deletes click events older than CLICK_RETENTION_DAYS. It's
+// skipped when the variable is unset or zero.
+func sweepRetention(ctx context.Context) error {
+	days, _ := strconv.Atoi(os.Getenv("CLICK_RETENTION_DAYS"))
+	if days <= 0 {
+		logger.InfoContext(ctx, "skipping click retention purge: CLICK_RETENTION_DAYS is not set")
+		return nil
+	}
+
+	removed, err := linkService.PurgeClicks(ctx, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "purged click events", "retention_days", days, "removed", removed)
+	return nil
+}
+
+// sweepDeletedLinks removes soft-deleted links whose DELETE_GRACE_DAYS have
+// passed. It's skipped when the variable is unset or zero, since links are
+// then deleted outright.
+func sweepDeletedLinks(ctx context.Context) error {
+	days, _ := strconv.Atoi(os.Getenv("DELETE_GRACE_DAYS"))
+	if days <= 0 {
+		logger.InfoContext(ctx, "skipping deleted link purge: DELETE_GRACE_DAYS is not set")
+		return nil
+	}
+
+	removed, err := linkService.PurgeDeletedLinks(ctx)
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "purged deleted links", "grace_days", days, "removed", removed)
+	return nil
+}
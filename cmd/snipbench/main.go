@@ -0,0 +1,195 @@
+// Package main is snipbench, a load generator for a running Snip server.
+// It sends a configurable mix of creates and redirects and reports latency
+// percentiles and error rates per operation, so performance regressions
+// in the service and repository layers show up as numbers.
+//
+//	snipbench -target http://localhost:8080 -duration 30s -concurrency 32 -create-ratio 0.05
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options are snipbench's command-line settings.
+type options struct {
+	target      string
+	duration    time.Duration
+	concurrency int
+	createRatio float64 // share of requests that create a link; the rest redirect
+	links       int     // links created up front for redirects to hit
+	timeout     time.Duration
+}
+
+func run() error {
+	var opts options
+	flag.StringVar(&opts.target, "target", "http://localhost:8080", "base URL of the server under test")
+	flag.DurationVar(&opts.duration, "duration", 10*time.Second, "how long to send traffic")
+	flag.IntVar(&opts.concurrency, "concurrency", 16, "concurrent workers")
+	flag.Float64Var(&opts.createRatio, "create-ratio", 0.1, "share of requests that create links (0 to 1); the rest are redirects")
+	flag.IntVar(&opts.links, "links", 100, "links created before the run for redirects to hit")
+	flag.DurationVar(&opts.timeout, "timeout", 5*time.Second, "per-request timeout")
+	flag.Parse()
+
+	if opts.concurrency <= 0 || opts.duration <= 0 {
+		return errors.New("-concurrency and -duration must be positive")
+	}
+	if opts.createRatio < 0 || opts.createRatio > 1 {
+		return errors.New("-create-ratio must be between 0 and 1")
+	}
+	if opts.createRatio < 1 && opts.links <= 0 {
+		return errors.New("redirects need -links of at least 1")
+	}
+	opts.target = strings.TrimSuffix(opts.target, "/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &bench{
+		opts: opts,
+		client: &http.Client{
+			Timeout: opts.timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // time the redirect itself, not the destination
+			},
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency},
+		},
+		creates:   newRecorder(),
+		redirects: newRecorder(),
+	}
+
+	codes, err := b.seed(ctx)
+	if err != nil {
+		return err
+	}
+	b.codes = codes
+
+	fmt.Printf("running %s against %s with %d workers (%.0f%% creates)\n",
+		opts.duration, opts.target, opts.concurrency, opts.createRatio*100)
+	elapsed := b.run(ctx)
+
+	report(os.Stdout, elapsed, map[string]*recorder{"create": b.creates, "redirect": b.redirects})
+	return nil
+}
+
+// bench holds the state shared by the workers.
+type bench struct {
+	opts   options
+	client *http.Client
+	codes  []string // short codes redirects pick from
+
+	creates   *recorder
+	redirects *recorder
+}
+
+// seed creates the links redirects are sent to. Seeding isn't timed.
+func (b *bench) seed(ctx context.Context) ([]string, error) {
+	if b.opts.createRatio == 1 {
+		return nil, nil
+	}
+	codes := make([]string, 0, b.opts.links)
+	for i := 0; i < b.opts.links; i++ {
+		code, err := b.create(ctx, fmt.Sprintf("seed-%d", i))
+		if err != nil {
+			return nil, fmt.Errorf("seeding links: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// run sends traffic until the duration passes or ctx is canceled, and
+// returns how long it ran.
+func (b *bench) run(ctx context.Context) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, b.opts.duration)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < b.opts.concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				if rand.Float64() < b.opts.createRatio {
+					begin := time.Now()
+					_, err := b.create(ctx, fmt.Sprintf("%d-%d", worker, i))
+					b.creates.add(ctx, time.Since(begin), err)
+				} else {
+					begin := time.Now()
+					err := b.redirect(ctx, b.codes[rand.IntN(len(b.codes))])
+					b.redirects.add(ctx, time.Since(begin), err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// create creates one link and returns its short code. Destinations are
+// distinct so the server can't dedupe them.
+func (b *bench) create(ctx context.Context, id string) (string, error) {
+	body, _ := json.Marshal(map[string]string{"url": fmt.Sprintf("https://example.com/snipbench/%d/%s", time.Now().UnixNano(), id)})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.opts.target+"/api/links", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("create returned status %d", resp.StatusCode)
+	}
+
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("decoding create response: %w", err)
+	}
+	return created.ShortCode, nil
+}
+
+// redirect follows one short link, without visiting the destination.
+func (b *bench) redirect(ctx context.Context, code string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.opts.target+"/"+code, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "snipbench/1.0")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return fmt.Errorf("redirect returned status %d", resp.StatusCode)
+	}
+	return nil
+}
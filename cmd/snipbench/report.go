@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recorder collects the outcomes of one kind of request.
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration // successful requests only
+	errors    map[string]int  // error message -> count
+}
+
+func newRecorder() *recorder {
+	return &recorder{errors: make(map[string]int)}
+}
+
+// add records one request. Requests cut off because the run ended aren't
+// counted either way.
+func (r *recorder) add(ctx context.Context, latency time.Duration, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[err.Error()]++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p / 100)
+	return sorted[i]
+}
+
+// report prints per-operation throughput, latency percentiles and errors.
+func report(w io.Writer, elapsed time.Duration, recorders map[string]*recorder) {
+	names := make([]string, 0, len(recorders))
+	for name := range recorders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\n%-10s %8s %9s %8s %9s %9s %9s %9s\n", "op", "requests", "req/s", "errors", "p50", "p90", "p99", "max")
+	for _, name := range names {
+		r := recorders[name]
+		r.mu.Lock()
+		latencies := slices.Clone(r.latencies)
+		failed := 0
+		for _, n := range r.errors {
+			failed += n
+		}
+		r.mu.Unlock()
+
+		total := len(latencies) + failed
+		if total == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+		fmt.Fprintf(w, "%-10s %8d %9.1f %7.2f%% %9s %9s %9s %9s\n",
+			name, total, float64(total)/elapsed.Seconds(), 100*float64(failed)/float64(total),
+			round(percentile(latencies, 50)), round(percentile(latencies, 90)),
+			round(percentile(latencies, 99)), round(percentile(latencies, 100)))
+	}
+
+	for _, name := range names {
+		r := recorders[name]
+		r.mu.Lock()
+		for msg, n := range r.errors {
+			fmt.Fprintf(w, "%s error (%d): %s\n", name, n, msg)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// round trims a latency to a readable precision.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
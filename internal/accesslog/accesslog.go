@@ -0,0 +1,119 @@
+// Package accesslog writes HTTP access logs to a dedicated sink,
+// independent of the application's structured logs.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Format selects how access log entries are rendered.
+type Format string
+
+// Supported formats.
+const (
+	FormatJSON     Format = "json"     // one JSON object per line
+	FormatCombined Format = "combined" // Apache/NGINX combined log format
+)
+
+// ParseFormat validates a format name. An empty name selects FormatJSON.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCombined:
+		return FormatCombined, nil
+	default:
+		return "", fmt.Errorf("unknown access log format %q", name)
+	}
+}
+
+// Entry describes a single completed HTTP request.
+type Entry struct {
+	Time       time.Time     `json:"time"`
+	RemoteAddr string        `json:"remote_addr"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Proto      string        `json:"proto"`
+	Status     int           `json:"status"`
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"-"`
+	Referrer   string        `json:"referrer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	TraceID    string        `json:"trace_id,omitempty"`
+}
+
+// Logger renders entries to an io.Writer. It is safe for concurrent use.
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+}
+
+// New creates a Logger writing entries in the given format.
+func New(w io.Writer, format Format) *Logger {
+	return &Logger{w: w, format: format}
+}
+
+// Log writes a single entry. Write errors are returned so callers can
+// surface them, but a failing sink never affects the request itself.
+func (l *Logger) Log(e Entry) error {
+	var line []byte
+	switch l.format {
+	case FormatCombined:
+		line = []byte(combinedLine(e))
+	default:
+		var err error
+		line, err = jsonLine(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.w.Write(line)
+	return err
+}
+
+// jsonLine renders an entry as a newline-terminated JSON object.
+func jsonLine(e Entry) ([]byte, error) {
+	type alias Entry
+	line, err := json.Marshal(struct {
+		alias
+		DurationMS float64 `json:"duration_ms"`
+	}{
+		alias:      alias(e),
+		DurationMS: float64(e.Duration.Microseconds()) / 1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// combinedLine renders an entry in the combined log format:
+//
+//	host - - [time] "METHOD path proto" status bytes "referrer" "user-agent"
+func combinedLine(e Entry) string {
+	return fmt.Sprintf("%s - - [%s] %q %d %d %q %q\n",
+		dashIfEmpty(e.RemoteAddr),
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method+" "+e.Path+" "+e.Proto,
+		e.Status,
+		e.Bytes,
+		dashIfEmpty(e.Referrer),
+		dashIfEmpty(e.UserAgent),
+	)
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testEntry() Entry {
+	return Entry{
+		Time:       time.Date(2025, 1, 17, 12, 0, 0, 0, time.UTC),
+		RemoteAddr: "1.2.3.4",
+		Method:     "GET",
+		Path:       "/abc1234",
+		Proto:      "HTTP/1.1",
+		Status:     301,
+		Bytes:      57,
+		Duration:   1500 * time.Microsecond,
+		UserAgent:  "curl/8.0",
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, FormatJSON)
+
+	if err := l.Log(testEntry()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode line %q: %v", buf.String(), err)
+	}
+
+	if got["path"] != "/abc1234" {
+		t.Errorf("expected path /abc1234, got %v", got["path"])
+	}
+	if got["duration_ms"] != 1.5 {
+		t.Errorf("expected duration_ms 1.5, got %v", got["duration_ms"])
+	}
+}
+
+func TestLogger_Combined(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, FormatCombined)
+
+	if err := l.Log(testEntry()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `1.2.3.4 - - [17/Jan/2025:12:00:00 +0000] "GET /abc1234 HTTP/1.1" 301 57 "-" "curl/8.0"` + "\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatJSON, false},
+		{"json", FormatJSON, false},
+		{"combined", FormatCombined, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRotatingFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	assertContent(t, path, "fourth\n")
+	assertContent(t, path+".1", "third\n")
+	assertContent(t, path+".2", "second\n")
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", path)
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rf, err := OpenRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rf.Write([]byte("new\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	rf.Close()
+
+	assertContent(t, path, "old\nnew\n")
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	if string(got) != want {
+		t.Errorf("%s: expected %q, got %q", filepath.Base(path), want, got)
+	}
+}
@@ -0,0 +1,113 @@
+package accesslog
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Default rotation settings.
+const (
+	DefaultMaxSize    = 100 << 20 // 100 MiB
+	DefaultMaxBackups = 5
+)
+
+// RotatingFile is an io.WriteCloser that rotates the underlying file once it
+// grows past MaxSize bytes. Rotated files are renamed path.1, path.2, ...
+// with at most MaxBackups kept.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenRotatingFile opens (or creates) the file at path for appending.
+// Non-positive limits fall back to the defaults.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	rf := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write appends p to the current file, rotating first if p would push the
+// file past its size limit.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	return rf.file.Close()
+}
+
+// open opens the active file and records its current size.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening access log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat access log: %w", err)
+	}
+
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate shifts existing backups up by one, moves the active file to
+// path.1 and starts a fresh file.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return fmt.Errorf("closing access log: %w", err)
+	}
+
+	// Oldest backup falls off the end
+	_ = os.Remove(rf.backupName(rf.maxBackups))
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(rf.backupName(i), rf.backupName(i+1))
+	}
+
+	if err := os.Rename(rf.path, rf.backupName(1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotating access log: %w", err)
+	}
+
+	return rf.open()
+}
+
+func (rf *RotatingFile) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", rf.path, n)
+}
@@ -0,0 +1,195 @@
+// Package auth issues and checks the credentials that protect the
+// management API: API keys, and the tokens user accounts sign in for.
+// Keys are random tokens shown once when created; only their SHA-256 hash
+// is stored, which is enough for tokens this long.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Errors returned by Keys.
+var (
+	ErrInvalidKey  = errors.New("auth: missing, unknown or revoked API key")
+	ErrKeyNotFound = apierror.New(apierror.CodeAPIKeyNotFound, "API key not found")
+)
+
+// MaxNameLength bounds the name given to a key.
+const MaxNameLength = 100
+
+// Key format: KeyPrefix followed by keyBytes of randomness, base64url
+// encoded. The prefix makes leaked keys easy to spot and scan for.
+const (
+	KeyPrefix  = "snip_"
+	keyBytes   = 32
+	hintLength = len(KeyPrefix) + 4
+)
+
+// Keys creates, revokes and checks API keys.
+type Keys struct {
+	repo repository.APIKeyRepository
+	now  func() time.Time
+}
+
+// New creates Keys stored in repo.
+func New(repo repository.APIKeyRepository) *Keys {
+	return &Keys{repo: repo, now: time.Now}
+}
+
+// Create issues a key. The response is the only place the key itself
+// appears. A missing or overlong name is reported as a validation error.
+func (k *Keys) Create(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	problem := ""
+	switch {
+	case name == "":
+		problem = apierror.CodeInvalidRequest
+	case len(name) > MaxNameLength:
+		problem = apierror.CodeTooLong
+	}
+	if problem != "" {
+		err := apierror.New(apierror.CodeValidationFailed, "one or more fields are invalid")
+		err.Fields = map[string]string{"name": problem}
+		return nil, err
+	}
+
+	secret := make([]byte, keyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating API key: %w", err)
+	}
+	token := KeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := model.APIKey{
+		ID:        model.NewID(),
+		Name:      name,
+		Hint:      token[:hintLength],
+		Hash:      Hash(token),
+		CreatedAt: k.now().UTC(),
+	}
+	if err := k.repo.Create(ctx, &key); err != nil {
+		return nil, fmt.Errorf("storing API key: %w", err)
+	}
+	return &model.CreateAPIKeyResponse{APIKey: key, Key: token}, nil
+}
+
+// List returns every key, revoked ones included, oldest first.
+func (k *Keys) List(ctx context.Context) (*model.ListAPIKeysResponse, error) {
+	keys, err := k.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	return &model.ListAPIKeysResponse{Keys: keys}, nil
+}
+
+// Revoke stops a key from authenticating, from the next request on. The
+// key stays listed, marked with when it was revoked.
+func (k *Keys) Revoke(ctx context.Context, id string) error {
+	if err := k.repo.Revoke(ctx, id, k.now().UTC()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrKeyNotFound
+		}
+		return fmt.Errorf("revoking API key: %w", err)
+	}
+	return nil
+}
+
+// Authenticate returns the live key token belongs to, or ErrInvalidKey.
+func (k *Keys) Authenticate(ctx context.Context, token string) (*model.APIKey, error) {
+	if !strings.HasPrefix(token, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	key, err := k.repo.GetByHash(ctx, Hash(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up API key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Hash is the stored form of a key.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Middleware passes requests carrying a live credential on to next, with
+// the key or user in their context. Credentials come as a Bearer token or
+// an X-API-Key header: API keys are checked against keys, anything else
+// against users' tokens; either may be nil to turn that kind off. Other
+// requests go to deny with the reason: ErrInvalidKey, ErrInvalidToken, or
+// a storage error that kept the key from being checked.
+func Middleware(keys *Keys, users *Users, next http.Handler, deny func(w http.ResponseWriter, r *http.Request, err error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+
+		ctx := r.Context()
+		if keys != nil && (users == nil || strings.HasPrefix(token, KeyPrefix)) {
+			key, err := keys.Authenticate(ctx, token)
+			if err != nil {
+				deny(w, r, err)
+				return
+			}
+			ctx = WithKey(ctx, key)
+		} else {
+			if users == nil {
+				deny(w, r, ErrInvalidToken)
+				return
+			}
+			user, err := users.Verify(token)
+			if err != nil {
+				deny(w, r, err)
+				return
+			}
+			ctx = WithUser(ctx, user)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type (
+	keyContextKey  struct{}
+	userContextKey struct{}
+)
+
+// WithKey returns ctx carrying the key a request authenticated with.
+func WithKey(ctx context.Context, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the key a request authenticated with, if any.
+func KeyFromContext(ctx context.Context) (*model.APIKey, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(*model.APIKey)
+	return key, ok
+}
+
+// WithUser returns ctx carrying the user a request authenticated as.
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user a request authenticated as, if any.
+func UserFromContext(ctx context.Context) (*model.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*model.User)
+	return user, ok
+}
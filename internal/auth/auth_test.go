@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestKeys_Lifecycle(t *testing.T) {
+	repo := repository.NewMemoryAPIKeyRepository()
+	keys := New(repo)
+	ctx := context.Background()
+
+	created, err := keys.Create(ctx, model.CreateAPIKeyRequest{Name: "ci-pipeline"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, KeyPrefix) || !strings.HasPrefix(created.Key, created.Hint) {
+		t.Errorf("unexpected key %q with hint %q", created.Key, created.Hint)
+	}
+	stored, _ := repo.GetByHash(ctx, Hash(created.Key))
+	if stored == nil || stored.ID != created.ID {
+		t.Fatal("expected the key to be stored under its hash")
+	}
+
+	key, err := keys.Authenticate(ctx, created.Key)
+	if err != nil || key.ID != created.ID {
+		t.Fatalf("expected the key to authenticate, got %v, %v", key, err)
+	}
+	for _, token := range []string{"", "snip_unknown", "not-a-key"} {
+		if _, err := keys.Authenticate(ctx, token); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", token, err)
+		}
+	}
+
+	if err := keys.Revoke(ctx, created.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := keys.Authenticate(ctx, created.Key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected a revoked key to be rejected, got %v", err)
+	}
+	if err := keys.Revoke(ctx, "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	list, _ := keys.List(ctx)
+	if len(list.Keys) != 1 || list.Keys[0].RevokedAt == nil {
+		t.Errorf("expected the revoked key to stay listed, got %+v", list.Keys)
+	}
+}
+
+func TestKeys_CreateValidatesName(t *testing.T) {
+	keys := New(repository.NewMemoryAPIKeyRepository())
+
+	for _, name := range []string{"", "  ", strings.Repeat("x", MaxNameLength+1)} {
+		_, err := keys.Create(context.Background(), model.CreateAPIKeyRequest{Name: name})
+		if apierror.CodeOf(err) != apierror.CodeValidationFailed {
+			t.Errorf("expected validation_failed for name %q, got %v", name, err)
+		}
+	}
+}
+
+func TestMiddleware_Keys(t *testing.T) {
+	keys := New(repository.NewMemoryAPIKeyRepository())
+	created, err := keys.Create(context.Background(), model.CreateAPIKeyRequest{Name: "dashboard"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := Middleware(keys, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := KeyFromContext(r.Context())
+		if !ok || key.ID != created.ID {
+			t.Error("expected the key in the request context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}), func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"bearer", "Authorization", "Bearer " + created.Key, http.StatusNoContent},
+		{"header", "X-API-Key", created.Key, http.StatusNoContent},
+		{"wrong key", "X-API-Key", created.Key + "x", http.StatusUnauthorized},
+		{"none", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/links", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Errors returned by Users.
+var (
+	ErrInvalidToken = errors.New("auth: missing, malformed or expired user token")
+	ErrEmailTaken   = apierror.New(apierror.CodeEmailTaken, "an account with this email already exists")
+	ErrLoginFailed  = apierror.New(apierror.CodeLoginFailed, "incorrect email or password")
+)
+
+// Account limits. The password cap bounds the work of hashing one.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 256
+	MaxEmailLength    = 254
+)
+
+// Token defaults and limits.
+const (
+	DefaultTokenTTL = 24 * time.Hour
+	MinSecretLength = 32 // bytes, the size of an HMAC-SHA256 key
+)
+
+// UsersConfig configures Users.
+type UsersConfig struct {
+	Secret   []byte        // signs user tokens; at least MinSecretLength bytes
+	TokenTTL time.Duration // how long a token is valid; defaults to DefaultTokenTTL
+}
+
+// Users signs users up and in, issuing the tokens they then authenticate
+// with. Tokens are stateless: they stay valid until they expire, and
+// changing the secret invalidates all of them.
+type Users struct {
+	repo       repository.UserRepository
+	secret     []byte
+	ttl        time.Duration
+	iterations int
+	now        func() time.Time
+}
+
+// NewUsers creates Users stored in repo.
+func NewUsers(repo repository.UserRepository, cfg UsersConfig) (*Users, error) {
+	if len(cfg.Secret) < MinSecretLength {
+		return nil, fmt.Errorf("auth: token secret must be at least %d bytes", MinSecretLength)
+	}
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = DefaultTokenTTL
+	}
+	return &Users{
+		repo:       repo,
+		secret:     cfg.Secret,
+		ttl:        cfg.TokenTTL,
+		iterations: passwordIterations,
+		now:        time.Now,
+	}, nil
+}
+
+// Signup creates an account and signs it in. Emails are compared
+// case-insensitively; an invalid email or password is reported as a
+// validation error, and an email already in use as ErrEmailTaken.
+func (u *Users) Signup(ctx context.Context, req model.Credentials) (*model.TokenResponse, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	fields := make(map[string]string)
+	switch {
+	case len(email) > MaxEmailLength:
+		fields["email"] = apierror.CodeTooLong
+	case !validEmail(email):
+		fields["email"] = apierror.CodeInvalidEmail
+	}
+	switch {
+	case len(req.Password) < MinPasswordLength:
+		fields["password"] = apierror.CodeTooShort
+	case len(req.Password) > MaxPasswordLength:
+		fields["password"] = apierror.CodeTooLong
+	}
+	if len(fields) > 0 {
+		err := apierror.New(apierror.CodeValidationFailed, "one or more fields are invalid")
+		err.Fields = fields
+		return nil, err
+	}
+
+	hash, err := hashPassword(req.Password, u.iterations)
+	if err != nil {
+		return nil, err
+	}
+	user := model.User{
+		ID:           model.NewID(),
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    u.now().UTC(),
+	}
+	if err := u.repo.Create(ctx, &user); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("storing user: %w", err)
+	}
+	return u.issue(user)
+}
+
+// Login signs in an existing account. A wrong password and an unknown
+// email both return ErrLoginFailed, so callers can't probe for accounts.
+func (u *Users) Login(ctx context.Context, req model.Credentials) (*model.TokenResponse, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if len(req.Password) > MaxPasswordLength {
+		return nil, ErrLoginFailed
+	}
+
+	user, err := u.repo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Hash anyway, so an unknown email takes as long as a wrong password
+		pbkdf2([]byte(req.Password), nil, u.iterations)
+		return nil, ErrLoginFailed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if !checkPassword(user.PasswordHash, req.Password) {
+		return nil, ErrLoginFailed
+	}
+	return u.issue(*user)
+}
+
+// Verify returns the user a token was issued to, with their ID and email,
+// or ErrInvalidToken.
+func (u *Users) Verify(token string) (*model.User, error) {
+	c, err := parseToken(u.secret, token, u.now())
+	if err != nil {
+		return nil, err
+	}
+	return &model.User{ID: c.Subject, Email: c.Email}, nil
+}
+
+// issue signs a token for user.
+func (u *Users) issue(user model.User) (*model.TokenResponse, error) {
+	now := u.now().UTC()
+	expires := now.Add(u.ttl).Truncate(time.Second)
+	token, err := signToken(u.secret, claims{
+		Subject:   user.ID,
+		Email:     user.Email,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signing token: %w", err)
+	}
+	return &model.TokenResponse{Token: token, TokenType: "Bearer", ExpiresAt: expires, User: user}, nil
+}
+
+// validEmail reports whether email is a bare address, without a display
+// name or angle brackets.
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+var testSecret = []byte(strings.Repeat("s", MinSecretLength))
+
+// newTestUsers returns Users with cheap password hashing.
+func newTestUsers(t *testing.T) *Users {
+	t.Helper()
+	users, err := NewUsers(repository.NewMemoryUserRepository(), UsersConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	users.iterations = 1000
+	return users
+}
+
+func TestUsers_SignupAndLogin(t *testing.T) {
+	users := newTestUsers(t)
+	ctx := context.Background()
+
+	signed, err := users.Signup(ctx, model.Credentials{Email: " Ada@Example.com ", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signed.User.Email != "ada@example.com" || signed.TokenType != "Bearer" {
+		t.Errorf("unexpected signup response %+v", signed)
+	}
+
+	if _, err := users.Signup(ctx, model.Credentials{Email: "ADA@example.com", Password: "another one"}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+
+	logged, err := users.Login(ctx, model.Credentials{Email: "ada@EXAMPLE.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, err := users.Verify(logged.Token)
+	if err != nil || user.ID != signed.User.ID || user.Email != "ada@example.com" {
+		t.Errorf("unexpected user %+v (%v)", user, err)
+	}
+
+	for _, creds := range []model.Credentials{
+		{Email: "ada@example.com", Password: "wrong horse"},
+		{Email: "grace@example.com", Password: "correct horse"},
+	} {
+		if _, err := users.Login(ctx, creds); !errors.Is(err, ErrLoginFailed) {
+			t.Errorf("expected ErrLoginFailed for %q, got %v", creds.Email, err)
+		}
+	}
+}
+
+func TestUsers_SignupValidates(t *testing.T) {
+	users := newTestUsers(t)
+
+	tests := []struct {
+		creds model.Credentials
+		field string
+		code  string
+	}{
+		{model.Credentials{Email: "", Password: "long enough"}, "email", apierror.CodeInvalidEmail},
+		{model.Credentials{Email: "Ada <ada@example.com>", Password: "long enough"}, "email", apierror.CodeInvalidEmail},
+		{model.Credentials{Email: strings.Repeat("a", MaxEmailLength) + "@example.com", Password: "long enough"}, "email", apierror.CodeTooLong},
+		{model.Credentials{Email: "ada@example.com", Password: "short"}, "password", apierror.CodeTooShort},
+		{model.Credentials{Email: "ada@example.com", Password: strings.Repeat("x", MaxPasswordLength+1)}, "password", apierror.CodeTooLong},
+	}
+	for _, tt := range tests {
+		_, err := users.Signup(context.Background(), tt.creds)
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) || apiErr.Fields[tt.field] != tt.code {
+			t.Errorf("expected %s for %s with %+v, got %v", tt.code, tt.field, tt.creds, err)
+		}
+	}
+}
+
+func TestUsers_Verify(t *testing.T) {
+	users := newTestUsers(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	users.now = func() time.Time { return now }
+
+	signed, err := users.Signup(context.Background(), model.Credentials{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !signed.ExpiresAt.Equal(now.Add(DefaultTokenTTL)) {
+		t.Errorf("expected the token to expire at %v, got %v", now.Add(DefaultTokenTTL), signed.ExpiresAt)
+	}
+
+	header, rest, _ := strings.Cut(signed.Token, ".")
+	payload, _, _ := strings.Cut(rest, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + payload + "."
+	forged, _ := signToken([]byte(strings.Repeat("x", MinSecretLength)), claims{Subject: signed.User.ID, ExpiresAt: now.Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"unsigned", unsigned},
+		{"other secret", forged},
+		{"tampered", header + "." + payload + "x." + strings.Split(signed.Token, ".")[2]},
+	}
+	for _, tt := range tests {
+		if _, err := users.Verify(tt.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", tt.name, err)
+		}
+	}
+
+	now = now.Add(DefaultTokenTTL)
+	if _, err := users.Verify(signed.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestNewUsers_ShortSecret(t *testing.T) {
+	if _, err := NewUsers(repository.NewMemoryUserRepository(), UsersConfig{Secret: []byte("short")}); err == nil {
+		t.Error("expected an error for a short secret")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11
+	got := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1))
+	if want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	stored, err := hashPassword("correct horse", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !checkPassword(stored, "correct horse") || checkPassword(stored, "wrong horse") {
+		t.Errorf("unexpected check results for %s", stored)
+	}
+}
+
+func TestMiddleware_Users(t *testing.T) {
+	users := newTestUsers(t)
+	keys := New(repository.NewMemoryAPIKeyRepository())
+	ctx := context.Background()
+	signed, err := users.Signup(ctx, model.Credentials{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, err := keys.Create(ctx, model.CreateAPIKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := Middleware(keys, users, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := UserFromContext(r.Context()); ok && user.ID == signed.User.ID {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if _, ok := KeyFromContext(r.Context()); ok {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		t.Error("expected a user or key in the request context")
+	}), func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"user", signed.Token, http.StatusNoContent},
+		{"key", key.Key, http.StatusAccepted},
+		{"garbage", "not-a-token", http.StatusUnauthorized},
+		{"none", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/links", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	bolt "go.etcd.io/bbolt"
+)
+
+// PrefixRepository is a repository.PrefixRepository backed by the store.
+type PrefixRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.PrefixRepository = (*PrefixRepository)(nil)
+
+// Create allocates a prefix.
+func (r *PrefixRepository) Create(ctx context.Context, prefix *model.Prefix) error {
+	return create(r.db, prefixesBucket, prefix.Name, prefix)
+}
+
+// Get retrieves a prefix by name.
+func (r *PrefixRepository) Get(ctx context.Context, name string) (*model.Prefix, error) {
+	var prefix model.Prefix
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(prefixesBucket), name, &prefix)
+	}); err != nil {
+		return nil, err
+	}
+	return &prefix, nil
+}
+
+// List returns all allocated prefixes, ordered by name.
+func (r *PrefixRepository) List(ctx context.Context) ([]model.Prefix, error) {
+	return list[model.Prefix](r.db, prefixesBucket)
+}
+
+// Delete releases a prefix.
+func (r *PrefixRepository) Delete(ctx context.Context, name string) error {
+	return remove(r.db, prefixesBucket, name)
+}
+
+// TemplateRepository is a repository.TemplateRepository backed by the
+// store.
+type TemplateRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.TemplateRepository = (*TemplateRepository)(nil)
+
+// Create saves a template.
+func (r *TemplateRepository) Create(ctx context.Context, template *model.Template) error {
+	return create(r.db, templatesBucket, template.ID, template)
+}
+
+// Get retrieves a template by ID.
+func (r *TemplateRepository) Get(ctx context.Context, id string) (*model.Template, error) {
+	var template model.Template
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(templatesBucket), id, &template)
+	}); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List returns all templates, ordered by ID.
+func (r *TemplateRepository) List(ctx context.Context) ([]model.Template, error) {
+	return list[model.Template](r.db, templatesBucket)
+}
+
+// Delete removes a template.
+func (r *TemplateRepository) Delete(ctx context.Context, id string) error {
+	return remove(r.db, templatesBucket, id)
+}
+
+// LinkAliasRepository is a repository.LinkAliasRepository backed by the
+// store. Aliases are few per link, so ListByShortCode scans them all.
+type LinkAliasRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.LinkAliasRepository = (*LinkAliasRepository)(nil)
+
+// Create saves an alias.
+func (r *LinkAliasRepository) Create(ctx context.Context, alias *model.LinkAlias) error {
+	return create(r.db, aliasesBucket, alias.Alias, alias)
+}
+
+// Get retrieves an alias by code.
+func (r *LinkAliasRepository) Get(ctx context.Context, alias string) (*model.LinkAlias, error) {
+	var stored model.LinkAlias
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(aliasesBucket), alias, &stored)
+	}); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// ListByShortCode returns a link's aliases, ordered by code.
+func (r *LinkAliasRepository) ListByShortCode(ctx context.Context, shortCode string) ([]model.LinkAlias, error) {
+	aliases, err := list[model.LinkAlias](r.db, aliasesBucket)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.LinkAlias{}
+	for _, alias := range aliases {
+		if alias.ShortCode == shortCode {
+			result = append(result, alias)
+		}
+	}
+	return result, nil
+}
+
+// Delete removes an alias.
+func (r *LinkAliasRepository) Delete(ctx context.Context, alias string) error {
+	return remove(r.db, aliasesBucket, alias)
+}
+
+// IdempotencyRepository is a repository.IdempotencyRepository backed by
+// the store.
+type IdempotencyRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+// Create reserves a key.
+func (r *IdempotencyRepository) Create(ctx context.Context, record *model.IdempotencyRecord) error {
+	return create(r.db, idempotencyBucket, record.Key, record)
+}
+
+// Get retrieves a record by key.
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(idempotencyBucket), key, &record)
+	}); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Update replaces the record for a key.
+func (r *IdempotencyRepository) Update(ctx context.Context, record *model.IdempotencyRecord) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(idempotencyBucket)
+		if b.Get([]byte(record.Key)) == nil {
+			return repository.ErrNotFound
+		}
+		return put(b, record.Key, record)
+	})
+}
+
+// Delete releases a key.
+func (r *IdempotencyRepository) Delete(ctx context.Context, key string) error {
+	return remove(r.db, idempotencyBucket, key)
+}
+
+// DeleteExpired removes records that expired before now.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	var expired [][]byte
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(idempotencyBucket)
+		err := b.ForEach(func(k, v []byte) error {
+			var record model.IdempotencyRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("decoding %s/%s: %w", idempotencyBucket, k, err)
+			}
+			if record.ExpiresAt.Before(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Deleted after the walk, since a bucket can't change under ForEach
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// APIKeyRepository is a repository.APIKeyRepository backed by the store.
+// Keys are stored under their hash, so authenticating is one read; the
+// rare lookups by ID scan them all.
+type APIKeyRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// Create saves a key.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		if b.Get([]byte(key.Hash)) != nil {
+			return repository.ErrAlreadyExists
+		}
+		if _, err := findAPIKey(b, key.ID); err == nil {
+			return repository.ErrAlreadyExists
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return put(b, key.Hash, key)
+	})
+}
+
+// GetByHash retrieves a key by the hash of its secret.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(apiKeysBucket), hash, &key)
+	}); err != nil {
+		return nil, err
+	}
+	key.Hash = hash
+	return &key, nil
+}
+
+// List returns all keys, oldest first.
+func (r *APIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	keys, err := list[model.APIKey](r.db, apiKeysBucket)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke marks a key revoked.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		hash, err := findAPIKey(b, id)
+		if err != nil {
+			return err
+		}
+		var key model.APIKey
+		if err := get(b, hash, &key); err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		key.RevokedAt = &at
+		return put(b, hash, &key)
+	})
+}
+
+// findAPIKey returns the hash the key with the given ID is stored under.
+func findAPIKey(b *bolt.Bucket, id string) (string, error) {
+	hash := ""
+	err := b.ForEach(func(k, v []byte) error {
+		var key model.APIKey
+		if err := json.Unmarshal(v, &key); err != nil {
+			return fmt.Errorf("decoding %s/%s: %w", apiKeysBucket, k, err)
+		}
+		if key.ID == id {
+			hash = string(k)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", repository.ErrNotFound
+	}
+	return hash, nil
+}
+
+// UserRepository is a repository.UserRepository backed by the store.
+type UserRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+// userRecord is a user as stored, with the password hash the API leaves
+// out.
+type userRecord struct {
+	model.User
+	PasswordHash string `json:"password_hash"`
+}
+
+// Create saves a user.
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	return create(r.db, usersBucket, user.Email, &userRecord{User: *user, PasswordHash: user.PasswordHash})
+}
+
+// GetByEmail retrieves a user by email.
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	var record userRecord
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(usersBucket), email, &record)
+	}); err != nil {
+		return nil, err
+	}
+	user := record.User
+	user.PasswordHash = record.PasswordHash
+	return &user, nil
+}
+
+// SettingsRepository is a repository.SettingsRepository backed by the
+// store.
+type SettingsRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.SettingsRepository = (*SettingsRepository)(nil)
+
+// codeLengthKey is the settings key of the code length.
+const codeLengthKey = "code_length"
+
+// GetCodeLength returns the stored code length.
+func (r *SettingsRepository) GetCodeLength(ctx context.Context) (int, error) {
+	var length int
+	if err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(settingsBucket), codeLengthKey, &length)
+	}); err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+
+// SetCodeLength stores the code length.
+func (r *SettingsRepository) SetCodeLength(ctx context.Context, length int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(settingsBucket), codeLengthKey, length)
+	})
+}
+
+// create stores v at key in bucket, or returns repository.ErrAlreadyExists
+// if the key is taken.
+func create(db *bolt.DB, bucket []byte, key string, v any) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b.Get([]byte(key)) != nil {
+			return repository.ErrAlreadyExists
+		}
+		return put(b, key, v)
+	})
+}
+
+// list decodes every value in bucket, in key order.
+func list[T any](db *bolt.DB, bucket []byte) ([]T, error) {
+	result := []T{}
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			var item T
+			if err := json.Unmarshal(v, &item); err != nil {
+				return fmt.Errorf("decoding %s/%s: %w", bucket, k, err)
+			}
+			result = append(result, item)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// remove deletes key from bucket, or returns repository.ErrNotFound if
+// it's absent.
+func remove(db *bolt.DB, bucket []byte, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b.Get([]byte(key)) == nil {
+			return repository.ErrNotFound
+		}
+		return b.Delete([]byte(key))
+	})
+}
@@ -0,0 +1,207 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ClickRepository is a repository.ClickRepository and ClickSweeper backed
+// by the store. Events are keyed by link ID and then click time, so a
+// link's events are read newest first with a reverse cursor scan.
+type ClickRepository struct {
+	db *bolt.DB
+}
+
+var (
+	_ repository.ClickRepository = (*ClickRepository)(nil)
+	_ repository.ClickSweeper    = (*ClickRepository)(nil)
+)
+
+// linkPrefix returns the key prefix shared by everything stored for linkID.
+func linkPrefix(linkID string) []byte {
+	return append([]byte(linkID), 0)
+}
+
+// clickKey returns the key of a click event: link ID, a zero byte, the
+// click time as big-endian nanoseconds, then the event ID to keep clicks
+// in the same nanosecond apart.
+func clickKey(event *model.ClickEvent) []byte {
+	key := linkPrefix(event.LinkID)
+	key = binary.BigEndian.AppendUint64(key, uint64(event.ClickedAt.UnixNano()))
+	return append(key, event.ID...)
+}
+
+// Record persists a click event. Concurrent records are batched into one
+// write.
+func (r *ClickRepository) Record(ctx context.Context, event *model.ClickEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding click event: %w", err)
+	}
+	return r.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(clicksBucket).Put(clickKey(event), data)
+	})
+}
+
+// GetByLinkID retrieves click events for a link, most recent first. A
+// limit of zero or less returns them all.
+func (r *ClickRepository) GetByLinkID(ctx context.Context, linkID string, limit int) ([]model.ClickEvent, error) {
+	result := []model.ClickEvent{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		prefix := linkPrefix(linkID)
+		c := tx.Bucket(clicksBucket).Cursor()
+
+		// Position on the link's last key: just before the first key past it
+		k, v := c.Seek(append([]byte(linkID), 1))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Prev() {
+			if limit > 0 && len(result) == limit {
+				break
+			}
+			var event model.ClickEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return fmt.Errorf("decoding click event: %w", err)
+			}
+			result = append(result, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetBetween returns every link's click events clicked in [from, to). It
+// scans the whole bucket.
+func (r *ClickRepository) GetBetween(ctx context.Context, from, to time.Time) ([]model.ClickEvent, error) {
+	result := []model.ClickEvent{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(clicksBucket).ForEach(func(k, v []byte) error {
+			var event model.ClickEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return fmt.Errorf("decoding click event: %w", err)
+			}
+			if !event.ClickedAt.Before(from) && event.ClickedAt.Before(to) {
+				result = append(result, event)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteBefore removes click events clicked before cutoff. It scans the
+// whole bucket.
+func (r *ClickRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	var removed int
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(clicksBucket)
+
+		// Collect first: deleting under a live cursor skips keys
+		var stale [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var event model.ClickEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return fmt.Errorf("decoding click event: %w", err)
+			}
+			if event.ClickedAt.Before(cutoff) {
+				stale = append(stale, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// StatsRollupRepository is a repository.StatsRollupRepository backed by
+// the store. Aggregates are keyed by link ID and then day, so a date range
+// is one cursor scan.
+type StatsRollupRepository struct {
+	db *bolt.DB
+}
+
+var _ repository.StatsRollupRepository = (*StatsRollupRepository)(nil)
+
+// rollupKey returns the key of a link's aggregate for day.
+func rollupKey(linkID, day string) []byte {
+	return append(linkPrefix(linkID), day...)
+}
+
+// AddClick counts one click from source on day. Concurrent adds are
+// batched into one write.
+func (r *StatsRollupRepository) AddClick(ctx context.Context, linkID, day, source string) error {
+	return r.db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket(rollupsBucket)
+		key := rollupKey(linkID, day)
+
+		rollup := model.DailyClicks{Date: day}
+		if data := b.Get(key); data != nil {
+			if err := json.Unmarshal(data, &rollup); err != nil {
+				return fmt.Errorf("decoding rollup: %w", err)
+			}
+		}
+		if rollup.BySource == nil {
+			rollup.BySource = make(map[string]int64)
+		}
+		rollup.Clicks++
+		rollup.BySource[source]++
+		return put(b, string(key), &rollup)
+	})
+}
+
+// GetRange returns a link's aggregates between from and to inclusive.
+func (r *StatsRollupRepository) GetRange(ctx context.Context, linkID, from, to string) ([]model.DailyClicks, error) {
+	result := []model.DailyClicks{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		end := rollupKey(linkID, to)
+		c := tx.Bucket(rollupsBucket).Cursor()
+		for k, v := c.Seek(rollupKey(linkID, from)); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+			var rollup model.DailyClicks
+			if err := json.Unmarshal(v, &rollup); err != nil {
+				return fmt.Errorf("decoding rollup: %w", err)
+			}
+			result = append(result, rollup)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetDay replaces a link's aggregate for rollup.Date.
+func (r *StatsRollupRepository) SetDay(ctx context.Context, linkID string, rollup model.DailyClicks) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(rollupsBucket), string(rollupKey(linkID, rollup.Date)), &rollup)
+	})
+}
@@ -0,0 +1,443 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	bolt "go.etcd.io/bbolt"
+)
+
+// LinkRepository is a repository.LinkRepository and LinkSweeper backed by
+// the store.
+type LinkRepository struct {
+	db *bolt.DB
+}
+
+var (
+	_ repository.LinkRepository = (*LinkRepository)(nil)
+	_ repository.LinkSweeper    = (*LinkRepository)(nil)
+)
+
+// Create persists a new link.
+func (r *LinkRepository) Create(ctx context.Context, link *model.Link) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		if b.Get([]byte(link.ShortCode)) != nil {
+			return repository.ErrAlreadyExists
+		}
+		if err := tx.Bucket(destinationsBucket).Put(destinationKey(link.OriginalURL, link.ShortCode), []byte{}); err != nil {
+			return err
+		}
+		if link.OwnerID != "" {
+			if err := tx.Bucket(ownersBucket).Put(ownerKey(link.OwnerID, link.ShortCode), []byte{}); err != nil {
+				return err
+			}
+		}
+		if link.Pinned {
+			if err := tx.Bucket(pinnedBucket).Put([]byte(link.ShortCode), []byte{}); err != nil {
+				return err
+			}
+		}
+		return put(b, link.ShortCode, link)
+	})
+}
+
+// GetByShortCode retrieves a link by its short code.
+func (r *LinkRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.Link, error) {
+	var link model.Link
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(linksBucket), shortCode, &link)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetByShortCodes retrieves the links that exist among shortCodes.
+func (r *LinkRepository) GetByShortCodes(ctx context.Context, shortCodes []string) (map[string]*model.Link, error) {
+	result := make(map[string]*model.Link, len(shortCodes))
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		for _, code := range shortCodes {
+			data := b.Get([]byte(code))
+			if data == nil {
+				continue
+			}
+			var link model.Link
+			if err := json.Unmarshal(data, &link); err != nil {
+				return fmt.Errorf("decoding link %s: %w", code, err)
+			}
+			result[code] = &link
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Update replaces the mutable fields of an existing link.
+func (r *LinkRepository) Update(ctx context.Context, link *model.Link) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		var stored model.Link
+		if err := get(b, link.ShortCode, &stored); err != nil {
+			return err
+		}
+		if stored.Version != link.Version {
+			return repository.ErrConflict
+		}
+		if stored.OriginalURL != link.OriginalURL {
+			destinations := tx.Bucket(destinationsBucket)
+			if err := destinations.Delete(destinationKey(stored.OriginalURL, stored.ShortCode)); err != nil {
+				return err
+			}
+			if err := destinations.Put(destinationKey(link.OriginalURL, stored.ShortCode), []byte{}); err != nil {
+				return err
+			}
+		}
+		if stored.Pinned != link.Pinned {
+			pinned := tx.Bucket(pinnedBucket)
+			var err error
+			if link.Pinned {
+				err = pinned.Put([]byte(stored.ShortCode), []byte{})
+			} else {
+				err = pinned.Delete([]byte(stored.ShortCode))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		stored.OriginalURL = link.OriginalURL
+		stored.Pinned = link.Pinned
+		stored.Notes = link.Notes
+		stored.Disabled = link.Disabled
+		stored.ScanStatus = link.ScanStatus
+		stored.Interstitial = link.Interstitial
+		stored.NotifyMilestones = link.NotifyMilestones
+		stored.DeletedAt = link.DeletedAt
+		stored.AllowedReferrers = link.AllowedReferrers
+		stored.Public = link.Public
+		stored.Title = link.Title
+		stored.Version++
+		if err := put(b, stored.ShortCode, &stored); err != nil {
+			return err
+		}
+		link.Version = stored.Version
+		return nil
+	})
+}
+
+// IncrementClickCount atomically increments the click count. Concurrent
+// increments are batched into one write.
+func (r *LinkRepository) IncrementClickCount(ctx context.Context, shortCode string) (int64, error) {
+	var count int64
+	err := r.db.Batch(func(tx *bolt.Tx) error {
+		var err error
+		count, err = addClicks(tx.Bucket(linksBucket), shortCode, 1)
+		return err
+	})
+	return count, err
+}
+
+// AddClickCount atomically adds delta to a link's click count.
+func (r *LinkRepository) AddClickCount(ctx context.Context, shortCode string, delta int64) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		_, err := addClicks(tx.Bucket(linksBucket), shortCode, delta)
+		return err
+	})
+}
+
+// addClicks adds delta to a link's stored click count and returns the new
+// count.
+func addClicks(b *bolt.Bucket, shortCode string, delta int64) (int64, error) {
+	var link model.Link
+	if err := get(b, shortCode, &link); err != nil {
+		return 0, err
+	}
+	link.ClickCount += delta
+	return link.ClickCount, put(b, shortCode, &link)
+}
+
+// Delete removes a link by its short code.
+func (r *LinkRepository) Delete(ctx context.Context, shortCode string, expectedVersion int64) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		var stored model.Link
+		if err := get(b, shortCode, &stored); err != nil {
+			return err
+		}
+		if expectedVersion != 0 && stored.Version != expectedVersion {
+			return repository.ErrConflict
+		}
+		if err := tx.Bucket(destinationsBucket).Delete(destinationKey(stored.OriginalURL, shortCode)); err != nil {
+			return err
+		}
+		if stored.OwnerID != "" {
+			if err := tx.Bucket(ownersBucket).Delete(ownerKey(stored.OwnerID, shortCode)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(pinnedBucket).Delete([]byte(shortCode)); err != nil {
+			return err
+		}
+		return b.Delete([]byte(shortCode))
+	})
+}
+
+// GetByDestination returns the links that redirect to originalURL.
+func (r *LinkRepository) GetByDestination(ctx context.Context, originalURL string) ([]*model.Link, error) {
+	prefix := []byte(repository.DestinationKey(originalURL))
+	links := []*model.Link{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		c := tx.Bucket(destinationsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var link model.Link
+			if err := get(b, string(k[len(prefix):]), &link); err != nil {
+				return err
+			}
+			links = append(links, &link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// destinationKey is the destinations bucket key for a link: its
+// destination's repository.DestinationKey followed by its short code.
+func destinationKey(originalURL, shortCode string) []byte {
+	return []byte(repository.DestinationKey(originalURL) + shortCode)
+}
+
+// linkIndexes are the buckets indexing links, with the key a link has in
+// each, or nil for links an index leaves out.
+var linkIndexes = []struct {
+	bucket []byte
+	key    func(link *model.Link) []byte
+}{
+	{destinationsBucket, func(link *model.Link) []byte {
+		return destinationKey(link.OriginalURL, link.ShortCode)
+	}},
+	{ownersBucket, func(link *model.Link) []byte {
+		if link.OwnerID == "" {
+			return nil
+		}
+		return ownerKey(link.OwnerID, link.ShortCode)
+	}},
+	{pinnedBucket, func(link *model.Link) []byte {
+		if !link.Pinned {
+			return nil
+		}
+		return []byte(link.ShortCode)
+	}},
+}
+
+// indexLinks creates the link indexes that don't exist yet and fills them
+// from the links already stored, for files written before they existed.
+func indexLinks(tx *bolt.Tx) error {
+	for _, index := range linkIndexes {
+		if tx.Bucket(index.bucket) != nil {
+			continue
+		}
+		bucket, err := tx.CreateBucket(index.bucket)
+		if err != nil {
+			return err
+		}
+		links := tx.Bucket(linksBucket)
+		if links == nil {
+			continue
+		}
+		err = links.ForEach(func(k, v []byte) error {
+			var link model.Link
+			if err := json.Unmarshal(v, &link); err != nil {
+				return fmt.Errorf("decoding link %s: %w", k, err)
+			}
+			if key := index.key(&link); key != nil {
+				return bucket.Put(key, []byte{})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultListLimit is the page size of List when none is given.
+const defaultListLimit = 1000
+
+// List returns links ordered by short code; the cursor is the last code of
+// the previous page. Limit 0 returns pages of defaultListLimit.
+func (r *LinkRepository) List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	links := []*model.Link{}
+	next := ""
+	err := r.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(linksBucket).Cursor()
+		k, v := c.First()
+		if cursor != "" {
+			k, v = c.Seek([]byte(cursor))
+			if k != nil && string(k) == cursor {
+				k, v = c.Next()
+			}
+		}
+		for ; k != nil; k, v = c.Next() {
+			if len(links) == limit {
+				next = links[len(links)-1].ShortCode
+				break
+			}
+			var link model.Link
+			if err := json.Unmarshal(v, &link); err != nil {
+				return fmt.Errorf("decoding link %s: %w", k, err)
+			}
+			links = append(links, &link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return links, next, nil
+}
+
+// ListByOwner returns the links owned by ownerID ordered by short code,
+// paged as by List. The owners bucket indexes them, so other owners'
+// links aren't read.
+func (r *LinkRepository) ListByOwner(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	prefix := ownerKey(ownerID, "")
+	links := []*model.Link{}
+	next := ""
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		c := tx.Bucket(ownersBucket).Cursor()
+		k, _ := c.Seek(ownerKey(ownerID, cursor))
+		if cursor != "" && k != nil && bytes.Equal(k, ownerKey(ownerID, cursor)) {
+			k, _ = c.Next()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if len(links) == limit {
+				next = links[len(links)-1].ShortCode
+				break
+			}
+			var link model.Link
+			if err := get(b, string(k[len(prefix):]), &link); err != nil {
+				return err
+			}
+			links = append(links, &link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return links, next, nil
+}
+
+// ListPinned returns the pinned links ordered by short code, of ownerID's
+// if it's not empty, paged as by List. The pinned bucket indexes them, so
+// unpinned links aren't read.
+func (r *LinkRepository) ListPinned(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	links := []*model.Link{}
+	next := ""
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		c := tx.Bucket(pinnedBucket).Cursor()
+		k, _ := c.First()
+		if cursor != "" {
+			k, _ = c.Seek([]byte(cursor))
+			if k != nil && string(k) == cursor {
+				k, _ = c.Next()
+			}
+		}
+		for ; k != nil; k, _ = c.Next() {
+			if len(links) == limit {
+				next = links[len(links)-1].ShortCode
+				break
+			}
+			var link model.Link
+			if err := get(b, string(k), &link); err != nil {
+				return err
+			}
+			if ownerID == "" || link.OwnerID == ownerID {
+				links = append(links, &link)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return links, next, nil
+}
+
+// ownerKey is the owners bucket key for a link. Owner IDs are ULIDs, so
+// the separator can't occur in them.
+func ownerKey(ownerID, shortCode string) []byte {
+	return []byte(ownerID + "/" + shortCode)
+}
+
+// DeletedBefore returns soft-deleted links deleted before cutoff.
+func (r *LinkRepository) DeletedBefore(ctx context.Context, cutoff time.Time) ([]*model.Link, error) {
+	var links []*model.Link
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(linksBucket).ForEach(func(k, v []byte) error {
+			var link model.Link
+			if err := json.Unmarshal(v, &link); err != nil {
+				return fmt.Errorf("decoding link %s: %w", k, err)
+			}
+			if link.DeletedAt != nil && link.DeletedAt.Before(cutoff) {
+				links = append(links, &link)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// get decodes the value at key into v, or returns repository.ErrNotFound.
+func get(b *bolt.Bucket, key string, v any) error {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return repository.ErrNotFound
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// put stores v as JSON at key.
+func put(b *bolt.Bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", key, err)
+	}
+	return b.Put([]byte(key), data)
+}
@@ -0,0 +1,217 @@
+// Package boltstore keeps links, clicks and the rest of the server's state
+// in a single bbolt file, so a lone API server has durable storage without
+// running a database. Values are stored as JSON.
+package boltstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the database file created in the data directory.
+const FileName = "snip.db"
+
+// Store defaults.
+const (
+	DefaultLockTimeout = time.Second
+	compactTxSize      = 64 << 20 // bytes copied per transaction while compacting
+)
+
+// Buckets, one per repository.
+var (
+	linksBucket        = []byte("links")        // short code -> link
+	destinationsBucket = []byte("destinations") // destination key, short code -> nothing
+	ownersBucket       = []byte("owners")       // owner ID, "/", short code -> nothing
+	pinnedBucket       = []byte("pinned")       // short code of a pinned link -> nothing
+	clicksBucket       = []byte("clicks")       // link ID, time, event ID -> click event
+	rollupsBucket      = []byte("rollups")      // link ID, day -> daily clicks
+	prefixesBucket     = []byte("prefixes")     // name -> prefix
+	templatesBucket    = []byte("templates")    // ID -> template
+	aliasesBucket      = []byte("aliases")      // alias -> link alias
+	idempotencyBucket  = []byte("idempotency")  // Idempotency-Key -> record
+	apiKeysBucket      = []byte("api_keys")     // key hash -> API key
+	usersBucket        = []byte("users")        // email -> user
+	settingsBucket     = []byte("settings")     // setting name -> value
+)
+
+// Config configures a Store.
+type Config struct {
+	// Dir is the data directory. It's created if missing, and holds
+	// FileName.
+	Dir string
+
+	// CompactFreeRatio, when positive, rewrites the file on Open if at
+	// least this fraction of it is free pages, e.g. 0.5 after half the
+	// clicks were purged. bbolt reuses free pages but never shrinks the
+	// file by itself.
+	CompactFreeRatio float64
+
+	// LockTimeout bounds waiting for another process to release the file.
+	// Defaults to DefaultLockTimeout.
+	LockTimeout time.Duration
+
+	Logger *slog.Logger // optional; defaults to discarding output
+}
+
+// Store is an open database. Its repositories share it; Close it once
+// they're no longer used.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the database in cfg.Dir, compacting it first when
+// cfg.CompactFreeRatio calls for it.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("boltstore: data directory is required")
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = DefaultLockTimeout
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+
+	path := filepath.Join(cfg.Dir, FileName)
+	options := &bolt.Options{Timeout: cfg.LockTimeout}
+	db, err := bolt.Open(path, 0o600, options)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	if cfg.CompactFreeRatio > 0 {
+		if db, err = compactIfFree(db, path, options, cfg.CompactFreeRatio, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := indexLinks(tx); err != nil {
+			return err
+		}
+		for _, name := range [][]byte{linksBucket, clicksBucket, rollupsBucket, prefixesBucket, templatesBucket, aliasesBucket, idempotencyBucket, apiKeysBucket, usersBucket, settingsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating buckets: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Links returns the store's link repository.
+func (s *Store) Links() *LinkRepository {
+	return &LinkRepository{db: s.db}
+}
+
+// Clicks returns the store's click repository.
+func (s *Store) Clicks() *ClickRepository {
+	return &ClickRepository{db: s.db}
+}
+
+// Rollups returns the store's daily click rollups.
+func (s *Store) Rollups() *StatsRollupRepository {
+	return &StatsRollupRepository{db: s.db}
+}
+
+// Prefixes returns the store's namespace prefix repository.
+func (s *Store) Prefixes() *PrefixRepository {
+	return &PrefixRepository{db: s.db}
+}
+
+// Templates returns the store's link template repository.
+func (s *Store) Templates() *TemplateRepository {
+	return &TemplateRepository{db: s.db}
+}
+
+// Aliases returns the store's secondary link alias repository.
+func (s *Store) Aliases() *LinkAliasRepository {
+	return &LinkAliasRepository{db: s.db}
+}
+
+// Idempotency returns the store's Idempotency-Key records.
+func (s *Store) Idempotency() *IdempotencyRepository {
+	return &IdempotencyRepository{db: s.db}
+}
+
+// APIKeys returns the store's API key repository.
+func (s *Store) APIKeys() *APIKeyRepository {
+	return &APIKeyRepository{db: s.db}
+}
+
+// Users returns the store's user repository.
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{db: s.db}
+}
+
+// Settings returns the store's settings repository.
+func (s *Store) Settings() *SettingsRepository {
+	return &SettingsRepository{db: s.db}
+}
+
+// compactIfFree rewrites the database at path into a fresh file when at
+// least ratio of it is free pages, and returns the database to use from
+// then on. The original is only replaced once the copy is complete.
+func compactIfFree(db *bolt.DB, path string, options *bolt.Options, ratio float64, logger *slog.Logger) (*bolt.DB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("checking %s: %w", path, err)
+	}
+	stats := db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(db.Info().PageSize)
+	if info.Size() == 0 || float64(free)/float64(info.Size()) < ratio {
+		return db, nil
+	}
+
+	tmpPath := path + ".compact"
+	os.Remove(tmpPath) // left over from an interrupted compaction
+	dst, err := bolt.Open(tmpPath, 0o600, options)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", tmpPath, err)
+	}
+	if err := bolt.Compact(dst, db, compactTxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		db.Close()
+		return nil, fmt.Errorf("compacting %s: %w", path, err)
+	}
+	dst.Close()
+	db.Close()
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("replacing %s: %w", path, err)
+	}
+	compacted, err := os.Stat(path)
+	if err == nil {
+		logger.Info("compacted data file", "path", path, "bytes_before", info.Size(), "bytes_after", compacted.Size())
+	}
+
+	db, err = bolt.Open(path, 0o600, options)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,489 @@
+package boltstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	store, err := Open(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := Open(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	link := &model.Link{ID: "link-1", ShortCode: "abc", OriginalURL: "https://example.com"}
+	if err := store.Links().Create(ctx, link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Links().IncrementClickCount(ctx, "abc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Settings().SetCodeLength(ctx, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Close()
+
+	store = openTestStore(t, dir)
+	got, err := store.Links().GetByShortCode(ctx, "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.OriginalURL != "https://example.com" || got.ClickCount != 1 {
+		t.Errorf("unexpected link after reopen: %+v", got)
+	}
+	if length, err := store.Settings().GetCodeLength(ctx); err != nil || length != 8 {
+		t.Errorf("expected code length 8, got %d (%v)", length, err)
+	}
+	if err := store.Links().Create(ctx, link); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+}
+
+func TestLinkRepository_UpdateChecksVersion(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Links()
+	ctx := context.Background()
+
+	repo.Create(ctx, &model.Link{ShortCode: "abc", OriginalURL: "https://example.com", ClickCount: 5})
+
+	update := &model.Link{ShortCode: "abc", OriginalURL: "https://example.org", Version: 0}
+	if err := repo.Update(ctx, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.Version != 1 {
+		t.Errorf("expected version 1 on the passed link, got %d", update.Version)
+	}
+
+	stale := &model.Link{ShortCode: "abc", OriginalURL: "https://example.net", Version: 0}
+	if err := repo.Update(ctx, stale); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	if err := repo.Delete(ctx, "abc", 3); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("expected ErrConflict deleting a stale version, got %v", err)
+	}
+
+	got, _ := repo.GetByShortCode(ctx, "abc")
+	if got.OriginalURL != "https://example.org" || got.ClickCount != 5 {
+		t.Errorf("unexpected link %+v", got)
+	}
+
+	if err := repo.Delete(ctx, "abc", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.GetByShortCode(ctx, "abc"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLinkRepository_List(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Links()
+	ctx := context.Background()
+
+	for _, code := range []string{"ccc", "aaa", "bbb", "ddd", "eee"} {
+		repo.Create(ctx, &model.Link{ShortCode: code, OriginalURL: "https://example.com"})
+	}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		links, next, err := repo.List(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var codes []string
+		for _, link := range links {
+			codes = append(codes, link.ShortCode)
+		}
+		pages = append(pages, codes)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if got := fmt.Sprint(pages); got != "[[aaa bbb] [ccc ddd] [eee]]" {
+		t.Errorf("unexpected pages %s", got)
+	}
+}
+
+func TestLinkRepository_ListByOwner(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	repo := store.Links()
+	ctx := context.Background()
+
+	for _, link := range []*model.Link{
+		{ShortCode: "a1", OwnerID: "ada"},
+		{ShortCode: "a2", OwnerID: "ada"},
+		{ShortCode: "a3", OwnerID: "ada"},
+		{ShortCode: "g1", OwnerID: "grace"},
+		{ShortCode: "n1"},
+	} {
+		if err := repo.Create(ctx, link); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := repo.Delete(ctx, "a2", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	page, next, err := repo.ListByOwner(ctx, "ada", "", 1)
+	if err != nil || len(page) != 1 || page[0].ShortCode != "a1" || next != "a1" {
+		t.Fatalf("unexpected first page %v, next %q (%v)", page, next, err)
+	}
+	page, next, err = repo.ListByOwner(ctx, "ada", next, 1)
+	if err != nil || len(page) != 1 || page[0].ShortCode != "a3" || next != "" {
+		t.Fatalf("unexpected last page %v, next %q (%v)", page, next, err)
+	}
+	if page, _, _ := repo.ListByOwner(ctx, "grace", "", 0); len(page) != 1 || page[0].ShortCode != "g1" {
+		t.Errorf("expected only g1 for grace, got %v", page)
+	}
+
+	// Files written before the index existed are indexed on open
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(ownersBucket)
+	})
+	if err != nil {
+		t.Fatalf("dropping index: %v", err)
+	}
+	store.Close()
+
+	repo = openTestStore(t, dir).Links()
+	if page, _, _ := repo.ListByOwner(ctx, "ada", "", 0); len(page) != 2 {
+		t.Errorf("expected ada's 2 links after reindexing, got %v", page)
+	}
+}
+
+func TestLinkRepository_ListPinned(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	repo := store.Links()
+	ctx := context.Background()
+
+	for _, link := range []*model.Link{
+		{ShortCode: "a1", OwnerID: "ada", Pinned: true},
+		{ShortCode: "a2", OwnerID: "ada"},
+		{ShortCode: "a3", OwnerID: "ada", Pinned: true},
+		{ShortCode: "g1", OwnerID: "grace", Pinned: true},
+	} {
+		if err := repo.Create(ctx, link); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Pinning and unpinning keep the index current
+	a2, _ := repo.GetByShortCode(ctx, "a2")
+	a2.Pinned = true
+	a3, _ := repo.GetByShortCode(ctx, "a3")
+	a3.Pinned = false
+	if err := repo.Update(ctx, a2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Update(ctx, a3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Delete(ctx, "a1", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	codes := func(repo *LinkRepository, ownerID string) string {
+		links, _, err := repo.ListPinned(ctx, ownerID, "", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var codes []string
+		for _, link := range links {
+			codes = append(codes, link.ShortCode)
+		}
+		return fmt.Sprint(codes)
+	}
+	if got := codes(repo, ""); got != "[a2 g1]" {
+		t.Errorf("expected [a2 g1] pinned, got %s", got)
+	}
+	if got := codes(repo, "ada"); got != "[a2]" {
+		t.Errorf("expected [a2] pinned for ada, got %s", got)
+	}
+
+	// Files written before the index existed are indexed on open
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(pinnedBucket)
+	})
+	if err != nil {
+		t.Fatalf("dropping index: %v", err)
+	}
+	store.Close()
+
+	if got := codes(openTestStore(t, dir).Links(), ""); got != "[a2 g1]" {
+		t.Errorf("expected [a2 g1] after reindexing, got %s", got)
+	}
+}
+
+func TestLinkRepository_GetByDestination(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := Open(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	repo := store.Links()
+	repo.Create(ctx, &model.Link{ShortCode: "aaa", OriginalURL: "https://example.com"})
+	repo.Create(ctx, &model.Link{ShortCode: "bbb", OriginalURL: "https://example.com"})
+	repo.Create(ctx, &model.Link{ShortCode: "ccc", OriginalURL: "https://example.org"})
+	if err := repo.Update(ctx, &model.Link{ShortCode: "bbb", OriginalURL: "https://example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Delete(ctx, "ccc", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	codes := func(repo *LinkRepository, url string) string {
+		t.Helper()
+		links, err := repo.GetByDestination(ctx, url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var codes []string
+		for _, link := range links {
+			codes = append(codes, link.ShortCode)
+		}
+		return fmt.Sprint(codes)
+	}
+	if got := codes(repo, "https://example.com"); got != "[aaa]" {
+		t.Errorf("expected [aaa] for example.com, got %s", got)
+	}
+	if got := codes(repo, "https://example.org"); got != "[bbb]" {
+		t.Errorf("expected [bbb] for example.org, got %s", got)
+	}
+
+	// Files written before the index existed are indexed on open
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(destinationsBucket)
+	})
+	if err != nil {
+		t.Fatalf("dropping index: %v", err)
+	}
+	store.Close()
+
+	repo = openTestStore(t, dir).Links()
+	if got := codes(repo, "https://example.org"); got != "[bbb]" {
+		t.Errorf("expected [bbb] after reindexing, got %s", got)
+	}
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).APIKeys()
+	ctx := context.Background()
+
+	key := &model.APIKey{ID: "key-1", Name: "ci", Hash: "hash-1", CreatedAt: time.Now()}
+	if err := repo.Create(ctx, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Create(ctx, &model.APIKey{ID: "key-1", Hash: "hash-2"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists for a taken ID, got %v", err)
+	}
+
+	got, err := repo.GetByHash(ctx, "hash-1")
+	if err != nil || got.ID != "key-1" || got.Hash != "hash-1" {
+		t.Fatalf("unexpected key %+v (%v)", got, err)
+	}
+
+	if err := repo.Revoke(ctx, "key-1", time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Revoke(ctx, "missing", time.Now()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	keys, _ := repo.List(ctx)
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("expected one revoked key, got %+v", keys)
+	}
+}
+
+func TestUserRepository(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Users()
+	ctx := context.Background()
+
+	user := &model.User{ID: "user-1", Email: "ada@example.com", PasswordHash: "hash-1", CreatedAt: time.Now()}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Create(ctx, &model.User{ID: "user-2", Email: "ada@example.com"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists for a taken email, got %v", err)
+	}
+
+	// The password hash is left out of the user's JSON but must be stored
+	got, err := repo.GetByEmail(ctx, "ada@example.com")
+	if err != nil || got.ID != "user-1" || got.PasswordHash != "hash-1" {
+		t.Fatalf("unexpected user %+v (%v)", got, err)
+	}
+	if _, err := repo.GetByEmail(ctx, "grace@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClickRepository_NewestFirst(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Clicks()
+	ctx := context.Background()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		repo.Record(ctx, &model.ClickEvent{ID: fmt.Sprintf("c%d", i), LinkID: "link-1", ClickedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	// A neighbouring link ID must not leak into link-1's events
+	repo.Record(ctx, &model.ClickEvent{ID: "x", LinkID: "link-10", ClickedAt: start.Add(time.Hour)})
+
+	events, err := repo.GetByLinkID(ctx, "link-1", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 3 || events[0].ID != "c4" || events[2].ID != "c2" {
+		t.Errorf("expected c4, c3, c2, got %+v", events)
+	}
+
+	events, _ = repo.GetByLinkID(ctx, "link-10", 0)
+	if len(events) != 1 || events[0].ID != "x" {
+		t.Errorf("expected only link-10's event, got %+v", events)
+	}
+	if events, _ := repo.GetByLinkID(ctx, "missing", 0); events == nil || len(events) != 0 {
+		t.Errorf("expected an empty slice, got %#v", events)
+	}
+
+	between, _ := repo.GetBetween(ctx, start.Add(time.Minute), start.Add(3*time.Minute))
+	if len(between) != 2 {
+		t.Errorf("expected 2 events between, got %d", len(between))
+	}
+
+	removed, err := repo.DeleteBefore(ctx, start.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
+	}
+	events, _ = repo.GetByLinkID(ctx, "link-1", 0)
+	if len(events) != 3 || events[2].ID != "c2" {
+		t.Errorf("expected c4, c3, c2 left, got %+v", events)
+	}
+}
+
+func TestStatsRollupRepository_GetRange(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Rollups()
+	ctx := context.Background()
+
+	repo.AddClick(ctx, "link-1", "2024-06-01", model.ClickSourceLink)
+	repo.AddClick(ctx, "link-1", "2024-06-01", model.ClickSourceQR)
+	repo.AddClick(ctx, "link-1", "2024-06-03", model.ClickSourceLink)
+	repo.AddClick(ctx, "link-1", "2024-06-05", model.ClickSourceLink)
+	repo.SetDay(ctx, "link-1", model.DailyClicks{Date: "2024-06-02", Clicks: 4, BySource: map[string]int64{model.ClickSourceLink: 4}})
+
+	days, err := repo.GetRange(ctx, "link-1", "2024-06-01", "2024-06-03")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(days) != 3 {
+		t.Fatalf("expected 3 days, got %+v", days)
+	}
+	if days[0].Clicks != 2 || days[0].BySource[model.ClickSourceQR] != 1 || days[1].Clicks != 4 || days[2].Date != "2024-06-03" {
+		t.Errorf("unexpected days %+v", days)
+	}
+}
+
+func TestIdempotencyRepository_DeleteExpired(t *testing.T) {
+	repo := openTestStore(t, t.TempDir()).Idempotency()
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, key := range []string{"a", "b", "c"} {
+		record := &model.IdempotencyRecord{Key: key, ExpiresAt: now.Add(time.Duration(i-1) * time.Hour)}
+		if err := repo.Create(ctx, record); err != nil {
+			t.Fatalf("failed to create record: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &model.IdempotencyRecord{Key: "a"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists for a held key, got %v", err)
+	}
+
+	removed, err := repo.DeleteExpired(ctx, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 record removed, got %d, %v", removed, err)
+	}
+	if _, err := repo.Get(ctx, "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the expired record to be gone, got %v", err)
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, err := repo.Get(ctx, key); err != nil {
+			t.Errorf("expected %s to be kept, got %v", key, err)
+		}
+	}
+}
+
+func TestOpen_CompactsFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	path := filepath.Join(dir, FileName)
+
+	store, err := Open(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	padding := string(make([]byte, 512))
+	// One transaction: Record batches, and would wait on every call
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		for i := 0; i < 2000; i++ {
+			event := &model.ClickEvent{ID: fmt.Sprintf("c%d", i), LinkID: "link-1", ClickedAt: start.Add(time.Duration(i) * time.Second), UserAgent: padding}
+			if err := put(tx.Bucket(clicksBucket), string(clickKey(event)), event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Clicks().DeleteBefore(ctx, start.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Close()
+
+	before, _ := os.Stat(path)
+	store = openTestStore(t, dir)
+	store.Close()
+	if after, _ := os.Stat(path); after.Size() != before.Size() {
+		t.Fatalf("expected no compaction without CompactFreeRatio")
+	}
+
+	store, err = Open(Config{Dir: dir, CompactFreeRatio: 0.5})
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	defer store.Close()
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size()/2 {
+		t.Errorf("expected the file to shrink from %d bytes, got %d", before.Size(), after.Size())
+	}
+	if _, err := os.Stat(path + ".compact"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package clickhouse stores click events in ClickHouse for analytics on
+// high-traffic deployments. It talks to ClickHouse's HTTP interface, so no
+// native driver is needed.
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// Click repository defaults.
+const (
+	DefaultTable         = "click_events"
+	DefaultBatchSize     = 1000
+	DefaultFlushInterval = 5 * time.Second
+	httpTimeout          = 30 * time.Second
+)
+
+// maxBufferedBatches bounds how many batches' worth of events are held
+// while ClickHouse is unreachable; beyond that the oldest are dropped.
+const maxBufferedBatches = 10
+
+// timeLayout is how clicked_at is written to and read from a DateTime64(3)
+// column in UTC.
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Schema is the table the repository expects, with the table name as the
+// format argument. Ordering by link and time keeps per-link reads fast.
+const Schema = `CREATE TABLE IF NOT EXISTS %s (
+    id         String,
+    link_id    String,
+    clicked_at DateTime64(3, 'UTC'),
+    referrer   String,
+    user_agent String,
+    ip_address String,
+    source     LowCardinality(String),
+    deduped    Bool DEFAULT false,
+    prefetch   Bool DEFAULT false
+) ENGINE = MergeTree
+ORDER BY (link_id, clicked_at)`
+
+// Config configures a ClickHouse click repository.
+type Config struct {
+	// URL is the HTTP interface, e.g. "http://localhost:8123".
+	URL      string
+	Database string // defaults to the user's default database
+	Table    string // defaults to DefaultTable
+	Username string
+	Password string
+
+	// Events are inserted in batches of BatchSize, or every FlushInterval
+	// if fewer have arrived. Defaults to DefaultBatchSize and
+	// DefaultFlushInterval.
+	BatchSize     int
+	FlushInterval time.Duration
+
+	Client *http.Client // defaults to a client with a 30s timeout
+	Logger *slog.Logger
+}
+
+// ClickRepository is a repository.ClickRepository backed by ClickHouse.
+// Record only buffers the event; a background loop inserts buffered
+// events in batches, so events become visible to GetByLinkID after the
+// next flush. Call Close on shutdown to flush what's left.
+type ClickRepository struct {
+	endpoint      string
+	table         string
+	username      string
+	password      string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	pending []model.ClickEvent
+
+	flushMu sync.Mutex // serializes inserts so batches keep their order
+
+	kick chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClickRepository creates a ClickHouse click repository and starts its
+// flush loop.
+func NewClickRepository(config Config) *ClickRepository {
+	r := &ClickRepository{
+		endpoint:      config.URL,
+		table:         config.Table,
+		username:      config.Username,
+		password:      config.Password,
+		batchSize:     config.BatchSize,
+		flushInterval: config.FlushInterval,
+		client:        config.Client,
+		logger:        config.Logger,
+		kick:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	if r.table == "" {
+		r.table = DefaultTable
+	}
+	if config.Database != "" {
+		r.table = config.Database + "." + r.table
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultBatchSize
+	}
+	if r.flushInterval <= 0 {
+		r.flushInterval = DefaultFlushInterval
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: httpTimeout}
+	}
+	if r.logger == nil {
+		r.logger = slog.Default()
+	}
+
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+// Record buffers a click event for the next batch insert.
+func (r *ClickRepository) Record(ctx context.Context, event *model.ClickEvent) error {
+	r.mu.Lock()
+	r.pending = append(r.pending, *event)
+	full := len(r.pending) >= r.batchSize
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// row is a click event as stored in ClickHouse.
+type row struct {
+	ID        string `json:"id"`
+	LinkID    string `json:"link_id"`
+	ClickedAt string `json:"clicked_at"`
+	Referrer  string `json:"referrer"`
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
+	Source    string `json:"source"`
+	Deduped   bool   `json:"deduped"`
+	Prefetch  bool   `json:"prefetch"`
+}
+
+// GetByLinkID retrieves a link's click events, most recent first. Events
+// still waiting to be flushed aren't included.
+func (r *ClickRepository) GetByLinkID(ctx context.Context, linkID string, limit int) ([]model.ClickEvent, error) {
+	query := fmt.Sprintf("SELECT id, link_id, clicked_at, referrer, user_agent, ip_address, source, deduped, prefetch FROM %s WHERE link_id = {link_id:String} ORDER BY clicked_at DESC", r.table)
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	query += " FORMAT JSONEachRow"
+
+	params := url.Values{"param_link_id": {linkID}}
+	body, err := r.do(ctx, params, []byte(query))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	events := []model.ClickEvent{}
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var rec row
+		if err := decoder.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("decoding click event: %w", err)
+		}
+		clickedAt, err := time.Parse(timeLayout, rec.ClickedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parsing clicked_at %q: %w", rec.ClickedAt, err)
+		}
+		events = append(events, model.ClickEvent{
+			ID:        rec.ID,
+			LinkID:    rec.LinkID,
+			ClickedAt: clickedAt,
+			Referrer:  rec.Referrer,
+			UserAgent: rec.UserAgent,
+			IPAddress: rec.IPAddress,
+			Source:    rec.Source,
+			Deduped:   rec.Deduped,
+			Prefetch:  rec.Prefetch,
+		})
+	}
+	return events, nil
+}
+
+// Flush inserts all buffered events. On failure the events are kept for
+// the next attempt.
+func (r *ClickRepository) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := r.insert(ctx, batch); err != nil {
+		r.requeue(batch)
+		return err
+	}
+	return nil
+}
+
+// Close stops the flush loop and flushes remaining events.
+func (r *ClickRepository) Close(ctx context.Context) error {
+	close(r.done)
+	r.wg.Wait()
+	return r.Flush(ctx)
+}
+
+// loop flushes on the interval, or sooner when a batch fills up.
+func (r *ClickRepository) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.kick:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+		if err := r.Flush(ctx); err != nil {
+			r.logger.Warn("failed to flush click events to clickhouse", "error", err)
+		}
+		cancel()
+	}
+}
+
+// requeue puts a failed batch back in front of newer events, dropping the
+// oldest once the buffer is over its bound.
+func (r *ClickRepository) requeue(batch []model.ClickEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(batch, r.pending...)
+	if limit := r.batchSize * maxBufferedBatches; len(r.pending) > limit {
+		dropped := len(r.pending) - limit
+		r.pending = r.pending[dropped:]
+		r.logger.Warn("dropped click events while clickhouse is unavailable", "count", dropped)
+	}
+}
+
+// insert writes a batch with a single INSERT ... FORMAT JSONEachRow.
+func (r *ClickRepository) insert(ctx context.Context, batch []model.ClickEvent) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "INSERT INTO %s FORMAT JSONEachRow\n", r.table)
+
+	encoder := json.NewEncoder(&buf)
+	for _, event := range batch {
+		err := encoder.Encode(row{
+			ID:        event.ID,
+			LinkID:    event.LinkID,
+			ClickedAt: event.ClickedAt.UTC().Format(timeLayout),
+			Referrer:  event.Referrer,
+			UserAgent: event.UserAgent,
+			IPAddress: event.IPAddress,
+			Source:    event.Source,
+			Deduped:   event.Deduped,
+			Prefetch:  event.Prefetch,
+		})
+		if err != nil {
+			return fmt.Errorf("encoding click event: %w", err)
+		}
+	}
+
+	body, err := r.do(ctx, nil, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("inserting %d click events: %w", len(batch), err)
+	}
+	return body.Close()
+}
+
+// do posts a statement to the HTTP interface and returns the response
+// body, or an error carrying ClickHouse's message for non-200 answers.
+func (r *ClickRepository) do(ctx context.Context, params url.Values, statement []byte) (io.ReadCloser, error) {
+	endpoint := r.endpoint + "/"
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(statement))
+	if err != nil {
+		return nil, err
+	}
+	if r.username != "" {
+		req.Header.Set("X-ClickHouse-User", r.username)
+		req.Header.Set("X-ClickHouse-Key", r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,172 @@
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// fakeServer records statements posted to a fake ClickHouse HTTP interface.
+type fakeServer struct {
+	mu         sync.Mutex
+	statements []string
+	fail       bool
+	inserted   chan struct{}
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		http.Error(w, "Code: 210. Connection refused", http.StatusServiceUnavailable)
+		return
+	}
+	f.statements = append(f.statements, string(body))
+
+	if strings.HasPrefix(string(body), "INSERT") {
+		select {
+		case f.inserted <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if r.URL.Query().Get("param_link_id") == "link-1" {
+		io.WriteString(w, `{"id":"c2","link_id":"link-1","clicked_at":"2024-06-01 12:00:01.500","referrer":"","user_agent":"curl","ip_address":"","source":"qr"}`+"\n")
+		io.WriteString(w, `{"id":"c1","link_id":"link-1","clicked_at":"2024-06-01 12:00:00.000","referrer":"","user_agent":"curl","ip_address":"","source":"link"}`+"\n")
+	}
+}
+
+func (f *fakeServer) inserts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var inserts []string
+	for _, statement := range f.statements {
+		if strings.HasPrefix(statement, "INSERT") {
+			inserts = append(inserts, statement)
+		}
+	}
+	return inserts
+}
+
+func newTestRepository(t *testing.T, fake *fakeServer, batchSize int) *ClickRepository {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	repo := NewClickRepository(Config{
+		URL:           server.URL,
+		Database:      "snip",
+		BatchSize:     batchSize,
+		FlushInterval: time.Hour, // flushes only when a batch fills up or on Close
+	})
+	t.Cleanup(func() { repo.Close(context.Background()) })
+	return repo
+}
+
+func TestClickRepository_BatchesInserts(t *testing.T) {
+	fake := &fakeServer{inserted: make(chan struct{}, 1)}
+	repo := newTestRepository(t, fake, 3)
+	ctx := context.Background()
+
+	clickedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		repo.Record(ctx, &model.ClickEvent{ID: "c", LinkID: "link-1", ClickedAt: clickedAt, Source: "link"})
+	}
+
+	select {
+	case <-fake.inserted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a full batch to be inserted")
+	}
+
+	inserts := fake.inserts()
+	if len(inserts) != 1 {
+		t.Fatalf("expected 1 insert, got %d", len(inserts))
+	}
+	scanner := bufio.NewScanner(strings.NewReader(inserts[0]))
+	scanner.Scan()
+	if got := scanner.Text(); got != "INSERT INTO snip.click_events FORMAT JSONEachRow" {
+		t.Errorf("unexpected insert statement %q", got)
+	}
+	rows := 0
+	for scanner.Scan() {
+		if !strings.Contains(scanner.Text(), `"clicked_at":"2024-06-01 12:00:00.000"`) {
+			t.Errorf("unexpected row %s", scanner.Text())
+		}
+		rows++
+	}
+	if rows != 3 {
+		t.Errorf("expected 3 rows, got %d", rows)
+	}
+}
+
+func TestClickRepository_FlushRetainsFailedBatch(t *testing.T) {
+	fake := &fakeServer{fail: true, inserted: make(chan struct{}, 1)}
+	repo := newTestRepository(t, fake, 100)
+	ctx := context.Background()
+
+	repo.Record(ctx, &model.ClickEvent{ID: "c1", LinkID: "link-1", ClickedAt: time.Now()})
+	if err := repo.Flush(ctx); err == nil || !strings.Contains(err.Error(), "Connection refused") {
+		t.Fatalf("expected flush to fail with the server's message, got %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.fail = false
+	fake.mu.Unlock()
+
+	repo.Record(ctx, &model.ClickEvent{ID: "c2", LinkID: "link-1", ClickedAt: time.Now()})
+	if err := repo.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inserts := fake.inserts()
+	if len(inserts) != 1 {
+		t.Fatalf("expected 1 insert, got %d", len(inserts))
+	}
+	first, second := strings.Index(inserts[0], `"c1"`), strings.Index(inserts[0], `"c2"`)
+	if first < 0 || second < first {
+		t.Errorf("expected the retried event before the new one, got %s", inserts[0])
+	}
+}
+
+func TestClickRepository_GetByLinkID(t *testing.T) {
+	fake := &fakeServer{inserted: make(chan struct{}, 1)}
+	repo := newTestRepository(t, fake, 100)
+
+	events, err := repo.GetByLinkID(context.Background(), "link-1", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	want := time.Date(2024, 6, 1, 12, 0, 1, 500_000_000, time.UTC)
+	if events[0].ID != "c2" || !events[0].ClickedAt.Equal(want) || events[0].Source != "qr" {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+
+	fake.mu.Lock()
+	query := fake.statements[len(fake.statements)-1]
+	fake.mu.Unlock()
+	if !strings.Contains(query, "FROM snip.click_events WHERE link_id = {link_id:String}") || !strings.Contains(query, "LIMIT 10") {
+		t.Errorf("unexpected query %q", query)
+	}
+
+	events, err = repo.GetByLinkID(context.Background(), "other", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events, got %d", len(events))
+	}
+}
@@ -0,0 +1,54 @@
+// Package directory renders the public directory page, an HTML listing
+// of the links their owners marked public, for community or documentation
+// deployments that want their short links browsable.
+package directory
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"net/url"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// DefaultTitle heads the page when Config.Title is empty.
+const DefaultTitle = "Links"
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/directory.html"))
+
+// Config configures a Renderer.
+type Config struct {
+	Title string // heading of the page; defaults to DefaultTitle
+}
+
+// Renderer renders directory pages.
+type Renderer struct {
+	title string
+}
+
+// New creates a Renderer.
+func New(config Config) *Renderer {
+	r := &Renderer{title: config.Title}
+	if r.title == "" {
+		r.title = DefaultTitle
+	}
+	return r
+}
+
+// Render writes one page of the directory, linking to the next page at
+// path when there is one.
+func (r *Renderer) Render(w io.Writer, path string, links *model.DirectoryPage) error {
+	next := ""
+	if links.NextCursor != "" {
+		next = path + "?" + url.Values{"cursor": {links.NextCursor}}.Encode()
+	}
+	return page.Execute(w, struct {
+		Title string
+		Links []model.DirectoryEntry
+		Next  string
+	}{r.title, links.Links, next})
+}
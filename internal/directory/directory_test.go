@@ -0,0 +1,48 @@
+package directory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	var b strings.Builder
+	links := &model.DirectoryPage{
+		Links: []model.DirectoryEntry{
+			{ShortCode: "docs", ShortURL: "https://snip.io/docs", OriginalURL: "https://example.com/docs", Title: "Docs <beta>"},
+			{ShortCode: "untitled", ShortURL: "https://snip.io/untitled", OriginalURL: "https://example.com/"},
+		},
+		NextCursor: "untitled",
+	}
+	if err := New(Config{Title: "Community Links"}).Render(&b, "/directory", links); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`<title>Community Links</title>`,
+		`<a class="title" href="https://snip.io/docs">Docs &lt;beta&gt;</a>`,
+		`<a class="title" href="https://snip.io/untitled">untitled</a>`,
+		`href="/directory?cursor=untitled"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected page to contain %s", want)
+		}
+	}
+}
+
+func TestRenderer_Render_LastPage(t *testing.T) {
+	var b strings.Builder
+	if err := New(Config{}).Render(&b, "/directory", &model.DirectoryPage{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+	if !strings.Contains(out, DefaultTitle) || !strings.Contains(out, "No public links yet.") {
+		t.Error("expected the default title and an empty listing")
+	}
+	if strings.Contains(out, `rel="next"`) {
+		t.Error("expected no next link on the last page")
+	}
+}
@@ -0,0 +1,30 @@
+// Package errreport forwards unexpected failures to an error aggregation
+// service so they are not only buried in logs.
+package errreport
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reporter receives unexpected errors (5xx responses, recovered panics).
+// Implementations must not block the caller for long.
+type Reporter interface {
+	Report(ctx context.Context, err error, tags map[string]string)
+}
+
+// Nop discards every report. It is the default when no reporter is configured.
+type Nop struct{}
+
+// Report implements Reporter.
+func (Nop) Report(context.Context, error, map[string]string) {}
+
+// PanicError wraps a value recovered from a panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
@@ -0,0 +1,201 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/colby/snip/internal/tracecontext"
+)
+
+// Sentry defaults.
+const (
+	sentryQueueSize   = 100
+	sentrySendTimeout = 5 * time.Second
+	sentryClient      = "snip-errreport/1.0"
+)
+
+// SentryConfig configures the Sentry reporter.
+type SentryConfig struct {
+	DSN         string // https://<key>@<host>/<project-id>
+	Environment string // e.g., "production"
+	Release     string // optional version identifier
+}
+
+// Sentry reports errors to a Sentry-compatible ingestion endpoint using the
+// store API. Events are queued and sent by a background worker so request
+// paths never wait on the network; when the queue is full events are dropped.
+type Sentry struct {
+	storeURL    string
+	authHeader  string
+	environment string
+	release     string
+	client      *http.Client
+	queue       chan sentryEvent
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+// NewSentry parses the DSN and starts the background sender.
+func NewSentry(cfg SentryConfig) (*Sentry, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sentry DSN: %w", err)
+	}
+
+	key := dsn.User.Username()
+	projectID := strings.Trim(dsn.Path[strings.LastIndex(dsn.Path, "/")+1:], "/")
+	if key == "" || projectID == "" || dsn.Host == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: expected scheme://key@host/project-id")
+	}
+
+	// Any path segments before the project ID are kept (self-hosted prefixes)
+	prefix := strings.TrimSuffix(dsn.Path[:strings.LastIndex(dsn.Path, "/")+1], "/")
+
+	s := &Sentry{
+		storeURL:    fmt.Sprintf("%s://%s%s/api/%s/store/", dsn.Scheme, dsn.Host, prefix, projectID),
+		authHeader:  fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, key),
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		client:      &http.Client{Timeout: sentrySendTimeout},
+		queue:       make(chan sentryEvent, sentryQueueSize),
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s, nil
+}
+
+// Report queues an error for delivery.
+func (s *Sentry) Report(ctx context.Context, err error, tags map[string]string) {
+	if err == nil {
+		return
+	}
+
+	event := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Environment: s.environment,
+		Release:     s.release,
+		Tags:        tags,
+		Exception: &sentryExceptions{Values: []sentryException{{
+			Type:  errorType(err),
+			Value: err.Error(),
+		}}},
+	}
+
+	if pe, ok := err.(*PanicError); ok {
+		event.Level = "fatal"
+		event.Extra = map[string]string{"stack": string(pe.Stack)}
+	}
+
+	if tc, ok := tracecontext.FromContext(ctx); ok {
+		event.Contexts = map[string]any{
+			"trace": map[string]string{"trace_id": tc.TraceID, "span_id": tc.SpanID},
+		}
+	}
+
+	select {
+	case s.queue <- event:
+	default:
+		// Queue full; dropping is preferable to slowing down requests
+	}
+}
+
+// Close stops accepting events and waits for queued events to be sent.
+func (s *Sentry) Close() {
+	s.closeOnce.Do(func() {
+		close(s.queue)
+	})
+	s.wg.Wait()
+}
+
+func (s *Sentry) run() {
+	defer s.wg.Done()
+
+	for event := range s.queue {
+		_ = s.send(event)
+	}
+}
+
+func (s *Sentry) send(event sentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sentrySendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.authHeader)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sentryEvent is the subset of the Sentry event payload we populate.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Contexts    map[string]any    `json:"contexts,omitempty"`
+	Exception   *sentryExceptions `json:"exception,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// errorType reports the innermost error's Go type, which Sentry uses for grouping.
+func errorType(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	return reflect.TypeOf(err).String()
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,58 @@
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewSentry_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://sentry.example.com/1", "https://key@sentry.example.com/"} {
+		if _, err := NewSentry(SentryConfig{DSN: dsn}); err == nil {
+			t.Errorf("expected error for DSN %q", dsn)
+		}
+	}
+}
+
+func TestSentry_Report(t *testing.T) {
+	type received struct {
+		path  string
+		auth  string
+		event sentryEvent
+	}
+	got := make(chan received, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event sentryEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		got <- received{path: r.URL.Path, auth: r.Header.Get("X-Sentry-Auth"), event: event}
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://publickey@", 1) + "/42"
+	s, err := NewSentry(SentryConfig{DSN: dsn, Environment: "test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.Report(context.Background(), errors.New("boom"), map[string]string{"route": "GET /{code}"})
+	s.Close()
+
+	r := <-got
+	if r.path != "/api/42/store/" {
+		t.Errorf("expected store path /api/42/store/, got %s", r.path)
+	}
+	if !strings.Contains(r.auth, "sentry_key=publickey") {
+		t.Errorf("expected auth header to carry the public key, got %q", r.auth)
+	}
+	if r.event.Environment != "test" || r.event.Tags["route"] != "GET /{code}" {
+		t.Errorf("unexpected event: %+v", r.event)
+	}
+	if r.event.Exception == nil || r.event.Exception.Values[0].Value != "boom" {
+		t.Errorf("expected exception value boom, got %+v", r.event.Exception)
+	}
+}
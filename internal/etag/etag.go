@@ -0,0 +1,40 @@
+// Package etag maps link versions to HTTP entity tags for optimistic
+// concurrency (ETag on reads, If-Match on writes).
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for If-Match values that don't name a single
+// link version.
+var ErrInvalid = errors.New("invalid If-Match value")
+
+// Format returns the strong entity tag for a version, e.g. "3" (quoted).
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseIfMatch returns the version named by an If-Match header. An empty
+// header or "*" imposes no version requirement and yields 0. Weak tags
+// (W/"3") are accepted since proxies may weaken them. Lists of tags are
+// rejected: a link has exactly one current version.
+func ParseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, ErrInvalid
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalid
+	}
+	return version, nil
+}
@@ -0,0 +1,39 @@
+package etag
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int64
+		wantErr bool
+	}{
+		{header: "", want: 0},
+		{header: "*", want: 0},
+		{header: `"3"`, want: 3},
+		{header: ` W/"12" `, want: 12},
+		{header: "3", wantErr: true},
+		{header: `"abc"`, wantErr: true},
+		{header: `"0"`, wantErr: true},
+		{header: `"1", "2"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := ParseIfMatch(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	got, err := ParseIfMatch(Format(42))
+	if err != nil || got != 42 {
+		t.Errorf("expected 42, got %d (%v)", got, err)
+	}
+}
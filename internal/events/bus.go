@@ -0,0 +1,111 @@
+// Package events provides an in-process publish/subscribe bus for domain events.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// Event types published on the bus.
+const (
+	TypeLinkCreated   = "link.created"
+	TypeLinkUpdated   = "link.updated"
+	TypeLinkFlagged   = "link.flagged" // URL scan flagged the destination; the link was disabled
+	TypeClickRecorded = "click.recorded"
+
+	// TypeMilestoneReached is published when a link with milestone
+	// notifications enabled reaches one of the configured click counts.
+	TypeMilestoneReached = "link.milestone"
+)
+
+// Event is a single domain event delivered to subscribers.
+type Event struct {
+	Type      string            `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	ShortCode string            `json:"short_code"`
+	Link      *model.Link       `json:"link,omitempty"`
+	Click     *model.ClickEvent `json:"click,omitempty"`
+	Milestone int64             `json:"milestone,omitempty"` // click count reached, for TypeMilestoneReached
+}
+
+// DefaultBufferSize is the channel buffer used when Subscribe is given a non-positive size.
+const DefaultBufferSize = 64
+
+// Bus fans out published events to all current subscribers.
+// Publishing never blocks: events are dropped for subscribers whose buffer is full,
+// so a slow consumer cannot stall redirects.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch     chan Event
+	filter func(Event) bool
+}
+
+// NewBus creates an empty event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish delivers the event to every subscriber whose filter accepts it.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// Subscriber is not keeping up; drop rather than block
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. A nil filter receives every event.
+// The returned cancel function unregisters the subscriber and closes the channel.
+func (b *Bus) Subscribe(buffer int, filter func(Event) bool) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+
+	sub := &subscription{
+		ch:     make(chan Event, buffer),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+
+	return sub.ch, cancel
+}
+
+// ForShortCode returns a filter matching events for a single short code.
+func ForShortCode(shortCode string) func(Event) bool {
+	return func(e Event) bool {
+		return e.ShortCode == shortCode
+	}
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	all, cancelAll := bus.Subscribe(4, nil)
+	defer cancelAll()
+
+	filtered, cancelFiltered := bus.Subscribe(4, ForShortCode("abc"))
+	defer cancelFiltered()
+
+	bus.Publish(Event{Type: TypeClickRecorded, ShortCode: "abc"})
+	bus.Publish(Event{Type: TypeClickRecorded, ShortCode: "xyz"})
+
+	if got := len(all); got != 2 {
+		t.Errorf("expected 2 events for unfiltered subscriber, got %d", got)
+	}
+
+	if got := len(filtered); got != 1 {
+		t.Fatalf("expected 1 event for filtered subscriber, got %d", got)
+	}
+
+	select {
+	case e := <-filtered:
+		if e.ShortCode != "abc" {
+			t.Errorf("expected short code abc, got %s", e.ShortCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestBus_DropsWhenFull(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(1, nil)
+	defer cancel()
+
+	bus.Publish(Event{ShortCode: "a"})
+	bus.Publish(Event{ShortCode: "b"})
+
+	if got := len(ch); got != 1 {
+		t.Errorf("expected buffer to hold 1 event, got %d", got)
+	}
+}
+
+func TestBus_Cancel(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(1, nil)
+	cancel()
+	cancel() // second cancel must be a no-op
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+
+	// Publishing after cancel must not panic
+	bus.Publish(Event{ShortCode: "a"})
+}
+
+func TestBus_NilPublish(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{ShortCode: "a"})
+}
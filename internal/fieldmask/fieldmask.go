@@ -0,0 +1,90 @@
+// Package fieldmask prunes JSON responses to the top-level fields a client
+// asked for with ?fields=a,b,c.
+package fieldmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Apply returns v reduced to the comma-separated fields in spec. An empty
+// spec returns v unchanged. Names that v doesn't have are reported as a
+// validation error with each offending name mapped to unknown_field.
+//
+// Fields are matched against v's JSON names, so names that v omits when
+// empty (omitempty) are still accepted and simply absent from the result.
+func Apply(v any, spec string) (any, error) {
+	if strings.TrimSpace(spec) == "" {
+		return v, nil
+	}
+
+	known, err := jsonFields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encoding response: %w", err)
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("response is not a JSON object: %w", err)
+	}
+
+	selected := make(map[string]json.RawMessage)
+	unknown := make(map[string]string)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			unknown[name] = apierror.CodeUnknownField
+			continue
+		}
+		if raw, ok := all[name]; ok {
+			selected[name] = raw
+		}
+	}
+
+	if len(unknown) > 0 {
+		apiErr := apierror.New(apierror.CodeValidationFailed, "unknown fields requested")
+		apiErr.Fields = unknown
+		return nil, apiErr
+	}
+	return selected, nil
+}
+
+// jsonFields lists the JSON member names a struct (or pointer to one) can
+// produce, including those normally dropped by omitempty.
+func jsonFields(v any) (map[string]bool, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("fieldmask: %T is not a struct", v)
+	}
+
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
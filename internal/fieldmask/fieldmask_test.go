@@ -0,0 +1,51 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+type sample struct {
+	ShortCode  string `json:"short_code"`
+	ClickCount int64  `json:"click_count"`
+	Notes      string `json:"notes,omitempty"`
+	hidden     string
+}
+
+func TestApply(t *testing.T) {
+	v := &sample{ShortCode: "abc1234", ClickCount: 42, hidden: "x"}
+
+	tests := []struct {
+		name     string
+		spec     string
+		want     string
+		wantCode string
+	}{
+		{name: "empty spec", spec: "", want: `{"short_code":"abc1234","click_count":42}`},
+		{name: "subset", spec: "short_code, click_count", want: `{"click_count":42,"short_code":"abc1234"}`},
+		{name: "omitted field", spec: "notes", want: `{}`},
+		{name: "unknown field", spec: "short_code,hidden", wantCode: apierror.CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(v, tt.spec)
+			if tt.wantCode != "" {
+				if apierror.CodeOf(err) != tt.wantCode {
+					t.Fatalf("expected %s, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, _ := json.Marshal(got)
+			if string(data) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// adminOnly rejects requests without the admin Bearer token, and those
+// from outside the admin networks or without a client certificate when
+// either is required.
+func (h *Handler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.internalClient(r) {
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeForbidden)
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(h.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="snip-admin"`)
+			h.writeError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RecountClicks handles POST /api/admin/links/{code}/recount
+func (h *Handler) RecountClicks(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	recount, err := h.linkService.RecountClicks(r.Context(), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		default:
+			h.internalError(w, r, "failed to recount clicks", err, "code", code)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, recount)
+}
+
+// MergeLinks handles POST /api/admin/links/{code}/merge
+func (h *Handler) MergeLinks(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	var req model.MergeLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	merge, err := h.linkService.MergeLinks(r.Context(), code, req.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		default:
+			h.internalError(w, r, "failed to merge links", err, "code", code, "into", req.Into)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, merge)
+}
+
+// internalClient reports whether r may reach the admin endpoints.
+func (h *Handler) internalClient(r *http.Request) bool {
+	if h.adminClientCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		return false
+	}
+	return len(h.adminNetworks) == 0 || inNetworks(clientIP(r, h.trustedProxies), h.adminNetworks)
+}
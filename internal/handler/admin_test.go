@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/service"
+)
+
+func TestHandler_RecountClicks(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	linkService := service.NewLinkService(linkRepo, clickRepo, service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	h := New(linkService, logger, Config{AdminToken: "admin-secret"})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	ctx := context.Background()
+	// A count that drifted above the two stored events
+	linkRepo.Create(ctx, &model.Link{ID: "link-1", ShortCode: "drift1", OriginalURL: "https://example.com", ClickCount: 5})
+	for _, id := range []string{"c1", "c2"} {
+		clickRepo.Record(ctx, &model.ClickEvent{ID: id, LinkID: "link-1", ClickedAt: time.Now()})
+	}
+
+	recount := func(code, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/links/"+code+"/recount", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := recount("drift1", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := recount("drift1", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d with a wrong token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := recount("missing", "admin-secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown link, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := recount("drift1", "admin-secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var got model.ClickRecount
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.PreviousClickCount != 5 || got.ClickCount != 2 {
+		t.Errorf("expected recount 5 -> 2, got %+v", got)
+	}
+
+	link, _ := linkRepo.GetByShortCode(ctx, "drift1")
+	if link.ClickCount != 2 {
+		t.Errorf("expected stored click count 2, got %d", link.ClickCount)
+	}
+}
+
+func TestHandler_MergeLinks(t *testing.T) {
+	config := service.DefaultConfig()
+	config.Aliases = repository.NewMemoryLinkAliasRepository()
+	linkRepo := repository.NewMemoryLinkRepository()
+	linkService := service.NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	h := New(linkService, logger, Config{AdminToken: "admin-secret"})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	ctx := context.Background()
+	linkRepo.Create(ctx, &model.Link{ID: "link-1", ShortCode: "keep", OriginalURL: "https://example.com", ClickCount: 2, Version: 1})
+	linkRepo.Create(ctx, &model.Link{ID: "link-2", ShortCode: "dupe", OriginalURL: "https://example.com", ClickCount: 3, Version: 1})
+
+	merge := func(code, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/links/"+code+"/merge", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := merge("dupe", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without into, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := merge("dupe", `{"into": "missing"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown link, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := merge("dupe", `{"into": "keep"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var got model.LinkMerge
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.ShortCode != "keep" || got.MergedCode != "dupe" || got.ClickCount != 5 {
+		t.Errorf("unexpected merge %+v", got)
+	}
+}
+
+func TestHandler_AdminNetworks(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	linkService := service.NewLinkService(linkRepo, repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	h := New(linkService, logger, Config{
+		AdminToken:    "admin-secret",
+		AdminNetworks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")},
+	})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	linkRepo.Create(context.Background(), &model.Link{ID: "link-1", ShortCode: "abc1234", OriginalURL: "https://example.com"})
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{"10.1.2.3:5000", "", http.StatusOK},
+		{"[::1]:5000", "", http.StatusOK},
+		{"203.0.113.7:5000", "", http.StatusForbidden},
+		{"203.0.113.7:5000", "10.1.2.3", http.StatusForbidden}, // forwarded headers aren't trusted
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/links/abc1234/recount", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s (forwarded %q): expected status %d, got %d", tt.remoteAddr, tt.forwarded, tt.want, rec.Code)
+		}
+	}
+
+	// Redirects stay public
+	req := httptest.NewRequest(http.MethodGet, "/abc1234", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMovedPermanently {
+		t.Errorf("expected status %d from an outside network, got %d", http.StatusMovedPermanently, rec.Code)
+	}
+}
+
+func TestHandler_AdminClientCert(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	linkService := service.NewLinkService(linkRepo, repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	h := New(linkService, logger, Config{AdminToken: "admin-secret", AdminClientCert: true})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	linkRepo.Create(context.Background(), &model.Link{ID: "link-1", ShortCode: "abc1234", OriginalURL: "https://example.com"})
+
+	recount := func(state *tls.ConnectionState) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/links/abc1234/recount", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		req.TLS = state
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := recount(nil); code != http.StatusForbidden {
+		t.Errorf("expected status %d over plain HTTP, got %d", http.StatusForbidden, code)
+	}
+	if code := recount(&tls.ConnectionState{}); code != http.StatusForbidden {
+		t.Errorf("expected status %d without a client certificate, got %d", http.StatusForbidden, code)
+	}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	if code := recount(verified); code != http.StatusOK {
+		t.Errorf("expected status %d with a verified client certificate, got %d", http.StatusOK, code)
+	}
+}
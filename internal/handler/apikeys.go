@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// requireAuth rejects requests without a live API key or user token.
+// Requests made with a user's token act for that user, who only sees and
+// changes their own links; API keys act for the operator and see them all.
+func (h *Handler) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	owned := func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			r = r.WithContext(service.WithOwner(r.Context(), user.ID))
+		}
+		next(w, r)
+	}
+	return auth.Middleware(h.apiKeys, h.users, http.HandlerFunc(owned), func(w http.ResponseWriter, r *http.Request, err error) {
+		if !errors.Is(err, auth.ErrInvalidKey) && !errors.Is(err, auth.ErrInvalidToken) {
+			h.internalError(w, r, "failed to check API key", err)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="snip"`)
+		h.writeError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized)
+	}).ServeHTTP
+}
+
+// CreateAPIKey handles POST /api/admin/keys
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	key, err := h.apiKeys.Create(r.Context(), req)
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.internalError(w, r, "failed to create API key", err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key created", "key_id", key.ID, "name", key.Name)
+	h.writeJSON(w, http.StatusCreated, key)
+}
+
+// ListAPIKeys handles GET /api/admin/keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeys.List(r.Context())
+	if err != nil {
+		h.internalError(w, r, "failed to list API keys", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /api/admin/keys/{id}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.apiKeys.Revoke(r.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeAPIKeyNotFound)
+			return
+		}
+		h.internalError(w, r, "failed to revoke API key", err, "key_id", id)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key revoked", "key_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
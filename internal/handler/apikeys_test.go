@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/service"
+)
+
+func TestHandler_APIKeys(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	h := New(linkService, logger, Config{
+		AdminToken: "admin-secret",
+		APIKeys:    auth.New(repository.NewMemoryAPIKeyRepository()),
+	})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/links", "", `{"url": "https://example.com", "custom_code": "guarded"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d without a key, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/admin/keys", "", `{"name": "ci"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d creating a key without the admin token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/api/admin/keys", "admin-secret", `{"name": "ci"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created model.CreateAPIKeyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if rec := do(http.MethodPost, "/api/links", created.Key, `{"url": "https://example.com", "custom_code": "guarded"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d with a key, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/guarded", "", ""); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("expected redirects to stay public, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/links/export/sitemap", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected the sitemap to stay public, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/api/admin/keys", "admin-secret", "")
+	if rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte(created.Key)) {
+		t.Errorf("expected the listing without the key itself, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodDelete, "/api/admin/keys/"+created.ID, "admin-secret", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d revoking, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/links/guarded", created.Key, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked key to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/admin/keys/missing", "admin-secret", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown key, got %d", http.StatusNotFound, rec.Code)
+	}
+}
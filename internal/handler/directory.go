@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// GetDirectory handles GET /api/directory
+func (h *Handler) GetDirectory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := h.linkService.PublicLinks(r.Context(), query.Get("cursor"), query.Get("limit"))
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.internalError(w, r, "failed to list public links", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, page)
+}
+
+// DirectoryPage handles GET /directory, the HTML view of GetDirectory.
+func (h *Handler) DirectoryPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := h.linkService.PublicLinks(r.Context(), query.Get("cursor"), query.Get("limit"))
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.internalError(w, r, "failed to list public links", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.directory.Render(w, r.URL.Path, page); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to render directory", "error", err)
+	}
+}
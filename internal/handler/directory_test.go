@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/colby/snip/internal/directory"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/service"
+)
+
+func TestHandler_Directory(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	ctx := context.Background()
+	for _, req := range []model.CreateLinkRequest{
+		{URL: "https://example.com/docs", CustomCode: "docs", Public: true, Title: "Project docs"},
+		{URL: "https://example.com/internal", CustomCode: "internal"},
+	} {
+		if _, err := linkService.CreateLink(ctx, req); err != nil {
+			t.Fatalf("failed to create link: %v", err)
+		}
+	}
+
+	h := New(linkService, logger, Config{PublicDirectory: true, Directory: directory.Config{Title: "Community"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/directory", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var page model.DirectoryPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(page.Links) != 1 || page.Links[0].ShortCode != "docs" || page.Links[0].Title != "Project docs" {
+		t.Errorf("expected only the public link, got %+v", page.Links)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/directory", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected an HTML page, got %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Project docs") || strings.Contains(body, "internal") {
+		t.Errorf("expected the page to list only the public link, got %s", body)
+	}
+
+	// The page's path is no longer available as a code
+	if _, err := linkService.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", CustomCode: "directory"}); err == nil {
+		t.Error("expected /directory to be reserved")
+	}
+}
+
+func TestHandler_Directory_Disabled(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/directory", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d without the directory enabled, got %d", http.StatusNotFound, rec.Code)
+	}
+}
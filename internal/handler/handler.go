@@ -3,135 +3,935 @@ package handler
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"net/netip"
+	"runtime/debug"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/directory"
+	"github.com/colby/snip/internal/errreport"
+	"github.com/colby/snip/internal/etag"
+	"github.com/colby/snip/internal/fieldmask"
+	"github.com/colby/snip/internal/i18n"
+	"github.com/colby/snip/internal/interstitial"
 	"github.com/colby/snip/internal/model"
 	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/pkg/apierror"
 )
 
 // Handler holds the HTTP handlers and their dependencies.
 type Handler struct {
-	linkService *service.LinkService
-	logger      *slog.Logger
+	linkService  service.Links
+	logger       *slog.Logger
+	reporter     errreport.Reporter
+	interstitial *interstitial.Renderer
+	directory    *directory.Renderer // nil unless the public directory is on
+	apiKeys      *auth.Keys          // nil turns API keys off
+	users        *auth.Users         // nil turns user accounts off
+	adminToken   string
+
+	adminNetworks   []netip.Prefix
+	adminClientCert bool
+
+	trustedProxies []netip.Prefix
+}
+
+// Config holds optional Handler settings. The zero value is valid.
+type Config struct {
+	ErrorReporter errreport.Reporter  // receives unexpected (5xx) errors; defaults to a no-op
+	Interstitial  interstitial.Config // branding of the countdown page for interstitial links
+
+	// PublicDirectory serves the links marked public at /directory and
+	// /api/directory. Off by default, since creators may expect a public
+	// link to be found by search engines but not browsed.
+	PublicDirectory bool
+	Directory       directory.Config // heading of the directory page
+
+	// APIKeys, when set, requires an API key on the /api endpoints, except
+	// those that are public by design (the directory and sitemap) or have
+	// credentials of their own (admin). Redirects stay public.
+	APIKeys *auth.Keys
+
+	// Users, when set, enables user accounts: signup and login under
+	// /api/auth, and a user's token is then accepted wherever an API key
+	// is. Like APIKeys, it requires credentials on the /api endpoints.
+	Users *auth.Users
+
+	// AdminToken enables the /api/admin endpoints for requests carrying it
+	// as a Bearer token. Empty leaves them unregistered.
+	AdminToken string
+
+	// AdminNetworks, when set, limits the admin endpoints to clients in
+	// these ranges, as identified by the trusted proxies.
+	AdminNetworks []netip.Prefix
+
+	// AdminClientCert limits the admin endpoints to TLS connections that
+	// presented a client certificate the server verified.
+	AdminClientCert bool
+
+	// TrustedProxies are the ranges of proxies and load balancers in front
+	// of the server. X-Forwarded-For and X-Real-IP are only honored from
+	// them; with none, the connection's address is the client's.
+	TrustedProxies []netip.Prefix
 }
 
 // New creates a new Handler with the given dependencies.
-func New(linkService *service.LinkService, logger *slog.Logger) *Handler {
+func New(linkService service.Links, logger *slog.Logger, config Config) *Handler {
+	reporter := config.ErrorReporter
+	if reporter == nil {
+		reporter = errreport.Nop{}
+	}
+
+	var dir *directory.Renderer
+	if config.PublicDirectory {
+		dir = directory.New(config.Directory)
+	}
+
 	return &Handler{
-		linkService: linkService,
-		logger:      logger,
+		linkService:  linkService,
+		logger:       logger,
+		reporter:     reporter,
+		interstitial: interstitial.New(config.Interstitial),
+		directory:    dir,
+		apiKeys:      config.APIKeys,
+		users:        config.Users,
+		adminToken:   config.AdminToken,
+
+		adminNetworks:   config.AdminNetworks,
+		adminClientCert: config.AdminClientCert,
+
+		trustedProxies: config.TrustedProxies,
 	}
 }
 
-// RegisterRoutes registers all HTTP routes on the given mux.
+// RegisterRoutes registers all HTTP routes on the given mux. The literal
+// first path segment of each route is reserved with the service, so no
+// alias or generated code can shadow it.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/links", h.CreateLink)
-	mux.HandleFunc("GET /api/links/{code}/stats", h.GetStats)
-	mux.HandleFunc("DELETE /api/links/{code}", h.DeleteLink)
-	mux.HandleFunc("GET /{code}", h.Redirect)
-	mux.HandleFunc("GET /health", h.HealthCheck)
+	routes := &routeSet{mux: mux}
+	if h.apiKeys != nil || h.users != nil {
+		routes.guard = h.requireAuth
+	}
+	routes.HandleFunc("POST /api/links", h.CreateLink)
+	routes.HandleFunc("GET /api/links", h.ListLinks)
+	routes.HandleFunc("GET /api/links/{code}", h.GetLink)
+	routes.HandlePublic("GET /api/links/export/sitemap", h.ExportSitemap)
+	routes.HandleFunc("GET /api/links/{code}/stats", h.GetStats)
+	routes.HandleFunc("GET /api/links/{code}/stats/daily", h.GetClickTimeseries)
+	if h.linkService.ThumbnailsEnabled() {
+		routes.HandleFunc("GET /api/links/{code}/thumbnail", h.GetThumbnail)
+	}
+	routes.HandleFunc("GET /api/links/{code}/qr", h.GetQRCode)
+	routes.HandleFunc("POST /api/stats/batch", h.GetStatsBatch)
+	routes.HandleFunc("PATCH /api/links/{code}", h.UpdateLink)
+	routes.HandleFunc("DELETE /api/links/{code}", h.DeleteLink)
+	routes.HandleFunc("POST /api/links/{code}/restore", h.RestoreLink)
+	routes.HandleFunc("POST /api/links/{code}/pin", h.PinLink)
+	routes.HandleFunc("DELETE /api/links/{code}/pin", h.UnpinLink)
+	routes.HandleFunc("GET /api/aliases/{alias}/availability", h.CheckAlias)
+	routes.HandleFunc("POST /api/aliases/suggest", h.SuggestAliases)
+	if h.linkService.PrefixesEnabled() {
+		routes.HandleFunc("POST /api/prefixes", h.CreatePrefix)
+		routes.HandleFunc("GET /api/prefixes", h.ListPrefixes)
+		routes.HandleFunc("DELETE /api/prefixes/{prefix}", h.DeletePrefix)
+	}
+	if h.linkService.TemplatesEnabled() {
+		routes.HandleFunc("POST /api/templates", h.CreateTemplate)
+		routes.HandleFunc("GET /api/templates", h.ListTemplates)
+		routes.HandleFunc("DELETE /api/templates/{id}", h.DeleteTemplate)
+		routes.HandleFunc("POST /api/templates/{id}/links", h.CreateLinkFromTemplate)
+	}
+	if h.linkService.LinkAliasesEnabled() {
+		routes.HandleFunc("POST /api/links/{code}/aliases", h.AddLinkAlias)
+		routes.HandleFunc("GET /api/links/{code}/aliases", h.ListLinkAliases)
+		routes.HandleFunc("DELETE /api/links/{code}/aliases/{alias}", h.RemoveLinkAlias)
+	}
+	if h.users != nil {
+		routes.HandlePublic("POST /api/auth/signup", h.Signup)
+		routes.HandlePublic("POST /api/auth/login", h.Login)
+	}
+	if h.directory != nil {
+		routes.HandlePublic("GET /api/directory", h.GetDirectory)
+		routes.HandleFunc("GET /directory", h.DirectoryPage)
+	}
+	if h.adminToken != "" {
+		routes.HandlePublic("POST /api/admin/links/{code}/recount", h.adminOnly(h.RecountClicks))
+		if h.linkService.LinkAliasesEnabled() {
+			routes.HandlePublic("POST /api/admin/links/{code}/merge", h.adminOnly(h.MergeLinks))
+		}
+		if h.apiKeys != nil {
+			routes.HandlePublic("POST /api/admin/keys", h.adminOnly(h.CreateAPIKey))
+			routes.HandlePublic("GET /api/admin/keys", h.adminOnly(h.ListAPIKeys))
+			routes.HandlePublic("DELETE /api/admin/keys/{id}", h.adminOnly(h.RevokeAPIKey))
+		}
+	}
+	routes.HandleFunc("GET /{code}", h.Redirect)
+	routes.HandleFunc("GET /{code}/{rest...}", h.Redirect)
+	routes.HandleFunc("GET /health", h.HealthCheck)
+
+	h.linkService.ReservePaths(routes.segments...)
+}
+
+// routeSet registers routes on a mux, collecting the literal first path
+// segment of each ("api" for "POST /api/links", nothing for "GET /{code}").
+// With a guard, routes under /api/ are registered behind it unless added
+// with HandlePublic.
+type routeSet struct {
+	mux      *http.ServeMux
+	segments []string
+	guard    func(http.HandlerFunc) http.HandlerFunc
+}
+
+func (rs *routeSet) HandleFunc(pattern string, handler http.HandlerFunc) {
+	_, path, _ := strings.Cut(pattern, " ")
+	if rs.guard != nil && strings.HasPrefix(path, "/api/") {
+		handler = rs.guard(handler)
+	}
+	rs.HandlePublic(pattern, handler)
+}
+
+// HandlePublic registers a route without the guard.
+func (rs *routeSet) HandlePublic(pattern string, handler http.HandlerFunc) {
+	rs.mux.HandleFunc(pattern, handler)
+
+	_, path, _ := strings.Cut(pattern, " ")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment != "" && !strings.HasPrefix(segment, "{") {
+		rs.segments = append(rs.segments, segment)
+	}
 }
 
 // CreateLink handles POST /api/links
 func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
-	var req model.CreateLinkRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid request body")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCreateBytes))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
 		return
 	}
 
-	resp, err := h.linkService.CreateLink(r.Context(), req.URL)
+	req, err := service.ParseCreateLinkRequest(r.Header.Get("Content-Type"), body)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrEmptyURL):
-			h.writeError(w, http.StatusBadRequest, "url is required")
-		case errors.Is(err, service.ErrInvalidURL):
-			h.writeError(w, http.StatusBadRequest, "invalid url format")
-		default:
-			h.logger.Error("failed to create link", "error", err)
-			h.writeError(w, http.StatusInternalServerError, "internal server error")
-		}
+		h.writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+	resp, err := h.linkService.CreateLink(r.Context(), req)
+	if err != nil {
+		h.writeCreateError(w, r, err)
 		return
 	}
 
+	if resp.Existing {
+		h.writeJSON(w, http.StatusOK, resp)
+		return
+	}
 	h.writeJSON(w, http.StatusCreated, resp)
 }
 
-// Redirect handles GET /{code}
+// writeCreateError reports a failed link creation.
+func (h *Handler) writeCreateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyURL):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeURLRequired)
+	case errors.Is(err, service.ErrInvalidURL):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidURL)
+	case errors.Is(err, service.ErrShortenerURL):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortenerURL)
+	case errors.Is(err, service.ErrSuspiciousURL):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeSuspiciousURL)
+	case errors.Is(err, service.ErrDeadURL):
+		h.writeError(w, r, http.StatusUnprocessableEntity, apierror.CodeDeadURL)
+	case errors.Is(err, service.ErrInvalidAlias):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidAlias)
+	case errors.Is(err, service.ErrReservedAlias):
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeReservedAlias)
+	case errors.Is(err, service.ErrAliasTaken):
+		h.writeError(w, r, http.StatusConflict, apierror.CodeAliasTaken)
+	case errors.Is(err, service.ErrReadOnly):
+		h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		h.writeError(w, r, http.StatusUnprocessableEntity, apierror.CodeIdempotencyReused)
+	case errors.Is(err, service.ErrIdempotencyKeyInUse):
+		h.writeError(w, r, http.StatusConflict, apierror.CodeIdempotencyInUse)
+	case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+		h.writeAPIError(w, r, http.StatusBadRequest, err)
+	default:
+		h.internalError(w, r, "failed to create link", err)
+	}
+}
+
+// purpose returns a request's Sec-Purpose header, falling back to the
+// older Purpose (and X-Purpose) headers some browsers send on prefetches.
+func purpose(header http.Header) string {
+	for _, name := range []string{"Sec-Purpose", "Purpose", "X-Purpose"} {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Redirect handles GET /{code} and, for wildcard links, GET /{code}/{rest...}
 func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	if code == "" {
-		h.writeError(w, http.StatusBadRequest, "short code is required")
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
 		return
 	}
 
 	metadata := service.ClickMetadata{
 		Referrer:  r.Header.Get("Referer"),
 		UserAgent: r.Header.Get("User-Agent"),
-		IPAddress: getClientIP(r),
+		IPAddress: clientIP(r, h.trustedProxies),
+		Source:    queryValue(r, "src"),
+		Purpose:   purpose(r.Header),
+
+		DoNotTrack: r.Header.Get("DNT") == "1" || r.Header.Get("Sec-GPC") == "1",
+	}
+
+	target, err := h.linkService.ResolveRedirect(r.Context(), code, r.PathValue("rest"), metadata)
+	if err != nil {
+		if errors.Is(err, service.ErrLinkNotFound) {
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrLinkDisabled) {
+			h.writeError(w, r, http.StatusGone, apierror.CodeLinkDisabled)
+			return
+		}
+		if errors.Is(err, service.ErrReferrerBlocked) {
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReferrerBlocked)
+			return
+		}
+		h.internalError(w, r, "failed to redirect", err, "code", code)
+		return
+	}
+
+	if target.Interstitial {
+		// Not cacheable, so turning the option off takes effect at once
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := h.interstitial.Render(w, target.URL); err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to render interstitial", "code", code, "error", err)
+		}
+		return
+	}
+
+	if target.Private {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	redirect(w, r, target.URL)
+}
+
+// redirect answers with a 301 to destination. Destinations are validated
+// absolute URLs, so unless one needs escaping, the Location header is set
+// directly, skipping http.Redirect's URL parsing and HTML body.
+func redirect(w http.ResponseWriter, r *http.Request, destination string) {
+	for i := 0; i < len(destination); i++ {
+		if c := destination[i]; c >= utf8.RuneSelf || c <= ' ' {
+			http.Redirect(w, r, destination, http.StatusMovedPermanently)
+			return
+		}
+	}
+	w.Header()["Location"] = []string{destination}
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// queryValue returns the first value of a query parameter, without
+// parsing the query when there isn't one.
+func queryValue(r *http.Request, key string) string {
+	if r.URL.RawQuery == "" {
+		return ""
+	}
+	return r.URL.Query().Get(key)
+}
+
+// GetLink handles GET /api/links/{code}
+func (h *Handler) GetLink(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
+		return
 	}
 
-	redirectURL, err := h.linkService.Redirect(r.Context(), code, metadata)
+	link, err := h.linkService.GetLink(r.Context(), code)
 	if err != nil {
 		if errors.Is(err, service.ErrLinkNotFound) {
-			h.writeError(w, http.StatusNotFound, "link not found")
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
 			return
 		}
-		h.logger.Error("failed to redirect", "code", code, "error", err)
-		h.writeError(w, http.StatusInternalServerError, "internal server error")
+		h.internalError(w, r, "failed to get link", err, "code", code)
 		return
 	}
 
-	http.Redirect(w, r, redirectURL, http.StatusMovedPermanently)
+	h.writeFields(w, r, link, link.Version)
 }
 
 // GetStats handles GET /api/links/{code}/stats
 func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	if code == "" {
-		h.writeError(w, http.StatusBadRequest, "short code is required")
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
 		return
 	}
 
 	stats, err := h.linkService.GetStats(r.Context(), code)
 	if err != nil {
 		if errors.Is(err, service.ErrLinkNotFound) {
-			h.writeError(w, http.StatusNotFound, "link not found")
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+			return
+		}
+		h.internalError(w, r, "failed to get stats", err, "code", code)
+		return
+	}
+
+	h.writeFields(w, r, stats, stats.Version)
+}
+
+// GetThumbnail handles GET /api/links/{code}/thumbnail
+func (h *Handler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	thumbnail, err := h.linkService.Thumbnail(r.Context(), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrThumbnailUnavailable):
+			h.writeError(w, r, http.StatusBadGateway, apierror.CodeNoThumbnail)
+		default:
+			h.internalError(w, r, "failed to get thumbnail", err, "code", code)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", thumbnail.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(thumbnail.Data)
+}
+
+// GetQRCode handles GET /api/links/{code}/qr
+func (h *Handler) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	query := r.URL.Query()
+
+	qr, err := h.linkService.QRCode(r.Context(), code, query.Get("format"), query.Get("size"), query.Get("level"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		default:
+			h.internalError(w, r, "failed to render QR code", err, "code", code)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", qr.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(qr.Data)
+}
+
+// GetClickTimeseries handles GET /api/links/{code}/stats/daily
+func (h *Handler) GetClickTimeseries(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	query := r.URL.Query()
+
+	series, err := h.linkService.ClickTimeseries(r.Context(), code, query.Get("from"), query.Get("to"), query.Get("tz"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		default:
+			h.internalError(w, r, "failed to get click timeseries", err, "code", code)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, series)
+}
+
+// ListLinks handles GET /api/links
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	resp, err := h.linkService.ListLinks(r.Context(), query.Get("cursor"), query.Get("limit"), query.Get("pinned"))
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.internalError(w, r, "failed to list links", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// sitemap is the <urlset> document written by ExportSitemap.
+type sitemap struct {
+	XMLName xml.Name           `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []model.SitemapURL `xml:"url"`
+}
+
+// ExportSitemap handles GET /api/links/export/sitemap, writing the public
+// links as an XML sitemap.
+func (h *Handler) ExportSitemap(w http.ResponseWriter, r *http.Request) {
+	urls, err := h.linkService.SitemapURLs(r.Context())
+	if err != nil {
+		h.internalError(w, r, "failed to export sitemap", err)
+		return
+	}
+
+	body, err := xml.MarshalIndent(sitemap{URLs: urls}, "", "  ")
+	if err != nil {
+		h.internalError(w, r, "failed to encode sitemap", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(service.SitemapCacheTTL.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	w.Write(body)
+}
+
+// GetStatsBatch handles POST /api/stats/batch
+func (h *Handler) GetStatsBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchStatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	resp, err := h.linkService.GetStatsBatch(r.Context(), req.ShortCodes)
+	if err != nil {
+		if apierror.CodeOf(err) == apierror.CodeValidationFailed {
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
 			return
 		}
-		h.logger.Error("failed to get stats", "code", code, "error", err)
-		h.writeError(w, http.StatusInternalServerError, "internal server error")
+		h.internalError(w, r, "failed to get batch stats", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateLink handles PATCH /api/links/{code} with a JSON Merge Patch body.
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
+		return
+	}
+
+	if !isMergePatch(r.Header.Get("Content-Type")) {
+		h.writeError(w, r, http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMedia)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	patch, err := service.ParseLinkPatch(body)
+	if err != nil {
+		h.writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.applyPatch(w, r, code, patch)
+}
+
+// PinLink handles POST /api/links/{code}/pin
+func (h *Handler) PinLink(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// UnpinLink handles DELETE /api/links/{code}/pin
+func (h *Handler) UnpinLink(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+func (h *Handler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
+		return
+	}
+
+	h.applyPatch(w, r, code, service.LinkPatch{Pinned: &pinned})
+}
+
+// applyPatch updates a link, honoring If-Match, and writes the new record.
+func (h *Handler) applyPatch(w http.ResponseWriter, r *http.Request, code string, patch service.LinkPatch) {
+	version, err := etag.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	link, err := h.linkService.UpdateLink(r.Context(), code, patch, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		case errors.Is(err, service.ErrVersionConflict):
+			h.writeError(w, r, http.StatusPreconditionFailed, apierror.CodeVersionConflict)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusUnprocessableEntity, err)
+		default:
+			h.internalError(w, r, "failed to update link", err, "code", code)
+		}
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, stats)
+	w.Header().Set("ETag", etag.Format(link.Version))
+	h.writeJSON(w, http.StatusOK, link)
 }
 
 // DeleteLink handles DELETE /api/links/{code}
 func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	if code == "" {
-		h.writeError(w, http.StatusBadRequest, "short code is required")
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
+		return
+	}
+
+	version, err := etag.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	err = h.linkService.DeleteLink(r.Context(), code, version)
+	if err != nil {
+		if errors.Is(err, service.ErrLinkNotFound) {
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrReadOnly) {
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+			return
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			h.writeError(w, r, http.StatusPreconditionFailed, apierror.CodeVersionConflict)
+			return
+		}
+		h.internalError(w, r, "failed to delete link", err, "code", code)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreLink handles POST /api/links/{code}/restore
+func (h *Handler) RestoreLink(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeShortCodeRequired)
+		return
+	}
+
+	link, err := h.linkService.RestoreLink(r.Context(), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		case errors.Is(err, service.ErrVersionConflict):
+			h.writeError(w, r, http.StatusConflict, apierror.CodeVersionConflict)
+		default:
+			h.internalError(w, r, "failed to restore link", err, "code", code)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag.Format(link.Version))
+	h.writeJSON(w, http.StatusOK, link)
+}
+
+// CheckAlias handles GET /api/aliases/{alias}/availability
+func (h *Handler) CheckAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+
+	result, err := h.linkService.CheckAlias(r.Context(), alias)
+	if err != nil {
+		h.internalError(w, r, "failed to check alias", err, "alias", alias)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// SuggestAliases handles POST /api/aliases/suggest
+func (h *Handler) SuggestAliases(w http.ResponseWriter, r *http.Request) {
+	var req model.SuggestAliasesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	suggestions, err := h.linkService.SuggestAliases(r.Context(), req.URL, req.Count)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyURL):
+			h.writeError(w, r, http.StatusBadRequest, apierror.CodeURLRequired)
+		case errors.Is(err, service.ErrInvalidURL):
+			h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidURL)
+		default:
+			h.internalError(w, r, "failed to suggest aliases", err)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, model.SuggestAliasesResponse{Suggestions: suggestions})
+}
+
+// CreatePrefix handles POST /api/prefixes
+func (h *Handler) CreatePrefix(w http.ResponseWriter, r *http.Request) {
+	var req model.CreatePrefixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	prefix, err := h.linkService.CreatePrefix(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPrefixTaken):
+			h.writeError(w, r, http.StatusConflict, apierror.CodePrefixTaken)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		default:
+			h.internalError(w, r, "failed to create prefix", err)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, prefix)
+}
+
+// ListPrefixes handles GET /api/prefixes
+func (h *Handler) ListPrefixes(w http.ResponseWriter, r *http.Request) {
+	prefixes, err := h.linkService.ListPrefixes(r.Context())
+	if err != nil {
+		h.internalError(w, r, "failed to list prefixes", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, model.ListPrefixesResponse{Prefixes: prefixes})
+}
+
+// DeletePrefix handles DELETE /api/prefixes/{prefix}
+func (h *Handler) DeletePrefix(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("prefix")
+
+	if err := h.linkService.DeletePrefix(r.Context(), name); err != nil {
+		switch {
+		case errors.Is(err, service.ErrPrefixNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodePrefixNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		default:
+			h.internalError(w, r, "failed to delete prefix", err, "prefix", name)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTemplate handles POST /api/templates
+func (h *Handler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	template, err := h.linkService.CreateTemplate(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTemplateTaken):
+			h.writeError(w, r, http.StatusConflict, apierror.CodeTemplateTaken)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		default:
+			h.internalError(w, r, "failed to create template", err)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, template)
+}
+
+// ListTemplates handles GET /api/templates
+func (h *Handler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.linkService.ListTemplates(r.Context())
+	if err != nil {
+		h.internalError(w, r, "failed to list templates", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, model.ListTemplatesResponse{Templates: templates})
+}
+
+// DeleteTemplate handles DELETE /api/templates/{id}
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.linkService.DeleteTemplate(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrTemplateNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeTemplateNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		default:
+			h.internalError(w, r, "failed to delete template", err, "template", id)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateLinkFromTemplate handles POST /api/templates/{id}/links
+func (h *Handler) CreateLinkFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateFromTemplateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCreateBytes)).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	resp, err := h.linkService.CreateLinkFromTemplate(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeTemplateNotFound)
+			return
+		}
+		h.writeCreateError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, resp)
+}
+
+// AddLinkAlias handles POST /api/links/{code}/aliases
+func (h *Handler) AddLinkAlias(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	var req model.AddLinkAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	alias, err := h.linkService.AddLinkAlias(r.Context(), code, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrInvalidAlias):
+			h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidAlias)
+		case errors.Is(err, service.ErrReservedAlias):
+			h.writeError(w, r, http.StatusBadRequest, apierror.CodeReservedAlias)
+		case errors.Is(err, service.ErrAliasTaken):
+			h.writeError(w, r, http.StatusConflict, apierror.CodeAliasTaken)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		default:
+			h.internalError(w, r, "failed to add alias", err, "code", code)
+		}
 		return
 	}
 
-	err := h.linkService.DeleteLink(r.Context(), code)
+	h.writeJSON(w, http.StatusCreated, alias)
+}
+
+// ListLinkAliases handles GET /api/links/{code}/aliases
+func (h *Handler) ListLinkAliases(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	aliases, err := h.linkService.ListLinkAliases(r.Context(), code)
 	if err != nil {
 		if errors.Is(err, service.ErrLinkNotFound) {
-			h.writeError(w, http.StatusNotFound, "link not found")
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
 			return
 		}
-		h.logger.Error("failed to delete link", "code", code, "error", err)
-		h.writeError(w, http.StatusInternalServerError, "internal server error")
+		h.internalError(w, r, "failed to list aliases", err, "code", code)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, aliases)
+}
+
+// RemoveLinkAlias handles DELETE /api/links/{code}/aliases/{alias}
+func (h *Handler) RemoveLinkAlias(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	if err := h.linkService.RemoveLinkAlias(r.Context(), code, r.PathValue("alias")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeLinkNotFound)
+		case errors.Is(err, service.ErrLinkAliasNotFound):
+			h.writeError(w, r, http.StatusNotFound, apierror.CodeAliasNotFound)
+		case errors.Is(err, service.ErrReadOnly):
+			h.writeError(w, r, http.StatusForbidden, apierror.CodeReadOnly)
+		default:
+			h.internalError(w, r, "failed to remove alias", err, "code", code)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Recover is middleware that turns panics in downstream handlers into a
+// 500 response, logging and reporting the recovered value.
+func (h *Handler) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Let the server abort the connection as it normally would
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			err := &errreport.PanicError{Value: rec, Stack: debug.Stack()}
+			h.internalError(w, r, "recovered from panic", err, "path", r.URL.Path)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // HealthCheck handles GET /health
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, map[string]string{
@@ -148,32 +948,128 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
-// writeError writes a JSON error response.
-func (h *Handler) writeError(w http.ResponseWriter, status int, message string) {
-	h.writeJSON(w, status, map[string]string{
-		"error": message,
-	})
+// writeFields writes a versioned resource, pruned to the fields named in
+// the request's ?fields= parameter.
+func (h *Handler) writeFields(w http.ResponseWriter, r *http.Request, data any, version int64) {
+	body, err := fieldmask.Apply(data, r.URL.Query().Get("fields"))
+	if err != nil {
+		h.writeAPIError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag.Format(version))
+	h.writeJSON(w, http.StatusOK, body)
+}
+
+// writeError writes a JSON error response carrying a stable error code and
+// a message in the language negotiated from the request's Accept-Language.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	h.writeAPIError(w, r, status, apierror.New(code, ""))
+}
+
+// writeAPIError writes err as a JSON error response, localizing its message
+// and keeping any field-level details.
+func (h *Handler) writeAPIError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	code := apierror.CodeOf(err)
+	resp := apierror.New(code, i18n.Message(lang, code))
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		resp.Fields = apiErr.Fields
+	}
+	h.writeJSON(w, status, resp)
+}
+
+// internalError logs and reports an unexpected failure, then writes a 500.
+// attrs are slog-style key/value pairs added to both the log and the report.
+func (h *Handler) internalError(w http.ResponseWriter, r *http.Request, msg string, err error, attrs ...any) {
+	h.logger.ErrorContext(r.Context(), msg, append(attrs, "error", err)...)
+	h.reportError(r, err, attrs...)
+	h.writeError(w, r, http.StatusInternalServerError, apierror.CodeInternal)
+}
+
+// reportError forwards err to the configured reporter with request tags.
+func (h *Handler) reportError(r *http.Request, err error, attrs ...any) {
+	tags := map[string]string{
+		"method": r.Method,
+		"route":  r.Pattern,
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if key, ok := attrs[i].(string); ok {
+			tags[key] = fmt.Sprint(attrs[i+1])
+		}
+	}
+	h.reporter.Report(r.Context(), err, tags)
+}
+
+// maxPatchBytes bounds the size of a merge patch document.
+const maxPatchBytes = 1 << 20
+
+// maxCreateBytes bounds the size of a create request body.
+const maxCreateBytes = 1 << 20
+
+// isMergePatch reports whether contentType is acceptable for a JSON Merge
+// Patch body. Plain JSON is accepted too since many clients can't set a
+// custom media type; an absent header is treated as JSON.
+func isMergePatch(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
 }
 
-// getClientIP extracts the client IP from the request.
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (common for proxies/load balancers)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
+// clientIP returns the address of the client that made r. Forwarding
+// headers are only believed when the connection comes from a trusted
+// proxy, and X-Forwarded-For is read from the right, skipping trusted
+// hops, so entries a client prepends itself are never used.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		peer = host
+	}
+	if !inNetworks(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if i == 0 || !inNetworks(hop, trusted) {
+				return hop
+			}
 		}
-		return strings.TrimSpace(xff)
 	}
 
-	// Check X-Real-IP header
 	if xri := r.Header.Get("X-Real-IP"); xri != "" {
 		return strings.TrimSpace(xri)
 	}
+	return peer
+}
 
-	// Fall back to RemoteAddr
-	if idx := strings.LastIndex(r.RemoteAddr, ":"); idx != -1 {
-		return r.RemoteAddr[:idx]
+// inNetworks reports whether ip, an address in text form, falls in one of
+// networks. IPv4-mapped IPv6 addresses match IPv4 ranges; anything that
+// isn't an address matches none.
+func inNetworks(ip string, networks []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
 	}
-	return r.RemoteAddr
+	return false
 }
@@ -2,25 +2,37 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/colby/snip/internal/errreport"
 	"github.com/colby/snip/internal/model"
 	"github.com/colby/snip/internal/repository"
 	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/pkg/apierror"
 )
 
 func setupTestHandler() (*Handler, *http.ServeMux) {
 	linkRepo := repository.NewMemoryLinkRepository()
 	clickRepo := repository.NewMemoryClickRepository()
-	linkService := service.NewLinkService(linkRepo, clickRepo, service.DefaultConfig())
+	config := service.DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	config.Templates = repository.NewMemoryTemplateRepository()
+	config.Aliases = repository.NewMemoryLinkAliasRepository()
+	config.Idempotency = repository.NewMemoryIdempotencyRepository()
+	linkService := service.NewLinkService(linkRepo, clickRepo, config)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	h := New(linkService, logger)
+	h := New(linkService, logger, Config{})
 	mux := http.NewServeMux()
 	h.RegisterRoutes(mux)
 
@@ -31,36 +43,81 @@ func TestHandler_CreateLink(t *testing.T) {
 	_, mux := setupTestHandler()
 
 	tests := []struct {
-		name       string
-		body       string
-		wantStatus int
+		name        string
+		contentType string // defaults to application/json
+		body        string
+		wantStatus  int
+		wantCode    string
 	}{
 		{
 			name:       "valid URL",
 			body:       `{"url": "https://example.com"}`,
 			wantStatus: http.StatusCreated,
 		},
+		{
+			name:        "form body",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fexample.com%2Fform&notes=from+a+form&interstitial=on&submit=Shorten",
+			wantStatus:  http.StatusCreated,
+		},
+		{
+			name:        "form body with a bad flag",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fexample.com&wildcard=maybe",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    apierror.CodeValidationFailed,
+		},
+		{
+			name:        "plain text body",
+			contentType: "text/plain; charset=utf-8",
+			body:        "https://example.com/text\n",
+			wantStatus:  http.StatusCreated,
+		},
 		{
 			name:       "empty body",
 			body:       `{}`,
 			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeURLRequired,
 		},
 		{
 			name:       "invalid JSON",
 			body:       `{invalid}`,
 			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
 		},
 		{
 			name:       "invalid URL",
 			body:       `{"url": "not-a-url"}`,
 			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidURL,
+		},
+		{
+			name:       "custom code",
+			body:       `{"url": "https://example.com/launch", "custom_code": "launch2024"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "custom code taken",
+			body:       `{"url": "https://example.com/other", "custom_code": "launch2024"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   apierror.CodeAliasTaken,
+		},
+		{
+			name:       "invalid custom code",
+			body:       `{"url": "https://example.com", "custom_code": "no spaces"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidAlias,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
 			req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(tt.body))
-			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Type", contentType)
 			rec := httptest.NewRecorder()
 
 			mux.ServeHTTP(rec, req)
@@ -77,6 +134,117 @@ func TestHandler_CreateLink(t *testing.T) {
 				if resp.ShortCode == "" {
 					t.Error("expected non-empty short code")
 				}
+				if got := resp.Links["stats"].Href; got != "http://localhost:8080/api/links/"+resp.ShortCode+"/stats" {
+					t.Errorf("unexpected stats link %q", got)
+				}
+			}
+
+			if tt.wantCode != "" {
+				var resp apierror.Error
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode error response: %v", err)
+				}
+				if resp.Code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, resp.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_CreateLink_IdempotencyKey(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	create := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var codes []string
+	for range 2 {
+		rec := create("mobile-42", `{"url": "https://example.com"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var resp model.CreateLinkResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		codes = append(codes, resp.ShortCode)
+	}
+	if codes[0] != codes[1] {
+		t.Errorf("expected the retry to return %s, got %s", codes[0], codes[1])
+	}
+
+	rec := create("mobile-42", `{"url": "https://example.com/else"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), apierror.CodeIdempotencyReused) {
+		t.Errorf("expected %d %s for a reused key, got %d: %s", http.StatusUnprocessableEntity, apierror.CodeIdempotencyReused, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_CreateLink_Dedupe(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	var codes []string
+	for _, want := range []int{http.StatusCreated, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com", "dedupe": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Fatalf("expected status %d, got %d: %s", want, rec.Code, rec.Body.String())
+		}
+		var resp model.CreateLinkResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Existing != (want == http.StatusOK) {
+			t.Errorf("expected existing %v, got %v", want == http.StatusOK, resp.Existing)
+		}
+		codes = append(codes, resp.ShortCode)
+	}
+	if codes[0] != codes[1] {
+		t.Errorf("expected %s to be returned again, got %s", codes[0], codes[1])
+	}
+}
+
+func TestHandler_GetQRCode(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com", "custom_code": "poster"}`))
+	createReq.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(httptest.NewRecorder(), createReq)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		contentType string
+	}{
+		{"PNG by default", "/api/links/poster/qr", http.StatusOK, "image/png"},
+		{"SVG", "/api/links/poster/qr?format=svg&size=512&level=Q", http.StatusOK, "image/svg+xml"},
+		{"bad size", "/api/links/poster/qr?size=huge", http.StatusBadRequest, "application/json"},
+		{"unknown link", "/api/links/missing/qr", http.StatusNotFound, "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("expected content type %s, got %s", tt.contentType, ct)
+			}
+			// QR codes are scoped to the link's owner, so shared caches mustn't keep them
+			if cc := rec.Header().Get("Cache-Control"); rec.Code == http.StatusOK && !strings.HasPrefix(cc, "private") {
+				t.Errorf("expected a private Cache-Control, got %q", cc)
 			}
 		})
 	}
@@ -111,6 +279,75 @@ func TestHandler_Redirect(t *testing.T) {
 	}
 }
 
+func TestHandler_Redirect_Interstitial(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com/external", "interstitial": true}`))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+createResp.ShortCode, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Error("expected no Location header on the interstitial page")
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected an HTML page, got %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `url=https://example.com/external`) {
+		t.Error("expected the page to forward to the destination")
+	}
+}
+
+func TestHandler_Redirect_AllowedReferrers(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com/gated", "allowed_referrers": ["Intranet.Example.com"]}`))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+
+	tests := []struct {
+		referrer string
+		want     int
+	}{
+		{"https://intranet.example.com/wiki/page", http.StatusMovedPermanently},
+		{"https://docs.intranet.example.com/", http.StatusMovedPermanently},
+		{"https://evil-intranet.example.com.attacker.net/", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/"+createResp.ShortCode, nil)
+		if tt.referrer != "" {
+			req.Header.Set("Referer", tt.referrer)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("referrer %q: expected status %d, got %d", tt.referrer, tt.want, rec.Code)
+		}
+		if rec.Code == http.StatusMovedPermanently && rec.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("referrer %q: expected the redirect not to be cacheable", tt.referrer)
+		}
+	}
+}
+
 func TestHandler_Redirect_NotFound(t *testing.T) {
 	_, mux := setupTestHandler()
 
@@ -156,6 +393,47 @@ func TestHandler_GetStats(t *testing.T) {
 	}
 }
 
+func TestHandler_GetLink(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com/details"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links/"+createResp.ShortCode, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var link model.LinkDetails
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if link.OriginalURL != "https://example.com/details" {
+		t.Errorf("expected original URL https://example.com/details, got %s", link.OriginalURL)
+	}
+	if link.ShortURL != createResp.ShortURL {
+		t.Errorf("expected short URL %s, got %s", createResp.ShortURL, link.ShortURL)
+	}
+	if rec.Header().Get("ETag") != `"1"` {
+		t.Errorf("expected ETag \"1\", got %q", rec.Header().Get("ETag"))
+	}
+
+	notFoundRec := httptest.NewRecorder()
+	mux.ServeHTTP(notFoundRec, httptest.NewRequest(http.MethodGet, "/api/links/nonexistent", nil))
+	if notFoundRec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, notFoundRec.Code)
+	}
+}
+
 func TestHandler_DeleteLink(t *testing.T) {
 	_, mux := setupTestHandler()
 
@@ -189,6 +467,512 @@ func TestHandler_DeleteLink(t *testing.T) {
 	}
 }
 
+func TestHandler_UpdateLink(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com/old"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	path := "/api/links/" + createResp.ShortCode
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "merge patch",
+			path:        path,
+			contentType: "application/merge-patch+json",
+			body:        `{"url": "https://example.com/new"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "invalid url",
+			path:        path,
+			contentType: "application/merge-patch+json",
+			body:        `{"url": "not-a-url"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    apierror.CodeValidationFailed,
+		},
+		{
+			name:        "immutable field",
+			path:        path,
+			contentType: "application/json",
+			body:        `{"click_count": 0}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    apierror.CodeValidationFailed,
+		},
+		{
+			name:        "wrong content type",
+			path:        path,
+			contentType: "text/plain",
+			body:        `{"url": "https://example.com/new"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    apierror.CodeUnsupportedMedia,
+		},
+		{
+			name:        "not found",
+			path:        "/api/links/nonexistent",
+			contentType: "application/merge-patch+json",
+			body:        `{"url": "https://example.com/new"}`,
+			wantStatus:  http.StatusNotFound,
+			wantCode:    apierror.CodeLinkNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantCode != "" {
+				var resp apierror.Error
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode error response: %v", err)
+				}
+				if resp.Code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, resp.Code)
+				}
+				return
+			}
+
+			var link model.LinkDetails
+			if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if link.OriginalURL != "https://example.com/new" {
+				t.Errorf("expected updated url, got %s", link.OriginalURL)
+			}
+		})
+	}
+}
+
+func TestHandler_IfMatch(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com/old"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	path := "/api/links/" + createResp.ShortCode
+
+	statsRec := httptest.NewRecorder()
+	mux.ServeHTTP(statsRec, httptest.NewRequest(http.MethodGet, path+"/stats", nil))
+	tag := statsRec.Header().Get("ETag")
+	if tag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", tag)
+	}
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"url": "https://example.com/new"}`))
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := patch(tag); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	} else if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("expected new ETag \"2\", got %q", got)
+	}
+
+	if rec := patch(tag); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d for stale If-Match, got %d", http.StatusPreconditionFailed, rec.Code)
+	}
+
+	if rec := patch("garbage"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for malformed If-Match, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	deleteReq := httptest.NewRequest(http.MethodDelete, path, nil)
+	deleteReq.Header.Set("If-Match", tag)
+	deleteRec := httptest.NewRecorder()
+	mux.ServeHTTP(deleteRec, deleteReq)
+	if deleteRec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d for stale delete, got %d", http.StatusPreconditionFailed, deleteRec.Code)
+	}
+}
+
+func TestHandler_CheckAlias(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/aliases/acme-pricing/availability", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var resp model.AliasAvailability
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Available || resp.Alias != "acme-pricing" {
+		t.Errorf("expected acme-pricing to be available, got %+v", resp)
+	}
+}
+
+func TestHandler_SuggestAliases(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/aliases/suggest", bytes.NewBufferString(`{"url": "https://acme.com/pricing", "count": 1}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp model.SuggestAliasesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Suggestions) != 1 || resp.Suggestions[0] != "acme-pricing" {
+		t.Errorf("expected [acme-pricing], got %v", resp.Suggestions)
+	}
+}
+
+func TestHandler_Prefixes(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/api/prefixes", `{"prefix": "eng", "description": "Engineering"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := post("/api/prefixes", `{"prefix": "eng"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a taken prefix, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := post("/api/prefixes", `{"prefix": "Eng!"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid prefix, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := post("/api/links", `{"url": "https://example.com", "prefix": "eng"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created model.CreateLinkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(created.ShortCode, "eng-") {
+		t.Errorf("expected code under eng-, got %s", created.ShortCode)
+	}
+
+	if rec := post("/api/links", `{"url": "https://example.com", "prefix": "ops"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unallocated prefix, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/prefixes", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var list model.ListPrefixesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Prefixes) != 1 || list.Prefixes[0].Name != "eng" {
+		t.Errorf("expected [eng], got %+v", list.Prefixes)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/prefixes/eng", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestHandler_Templates(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	template := `{"id": "product", "url": "https://shop.example.com/p/{sku}", "utm": {"source": "newsletter"}}`
+	if rec := post("/api/templates", template); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := post("/api/templates", template); rec.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a taken ID, got %d", http.StatusConflict, rec.Code)
+	}
+
+	rec := post("/api/templates/product/links", `{"params": {"sku": "A-100"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created model.CreateLinkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := "https://shop.example.com/p/A-100?utm_source=newsletter"; created.OriginalURL != want {
+		t.Errorf("expected destination %s, got %s", want, created.OriginalURL)
+	}
+
+	if rec := post("/api/templates/product/links", `{"params": {}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a missing param, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := post("/api/templates/nope/links", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown template, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/templates/product", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestHandler_LinkAliases(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/links", `{"url": "https://example.com", "custom_code": "oldbrand"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/links/oldbrand/aliases", `{"alias": "newbrand"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/links/oldbrand/aliases", `{"alias": "newbrand"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a taken alias, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/links/missing/aliases", `{"alias": "other"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown link, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/newbrand", "")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" {
+		t.Errorf("expected the alias to redirect, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = do(http.MethodGet, "/api/links/oldbrand/aliases", "")
+	var list model.ListLinkAliasesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Aliases) != 1 || list.Aliases[0].Alias != "newbrand" {
+		t.Errorf("unexpected aliases %+v", list)
+	}
+
+	if rec := do(http.MethodDelete, "/api/links/oldbrand/aliases/newbrand", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = do(http.MethodDelete, "/api/links/oldbrand/aliases/newbrand", "")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), apierror.CodeAliasNotFound) {
+		t.Errorf("expected %s, got %d: %s", apierror.CodeAliasNotFound, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_Pin(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	path := "/api/links/" + createResp.ShortCode + "/pin"
+
+	for _, tt := range []struct {
+		method string
+		want   bool
+	}{
+		{http.MethodPost, true},
+		{http.MethodDelete, false},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", tt.method, http.StatusOK, rec.Code)
+		}
+
+		var link model.LinkDetails
+		if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if link.Pinned != tt.want {
+			t.Errorf("%s: expected pinned=%v, got %v", tt.method, tt.want, link.Pinned)
+		}
+	}
+}
+
+func TestHandler_ListLinks(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	for _, code := range []string{"first", "second", "third"} {
+		rec := httptest.NewRecorder()
+		body := `{"url": "https://example.com/` + code + `", "custom_code": "` + code + `"}`
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("failed to create link: %d %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, model.ListLinksResponse) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links"+query, nil))
+		var resp model.ListLinksResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, page := list("?limit=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(page.Links) != 2 || page.Links[0].ShortCode != "first" || page.Links[0].OriginalURL != "https://example.com/first" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	_, page = list("?limit=2&cursor=" + page.NextCursor)
+	if len(page.Links) != 1 || page.Links[0].ShortCode != "third" || page.NextCursor != "" {
+		t.Errorf("unexpected last page %+v", page)
+	}
+
+	if rec, _ := list("?limit=lots"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a bad limit, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/links/third/pin", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("failed to pin link: %d %s", rec.Code, rec.Body.String())
+	}
+	_, page = list("?limit=2")
+	if len(page.Links) != 2 || page.Links[0].ShortCode != "third" || !page.Links[0].Pinned {
+		t.Errorf("expected the pinned link first, got %+v", page)
+	}
+	_, page = list("?pinned=true")
+	if len(page.Links) != 1 || page.Links[0].ShortCode != "third" {
+		t.Errorf("expected only the pinned link, got %+v", page)
+	}
+	if rec, _ := list("?pinned=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a bad pinned filter, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandler_ExportSitemap(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	for _, body := range []string{
+		`{"url": "https://example.com/a", "custom_code": "listed", "public": true}`,
+		`{"url": "https://example.com/b", "custom_code": "unlisted"}`,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("failed to create link: %d %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/export/sitemap", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("expected an XML content type, got %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+		t.Errorf("expected the sitemap to be cacheable for 5 minutes, got %q", cc)
+	}
+	var got struct {
+		Namespace string   `xml:"xmlns,attr"`
+		Locs      []string `xml:"url>loc"`
+	}
+	if err := xml.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode sitemap: %v", err)
+	}
+	if got.Namespace != "http://www.sitemaps.org/schemas/sitemap/0.9" {
+		t.Errorf("unexpected namespace %q", got.Namespace)
+	}
+	if len(got.Locs) != 1 || !strings.HasSuffix(got.Locs[0], "/listed") {
+		t.Errorf("expected only the public link, got %v", got.Locs)
+	}
+}
+
+func TestHandler_FieldSelection(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	var createResp model.CreateLinkResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&createResp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	path := "/api/links/" + createResp.ShortCode + "/stats"
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?fields=short_code,click_count", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != 2 || got["short_code"] != createResp.ShortCode {
+		t.Errorf("expected only short_code and click_count, got %v", got)
+	}
+
+	badRec := httptest.NewRecorder()
+	mux.ServeHTTP(badRec, httptest.NewRequest(http.MethodGet, path+"?fields=nope", nil))
+	if badRec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for unknown field, got %d", http.StatusBadRequest, badRec.Code)
+	}
+}
+
 func TestHandler_HealthCheck(t *testing.T) {
 	_, mux := setupTestHandler()
 
@@ -210,37 +994,76 @@ func TestHandler_HealthCheck(t *testing.T) {
 	}
 }
 
-func TestGetClientIP(t *testing.T) {
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
 	tests := []struct {
 		name       string
 		headers    map[string]string
 		remoteAddr string
+		trusted    []netip.Prefix
 		want       string
 	}{
 		{
-			name:       "X-Forwarded-For single IP",
+			name:       "X-Forwarded-For from a trusted proxy",
 			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
-			remoteAddr: "5.6.7.8:12345",
+			remoteAddr: "10.0.0.5:12345",
+			trusted:    trusted,
 			want:       "1.2.3.4",
 		},
 		{
-			name:       "X-Forwarded-For multiple IPs",
-			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 5.6.7.8"},
-			remoteAddr: "9.10.11.12:12345",
+			name:       "X-Forwarded-For through several trusted proxies",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 10.0.0.9"},
+			remoteAddr: "10.0.0.5:12345",
+			trusted:    trusted,
 			want:       "1.2.3.4",
 		},
 		{
-			name:       "X-Real-IP",
-			headers:    map[string]string{"X-Real-IP": "1.2.3.4"},
+			name:       "X-Forwarded-For entries prepended by the client are ignored",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 1.2.3.4"},
+			remoteAddr: "10.0.0.5:12345",
+			trusted:    trusted,
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "X-Forwarded-For from an untrusted client",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
 			remoteAddr: "5.6.7.8:12345",
+			trusted:    trusted,
+			want:       "5.6.7.8",
+		},
+		{
+			name:       "X-Forwarded-For with no trusted proxies",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			remoteAddr: "10.0.0.5:12345",
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "X-Real-IP from a trusted proxy",
+			headers:    map[string]string{"X-Real-IP": "1.2.3.4"},
+			remoteAddr: "10.0.0.5:12345",
+			trusted:    trusted,
 			want:       "1.2.3.4",
 		},
+		{
+			name:       "X-Real-IP from an untrusted client",
+			headers:    map[string]string{"X-Real-IP": "1.2.3.4"},
+			remoteAddr: "5.6.7.8:12345",
+			trusted:    trusted,
+			want:       "5.6.7.8",
+		},
 		{
 			name:       "fallback to RemoteAddr",
 			headers:    map[string]string{},
 			remoteAddr: "1.2.3.4:12345",
 			want:       "1.2.3.4",
 		},
+		{
+			name:       "IPv6 RemoteAddr",
+			headers:    map[string]string{},
+			remoteAddr: "[2001:db8::1]:12345",
+			want:       "2001:db8::1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,10 +1074,187 @@ func TestGetClientIP(t *testing.T) {
 				req.Header.Set(k, v)
 			}
 
-			got := getClientIP(req)
+			got := clientIP(req, tt.trusted)
 			if got != tt.want {
 				t.Errorf("expected %s, got %s", tt.want, got)
 			}
 		})
 	}
 }
+
+type recordingReporter struct {
+	errs []error
+	tags []map[string]string
+}
+
+func (r *recordingReporter) Report(_ context.Context, err error, tags map[string]string) {
+	r.errs = append(r.errs, err)
+	r.tags = append(r.tags, tags)
+}
+
+func TestHandler_Recover(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+	reporter := &recordingReporter{}
+	h := New(linkService, logger, Config{ErrorReporter: reporter})
+
+	panicking := h.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something broke")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	rec := httptest.NewRecorder()
+	panicking.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+
+	if len(reporter.errs) != 1 {
+		t.Fatalf("expected 1 reported error, got %d", len(reporter.errs))
+	}
+
+	var panicErr *errreport.PanicError
+	if !errors.As(reporter.errs[0], &panicErr) {
+		t.Errorf("expected a PanicError, got %T", reporter.errs[0])
+	}
+	if reporter.tags[0]["path"] != "/boom" {
+		t.Errorf("expected path tag /boom, got %q", reporter.tags[0]["path"])
+	}
+}
+
+func TestHandler_ReadOnly(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	h := New(linkService, logger, Config{})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	linkService.SetReadOnly(true)
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url": "https://example.com"}`))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	if createRec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d on create, got %d", http.StatusForbidden, createRec.Code)
+	}
+
+	deleteReq := httptest.NewRequest(http.MethodDelete, "/api/links/abc1234", nil)
+	deleteRec := httptest.NewRecorder()
+	mux.ServeHTTP(deleteRec, deleteReq)
+
+	if deleteRec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d on delete, got %d", http.StatusForbidden, deleteRec.Code)
+	}
+}
+
+func TestHandler_LocalizedErrors(t *testing.T) {
+	_, mux := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links/nonexistent/stats", nil)
+	req.Header.Set("Accept-Language", "es-ES,es;q=0.9,en;q=0.5")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "es" {
+		t.Errorf("expected Content-Language es, got %q", got)
+	}
+
+	var resp apierror.Error
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if resp.Code != apierror.CodeLinkNotFound {
+		t.Errorf("expected code %s, got %s", apierror.CodeLinkNotFound, resp.Code)
+	}
+	if resp.Message != "enlace no encontrado" {
+		t.Errorf("expected Spanish message, got %q", resp.Message)
+	}
+}
+
+// stubLinks serves GetLink from a function; the other methods panic if
+// called, except those needed to register routes.
+type stubLinks struct {
+	service.Links
+	getLink func(ctx context.Context, shortCode string) (*model.LinkDetails, error)
+}
+
+func (s stubLinks) GetLink(ctx context.Context, shortCode string) (*model.LinkDetails, error) {
+	return s.getLink(ctx, shortCode)
+}
+
+func (stubLinks) PrefixesEnabled() bool    { return false }
+func (stubLinks) TemplatesEnabled() bool   { return false }
+func (stubLinks) LinkAliasesEnabled() bool { return false }
+func (stubLinks) ThumbnailsEnabled() bool  { return false }
+func (stubLinks) ReservePaths(...string)   {}
+
+func TestHandler_GetLink_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", service.ErrLinkNotFound, http.StatusNotFound},
+		{"storage failure", errors.New("dynamodb: throttled"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := stubLinks{getLink: func(context.Context, string) (*model.LinkDetails, error) {
+				return nil, tt.err
+			}}
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+			h := New(links, logger, Config{})
+			mux := http.NewServeMux()
+			h.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/abc1234", nil))
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func BenchmarkHandler_Redirect(b *testing.B) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	config := service.DefaultConfig()
+	config.ClickRecorder = service.BoundedClickRecorder{} // count the click's work too
+	linkService := service.NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	h := New(linkService, logger, Config{})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	linkRepo.Create(context.Background(), &model.Link{ID: "link-1", ShortCode: "abc1234", OriginalURL: "https://example.com/target"})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc1234", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:130.0) Gecko/20100101 Firefox/130.0")
+	req.Header.Set("Referer", "https://news.example.org/")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestRedirect_EscapesNonASCII(t *testing.T) {
+	for destination, want := range map[string]string{
+		"https://example.com/a?b=c": "https://example.com/a?b=c",
+		"https://example.com/café":  "https://example.com/caf%c3%a9",
+	} {
+		rec := httptest.NewRecorder()
+		redirect(rec, httptest.NewRequest(http.MethodGet, "/abc1234", nil), destination)
+		if rec.Code != http.StatusMovedPermanently {
+			t.Errorf("%s: expected status %d, got %d", destination, http.StatusMovedPermanently, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != want {
+			t.Errorf("%s: expected Location %s, got %s", destination, want, got)
+		}
+	}
+}
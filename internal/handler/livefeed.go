@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/i18n"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// liveFeedWriteTimeout bounds how long a single frame write may block.
+const liveFeedWriteTimeout = 5 * time.Second
+
+// LiveFeed streams link-created and click events to WebSocket clients.
+// Clients authenticate with a shared token, sent either as a Bearer
+// Authorization header or a "token" query parameter (browsers cannot
+// set headers on WebSocket handshakes).
+type LiveFeed struct {
+	bus            *events.Bus
+	token          string
+	originPatterns []string
+	logger         *slog.Logger
+}
+
+// NewLiveFeed creates a live feed backed by the given event bus.
+// originPatterns lists the browser origins allowed to connect (see
+// websocket.AcceptOptions); same-origin requests are always allowed.
+func NewLiveFeed(bus *events.Bus, token string, originPatterns []string, logger *slog.Logger) *LiveFeed {
+	return &LiveFeed{
+		bus:            bus,
+		token:          token,
+		originPatterns: originPatterns,
+		logger:         logger,
+	}
+}
+
+// ServeHTTP handles GET /api/ws
+func (f *LiveFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Language", lang)
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(apierror.New(apierror.CodeUnauthorized, i18n.Message(lang, apierror.CodeUnauthorized)))
+		return
+	}
+
+	// The server's read/write timeouts are meant for ordinary requests;
+	// clear them so the long-lived connection isn't cut off.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: f.originPatterns,
+	})
+	if err != nil {
+		f.logger.Warn("failed to accept websocket", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// We never expect messages from the client; CloseRead handles control
+	// frames and cancels ctx when the client goes away.
+	ctx := conn.CloseRead(context.Background())
+
+	feed, cancel := f.bus.Subscribe(0, nil)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-feed:
+			if !ok {
+				return
+			}
+			writeCtx, cancelWrite := context.WithTimeout(ctx, liveFeedWriteTimeout)
+			err := wsjson.Write(writeCtx, conn, event)
+			cancelWrite()
+			if err != nil {
+				f.logger.Debug("live feed client dropped", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// authorized reports whether the request carries the configured token.
+func (f *LiveFeed) authorized(r *http.Request) bool {
+	if f.token == "" {
+		return false
+	}
+
+	provided := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		provided = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(f.token)) == 1
+}
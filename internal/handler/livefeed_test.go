@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/colby/snip/internal/events"
+)
+
+func TestLiveFeed_Unauthorized(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	feed := NewLiveFeed(events.NewBus(), "secret", nil, logger)
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+	}{
+		{name: "no token", target: "/api/ws"},
+		{name: "wrong query token", target: "/api/ws?token=nope"},
+		{name: "wrong bearer token", target: "/api/ws", header: "Bearer nope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			feed.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLiveFeed_StreamsEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	bus := events.NewBus()
+	server := httptest.NewServer(NewLiveFeed(bus, "secret", nil, logger))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws?token=secret"
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.CloseNow()
+
+	// The subscription is registered after the handshake; keep publishing
+	// until the first frame arrives.
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				bus.Publish(events.Event{Type: events.TypeClickRecorded, ShortCode: "abc1234"})
+			}
+		}
+	}()
+
+	var got events.Event
+	if err := wsjson.Read(ctx, conn, &got); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+
+	if got.Type != events.TypeClickRecorded || got.ShortCode != "abc1234" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+}
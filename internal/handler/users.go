@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Signup handles POST /api/auth/signup
+func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
+	var req model.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	token, err := h.users.Signup(r.Context(), req)
+	if err != nil {
+		switch {
+		case apierror.CodeOf(err) == apierror.CodeValidationFailed:
+			h.writeAPIError(w, r, http.StatusBadRequest, err)
+		case errors.Is(err, auth.ErrEmailTaken):
+			h.writeError(w, r, http.StatusConflict, apierror.CodeEmailTaken)
+		default:
+			h.internalError(w, r, "failed to sign up", err)
+		}
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "user signed up", "user_id", token.User.ID)
+	h.writeJSON(w, http.StatusCreated, token)
+}
+
+// Login handles POST /api/auth/login
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req model.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest)
+		return
+	}
+
+	token, err := h.users.Login(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, auth.ErrLoginFailed) {
+			h.writeError(w, r, http.StatusUnauthorized, apierror.CodeLoginFailed)
+			return
+		}
+		h.internalError(w, r, "failed to log in", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, token)
+}
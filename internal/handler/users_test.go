@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/colby/snip/internal/auth"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/internal/service"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestHandler_Users(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	users, err := auth.NewUsers(repository.NewMemoryUserRepository(), auth.UsersConfig{Secret: []byte(strings.Repeat("s", auth.MinSecretLength))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := New(linkService, logger, Config{Users: users})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) string {
+		var body apierror.Error
+		json.NewDecoder(rec.Body).Decode(&body)
+		return body.Code
+	}
+
+	rec := do(http.MethodPost, "/api/auth/signup", "", `{"email": "ada@example.com", "password": "correct horse"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "pbkdf2") {
+		t.Errorf("expected the password hash to be left out, got %s", rec.Body.String())
+	}
+
+	rec = do(http.MethodPost, "/api/auth/signup", "", `{"email": "ada@example.com", "password": "correct horse"}`)
+	if rec.Code != http.StatusConflict || code(rec) != apierror.CodeEmailTaken {
+		t.Errorf("expected status %d with %s, got %d", http.StatusConflict, apierror.CodeEmailTaken, rec.Code)
+	}
+	rec = do(http.MethodPost, "/api/auth/login", "", `{"email": "ada@example.com", "password": "wrong horse"}`)
+	if rec.Code != http.StatusUnauthorized || code(rec) != apierror.CodeLoginFailed {
+		t.Errorf("expected status %d with %s, got %d", http.StatusUnauthorized, apierror.CodeLoginFailed, rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/api/auth/login", "", `{"email": "ada@example.com", "password": "correct horse"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var token model.TokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&token); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if rec := do(http.MethodPost, "/api/links", "", `{"url": "https://example.com"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/links", token.Token, `{"url": "https://example.com"}`); rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d with a token, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_LinkOwners(t *testing.T) {
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), service.DefaultConfig())
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	users, err := auth.NewUsers(repository.NewMemoryUserRepository(), auth.UsersConfig{Secret: []byte(strings.Repeat("s", auth.MinSecretLength))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := New(linkService, logger, Config{Users: users})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	signup := func(email string) string {
+		rec := do(http.MethodPost, "/api/auth/signup", "", `{"email": "`+email+`", "password": "correct horse"}`)
+		var token model.TokenResponse
+		if err := json.NewDecoder(rec.Body).Decode(&token); err != nil || token.Token == "" {
+			t.Fatalf("failed to sign up %s: %d %v", email, rec.Code, err)
+		}
+		return token.Token
+	}
+	ada, grace := signup("ada@example.com"), signup("grace@example.com")
+
+	if rec := do(http.MethodPost, "/api/links", ada, `{"url": "https://example.com", "custom_code": "adas"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodDelete, "/api/links/adas", grace, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d deleting another user's link, got %d", http.StatusNotFound, rec.Code)
+	}
+	rec := do(http.MethodGet, "/api/links", grace, "")
+	var list model.ListLinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list.Links) != 0 {
+		t.Errorf("expected an empty list for another user, got %d: %+v", rec.Code, list)
+	}
+	if rec := do(http.MethodGet, "/adas", "", ""); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("expected the redirect to work for anyone, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/links/adas", ada, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d deleting one's own link, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestHandler_PrefixAndTemplateOwners(t *testing.T) {
+	config := service.DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	config.Templates = repository.NewMemoryTemplateRepository()
+	linkService := service.NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	users, err := auth.NewUsers(repository.NewMemoryUserRepository(), auth.UsersConfig{Secret: []byte(strings.Repeat("s", auth.MinSecretLength))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := New(linkService, logger, Config{Users: users})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	signup := func(email string) string {
+		rec := do(http.MethodPost, "/api/auth/signup", "", `{"email": "`+email+`", "password": "correct horse"}`)
+		var token model.TokenResponse
+		if err := json.NewDecoder(rec.Body).Decode(&token); err != nil || token.Token == "" {
+			t.Fatalf("failed to sign up %s: %d %v", email, rec.Code, err)
+		}
+		return token.Token
+	}
+	ada, grace := signup("ada@example.com"), signup("grace@example.com")
+
+	if rec := do(http.MethodPost, "/api/prefixes", ada, `{"prefix": "eng"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/templates", ada, `{"id": "product", "url": "https://example.com/p/{sku}"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"delete prefix", http.MethodDelete, "/api/prefixes/eng", ""},
+		{"delete template", http.MethodDelete, "/api/templates/product", ""},
+		{"create from template", http.MethodPost, "/api/templates/product/links", `{"params": {"sku": "1"}}`},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.path, grace, tt.body); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d for another user's, got %d", tt.name, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	var prefixes model.ListPrefixesResponse
+	json.NewDecoder(do(http.MethodGet, "/api/prefixes", grace, "").Body).Decode(&prefixes)
+	var templates model.ListTemplatesResponse
+	json.NewDecoder(do(http.MethodGet, "/api/templates", grace, "").Body).Decode(&templates)
+	if len(prefixes.Prefixes) != 0 || len(templates.Templates) != 0 {
+		t.Errorf("expected another user to list nothing, got %+v and %+v", prefixes, templates)
+	}
+
+	if rec := do(http.MethodDelete, "/api/templates/product", ada, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d deleting one's own template, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/prefixes/eng", ada, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d deleting one's own prefix, got %d", http.StatusNoContent, rec.Code)
+	}
+}
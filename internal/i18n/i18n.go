@@ -0,0 +1,109 @@
+// Package i18n localizes user-facing strings. Translations are embedded JSON
+// bundles keyed by message ID (for errors, the apierror code) and selected
+// from the request's Accept-Language header.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when no requested language is supported and as the
+// fallback for messages missing from a bundle.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// bundles maps a base language tag ("en", "es", ...) to its messages.
+var bundles = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: reading bundles: %v", err))
+	}
+
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := locales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading %s: %v", entry.Name(), err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: parsing %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return loaded
+}
+
+// Languages returns the supported language tags in sorted order.
+func Languages() []string {
+	langs := make([]string, 0, len(bundles))
+	for lang := range bundles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Negotiate picks the supported language that best matches an
+// Accept-Language header value, honoring q-values. Region subtags are
+// ignored ("es-MX" matches "es"). It returns DefaultLanguage when nothing
+// matches.
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := bundles[base]; !ok || q <= bestQ {
+			continue
+		}
+		best, bestQ = base, q
+	}
+	return best
+}
+
+// Message returns the translation of id in lang, falling back to
+// DefaultLanguage and then to id itself.
+func Message(lang, id string) string {
+	if msg, ok := bundles[lang][id]; ok {
+		return msg
+	}
+	if msg, ok := bundles[DefaultLanguage][id]; ok {
+		return msg
+	}
+	return id
+}
+
+type contextKey struct{}
+
+// WithLanguage returns a copy of ctx carrying lang.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// Language returns the language stored in ctx, or DefaultLanguage.
+func Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok {
+		return lang
+	}
+	return DefaultLanguage
+}
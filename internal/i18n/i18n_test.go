@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"fr-FR,fr;q=0.9,es;q=0.5", "es"},
+		{"en;q=0.4, es-MX;q=0.7", "es"},
+		{"fr", "en"},
+		{"es;q=bogus, de", "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Negotiate(tt.header); got != tt.want {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("es", "link_not_found"); got != "enlace no encontrado" {
+		t.Errorf("unexpected Spanish message: %s", got)
+	}
+	if got := Message("fr", "link_not_found"); got != "link not found" {
+		t.Errorf("expected English fallback, got %s", got)
+	}
+	if got := Message("de", "no_such_message"); got != "no_such_message" {
+		t.Errorf("expected ID fallback, got %s", got)
+	}
+}
+
+// Every bundle must translate every message in the default bundle.
+func TestBundlesComplete(t *testing.T) {
+	for _, lang := range Languages() {
+		for id := range bundles[DefaultLanguage] {
+			if _, ok := bundles[lang][id]; !ok {
+				t.Errorf("%s bundle is missing %q", lang, id)
+			}
+		}
+	}
+}
+
+func TestLanguageContext(t *testing.T) {
+	if got := Language(context.Background()); got != DefaultLanguage {
+		t.Errorf("expected default language, got %s", got)
+	}
+	if got := Language(WithLanguage(context.Background(), "de")); got != "de" {
+		t.Errorf("expected de, got %s", got)
+	}
+}
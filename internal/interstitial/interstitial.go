@@ -0,0 +1,55 @@
+// Package interstitial renders the countdown page shown, instead of an
+// immediate redirect, for links that ask for one. Some compliance teams
+// require visitors to be told they are leaving for an external site.
+package interstitial
+
+import (
+	"embed"
+	"html/template"
+	"io"
+)
+
+// Defaults for Config.
+const (
+	DefaultBrand   = "Snip"
+	DefaultSeconds = 3
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/interstitial.html"))
+
+// Config configures a Renderer.
+type Config struct {
+	Brand   string // shown at the top of the page; defaults to DefaultBrand
+	Seconds int    // countdown length; defaults to DefaultSeconds
+}
+
+// Renderer renders interstitial pages.
+type Renderer struct {
+	brand   string
+	seconds int
+}
+
+// New creates a Renderer.
+func New(config Config) *Renderer {
+	r := &Renderer{brand: config.Brand, seconds: config.Seconds}
+	if r.brand == "" {
+		r.brand = DefaultBrand
+	}
+	if r.seconds <= 0 {
+		r.seconds = DefaultSeconds
+	}
+	return r
+}
+
+// Render writes the page forwarding to destination. destination must
+// already be a validated http(s) URL.
+func (r *Renderer) Render(w io.Writer, destination string) error {
+	return page.Execute(w, struct {
+		Brand       string
+		Seconds     int
+		Destination string
+	}{r.brand, r.seconds, destination})
+}
@@ -0,0 +1,39 @@
+package interstitial
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	var b strings.Builder
+	r := New(Config{Brand: "Acme <Legal>", Seconds: 5})
+	if err := r.Render(&b, `https://example.com/?q="x"&y=<z>`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`content="5;url=https://example.com/?q=&#34;x&#34;&amp;y=&lt;z&gt;"`,
+		`href="https://example.com/?q=%22x%22&amp;y=%3cz%3e"`,
+		`Acme &lt;Legal&gt;`,
+		`var remaining =  5 ;`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected page to contain %s", want)
+		}
+	}
+	if strings.Contains(out, "<z>") || strings.Contains(out, "<Legal>") {
+		t.Error("expected user-supplied values to be escaped")
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	var b strings.Builder
+	if err := New(Config{}).Render(&b, "https://example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), DefaultBrand) || !strings.Contains(b.String(), `content="3;url=`) {
+		t.Error("expected the default brand and countdown")
+	}
+}
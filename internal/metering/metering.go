@@ -0,0 +1,25 @@
+// Package metering counts billable usage, such as redirects served and
+// links created, and reports it to a billing system, so hosted operators
+// can bill on actual usage.
+package metering
+
+import "context"
+
+// Metrics recorded by the service.
+const (
+	MetricRedirects    = "redirects"     // redirects served, interstitial pages included
+	MetricLinksCreated = "links_created" // links created, by any route
+)
+
+// Meter receives usage as it happens. Record is called on the redirect
+// path, so implementations must not block; they batch and report in the
+// background.
+type Meter interface {
+	Record(ctx context.Context, metric string, quantity int64)
+}
+
+// Nop discards usage. It is the default when no meter is configured.
+type Nop struct{}
+
+// Record implements Meter.
+func (Nop) Record(context.Context, string, int64) {}
@@ -0,0 +1,224 @@
+package metering
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stripe defaults.
+const (
+	DefaultStripeEndpoint = "https://api.stripe.com"
+	DefaultFlushInterval  = time.Minute
+	stripeTimeout         = 10 * time.Second
+)
+
+// errRejected marks usage records Stripe refused outright, such as for an
+// unknown subscription item. Sending them again wouldn't help.
+var errRejected = errors.New("usage record rejected")
+
+// StripeConfig configures a Stripe meter.
+type StripeConfig struct {
+	APIKey string // secret key, sk_...
+
+	// SubscriptionItems maps each metric to the metered subscription item
+	// its usage is reported against, e.g. {"redirects": "si_..."}.
+	// Metrics without an item aren't reported.
+	SubscriptionItems map[string]string
+
+	// FlushInterval is how often counted usage is sent. Stripe rate-limits
+	// usage records, so usage is summed between flushes rather than sent
+	// per event. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	Endpoint string       // defaults to DefaultStripeEndpoint
+	Client   *http.Client // defaults to a client with a 10s timeout
+	Logger   *slog.Logger // optional; defaults to discarding output
+}
+
+// Stripe reports usage to Stripe as usage records with action=increment,
+// one per metric per flush. A record that fails is retried on the next
+// flush with the same idempotency key, so a request that reached Stripe
+// but whose response was lost isn't counted twice; usage counted in the
+// meantime waits for the following flush.
+type Stripe struct {
+	endpoint string
+	apiKey   string
+	items    map[string]string
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	pending  map[string]int64        // metric -> usage not yet in a record
+	inflight map[string]*usageRecord // metric -> record not yet accepted
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// usageRecord is a batch of usage sent as one Stripe usage record.
+type usageRecord struct {
+	quantity       int64
+	timestamp      time.Time
+	idempotencyKey string
+}
+
+// NewStripe creates a Stripe meter and starts flushing in the background.
+// Close it on shutdown to send the remaining usage.
+func NewStripe(config StripeConfig) (*Stripe, error) {
+	if config.APIKey == "" {
+		return nil, errors.New("metering: Stripe API key is required")
+	}
+	if len(config.SubscriptionItems) == 0 {
+		return nil, errors.New("metering: at least one subscription item is required")
+	}
+	interval := config.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	m := &Stripe{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		apiKey:   config.APIKey,
+		items:    config.SubscriptionItems,
+		client:   config.Client,
+		logger:   config.Logger,
+		pending:  make(map[string]int64),
+		inflight: make(map[string]*usageRecord),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if m.endpoint == "" {
+		m.endpoint = DefaultStripeEndpoint
+	}
+	if m.client == nil {
+		m.client = &http.Client{Timeout: stripeTimeout}
+	}
+	if m.logger == nil {
+		m.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	go m.run(interval)
+	return m, nil
+}
+
+// Record implements Meter.
+func (m *Stripe) Record(_ context.Context, metric string, quantity int64) {
+	if m.items[metric] == "" || quantity <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.pending[metric] += quantity
+	m.mu.Unlock()
+}
+
+// Close stops the background flushes and sends the remaining usage.
+func (m *Stripe) Close(ctx context.Context) error {
+	m.closeOnce.Do(func() { close(m.stop) })
+	<-m.done
+	return m.Flush(ctx)
+}
+
+func (m *Stripe) run(interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := m.Flush(ctx); err != nil {
+				m.logger.Warn("reporting usage to Stripe failed; retrying next flush", "error", err)
+			}
+			cancel()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Flush sends the usage counted so far, along with records that failed
+// before. Records that fail again are kept for the next flush, unless
+// Stripe rejected them, in which case their usage is dropped.
+func (m *Stripe) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	now := time.Now()
+	for metric, quantity := range m.pending {
+		if m.inflight[metric] != nil {
+			continue // sent once the earlier record is accepted
+		}
+		m.inflight[metric] = &usageRecord{quantity: quantity, timestamp: now, idempotencyKey: newIdempotencyKey()}
+		delete(m.pending, metric)
+	}
+	records := make(map[string]*usageRecord, len(m.inflight))
+	for metric, record := range m.inflight {
+		records[metric] = record
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for metric, record := range records {
+		err := m.send(ctx, m.items[metric], record)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", metric, err))
+			if !errors.Is(err, errRejected) {
+				continue
+			}
+			m.logger.Error("dropping usage Stripe rejected", "metric", metric, "quantity", record.quantity)
+		}
+		m.mu.Lock()
+		delete(m.inflight, metric)
+		m.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// send creates one usage record on a subscription item.
+func (m *Stripe) send(ctx context.Context, item string, record *usageRecord) error {
+	form := url.Values{
+		"quantity":  {strconv.FormatInt(record.quantity, 10)},
+		"timestamp": {strconv.FormatInt(record.timestamp.Unix(), 10)},
+		"action":    {"increment"},
+	}
+	endpoint := fmt.Sprintf("%s/v1/subscription_items/%s/usage_records", m.endpoint, url.PathEscape(item))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	req.Header.Set("Idempotency-Key", record.idempotencyKey)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("stripe answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	// 409 is a concurrent request with the same key, 429 rate limiting
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusConflict && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", errRejected, err)
+	}
+	return err
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "snip-usage-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,149 @@
+package metering
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// usageRequest is a usage record as received by the fake Stripe server.
+type usageRequest struct {
+	path, auth, key, quantity, action string
+}
+
+// fakeStripe records usage record requests, answering each with the next
+// status in statuses, then 200.
+func fakeStripe(t *testing.T, statuses ...int) (*httptest.Server, func() []usageRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []usageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, usageRequest{
+			path:     r.URL.Path,
+			auth:     r.Header.Get("Authorization"),
+			key:      r.Header.Get("Idempotency-Key"),
+			quantity: r.PostForm.Get("quantity"),
+			action:   r.PostForm.Get("action"),
+		})
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []usageRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]usageRequest(nil), requests...)
+	}
+}
+
+func newTestStripe(t *testing.T, endpoint string) *Stripe {
+	t.Helper()
+	m, err := NewStripe(StripeConfig{
+		APIKey:            "sk_test_123",
+		SubscriptionItems: map[string]string{MetricRedirects: "si_redirects"},
+		FlushInterval:     time.Hour,
+		Endpoint:          endpoint,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { m.Close(context.Background()) })
+	return m
+}
+
+func TestStripe_Flush(t *testing.T) {
+	server, requests := fakeStripe(t)
+	m := newTestStripe(t, server.URL)
+	ctx := context.Background()
+
+	m.Record(ctx, MetricRedirects, 1)
+	m.Record(ctx, MetricRedirects, 2)
+	m.Record(ctx, MetricLinksCreated, 1) // no subscription item
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 usage record, got %d", len(got))
+	}
+	want := usageRequest{path: "/v1/subscription_items/si_redirects/usage_records", auth: "Bearer sk_test_123", key: got[0].key, quantity: "3", action: "increment"}
+	if got[0] != want || got[0].key == "" {
+		t.Errorf("expected %+v, got %+v", want, got[0])
+	}
+
+	// Nothing new to report
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests()) != 1 {
+		t.Errorf("expected no record without new usage, got %d", len(requests()))
+	}
+}
+
+func TestStripe_FlushRetriesWithSameKey(t *testing.T) {
+	server, requests := fakeStripe(t, http.StatusInternalServerError)
+	m := newTestStripe(t, server.URL)
+	ctx := context.Background()
+
+	m.Record(ctx, MetricRedirects, 5)
+	if err := m.Flush(ctx); err == nil {
+		t.Fatal("expected the failed flush to report an error")
+	}
+	m.Record(ctx, MetricRedirects, 2)
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(got))
+	}
+	if got[1].key != got[0].key || got[1].quantity != "5" {
+		t.Errorf("expected the failed record to be retried as is, got %+v after %+v", got[1], got[0])
+	}
+	if got[2].key == got[0].key || got[2].quantity != "2" {
+		t.Errorf("expected later usage in a new record, got %+v", got[2])
+	}
+}
+
+func TestStripe_FlushDropsRejected(t *testing.T) {
+	server, requests := fakeStripe(t, http.StatusBadRequest)
+	m := newTestStripe(t, server.URL)
+	ctx := context.Background()
+
+	m.Record(ctx, MetricRedirects, 5)
+	if err := m.Flush(ctx); err == nil {
+		t.Fatal("expected the rejected record to be reported")
+	}
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests()) != 1 {
+		t.Errorf("expected the rejected record not to be retried, got %d requests", len(requests()))
+	}
+}
+
+func TestStripe_CloseFlushes(t *testing.T) {
+	server, requests := fakeStripe(t)
+	m := newTestStripe(t, server.URL)
+
+	m.Record(context.Background(), MetricRedirects, 1)
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests()) != 1 {
+		t.Errorf("expected Close to send the remaining usage, got %d requests", len(requests()))
+	}
+}
@@ -0,0 +1,47 @@
+// Package metrics holds process-wide counters, published through expvar so
+// they can be scraped as JSON from /debug/vars.
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Short code generation counters.
+var (
+	// LinksCreated counts links successfully created.
+	LinksCreated = expvar.NewInt("links_created")
+
+	// CodeCollisions counts generated codes that were already taken and
+	// had to be retried.
+	CodeCollisions = expvar.NewInt("code_collisions")
+
+	// CodeGenerationFailures counts creates that gave up after exhausting
+	// their retries (ErrCodeGeneration).
+	CodeGenerationFailures = expvar.NewInt("code_generation_failures")
+)
+
+// Click recording counters. Clicks are written after the redirect is
+// decided, so these are the only trace of analytics that were lost.
+var (
+	// ClickCountFailures counts clicks whose link click_count couldn't be
+	// incremented, even after a retry.
+	ClickCountFailures = expvar.NewInt("click_count_failures")
+
+	// ClickEventFailures counts click events that couldn't be stored, even
+	// after a retry.
+	ClickEventFailures = expvar.NewInt("click_event_failures")
+)
+
+// CoalescedReads counts link reads that joined an identical read already
+// in flight instead of going to the repository.
+var CoalescedReads = expvar.NewInt("coalesced_reads")
+
+// CachedNotFound counts lookups answered as not found from the miss cache.
+var CachedNotFound = expvar.NewInt("cached_not_found")
+
+// Handler serves all published variables as JSON, including the runtime's
+// memstats and cmdline.
+func Handler() http.Handler {
+	return expvar.Handler()
+}
@@ -1,7 +1,11 @@
 // Package model defines the core domain types for Snip.
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/colby/snip/pkg/ulid"
+)
 
 // Link represents a shortened URL mapping.
 type Link struct {
@@ -10,6 +14,40 @@ type Link struct {
 	OriginalURL string    `json:"original_url"`
 	CreatedAt   time.Time `json:"created_at"`
 	ClickCount  int64     `json:"click_count"`
+	Pinned      bool      `json:"pinned"`
+	Notes       string    `json:"notes,omitempty"`
+	Wildcard    bool      `json:"wildcard,omitempty"` // extra path segments are appended to OriginalURL
+	Disabled    bool      `json:"disabled,omitempty"` // redirects refused, e.g. after a failed URL scan
+	ScanStatus  string    `json:"scan_status,omitempty"`
+
+	Interstitial bool `json:"interstitial,omitempty"` // show a countdown page before forwarding
+
+	NotifyMilestones bool `json:"notify_milestones,omitempty"` // publish an event when the click count crosses a milestone
+
+	AllowedReferrers []string `json:"allowed_referrers,omitempty"` // hosts visitors must come from; empty allows all
+
+	Public bool   `json:"public,omitempty"` // listed in the sitemap export and public directory
+	Title  string `json:"title,omitempty"`  // name shown in the public directory
+
+	OwnerID string `json:"owner_id,omitempty"` // user who created the link; empty for links created without an account
+
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // soft-deleted; restorable until purged
+
+	Version int64 `json:"version"` // incremented on every update; clicks don't count
+}
+
+// URL scan statuses. Links created without a scanner have no status.
+const (
+	ScanStatusPending = "pending"
+	ScanStatusClean   = "clean"
+	ScanStatusFlagged = "flagged"
+	ScanStatusFailed  = "failed"
+)
+
+// NewID returns a new identifier for a link or click event. IDs are ULIDs,
+// so they sort by creation time and are independent of the short code.
+func NewID() string {
+	return ulid.New()
 }
 
 // ClickEvent represents a single redirect event for analytics.
@@ -20,11 +58,69 @@ type ClickEvent struct {
 	Referrer  string    `json:"referrer,omitempty"`
 	UserAgent string    `json:"user_agent,omitempty"`
 	IPAddress string    `json:"ip_address,omitempty"`
+	Source    string    `json:"source,omitempty"` // ClickSourceLink or ClickSourceQR
+
+	// Deduped marks a repeat click by the same visitor within the dedupe
+	// window. It's stored but left out of click counts.
+	Deduped bool `json:"deduped,omitempty"`
+
+	// Prefetch marks a fetch by a link preview bot or a speculative
+	// browser prefetch rather than a visitor. It's stored but, by default,
+	// left out of click counts.
+	Prefetch bool `json:"prefetch,omitempty"`
 }
 
+// Click sources. QR codes point at the short URL with ?src=qr so scans can
+// be told apart from ordinary clicks and taps.
+const (
+	ClickSourceLink = "link"
+	ClickSourceQR   = "qr"
+)
+
 // CreateLinkRequest represents the input for creating a new short link.
 type CreateLinkRequest struct {
-	URL string `json:"url"`
+	URL    string `json:"url"`
+	Notes  string `json:"notes,omitempty"`
+	Prefix string `json:"prefix,omitempty"` // namespace prefix for the generated code
+
+	// CustomCode claims a chosen alias, e.g. "launch2024", instead of a
+	// generated code. Creation fails if it's invalid, reserved or taken.
+	CustomCode string `json:"custom_code,omitempty"`
+
+	// Wildcard makes /{code}/rest/of/path redirect to the destination with
+	// rest/of/path appended, covering a whole section of a site.
+	Wildcard bool `json:"wildcard,omitempty"`
+
+	// Verify checks that the destination answers before creating the
+	// link, so typos are caught before it's shared.
+	Verify bool `json:"verify,omitempty"`
+
+	// Interstitial shows visitors a "redirecting in N seconds" page
+	// before forwarding them.
+	Interstitial bool `json:"interstitial,omitempty"`
+
+	// NotifyMilestones sends a notification each time the link's click
+	// count reaches one of the configured milestones.
+	NotifyMilestones bool `json:"notify_milestones,omitempty"`
+
+	// AllowedReferrers locks the link to visitors arriving from these
+	// hosts or their subdomains, e.g. ["intranet.example.com"].
+	AllowedReferrers []string `json:"allowed_referrers,omitempty"`
+
+	// Public opts the link into listings meant for anyone, such as the
+	// sitemap export and the public directory.
+	Public bool `json:"public,omitempty"`
+
+	// Title names the link in the public directory.
+	Title string `json:"title,omitempty"`
+
+	// Dedupe returns an existing link to the same destination, when
+	// there is one, rather than creating another. Ignored with CustomCode.
+	Dedupe bool `json:"dedupe,omitempty"`
+
+	// IdempotencyKey, from the Idempotency-Key header, makes retries of
+	// this request return the link the first attempt created.
+	IdempotencyKey string `json:"-"`
 }
 
 // CreateLinkResponse represents the output after creating a short link.
@@ -32,6 +128,175 @@ type CreateLinkResponse struct {
 	ShortCode   string `json:"short_code"`
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+
+	// Warnings lists phishing heuristics the destination matched without
+	// reaching the block threshold.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Existing is set when deduplication returned a link that was already
+	// there instead of creating one.
+	Existing bool `json:"existing,omitempty"`
+
+	Links map[string]HALLink `json:"_links,omitempty"`
+}
+
+// HALLink is a navigational link to a related API resource, in the style
+// of HAL's _links object. Method is set when it isn't GET.
+type HALLink struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// LinkDetails is the full record of a link, without analytics.
+type LinkDetails struct {
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	Pinned      bool      `json:"pinned"`
+	Notes       string    `json:"notes,omitempty"`
+	Wildcard    bool      `json:"wildcard,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	ScanStatus  string    `json:"scan_status,omitempty"`
+
+	Interstitial     bool     `json:"interstitial,omitempty"`
+	NotifyMilestones bool     `json:"notify_milestones,omitempty"`
+	AllowedReferrers []string `json:"allowed_referrers,omitempty"`
+	Public           bool     `json:"public,omitempty"`
+	Title            string   `json:"title,omitempty"`
+	OwnerID          string   `json:"owner_id,omitempty"`
+
+	Version int64 `json:"version"`
+
+	Links map[string]HALLink `json:"_links,omitempty"`
+}
+
+// LinkSummary is a link as listed by GET /api/links.
+type LinkSummary struct {
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	ClickCount  int64     `json:"click_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	Pinned      bool      `json:"pinned"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// ListLinksResponse is one page of links. NextCursor is empty on the last
+// page.
+type ListLinksResponse struct {
+	Links      []LinkSummary `json:"links"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// IdempotencyRecord remembers a create made with an Idempotency-Key.
+type IdempotencyRecord struct {
+	Key         string              `json:"key"`
+	Fingerprint string              `json:"fingerprint"`        // hash of the request, to catch a key reused for another
+	Response    *CreateLinkResponse `json:"response,omitempty"` // nil while the create is in progress
+	ExpiresAt   time.Time           `json:"expires_at"`
+}
+
+// APIKey is a credential for the management API. Only a hash of the
+// secret is kept; the key itself is shown once, when it's created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hint      string     `json:"hint"` // first characters of the key, to tell keys apart
+	Hash      string     `json:"-"`    // SHA-256 of the key
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest is the request body for creating an API key.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"` // who or what the key is for, e.g. "ci-pipeline"
+}
+
+// CreateAPIKeyResponse is a newly created API key, with the key itself.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// ListAPIKeysResponse lists API keys, revoked ones included.
+type ListAPIKeysResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// User is an account that signs in to the API with an email and password.
+// Only a hash of the password is kept.
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"` // lower-cased
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Credentials is the request body for signing up and logging in.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// TokenResponse is the access token a user signed in with.
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"` // always "Bearer"
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// DirectoryEntry is a public link as listed in the public directory.
+type DirectoryEntry struct {
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	Title       string    `json:"title,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DirectoryPage is one page of the public directory. NextCursor is empty
+// on the last page.
+type DirectoryPage struct {
+	Links      []DirectoryEntry `json:"links"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// SitemapURL is one <url> entry of a sitemap export.
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"` // YYYY-MM-DD
+}
+
+// BatchStatsRequest is the input for fetching stats for several links.
+type BatchStatsRequest struct {
+	ShortCodes []string `json:"short_codes"`
+}
+
+// BatchStatsResponse holds stats for the requested links that exist, in
+// request order, and lists the codes that don't.
+type BatchStatsResponse struct {
+	Stats    []LinkStats `json:"stats"`
+	NotFound []string    `json:"not_found,omitempty"`
+}
+
+// AliasAvailability reports whether a custom alias can be used.
+type AliasAvailability struct {
+	Alias     string `json:"alias"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // apierror code when unavailable
+}
+
+// SuggestAliasesRequest is the input for alias suggestions.
+type SuggestAliasesRequest struct {
+	URL   string `json:"url"`
+	Count int    `json:"count,omitempty"`
+}
+
+// SuggestAliasesResponse lists available alias suggestions, best first.
+type SuggestAliasesResponse struct {
+	Suggestions []string `json:"suggestions"`
 }
 
 // LinkStats represents analytics for a link.
@@ -40,4 +305,137 @@ type LinkStats struct {
 	OriginalURL string    `json:"original_url"`
 	ClickCount  int64     `json:"click_count"`
 	CreatedAt   time.Time `json:"created_at"`
+	Version     int64     `json:"version"`
+
+	// ClicksBySource splits recorded click events by source. It is omitted
+	// when the click store doesn't keep individual events.
+	ClicksBySource map[string]int64 `json:"clicks_by_source,omitempty"`
+}
+
+// ClickRecount reports a click count recomputed from click events.
+type ClickRecount struct {
+	ShortCode          string `json:"short_code"`
+	PreviousClickCount int64  `json:"previous_click_count"`
+	ClickCount         int64  `json:"click_count"`
+}
+
+// MergeLinksRequest names the canonical link a duplicate is merged into.
+type MergeLinksRequest struct {
+	Into string `json:"into"`
+}
+
+// LinkMerge reports a duplicate link folded into a canonical one.
+type LinkMerge struct {
+	ShortCode    string `json:"short_code"` // the canonical link
+	MergedCode   string `json:"merged_code"`
+	ClickCount   int64  `json:"click_count"` // the canonical link's count after the merge
+	EventsMoved  int    `json:"events_moved"`
+	AliasesMoved int    `json:"aliases_moved"`
+}
+
+// DailyClicks aggregates a link's clicks over one UTC day.
+type DailyClicks struct {
+	Date     string           `json:"date"` // YYYY-MM-DD
+	Clicks   int64            `json:"clicks"`
+	BySource map[string]int64 `json:"by_source,omitempty"`
+}
+
+// ClickTimeseries is a link's daily click counts over a date range, with
+// every day in the range present.
+type ClickTimeseries struct {
+	ShortCode string        `json:"short_code"`
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	TimeZone  string        `json:"tz"` // IANA zone the days run in
+	Days      []DailyClicks `json:"days"`
+}
+
+// Prefix is a namespace prefix allocated to a team or campaign. Codes
+// generated under it look like "eng-x7Gh2".
+type Prefix struct {
+	Name        string    `json:"prefix"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	OwnerID     string    `json:"owner_id,omitempty"` // user who allocated the prefix; empty when allocated without an account
+}
+
+// CreatePrefixRequest is the input for allocating a namespace prefix.
+type CreatePrefixRequest struct {
+	Prefix      string `json:"prefix"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListPrefixesResponse lists the allocated namespace prefixes.
+type ListPrefixesResponse struct {
+	Prefixes []Prefix `json:"prefixes"`
+}
+
+// Template is a saved recipe for links that share a destination pattern,
+// such as product pages tagged for one campaign. {name} placeholders in
+// URL are filled in when a link is created from the template.
+type Template struct {
+	ID        string            `json:"id"`
+	URL       string            `json:"url"`           // e.g. "https://shop.example.com/products/{sku}"
+	UTM       map[string]string `json:"utm,omitempty"` // e.g. {"source": "newsletter"}, added as utm_source
+	Notes     string            `json:"notes,omitempty"`
+	Prefix    string            `json:"prefix,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	OwnerID   string            `json:"owner_id,omitempty"` // user who saved the template; empty when saved without an account
+}
+
+// CreateTemplateRequest is the input for saving a template.
+type CreateTemplateRequest struct {
+	ID     string            `json:"id"`
+	URL    string            `json:"url"`
+	UTM    map[string]string `json:"utm,omitempty"`
+	Notes  string            `json:"notes,omitempty"`
+	Prefix string            `json:"prefix,omitempty"`
+}
+
+// ListTemplatesResponse lists the saved templates.
+type ListTemplatesResponse struct {
+	Templates []Template `json:"templates"`
+}
+
+// CreateFromTemplateRequest is the input for creating a link from a
+// template. Params fill the template's placeholders; UTM values override
+// the template's defaults. Notes and CustomCode apply to the new link.
+type CreateFromTemplateRequest struct {
+	Params     map[string]string `json:"params,omitempty"`
+	UTM        map[string]string `json:"utm,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+	CustomCode string            `json:"custom_code,omitempty"`
+}
+
+// LinkAlias is a secondary code that redirects to an existing link, for
+// example after a rebrand. Clicks through it count toward that link.
+type LinkAlias struct {
+	Alias     string    `json:"alias"`
+	ShortCode string    `json:"short_code"` // the link's own code
+	ShortURL  string    `json:"short_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddLinkAliasRequest is the input for attaching an alias to a link.
+type AddLinkAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// ListLinkAliasesResponse lists a link's secondary aliases.
+type ListLinkAliasesResponse struct {
+	ShortCode string      `json:"short_code"`
+	Aliases   []LinkAlias `json:"aliases"`
+}
+
+// Thumbnail is a captured image of a link's destination.
+type Thumbnail struct {
+	ContentType string // e.g. "image/png"
+	SourceURL   string // destination the image was captured from
+	Data        []byte
+}
+
+// QRCode is a rendered QR code image for a link.
+type QRCode struct {
+	ContentType string // "image/png" or "image/svg+xml"
+	Data        []byte
 }
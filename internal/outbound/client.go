@@ -0,0 +1,134 @@
+// Package outbound makes HTTP requests to user-supplied destinations, such
+// as following a link's redirect chain. Because the URLs come from users,
+// requests to loopback, private, link-local and other non-public addresses
+// are refused by default so the server can't be used to probe its own
+// network. Every server-initiated fetch should use NewClient, so those
+// guards, the timeout and the response size cap apply in one place.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout bounds a whole outbound operation, redirects included.
+const DefaultTimeout = 5 * time.Second
+
+// DefaultMaxBodyBytes caps how much of a response body is read.
+const DefaultMaxBodyBytes = 1 << 20
+
+// userAgent identifies Snip to destination servers.
+const userAgent = "snip-outbound/1.0"
+
+// ErrBlockedAddress is returned when a destination resolves to an address
+// outbound requests may not reach.
+var ErrBlockedAddress = errors.New("destination address is not publicly routable")
+
+// ClientConfig configures NewClient.
+type ClientConfig struct {
+	Timeout      time.Duration // defaults to DefaultTimeout
+	MaxBodyBytes int64         // reading more of a body fails; defaults to DefaultMaxBodyBytes
+	AllowPrivate bool          // permit non-public addresses; for tests and local development
+}
+
+// NewClient returns an HTTP client for outbound requests. It never follows
+// redirects itself, so callers see (and can bound) every hop.
+func NewClient(config ClientConfig) *http.Client {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxBody := config.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodyBytes
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !config.AllowPrivate {
+		// Checked after DNS resolution, on the address actually dialed
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if addr, err := netip.ParseAddr(host); err != nil || !isPublic(addr) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would dial on our behalf, bypassing the check
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: limitedTransport{next: transport, maxBody: maxBody},
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// limitedTransport caps the size of response bodies.
+type limitedTransport struct {
+	next    http.RoundTripper
+	maxBody int64
+}
+
+func (t limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = http.MaxBytesReader(nil, resp.Body, t.maxBody)
+	return resp, nil
+}
+
+// nonPublic lists special-purpose ranges that the netip predicates in
+// isPublic don't cover.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, which can embed private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, likewise
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+}
+
+// isPublic reports whether addr is a globally routable unicast address.
+// IPv4-mapped IPv6 addresses are judged by the IPv4 address they carry.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequest builds an outbound request carrying Snip's user agent.
+func newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
@@ -0,0 +1,142 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/final?x=1", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resolver := NewResolver(NewClient(ClientConfig{AllowPrivate: true}), 3)
+	ctx := context.Background()
+
+	got, err := resolver.Resolve(ctx, srv.URL+"/a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := srv.URL + "/final?x=1"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if got, err := resolver.Resolve(ctx, srv.URL+"/final"); err != nil || got != srv.URL+"/final" {
+		t.Errorf("expected a non-redirecting URL to resolve to itself, got %s (%v)", got, err)
+	}
+
+	if _, err := resolver.Resolve(ctx, srv.URL+"/loop"); !errors.Is(err, ErrTooManyHops) {
+		t.Errorf("expected ErrTooManyHops, got %v", err)
+	}
+}
+
+func TestResolver_Verify(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/missing", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resolver := NewResolver(NewClient(ClientConfig{AllowPrivate: true}), 3)
+	ctx := context.Background()
+
+	for _, path := range []string{"/private", "/no-head"} {
+		if err := resolver.Verify(ctx, srv.URL+path); err != nil {
+			t.Errorf("Verify(%s) unexpected error: %v", path, err)
+		}
+	}
+	if err := resolver.Verify(ctx, srv.URL+"/moved"); !errors.Is(err, ErrDeadDestination) {
+		t.Errorf("expected ErrDeadDestination at the end of a redirect, got %v", err)
+	}
+
+	srv.Close()
+	if err := resolver.Verify(ctx, srv.URL+"/private"); err == nil {
+		t.Error("expected an error for a server that's gone")
+	}
+}
+
+func TestClient_BlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	resolver := NewResolver(NewClient(ClientConfig{}), 0)
+	if _, err := resolver.Resolve(context.Background(), srv.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected ErrBlockedAddress for a loopback server, got %v", err)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:93.184.216.34", true},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestClient_CapsResponseBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2048))
+	}))
+	defer srv.Close()
+
+	client := NewClient(ClientConfig{AllowPrivate: true, MaxBodyBytes: 1024})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	_, err = io.ReadAll(resp.Body)
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("expected a MaxBytesError reading past the cap, got %v", err)
+	}
+}
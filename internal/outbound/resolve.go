@@ -0,0 +1,155 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxHops is the default redirect chain length Resolve follows.
+const DefaultMaxHops = 5
+
+// ErrTooManyHops is returned when a redirect chain is longer than allowed.
+var ErrTooManyHops = errors.New("too many redirects")
+
+// ErrDeadDestination is returned by Verify when a destination answers with
+// a status that means there's nothing there.
+var ErrDeadDestination = errors.New("destination is dead")
+
+// Resolver follows a destination's redirect chain to its final URL.
+type Resolver struct {
+	client  *http.Client
+	maxHops int
+}
+
+// NewResolver creates a Resolver. client should not follow redirects
+// itself (see NewClient); maxHops <= 0 uses DefaultMaxHops.
+func NewResolver(client *http.Client, maxHops int) *Resolver {
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	return &Resolver{client: client, maxHops: maxHops}
+}
+
+// Resolve returns the URL that rawURL finally lands on, following at most
+// maxHops redirects. A URL that doesn't redirect resolves to itself.
+// Requests use HEAD, falling back to GET for servers that reject it.
+func (r *Resolver) Resolve(ctx context.Context, rawURL string) (string, error) {
+	current := rawURL
+	for hop := 0; ; hop++ {
+		next, err := r.next(ctx, current)
+		if err != nil {
+			return "", err
+		}
+		if next == "" {
+			return current, nil
+		}
+		if hop == r.maxHops {
+			return "", ErrTooManyHops
+		}
+		current = next
+	}
+}
+
+// Verify checks that rawURL leads somewhere. It follows redirects like
+// Resolve and fails with ErrDeadDestination when the final response is
+// 404, 410 or a server error, or with the request error when the
+// destination can't be reached at all. Statuses such as 401 and 403 pass:
+// the page exists, it just isn't public.
+func (r *Resolver) Verify(ctx context.Context, rawURL string) error {
+	current := rawURL
+	for hop := 0; ; hop++ {
+		resp, err := r.do(ctx, http.MethodHead, current)
+		if err == nil && resp.StatusCode >= 400 {
+			// Plenty of servers mishandle HEAD; let GET have the final say
+			resp, err = r.do(ctx, http.MethodGet, current)
+		}
+		if err != nil {
+			return err
+		}
+
+		next, err := redirectTarget(current, resp)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			if isDead(resp.StatusCode) {
+				return fmt.Errorf("%w: %s answered %d", ErrDeadDestination, current, resp.StatusCode)
+			}
+			return nil
+		}
+		if hop == r.maxHops {
+			return ErrTooManyHops
+		}
+		current = next
+	}
+}
+
+// next returns where u redirects to, or "" when it doesn't.
+func (r *Resolver) next(ctx context.Context, u string) (string, error) {
+	resp, err := r.do(ctx, http.MethodHead, u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = r.do(ctx, http.MethodGet, u)
+	}
+	if err != nil {
+		return "", err
+	}
+	return redirectTarget(u, resp)
+}
+
+// redirectTarget returns where resp, the response for u, redirects to, or
+// "" when it doesn't.
+func redirectTarget(u string, resp *http.Response) (string, error) {
+	if !isRedirect(resp.StatusCode) {
+		return "", nil
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", nil
+	}
+
+	base, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	target, err := base.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("bad redirect location %q: %w", location, err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return "", fmt.Errorf("redirect to unsupported scheme %q", target.Scheme)
+	}
+	return target.String(), nil
+}
+
+// do sends one request and discards the body.
+func (r *Resolver) do(ctx context.Context, method, u string) (*http.Response, error) {
+	req, err := newRequest(ctx, method, u)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// isDead reports whether a final status means the destination is gone.
+func isDead(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone || status >= 500
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Package phishing scores destination URLs against lightweight, offline
+// heuristics for common phishing tricks. It complements reputation
+// scanners: it needs no network access and catches links nobody has
+// reported yet, at the cost of judging only the URL's shape.
+package phishing
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Signal names a heuristic that matched a URL.
+type Signal string
+
+// Signals reported by Assess.
+const (
+	// SignalPunycodeHost: a host label is internationalized (punycode or
+	// raw Unicode), the usual vehicle for homoglyph lookalike domains.
+	SignalPunycodeHost Signal = "punycode_host"
+
+	// SignalIPLiteralHost: the host is an IP address, including the
+	// decimal and hex forms browsers accept (http://3232235777/).
+	SignalIPLiteralHost Signal = "ip_literal_host"
+
+	// SignalCredentialParam: a query parameter looks like it carries a
+	// password, card number or similar secret.
+	SignalCredentialParam Signal = "credential_param"
+
+	// SignalDataPayload: a query value or the fragment embeds a data: or
+	// javascript: URL, used to smuggle a page past link checkers.
+	SignalDataPayload Signal = "data_payload"
+
+	// SignalUserinfo: the URL has a user@ part, which makes
+	// https://bank.example@evil.test/ read like a bank link.
+	SignalUserinfo Signal = "userinfo"
+)
+
+// weights is the score each signal adds.
+var weights = map[Signal]int{
+	SignalPunycodeHost:    3,
+	SignalIPLiteralHost:   3,
+	SignalCredentialParam: 2,
+	SignalDataPayload:     4,
+	SignalUserinfo:        3,
+}
+
+// credentialParams are query parameter names that carry secrets no link
+// should contain.
+var credentialParams = map[string]bool{
+	"pass": true, "passwd": true, "password": true, "passcode": true, "pwd": true,
+	"pin": true, "otp": true, "ssn": true, "cvv": true, "cvc": true,
+	"cardnumber": true, "card_number": true, "ccnum": true,
+}
+
+// Assessment is the outcome of scoring a URL.
+type Assessment struct {
+	Score   int      // sum of the matched signals' weights
+	Signals []Signal // matched signals, in a stable order
+}
+
+// Assess scores rawURL. URLs that don't parse score zero; validating them
+// is the caller's job.
+func Assess(rawURL string) Assessment {
+	var a Assessment
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return a
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if hasInternationalLabel(host) {
+		a.add(SignalPunycodeHost)
+	}
+	if isIPLiteral(host) {
+		a.add(SignalIPLiteralHost)
+	}
+
+	query := u.Query()
+	for name := range query {
+		if credentialParams[strings.ToLower(name)] {
+			a.add(SignalCredentialParam)
+			break
+		}
+	}
+
+	if isDataPayload(u.Fragment) {
+		a.add(SignalDataPayload)
+	} else {
+	values:
+		for _, vs := range query {
+			for _, v := range vs {
+				if isDataPayload(v) {
+					a.add(SignalDataPayload)
+					break values
+				}
+			}
+		}
+	}
+
+	if u.User != nil {
+		a.add(SignalUserinfo)
+	}
+	return a
+}
+
+func (a *Assessment) add(signal Signal) {
+	a.Score += weights[signal]
+	a.Signals = append(a.Signals, signal)
+}
+
+// hasInternationalLabel reports whether any label of host is punycode or
+// contains non-ASCII characters.
+func hasInternationalLabel(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+		for i := 0; i < len(label); i++ {
+			if label[i] >= 0x80 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIPLiteral reports whether host is an IP address, or a bare decimal or
+// hex number browsers would treat as one.
+func isIPLiteral(host string) bool {
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+
+	digits := host
+	if strings.HasPrefix(digits, "0x") {
+		digits = digits[2:]
+		return digits != "" && strings.Trim(digits, "0123456789abcdef") == ""
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
+
+// isDataPayload reports whether value is a data: or javascript: URL.
+func isDataPayload(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.HasPrefix(value, "data:") || strings.HasPrefix(value, "javascript:")
+}
@@ -0,0 +1,40 @@
+package phishing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssess(t *testing.T) {
+	tests := []struct {
+		url     string
+		signals []Signal
+	}{
+		{"https://example.com/page?q=go", nil},
+		{"https://xn--pypal-4ve.com/login", []Signal{SignalPunycodeHost}},
+		{"https://pаypal.com/", []Signal{SignalPunycodeHost}}, // Cyrillic а
+		{"http://192.168.1.1/admin", []Signal{SignalIPLiteralHost}},
+		{"http://[::1]:8080/", []Signal{SignalIPLiteralHost}},
+		{"http://3232235777/", []Signal{SignalIPLiteralHost}},
+		{"http://0xc0a80101/", []Signal{SignalIPLiteralHost}},
+		{"https://example.com/?user=a&Password=b", []Signal{SignalCredentialParam}},
+		{"https://example.com/#data:text/html;base64,PGgxPg==", []Signal{SignalDataPayload}},
+		{"https://example.com/?next=javascript:alert(1)", []Signal{SignalDataPayload}},
+		{"https://bank.example@evil.test/", []Signal{SignalUserinfo}},
+		{"http://user@10.0.0.1/?pwd=x", []Signal{SignalIPLiteralHost, SignalCredentialParam, SignalUserinfo}},
+	}
+
+	for _, tt := range tests {
+		got := Assess(tt.url)
+		if !reflect.DeepEqual(got.Signals, tt.signals) {
+			t.Errorf("Assess(%q) signals = %v, want %v", tt.url, got.Signals, tt.signals)
+		}
+		score := 0
+		for _, signal := range tt.signals {
+			score += weights[signal]
+		}
+		if got.Score != score {
+			t.Errorf("Assess(%q) score = %d, want %d", tt.url, got.Score, score)
+		}
+	}
+}
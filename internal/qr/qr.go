@@ -0,0 +1,104 @@
+// Package qr renders QR codes, as PNG or SVG, so short links can be
+// printed on posters, packaging and other offline media.
+package qr
+
+import (
+	"bytes"
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Formats a code can be rendered in.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Image sizes, in pixels per side, including the quiet zone around the
+// code.
+const (
+	DefaultSize = 256
+	MinSize     = 64
+	MaxSize     = 2048
+)
+
+// Error correction levels. Higher levels survive more damage or a logo
+// printed over the middle, at the cost of a denser code.
+const (
+	LevelLow      = "L" // about 7% of the code can be lost
+	LevelMedium   = "M" // about 15%
+	LevelQuartile = "Q" // about 25%
+	LevelHigh     = "H" // about 30%
+
+	DefaultLevel = LevelMedium
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	LevelLow:      qrcode.Low,
+	LevelMedium:   qrcode.Medium,
+	LevelQuartile: qrcode.High, // the library names the levels one step up
+	LevelHigh:     qrcode.Highest,
+}
+
+// ValidFormat reports whether format is FormatPNG or FormatSVG.
+func ValidFormat(format string) bool {
+	return format == FormatPNG || format == FormatSVG
+}
+
+// ValidLevel reports whether level is one of the Level constants.
+func ValidLevel(level string) bool {
+	_, ok := levels[level]
+	return ok
+}
+
+// Encode renders content as a size by size pixel image in format, and
+// returns it with its content type.
+func Encode(content, format, level string, size int) ([]byte, string, error) {
+	recovery, ok := levels[level]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown error correction level %q", level)
+	}
+	code, err := qrcode.New(content, recovery)
+	if err != nil {
+		return nil, "", fmt.Errorf("encoding QR code: %w", err)
+	}
+
+	switch format {
+	case FormatPNG:
+		data, err := code.PNG(size)
+		if err != nil {
+			return nil, "", fmt.Errorf("rendering PNG: %w", err)
+		}
+		return data, "image/png", nil
+	case FormatSVG:
+		return svg(code.Bitmap(), size), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// svg draws bitmap, quiet zone included, as one path over a white square.
+// The view box counts modules, so the image scales without blurring.
+func svg(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.Bytes()
+}
@@ -0,0 +1,48 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncode_PNG(t *testing.T) {
+	data, contentType, err := Encode("https://sn.ip/abc1234?src=qr", FormatPNG, LevelHigh, 300)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "image/png" {
+		t.Errorf("expected image/png, got %s", contentType)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 300 || bounds.Dy() != 300 {
+		t.Errorf("expected a 300x300 image, got %v", bounds)
+	}
+}
+
+func TestEncode_SVG(t *testing.T) {
+	data, contentType, err := Encode("https://sn.ip/abc1234?src=qr", FormatSVG, LevelLow, 128)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "image/svg+xml" {
+		t.Errorf("expected image/svg+xml, got %s", contentType)
+	}
+	svg := string(data)
+	if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, `width="128"`) || !strings.Contains(svg, `d="M`) {
+		t.Errorf("unexpected SVG %s", svg)
+	}
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	if _, _, err := Encode("https://sn.ip/abc", FormatPNG, "X", DefaultSize); err == nil {
+		t.Error("expected an unknown level to fail")
+	}
+	if _, _, err := Encode("https://sn.ip/abc", "gif", DefaultLevel, DefaultSize); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
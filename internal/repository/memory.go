@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/colby/snip/internal/model"
 )
@@ -10,14 +12,16 @@ import (
 // MemoryLinkRepository is an in-memory implementation of LinkRepository.
 // Useful for local development and testing.
 type MemoryLinkRepository struct {
-	mu    sync.RWMutex
-	links map[string]*model.Link // keyed by short code
+	mu           sync.RWMutex
+	links        map[string]*model.Link         // keyed by short code
+	destinations map[string]map[string]struct{} // destination key -> short codes
 }
 
 // NewMemoryLinkRepository creates a new in-memory link repository.
 func NewMemoryLinkRepository() *MemoryLinkRepository {
 	return &MemoryLinkRepository{
-		links: make(map[string]*model.Link),
+		links:        make(map[string]*model.Link),
+		destinations: make(map[string]map[string]struct{}),
 	}
 }
 
@@ -33,6 +37,7 @@ func (r *MemoryLinkRepository) Create(ctx context.Context, link *model.Link) err
 	// Store a copy to avoid external mutations
 	stored := *link
 	r.links[link.ShortCode] = &stored
+	r.index(stored.OriginalURL, stored.ShortCode)
 	return nil
 }
 
@@ -51,33 +56,194 @@ func (r *MemoryLinkRepository) GetByShortCode(ctx context.Context, shortCode str
 	return &result, nil
 }
 
+// GetByShortCodes retrieves the links that exist among shortCodes.
+func (r *MemoryLinkRepository) GetByShortCodes(ctx context.Context, shortCodes []string) (map[string]*model.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]*model.Link, len(shortCodes))
+	for _, code := range shortCodes {
+		if link, exists := r.links[code]; exists {
+			copied := *link
+			result[code] = &copied
+		}
+	}
+	return result, nil
+}
+
+// Update replaces the mutable fields of an existing link.
+func (r *MemoryLinkRepository) Update(ctx context.Context, link *model.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.links[link.ShortCode]
+	if !exists {
+		return ErrNotFound
+	}
+
+	if stored.Version != link.Version {
+		return ErrConflict
+	}
+
+	if stored.OriginalURL != link.OriginalURL {
+		r.unindex(stored.OriginalURL, stored.ShortCode)
+		r.index(link.OriginalURL, stored.ShortCode)
+	}
+	stored.OriginalURL = link.OriginalURL
+	stored.Pinned = link.Pinned
+	stored.Notes = link.Notes
+	stored.Disabled = link.Disabled
+	stored.ScanStatus = link.ScanStatus
+	stored.Interstitial = link.Interstitial
+	stored.NotifyMilestones = link.NotifyMilestones
+	stored.DeletedAt = link.DeletedAt
+	stored.AllowedReferrers = link.AllowedReferrers
+	stored.Public = link.Public
+	stored.Title = link.Title
+	stored.Version++
+	link.Version = stored.Version
+	return nil
+}
+
 // IncrementClickCount atomically increments the click count.
-func (r *MemoryLinkRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+func (r *MemoryLinkRepository) IncrementClickCount(ctx context.Context, shortCode string) (int64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	link, exists := r.links[shortCode]
 	if !exists {
-		return ErrNotFound
+		return 0, ErrNotFound
 	}
 
 	link.ClickCount++
+	return link.ClickCount, nil
+}
+
+// AddClickCount atomically adds delta to a link's click count.
+func (r *MemoryLinkRepository) AddClickCount(ctx context.Context, shortCode string, delta int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, exists := r.links[shortCode]
+	if !exists {
+		return ErrNotFound
+	}
+
+	link.ClickCount += delta
 	return nil
 }
 
 // Delete removes a link by its short code.
-func (r *MemoryLinkRepository) Delete(ctx context.Context, shortCode string) error {
+func (r *MemoryLinkRepository) Delete(ctx context.Context, shortCode string, expectedVersion int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.links[shortCode]; !exists {
+	link, exists := r.links[shortCode]
+	if !exists {
 		return ErrNotFound
 	}
+	if expectedVersion != 0 && link.Version != expectedVersion {
+		return ErrConflict
+	}
 
 	delete(r.links, shortCode)
+	r.unindex(link.OriginalURL, shortCode)
 	return nil
 }
 
+// GetByDestination returns the links that redirect to originalURL.
+func (r *MemoryLinkRepository) GetByDestination(ctx context.Context, originalURL string) ([]*model.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	codes := r.destinations[DestinationKey(originalURL)]
+	links := make([]*model.Link, 0, len(codes))
+	for code := range codes {
+		copied := *r.links[code]
+		links = append(links, &copied)
+	}
+	return links, nil
+}
+
+// index adds shortCode to the destination index. Callers hold the lock.
+func (r *MemoryLinkRepository) index(originalURL, shortCode string) {
+	key := DestinationKey(originalURL)
+	if r.destinations[key] == nil {
+		r.destinations[key] = make(map[string]struct{})
+	}
+	r.destinations[key][shortCode] = struct{}{}
+}
+
+// unindex removes shortCode from the destination index. Callers hold the
+// lock.
+func (r *MemoryLinkRepository) unindex(originalURL, shortCode string) {
+	key := DestinationKey(originalURL)
+	delete(r.destinations[key], shortCode)
+	if len(r.destinations[key]) == 0 {
+		delete(r.destinations, key)
+	}
+}
+
+// DeletedBefore returns soft-deleted links deleted before cutoff.
+func (r *MemoryLinkRepository) DeletedBefore(ctx context.Context, cutoff time.Time) ([]*model.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*model.Link
+	for _, link := range r.links {
+		if link.DeletedAt != nil && link.DeletedAt.Before(cutoff) {
+			copied := *link
+			links = append(links, &copied)
+		}
+	}
+	return links, nil
+}
+
+// List returns links ordered by short code; the cursor is the last code
+// of the previous page. Limit 0 returns all remaining links.
+func (r *MemoryLinkRepository) List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+	return r.list(cursor, limit, func(*model.Link) bool { return true })
+}
+
+// ListByOwner returns the links owned by ownerID, paged as by List.
+func (r *MemoryLinkRepository) ListByOwner(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	return r.list(cursor, limit, func(link *model.Link) bool { return link.OwnerID == ownerID })
+}
+
+// ListPinned returns the pinned links, of ownerID's if it's not empty,
+// paged as by List.
+func (r *MemoryLinkRepository) ListPinned(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	return r.list(cursor, limit, func(link *model.Link) bool {
+		return link.Pinned && (ownerID == "" || link.OwnerID == ownerID)
+	})
+}
+
+// list returns the page of links after cursor that pass keep.
+func (r *MemoryLinkRepository) list(cursor string, limit int, keep func(*model.Link) bool) ([]*model.Link, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	codes := make([]string, 0, len(r.links))
+	for code, link := range r.links {
+		if code > cursor && keep(link) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	next := ""
+	if limit > 0 && len(codes) > limit {
+		codes = codes[:limit]
+		next = codes[limit-1]
+	}
+	links := make([]*model.Link, len(codes))
+	for i, code := range codes {
+		copied := *r.links[code]
+		links[i] = &copied
+	}
+	return links, next, nil
+}
+
 // MemoryClickRepository is an in-memory implementation of ClickRepository.
 type MemoryClickRepository struct {
 	mu     sync.RWMutex
@@ -123,3 +289,493 @@ func (r *MemoryClickRepository) GetByLinkID(ctx context.Context, linkID string,
 
 	return result, nil
 }
+
+// GetBetween returns every link's click events clicked in [from, to).
+func (r *MemoryClickRepository) GetBetween(ctx context.Context, from, to time.Time) ([]model.ClickEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []model.ClickEvent{}
+	for _, events := range r.clicks {
+		for _, event := range events {
+			if !event.ClickedAt.Before(from) && event.ClickedAt.Before(to) {
+				result = append(result, event)
+			}
+		}
+	}
+	return result, nil
+}
+
+// DeleteBefore removes click events clicked before cutoff.
+func (r *MemoryClickRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for linkID, events := range r.clicks {
+		kept := events[:0]
+		for _, event := range events {
+			if event.ClickedAt.Before(cutoff) {
+				removed++
+				continue
+			}
+			kept = append(kept, event)
+		}
+		if len(kept) == 0 {
+			delete(r.clicks, linkID)
+		} else {
+			r.clicks[linkID] = kept
+		}
+	}
+	return removed, nil
+}
+
+// MemoryStatsRollupRepository is an in-memory implementation of
+// StatsRollupRepository.
+type MemoryStatsRollupRepository struct {
+	mu   sync.RWMutex
+	days map[string]map[string]*model.DailyClicks // link ID -> day -> aggregate
+}
+
+// NewMemoryStatsRollupRepository creates a new in-memory rollup repository.
+func NewMemoryStatsRollupRepository() *MemoryStatsRollupRepository {
+	return &MemoryStatsRollupRepository{
+		days: make(map[string]map[string]*model.DailyClicks),
+	}
+}
+
+// AddClick counts one click from source on day.
+func (r *MemoryStatsRollupRepository) AddClick(ctx context.Context, linkID, day, source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days, exists := r.days[linkID]
+	if !exists {
+		days = make(map[string]*model.DailyClicks)
+		r.days[linkID] = days
+	}
+	rollup, exists := days[day]
+	if !exists {
+		rollup = &model.DailyClicks{Date: day, BySource: make(map[string]int64)}
+		days[day] = rollup
+	}
+	rollup.Clicks++
+	rollup.BySource[source]++
+	return nil
+}
+
+// GetRange returns a link's aggregates between from and to inclusive.
+func (r *MemoryStatsRollupRepository) GetRange(ctx context.Context, linkID, from, to string) ([]model.DailyClicks, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []model.DailyClicks{}
+	for day, rollup := range r.days[linkID] {
+		if day < from || day > to {
+			continue
+		}
+		copied := *rollup
+		copied.BySource = make(map[string]int64, len(rollup.BySource))
+		for source, clicks := range rollup.BySource {
+			copied.BySource[source] = clicks
+		}
+		result = append(result, copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result, nil
+}
+
+// SetDay replaces a link's aggregate for rollup.Date.
+func (r *MemoryStatsRollupRepository) SetDay(ctx context.Context, linkID string, rollup model.DailyClicks) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days, exists := r.days[linkID]
+	if !exists {
+		days = make(map[string]*model.DailyClicks)
+		r.days[linkID] = days
+	}
+	stored := rollup
+	stored.BySource = make(map[string]int64, len(rollup.BySource))
+	for source, clicks := range rollup.BySource {
+		stored.BySource[source] = clicks
+	}
+	days[rollup.Date] = &stored
+	return nil
+}
+
+// MemoryPrefixRepository is an in-memory implementation of PrefixRepository.
+type MemoryPrefixRepository struct {
+	mu       sync.RWMutex
+	prefixes map[string]model.Prefix
+}
+
+// NewMemoryPrefixRepository creates a new in-memory prefix repository.
+func NewMemoryPrefixRepository() *MemoryPrefixRepository {
+	return &MemoryPrefixRepository{
+		prefixes: make(map[string]model.Prefix),
+	}
+}
+
+// Create allocates a prefix.
+func (r *MemoryPrefixRepository) Create(ctx context.Context, prefix *model.Prefix) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.prefixes[prefix.Name]; exists {
+		return ErrAlreadyExists
+	}
+	r.prefixes[prefix.Name] = *prefix
+	return nil
+}
+
+// Get retrieves a prefix by name.
+func (r *MemoryPrefixRepository) Get(ctx context.Context, name string) (*model.Prefix, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix, exists := r.prefixes[name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &prefix, nil
+}
+
+// List returns all prefixes ordered by name.
+func (r *MemoryPrefixRepository) List(ctx context.Context) ([]model.Prefix, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefixes := make([]model.Prefix, 0, len(r.prefixes))
+	for _, prefix := range r.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Name < prefixes[j].Name })
+	return prefixes, nil
+}
+
+// Delete releases a prefix.
+func (r *MemoryPrefixRepository) Delete(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.prefixes[name]; !exists {
+		return ErrNotFound
+	}
+	delete(r.prefixes, name)
+	return nil
+}
+
+// MemoryTemplateRepository is an in-memory implementation of
+// TemplateRepository.
+type MemoryTemplateRepository struct {
+	mu        sync.RWMutex
+	templates map[string]model.Template
+}
+
+// NewMemoryTemplateRepository creates a new in-memory template repository.
+func NewMemoryTemplateRepository() *MemoryTemplateRepository {
+	return &MemoryTemplateRepository{
+		templates: make(map[string]model.Template),
+	}
+}
+
+// Create saves a template.
+func (r *MemoryTemplateRepository) Create(ctx context.Context, template *model.Template) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.templates[template.ID]; exists {
+		return ErrAlreadyExists
+	}
+	r.templates[template.ID] = *template
+	return nil
+}
+
+// Get retrieves a template by ID.
+func (r *MemoryTemplateRepository) Get(ctx context.Context, id string) (*model.Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	template, exists := r.templates[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &template, nil
+}
+
+// List returns all templates ordered by ID.
+func (r *MemoryTemplateRepository) List(ctx context.Context) ([]model.Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]model.Template, 0, len(r.templates))
+	for _, template := range r.templates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+// Delete removes a template.
+func (r *MemoryTemplateRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.templates[id]; !exists {
+		return ErrNotFound
+	}
+	delete(r.templates, id)
+	return nil
+}
+
+// MemoryLinkAliasRepository is an in-memory implementation of
+// LinkAliasRepository.
+type MemoryLinkAliasRepository struct {
+	mu      sync.RWMutex
+	aliases map[string]model.LinkAlias
+}
+
+// NewMemoryLinkAliasRepository creates a new in-memory alias repository.
+func NewMemoryLinkAliasRepository() *MemoryLinkAliasRepository {
+	return &MemoryLinkAliasRepository{
+		aliases: make(map[string]model.LinkAlias),
+	}
+}
+
+// Create saves an alias.
+func (r *MemoryLinkAliasRepository) Create(ctx context.Context, alias *model.LinkAlias) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.aliases[alias.Alias]; exists {
+		return ErrAlreadyExists
+	}
+	r.aliases[alias.Alias] = *alias
+	return nil
+}
+
+// Get retrieves an alias by code.
+func (r *MemoryLinkAliasRepository) Get(ctx context.Context, alias string) (*model.LinkAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.aliases[alias]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &stored, nil
+}
+
+// ListByShortCode returns a link's aliases ordered by code.
+func (r *MemoryLinkAliasRepository) ListByShortCode(ctx context.Context, shortCode string) ([]model.LinkAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aliases := []model.LinkAlias{}
+	for _, alias := range r.aliases {
+		if alias.ShortCode == shortCode {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return aliases, nil
+}
+
+// Delete removes an alias.
+func (r *MemoryLinkAliasRepository) Delete(ctx context.Context, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.aliases[alias]; !exists {
+		return ErrNotFound
+	}
+	delete(r.aliases, alias)
+	return nil
+}
+
+// MemoryIdempotencyRepository is an in-memory implementation of
+// IdempotencyRepository.
+type MemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository creates a new in-memory idempotency
+// repository.
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{
+		records: make(map[string]model.IdempotencyRecord),
+	}
+}
+
+// Create reserves a key.
+func (r *MemoryIdempotencyRepository) Create(ctx context.Context, record *model.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[record.Key]; exists {
+		return ErrAlreadyExists
+	}
+	r.records[record.Key] = *record
+	return nil
+}
+
+// Get retrieves a record by key.
+func (r *MemoryIdempotencyRepository) Get(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.records[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &stored, nil
+}
+
+// Update replaces the record for a key.
+func (r *MemoryIdempotencyRepository) Update(ctx context.Context, record *model.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[record.Key]; !exists {
+		return ErrNotFound
+	}
+	r.records[record.Key] = *record
+	return nil
+}
+
+// Delete releases a key.
+func (r *MemoryIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[key]; !exists {
+		return ErrNotFound
+	}
+	delete(r.records, key)
+	return nil
+}
+
+// DeleteExpired removes records that expired before now.
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for key, record := range r.records {
+		if record.ExpiresAt.Before(now) {
+			delete(r.records, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// MemoryAPIKeyRepository is an in-memory implementation of
+// APIKeyRepository.
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]model.APIKey // keyed by hash
+}
+
+// NewMemoryAPIKeyRepository creates a new in-memory API key repository.
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys: make(map[string]model.APIKey),
+	}
+}
+
+// Create saves a key.
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, stored := range r.keys {
+		if hash == key.Hash || stored.ID == key.ID {
+			return ErrAlreadyExists
+		}
+	}
+	r.keys[key.Hash] = *key
+	return nil
+}
+
+// GetByHash retrieves a key by the hash of its secret.
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.keys[hash]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &stored, nil
+}
+
+// List returns all keys, oldest first.
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]model.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke marks a key revoked.
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, key := range r.keys {
+		if key.ID != id {
+			continue
+		}
+		if key.RevokedAt == nil {
+			key.RevokedAt = &at
+			r.keys[hash] = key
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+// MemoryUserRepository is an in-memory implementation of UserRepository.
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]model.User // keyed by email
+}
+
+// NewMemoryUserRepository creates a new in-memory user repository.
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[string]model.User),
+	}
+}
+
+// Create saves a user.
+func (r *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.Email]; exists {
+		return ErrAlreadyExists
+	}
+	r.users[user.Email] = *user
+	return nil
+}
+
+// GetByEmail retrieves a user by email.
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[email]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
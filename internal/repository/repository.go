@@ -3,7 +3,10 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"time"
 
 	"github.com/colby/snip/internal/model"
 )
@@ -12,6 +15,7 @@ import (
 var (
 	ErrNotFound      = errors.New("link not found")
 	ErrAlreadyExists = errors.New("short code already exists")
+	ErrConflict      = errors.New("link version does not match")
 )
 
 // LinkRepository defines the interface for link persistence operations.
@@ -24,11 +28,60 @@ type LinkRepository interface {
 	// GetByShortCode retrieves a link by its short code. Returns ErrNotFound if not found.
 	GetByShortCode(ctx context.Context, shortCode string) (*model.Link, error)
 
-	// IncrementClickCount atomically increments the click count for a link.
-	IncrementClickCount(ctx context.Context, shortCode string) error
+	// GetByShortCodes retrieves several links at once, keyed by short code.
+	// Codes with no link are simply absent from the result.
+	GetByShortCodes(ctx context.Context, shortCodes []string) (map[string]*model.Link, error)
 
-	// Delete removes a link by its short code.
-	Delete(ctx context.Context, shortCode string) error
+	// Update persists changes to a link's mutable fields (OriginalURL, Pinned,
+	// Notes, Disabled, ScanStatus, Interstitial, NotifyMilestones,
+	// AllowedReferrers, Public, Title, DeletedAt).
+	// Click count and creation time are left untouched so concurrent clicks
+	// aren't lost. The write only succeeds if the stored version still equals
+	// link.Version, returning ErrConflict otherwise; on success the version is
+	// incremented, both in storage and on link. Returns ErrNotFound if the
+	// link does not exist.
+	Update(ctx context.Context, link *model.Link) error
+
+	// IncrementClickCount atomically increments the click count for a link
+	// and returns the new count.
+	IncrementClickCount(ctx context.Context, shortCode string) (int64, error)
+
+	// AddClickCount atomically adds delta (which may be negative) to a
+	// link's click count. Returns ErrNotFound if the link does not exist.
+	AddClickCount(ctx context.Context, shortCode string, delta int64) error
+
+	// Delete removes a link by its short code. A non-zero expectedVersion
+	// makes the delete conditional on the stored version, returning
+	// ErrConflict on mismatch.
+	Delete(ctx context.Context, shortCode string, expectedVersion int64) error
+
+	// List returns one page of stored links, soft-deleted ones included,
+	// and the cursor for the next page ("" when there are no more). An
+	// empty cursor starts from the beginning; limit 0 lets the
+	// implementation pick the page size. The order is up to the
+	// implementation, but pages never overlap or skip links that exist
+	// throughout the listing.
+	List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error)
+
+	// ListByOwner is List restricted to the links whose OwnerID is
+	// ownerID, with the same order and cursors.
+	ListByOwner(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error)
+
+	// ListPinned is List restricted to pinned links, and to those owned
+	// by ownerID unless it's empty, with the same order and cursors.
+	ListPinned(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error)
+
+	// GetByDestination returns the links whose OriginalURL is exactly
+	// originalURL, soft-deleted ones included, in no particular order.
+	// Implementations index links by DestinationKey so this doesn't scan.
+	GetByDestination(ctx context.Context, originalURL string) ([]*model.Link, error)
+}
+
+// DestinationKey is the key links are indexed under for GetByDestination:
+// the hex SHA-256 of the destination URL, so long URLs make short keys.
+func DestinationKey(originalURL string) string {
+	sum := sha256.Sum256([]byte(originalURL))
+	return hex.EncodeToString(sum[:])
 }
 
 // ClickRepository defines the interface for click event persistence.
@@ -39,3 +92,138 @@ type ClickRepository interface {
 	// GetByLinkID retrieves all click events for a given link.
 	GetByLinkID(ctx context.Context, linkID string, limit int) ([]model.ClickEvent, error)
 }
+
+// ClickSweeper is implemented by click repositories that support
+// maintenance sweeps across all links. Sweeps are expected to run off the
+// request path, so implementations may scan the whole store.
+type ClickSweeper interface {
+	// GetBetween returns every link's click events clicked in [from, to).
+	GetBetween(ctx context.Context, from, to time.Time) ([]model.ClickEvent, error)
+
+	// DeleteBefore removes click events clicked before cutoff and returns
+	// how many were removed.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// LinkSweeper is implemented by link repositories that can find
+// soft-deleted links for purging. Like ClickSweeper, it may scan the whole
+// store.
+type LinkSweeper interface {
+	// DeletedBefore returns links whose DeletedAt is before cutoff.
+	DeletedBefore(ctx context.Context, cutoff time.Time) ([]*model.Link, error)
+}
+
+// StatsRollupRepository holds per-link, per-day click aggregates, so stats
+// can be served without scanning raw click events. Days are UTC dates in
+// YYYY-MM-DD form, which sort chronologically as strings.
+type StatsRollupRepository interface {
+	// AddClick counts one click from source on day.
+	AddClick(ctx context.Context, linkID, day, source string) error
+
+	// GetRange returns a link's aggregates from day from to day to
+	// inclusive, in date order. Days without clicks are absent.
+	GetRange(ctx context.Context, linkID, from, to string) ([]model.DailyClicks, error)
+
+	// SetDay replaces a link's aggregate for rollup.Date.
+	SetDay(ctx context.Context, linkID string, rollup model.DailyClicks) error
+}
+
+// PrefixRepository defines the interface for namespace prefix persistence.
+type PrefixRepository interface {
+	// Create allocates a prefix. Returns ErrAlreadyExists if it is taken.
+	Create(ctx context.Context, prefix *model.Prefix) error
+
+	// Get retrieves a prefix by name. Returns ErrNotFound if not allocated.
+	Get(ctx context.Context, name string) (*model.Prefix, error)
+
+	// List returns all allocated prefixes, ordered by name.
+	List(ctx context.Context) ([]model.Prefix, error)
+
+	// Delete releases a prefix. Returns ErrNotFound if not allocated.
+	Delete(ctx context.Context, name string) error
+}
+
+// TemplateRepository defines the interface for link template persistence.
+type TemplateRepository interface {
+	// Create saves a template. Returns ErrAlreadyExists if the ID is taken.
+	Create(ctx context.Context, template *model.Template) error
+
+	// Get retrieves a template by ID. Returns ErrNotFound if there is none.
+	Get(ctx context.Context, id string) (*model.Template, error)
+
+	// List returns all templates, ordered by ID.
+	List(ctx context.Context) ([]model.Template, error)
+
+	// Delete removes a template. Returns ErrNotFound if there is none.
+	Delete(ctx context.Context, id string) error
+}
+
+// IdempotencyRepository defines the interface for idempotency record
+// persistence. Expiry is left to the caller: records are returned until
+// deleted, whatever their ExpiresAt.
+type IdempotencyRepository interface {
+	// Create reserves record.Key. Returns ErrAlreadyExists if the key is
+	// held.
+	Create(ctx context.Context, record *model.IdempotencyRecord) error
+
+	// Get retrieves a record by key. Returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (*model.IdempotencyRecord, error)
+
+	// Update replaces the record for record.Key. Returns ErrNotFound if
+	// there is none.
+	Update(ctx context.Context, record *model.IdempotencyRecord) error
+
+	// Delete releases a key. Returns ErrNotFound if there is none.
+	Delete(ctx context.Context, key string) error
+
+	// DeleteExpired removes records that expired before now and returns
+	// how many were removed.
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// APIKeyRepository defines the interface for API key persistence. Keys
+// are looked up by the hash of their secret, never the secret itself.
+type APIKeyRepository interface {
+	// Create saves a key. Returns ErrAlreadyExists if its ID or hash is
+	// taken.
+	Create(ctx context.Context, key *model.APIKey) error
+
+	// GetByHash retrieves a key by the hash of its secret, revoked or
+	// not. Returns ErrNotFound if there is none.
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+
+	// List returns all keys, revoked ones included, oldest first.
+	List(ctx context.Context) ([]model.APIKey, error)
+
+	// Revoke marks a key revoked at the given time. Revoking a revoked key
+	// keeps the first time. Returns ErrNotFound if there is no such key.
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+// UserRepository defines the interface for user account persistence.
+// Emails are stored lower-cased, so they're looked up exactly.
+type UserRepository interface {
+	// Create saves a user. Returns ErrAlreadyExists if the email is
+	// taken.
+	Create(ctx context.Context, user *model.User) error
+
+	// GetByEmail retrieves a user by email. Returns ErrNotFound if there
+	// is none.
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+}
+
+// LinkAliasRepository defines the interface for secondary alias
+// persistence. Aliases point at a link by its short code.
+type LinkAliasRepository interface {
+	// Create saves an alias. Returns ErrAlreadyExists if the code is taken.
+	Create(ctx context.Context, alias *model.LinkAlias) error
+
+	// Get retrieves an alias by code. Returns ErrNotFound if there is none.
+	Get(ctx context.Context, alias string) (*model.LinkAlias, error)
+
+	// ListByShortCode returns a link's aliases, ordered by code.
+	ListByShortCode(ctx context.Context, shortCode string) ([]model.LinkAlias, error)
+
+	// Delete removes an alias. Returns ErrNotFound if there is none.
+	Delete(ctx context.Context, alias string) error
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SettingsRepository persists service-wide settings that must survive
+// restarts, such as a short code length the service grew into.
+type SettingsRepository interface {
+	// GetCodeLength returns the stored code length, or ErrNotFound if none
+	// has been stored.
+	GetCodeLength(ctx context.Context) (int, error)
+
+	// SetCodeLength stores the code length.
+	SetCodeLength(ctx context.Context, length int) error
+}
+
+// MemorySettingsRepository is an in-memory SettingsRepository. Settings are
+// lost on restart, so it's only useful for tests.
+type MemorySettingsRepository struct {
+	mu         sync.Mutex
+	codeLength int
+}
+
+// NewMemorySettingsRepository creates an empty in-memory settings repository.
+func NewMemorySettingsRepository() *MemorySettingsRepository {
+	return &MemorySettingsRepository{}
+}
+
+// GetCodeLength returns the stored code length.
+func (r *MemorySettingsRepository) GetCodeLength(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.codeLength == 0 {
+		return 0, ErrNotFound
+	}
+	return r.codeLength, nil
+}
+
+// SetCodeLength stores the code length.
+func (r *MemorySettingsRepository) SetCodeLength(ctx context.Context, length int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codeLength = length
+	return nil
+}
+
+// FileSettingsRepository stores settings as a small JSON file, for
+// single-instance deployments without a database.
+type FileSettingsRepository struct {
+	mu   sync.Mutex
+	path string
+}
+
+// fileSettings is the on-disk format of FileSettingsRepository.
+type fileSettings struct {
+	CodeLength int `json:"code_length,omitempty"`
+}
+
+// NewFileSettingsRepository creates a settings repository backed by path.
+// The file is created on the first write.
+func NewFileSettingsRepository(path string) *FileSettingsRepository {
+	return &FileSettingsRepository{path: path}
+}
+
+// GetCodeLength returns the stored code length.
+func (r *FileSettingsRepository) GetCodeLength(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings, err := r.read()
+	if err != nil {
+		return 0, err
+	}
+	if settings.CodeLength == 0 {
+		return 0, ErrNotFound
+	}
+	return settings.CodeLength, nil
+}
+
+// SetCodeLength stores the code length.
+func (r *FileSettingsRepository) SetCodeLength(ctx context.Context, length int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings, err := r.read()
+	if err != nil {
+		return err
+	}
+	settings.CodeLength = length
+	return r.write(settings)
+}
+
+func (r *FileSettingsRepository) read() (fileSettings, error) {
+	var settings fileSettings
+
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("reading settings: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("parsing settings %s: %w", r.path, err)
+	}
+	return settings, nil
+}
+
+// write replaces the file atomically so a crash can't leave it truncated.
+func (r *FileSettingsRepository) write(settings fileSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding settings: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing settings: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing settings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing settings: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("writing settings: %w", err)
+	}
+	return nil
+}
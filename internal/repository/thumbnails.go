@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// ThumbnailRepository stores destination thumbnails, one per link, keyed
+// by link ID.
+type ThumbnailRepository interface {
+	// Get returns a link's thumbnail, or ErrNotFound if none is stored.
+	Get(ctx context.Context, linkID string) (*model.Thumbnail, error)
+
+	// Put stores a link's thumbnail, replacing any previous one.
+	Put(ctx context.Context, linkID string, thumbnail *model.Thumbnail) error
+
+	// Delete removes a link's thumbnail. Deleting a missing thumbnail is
+	// not an error.
+	Delete(ctx context.Context, linkID string) error
+}
+
+// MemoryThumbnailRepository is an in-memory ThumbnailRepository.
+type MemoryThumbnailRepository struct {
+	mu         sync.RWMutex
+	thumbnails map[string]model.Thumbnail
+}
+
+// NewMemoryThumbnailRepository creates an empty in-memory thumbnail repository.
+func NewMemoryThumbnailRepository() *MemoryThumbnailRepository {
+	return &MemoryThumbnailRepository{thumbnails: make(map[string]model.Thumbnail)}
+}
+
+// Get returns a link's thumbnail.
+func (r *MemoryThumbnailRepository) Get(ctx context.Context, linkID string) (*model.Thumbnail, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	thumbnail, exists := r.thumbnails[linkID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &thumbnail, nil
+}
+
+// Put stores a link's thumbnail.
+func (r *MemoryThumbnailRepository) Put(ctx context.Context, linkID string, thumbnail *model.Thumbnail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.thumbnails[linkID] = *thumbnail
+	return nil
+}
+
+// Delete removes a link's thumbnail.
+func (r *MemoryThumbnailRepository) Delete(ctx context.Context, linkID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.thumbnails, linkID)
+	return nil
+}
+
+// DirThumbnailRepository stores thumbnails on local disk: the image in
+// <dir>/<id> and its metadata alongside in <dir>/<id>.json.
+type DirThumbnailRepository struct {
+	dir string
+}
+
+// thumbnailMeta is the on-disk metadata of a DirThumbnailRepository entry.
+type thumbnailMeta struct {
+	ContentType string `json:"content_type"`
+	SourceURL   string `json:"source_url"`
+}
+
+// NewDirThumbnailRepository creates a thumbnail repository in dir, which
+// is created on the first write.
+func NewDirThumbnailRepository(dir string) *DirThumbnailRepository {
+	return &DirThumbnailRepository{dir: dir}
+}
+
+// Get returns a link's thumbnail.
+func (r *DirThumbnailRepository) Get(ctx context.Context, linkID string) (*model.Thumbnail, error) {
+	path := r.path(linkID)
+
+	raw, err := os.ReadFile(path + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading thumbnail: %w", err)
+	}
+	var meta thumbnailMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("parsing thumbnail metadata %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading thumbnail: %w", err)
+	}
+
+	return &model.Thumbnail{ContentType: meta.ContentType, SourceURL: meta.SourceURL, Data: data}, nil
+}
+
+// Put stores a link's thumbnail. The image is written before its metadata,
+// so a reader never sees metadata for a half-written image.
+func (r *DirThumbnailRepository) Put(ctx context.Context, linkID string, thumbnail *model.Thumbnail) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("writing thumbnail: %w", err)
+	}
+
+	meta, err := json.Marshal(thumbnailMeta{ContentType: thumbnail.ContentType, SourceURL: thumbnail.SourceURL})
+	if err != nil {
+		return fmt.Errorf("encoding thumbnail metadata: %w", err)
+	}
+
+	path := r.path(linkID)
+	if err := writeFileAtomic(path, thumbnail.Data); err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".json", meta)
+}
+
+// Delete removes a link's thumbnail.
+func (r *DirThumbnailRepository) Delete(ctx context.Context, linkID string) error {
+	path := r.path(linkID)
+	for _, name := range []string{path + ".json", path} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("deleting thumbnail: %w", err)
+		}
+	}
+	return nil
+}
+
+// path returns the image path for a link. Link IDs are ULIDs, so they are
+// safe file names; Base guards against anything else.
+func (r *DirThumbnailRepository) path(linkID string) string {
+	return filepath.Join(r.dir, filepath.Base(linkID))
+}
+
+// writeFileAtomic replaces path via a temporary file and a rename.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing thumbnail: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing thumbnail: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing thumbnail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// errNoString is returned for secrets that only hold binary data.
+var errNoString = errors.New("secret has no string value")
+
+// SSM reads SecureString (or plain) parameters from SSM Parameter Store.
+type SSM struct {
+	client *ssm.Client
+}
+
+// NewSSM creates a Parameter Store source.
+func NewSSM(cfg aws.Config) *SSM {
+	return &SSM{client: ssm.NewFromConfig(cfg)}
+}
+
+// Fetch returns a parameter's decrypted value.
+func (s *SSM) Fetch(ctx context.Context, name string) (string, error) {
+	out, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", errNoString
+	}
+	return *out.Parameter.Value, nil
+}
+
+// SecretsManager reads secrets from AWS Secrets Manager. The current
+// version (AWSCURRENT) is read, so rotations take effect once the cached
+// value expires.
+type SecretsManager struct {
+	client *secretsmanager.Client
+}
+
+// NewSecretsManager creates a Secrets Manager source.
+func NewSecretsManager(cfg aws.Config) *SecretsManager {
+	return &SecretsManager{client: secretsmanager.NewFromConfig(cfg)}
+}
+
+// Fetch returns a secret's string value.
+func (s *SecretsManager) Fetch(ctx context.Context, name string) (string, error) {
+	out, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", errNoString
+	}
+	return *out.SecretString, nil
+}
+
+// AWSSources returns the SSM and Secrets Manager sources for a Resolver.
+func AWSSources(cfg aws.Config) map[string]Source {
+	return map[string]Source{
+		SchemeSSM:            NewSSM(cfg),
+		SchemeSecretsManager: NewSecretsManager(cfg),
+	}
+}
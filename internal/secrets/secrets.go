@@ -0,0 +1,131 @@
+// Package secrets resolves configuration values that refer to secrets held
+// in a secret store instead of containing them, such as
+// "ssm:/snip/prod/sentry-dsn" or "secretsmanager:snip/prod#virustotal".
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference schemes.
+const (
+	SchemeSSM            = "ssm"
+	SchemeSecretsManager = "secretsmanager"
+)
+
+// DefaultTTL is how long a resolved secret is cached.
+const DefaultTTL = 5 * time.Minute
+
+// Source fetches secrets by name from one store.
+type Source interface {
+	Fetch(ctx context.Context, name string) (string, error)
+}
+
+// Config configures a Resolver.
+type Config struct {
+	// Sources maps reference schemes to the stores they read from.
+	Sources map[string]Source
+
+	// TTL is how long fetched secrets are cached before being fetched
+	// again, so rotated secrets are picked up. Defaults to DefaultTTL.
+	TTL time.Duration
+}
+
+// Resolver turns secret references into secret values. A reference is
+// "<scheme>:<name>", optionally followed by "#<field>" to pick a field
+// from a JSON object secret. Values are cached per name for the TTL.
+type Resolver struct {
+	sources map[string]Source
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	fetched time.Time
+}
+
+// New creates a Resolver.
+func New(config Config) *Resolver {
+	r := &Resolver{
+		sources: config.Sources,
+		ttl:     config.TTL,
+		cache:   make(map[string]cachedSecret),
+	}
+	if r.ttl <= 0 {
+		r.ttl = DefaultTTL
+	}
+	return r
+}
+
+// IsReference reports whether value refers to a secret rather than being
+// a plain setting.
+func IsReference(value string) bool {
+	scheme, name, ok := strings.Cut(value, ":")
+	return ok && name != "" && (scheme == SchemeSSM || scheme == SchemeSecretsManager)
+}
+
+// Resolve returns the secret value refers to, or value itself when it
+// isn't a reference.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	scheme, ref, _ := strings.Cut(value, ":")
+	name, field, hasField := strings.Cut(ref, "#")
+
+	secret, err := r.fetch(ctx, scheme, name)
+	if err != nil {
+		return "", err
+	}
+	if !hasField {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s: not a JSON object, can't read field %q", name, field)
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s: no field %q", name, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// fetch returns a secret from the cache, or from its source when the
+// cached copy is missing or older than the TTL.
+func (r *Resolver) fetch(ctx context.Context, scheme, name string) (string, error) {
+	key := scheme + ":" + name
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Since(cached.fetched) < r.ttl {
+		return cached.value, nil
+	}
+
+	source := r.sources[scheme]
+	if source == nil {
+		return "", fmt.Errorf("secret %s: no %s source configured", name, scheme)
+	}
+	value, err := source.Fetch(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("fetching secret %s from %s: %w", name, scheme, err)
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cachedSecret{value: value, fetched: time.Now()}
+	r.mu.Unlock()
+	return value, nil
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeSource serves secrets from a map and counts fetches.
+type fakeSource struct {
+	values  map[string]string
+	fetches int
+}
+
+func (f *fakeSource) Fetch(ctx context.Context, name string) (string, error) {
+	f.fetches++
+	value, ok := f.values[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	source := &fakeSource{values: map[string]string{
+		"/snip/dsn":  "https://key@sentry.example.com/1",
+		"snip/prod":  `{"virustotal": "vt-key", "port": 8080}`,
+		"snip/plain": "not json",
+	}}
+	resolver := New(Config{Sources: map[string]Source{
+		SchemeSSM:            source,
+		SchemeSecretsManager: source,
+	}})
+	ctx := context.Background()
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain-value", "plain-value", false},
+		{"https://example.com", "https://example.com", false},
+		{"ssm:/snip/dsn", "https://key@sentry.example.com/1", false},
+		{"secretsmanager:snip/prod#virustotal", "vt-key", false},
+		{"secretsmanager:snip/prod#port", "8080", false},
+		{"secretsmanager:snip/prod#missing", "", true},
+		{"secretsmanager:snip/plain#field", "", true},
+		{"ssm:/snip/unknown", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := resolver.Resolve(ctx, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolver_Caches(t *testing.T) {
+	source := &fakeSource{values: map[string]string{"/snip/token": "v1"}}
+	resolver := New(Config{Sources: map[string]Source{SchemeSSM: source}, TTL: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.Resolve(ctx, "ssm:/snip/token"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if source.fetches != 1 {
+		t.Errorf("expected 1 fetch while cached, got %d", source.fetches)
+	}
+
+	// A rotated secret is picked up once the cached copy expires
+	source.values["/snip/token"] = "v2"
+	time.Sleep(60 * time.Millisecond)
+	got, err := resolver.Resolve(ctx, "ssm:/snip/token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "v2" {
+		t.Errorf("expected rotated value v2, got %q", got)
+	}
+}
+
+func TestResolver_MissingSource(t *testing.T) {
+	resolver := New(Config{})
+	if _, err := resolver.Resolve(context.Background(), "ssm:/snip/token"); err == nil {
+		t.Error("expected an error without an ssm source")
+	}
+}
@@ -0,0 +1,135 @@
+// Package seed preloads repositories with fixture links and synthetic click
+// history so local development and demos start with realistic data.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/ulid"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures is the top-level structure of a seed file.
+type Fixtures struct {
+	Links []LinkFixture `yaml:"links"`
+}
+
+// LinkFixture describes one link and the click history to synthesize for it.
+type LinkFixture struct {
+	ShortCode  string    `yaml:"short_code"`
+	URL        string    `yaml:"url"`
+	CreatedAt  time.Time `yaml:"created_at"` // defaults to 30 days ago
+	Clicks     int       `yaml:"clicks"`     // number of synthetic click events
+	Referrers  []string  `yaml:"referrers"`  // sampled for each click; empty means direct traffic
+	UserAgents []string  `yaml:"user_agents"`
+}
+
+// defaultUserAgents are used when a fixture does not list its own.
+var defaultUserAgents = []string{
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148",
+	"curl/8.4.0",
+}
+
+// LoadFile parses a YAML fixtures file.
+func LoadFile(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading seed file: %w", err)
+	}
+
+	var fixtures Fixtures
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("parsing seed file: %w", err)
+	}
+
+	for i, l := range fixtures.Links {
+		if l.ShortCode == "" || l.URL == "" {
+			return nil, fmt.Errorf("seed link %d: short_code and url are required", i)
+		}
+		if l.Clicks < 0 {
+			return nil, fmt.Errorf("seed link %q: clicks cannot be negative", l.ShortCode)
+		}
+	}
+
+	return &fixtures, nil
+}
+
+// Apply writes the fixtures into the repositories. Click timestamps are
+// spread randomly between each link's creation time and now; the random
+// source is fixed so repeated runs produce the same data.
+func Apply(ctx context.Context, fixtures *Fixtures, links repository.LinkRepository, clicks repository.ClickRepository, now time.Time) error {
+	rng := rand.New(rand.NewPCG(1, 2))
+	entropy := rand.NewChaCha8([32]byte{}) // deterministic ID randomness
+
+	for _, f := range fixtures.Links {
+		createdAt := f.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now.AddDate(0, 0, -30)
+		}
+
+		linkID, err := ulid.Make(createdAt, entropy)
+		if err != nil {
+			return fmt.Errorf("seeding link %q: %w", f.ShortCode, err)
+		}
+
+		link := &model.Link{
+			ID:          linkID,
+			ShortCode:   f.ShortCode,
+			OriginalURL: f.URL,
+			CreatedAt:   createdAt.UTC(),
+			ClickCount:  int64(f.Clicks),
+			Version:     1,
+		}
+		if err := links.Create(ctx, link); err != nil {
+			return fmt.Errorf("seeding link %q: %w", f.ShortCode, err)
+		}
+
+		userAgents := f.UserAgents
+		if len(userAgents) == 0 {
+			userAgents = defaultUserAgents
+		}
+
+		window := now.Sub(createdAt)
+		for i := 0; i < f.Clicks; i++ {
+			clickedAt := createdAt
+			if window > 0 {
+				clickedAt = createdAt.Add(time.Duration(rng.Int64N(int64(window))))
+			}
+
+			eventID, err := ulid.Make(clickedAt, entropy)
+			if err != nil {
+				return fmt.Errorf("seeding clicks for %q: %w", f.ShortCode, err)
+			}
+
+			event := &model.ClickEvent{
+				ID:        eventID,
+				LinkID:    link.ID,
+				ClickedAt: clickedAt.UTC(),
+				Referrer:  pick(rng, f.Referrers),
+				UserAgent: pick(rng, userAgents),
+				IPAddress: fmt.Sprintf("203.0.113.%d", rng.IntN(254)+1), // TEST-NET-3
+			}
+			if err := clicks.Record(ctx, event); err != nil {
+				return fmt.Errorf("seeding clicks for %q: %w", f.ShortCode, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// pick returns a random element of values, or "" if it is empty.
+func pick(rng *rand.Rand, values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[rng.IntN(len(values))]
+}
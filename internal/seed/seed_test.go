@@ -0,0 +1,100 @@
+package seed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/repository"
+)
+
+const testFixtures = `
+links:
+  - short_code: docs
+    url: https://example.com/docs
+    created_at: 2025-01-01T00:00:00Z
+    clicks: 25
+    referrers:
+      - https://google.com
+  - short_code: blog
+    url: https://example.com/blog
+`
+
+func writeFixtures(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAndApply(t *testing.T) {
+	fixtures, err := LoadFile(writeFixtures(t, testFixtures))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	links := repository.NewMemoryLinkRepository()
+	clicks := repository.NewMemoryClickRepository()
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	if err := Apply(ctx, fixtures, links, clicks, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	docs, err := links.GetByShortCode(ctx, "docs")
+	if err != nil {
+		t.Fatalf("expected docs link, got %v", err)
+	}
+	if docs.ClickCount != 25 {
+		t.Errorf("expected click count 25, got %d", docs.ClickCount)
+	}
+
+	events, err := clicks.GetByLinkID(ctx, docs.ID, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 25 {
+		t.Fatalf("expected 25 click events, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.ClickedAt.Before(docs.CreatedAt) || e.ClickedAt.After(now) {
+			t.Errorf("click at %s outside [%s, %s]", e.ClickedAt, docs.CreatedAt, now)
+		}
+		if e.Referrer != "https://google.com" {
+			t.Errorf("expected referrer from fixture, got %q", e.Referrer)
+		}
+	}
+
+	blog, err := links.GetByShortCode(ctx, "blog")
+	if err != nil {
+		t.Fatalf("expected blog link, got %v", err)
+	}
+	if !blog.CreatedAt.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("expected default created_at 30 days ago, got %s", blog.CreatedAt)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing url", "links:\n  - short_code: x\n"},
+		{"negative clicks", "links:\n  - short_code: x\n    url: https://example.com\n    clicks: -1\n"},
+		{"malformed yaml", "links: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadFile(writeFixtures(t, tt.content)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// Errors for custom codes that can't be claimed.
+var (
+	ErrInvalidAlias  = apierror.New(apierror.CodeInvalidAlias, shortcode.ErrInvalidAlias.Error())
+	ErrReservedAlias = apierror.New(apierror.CodeReservedAlias, "alias is reserved")
+	ErrAliasTaken    = apierror.New(apierror.CodeAliasTaken, "alias is already in use")
+)
+
+// reservedAliases can never be used as short codes because they collide
+// with API routes or well-known paths served at the root. Compared
+// case-insensitively.
+var reservedAliases = map[string]bool{
+	"api":         true,
+	"health":      true,
+	"debug":       true,
+	"admin":       true,
+	"static":      true,
+	"assets":      true,
+	"favicon.ico": true,
+	"robots.txt":  true,
+	"sitemap.xml": true,
+	"well-known":  true,
+}
+
+// IsReservedAlias reports whether alias is reserved for the service itself.
+// Paths reserved at runtime with ReservePaths are checked by the service,
+// not here.
+func IsReservedAlias(alias string) bool {
+	return reservedAliases[strings.ToLower(alias)]
+}
+
+// ReservePaths reserves top-level path segments served by routes, so no
+// alias or generated code can shadow them. The HTTP handler calls it with
+// the routes it registers.
+func (s *LinkService) ReservePaths(segments ...string) {
+	s.reservedMu.Lock()
+	defer s.reservedMu.Unlock()
+
+	if s.reservedPaths == nil {
+		s.reservedPaths = make(map[string]bool, len(segments))
+	}
+	for _, segment := range segments {
+		s.reservedPaths[strings.ToLower(segment)] = true
+	}
+}
+
+// isReserved reports whether code is a reserved alias or route segment.
+func (s *LinkService) isReserved(code string) bool {
+	if IsReservedAlias(code) {
+		return true
+	}
+	s.reservedMu.RLock()
+	defer s.reservedMu.RUnlock()
+	return s.reservedPaths[strings.ToLower(code)]
+}
+
+// CheckBaseURL reports an error when the base URL's path starts with a
+// reserved segment, e.g. https://go.example.com/api, which would send
+// every short URL to a route instead of a redirect. Any other path prefix
+// is logged as a warning: codes are routed at the root, so the prefix
+// must be stripped by a proxy in front of the service.
+func (s *LinkService) CheckBaseURL() error {
+	parsed, err := url.Parse(s.baseURL)
+	if err != nil {
+		return fmt.Errorf("parsing base URL: %w", err)
+	}
+
+	path := strings.Trim(parsed.Path, "/")
+	if path == "" {
+		return nil
+	}
+	first, _, _ := strings.Cut(path, "/")
+	if s.isReserved(first) {
+		return fmt.Errorf("base URL %s: path /%s is a reserved route and would shadow short codes", s.baseURL, first)
+	}
+	s.logger.Warn("base URL has a path prefix; short codes are served at the root, so a proxy must strip it", "base_url", s.baseURL)
+	return nil
+}
+
+// CheckAlias reports whether alias could be used as a custom short code:
+// it must be well-formed, not reserved, not inside an allocated namespace
+// prefix and not already taken. When it isn't available, Reason holds the
+// apierror code explaining why.
+func (s *LinkService) CheckAlias(ctx context.Context, alias string) (*model.AliasAvailability, error) {
+	result := &model.AliasAvailability{Alias: alias}
+
+	if shortcode.ValidateAlias(alias) != nil {
+		result.Reason = apierror.CodeInvalidAlias
+		return result, nil
+	}
+	prefixed, err := s.prefixAllocated(ctx, alias)
+	if err != nil {
+		return nil, fmt.Errorf("checking alias: %w", err)
+	}
+
+	switch {
+	case s.isReserved(alias) || prefixed:
+		result.Reason = apierror.CodeReservedAlias
+	default:
+		_, err := s.findLink(ctx, alias)
+		switch {
+		case err == nil:
+			result.Reason = apierror.CodeAliasTaken
+		case errors.Is(err, ErrLinkNotFound):
+			result.Available = true
+		default:
+			return nil, fmt.Errorf("checking alias: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// customCode returns the code requested by a create, or "" to generate
+// one. It applies the same rules as CheckAlias, except that whether the
+// code is taken is left to the create itself. Under a prefix, the alias
+// follows it: prefix "eng" and alias "launch" make "eng-launch".
+func (s *LinkService) customCode(ctx context.Context, req model.CreateLinkRequest) (string, error) {
+	if req.CustomCode == "" {
+		return "", nil
+	}
+
+	code := req.CustomCode
+	if req.Prefix != "" {
+		code = req.Prefix + shortcode.PrefixSeparator + code
+	}
+	if shortcode.ValidateAlias(code) != nil {
+		return "", ErrInvalidAlias
+	}
+	if s.caseInsensitive {
+		code = shortcode.Canonicalize(code)
+	}
+	if s.isReserved(code) {
+		return "", ErrReservedAlias
+	}
+	if req.Prefix == "" {
+		prefixed, err := s.prefixAllocated(ctx, code)
+		if err != nil {
+			return "", fmt.Errorf("checking alias: %w", err)
+		}
+		if prefixed {
+			return "", ErrReservedAlias
+		}
+	}
+	return code, nil
+}
+
+// Suggestion limits for SuggestAliases.
+const (
+	DefaultSuggestions = 3
+	MaxSuggestions     = 10
+)
+
+// SuggestAliases proposes up to count readable, currently available aliases
+// for a destination, built from its domain and path words (for example
+// https://www.acme.com/pricing yields "acme-pricing"). Numbered variants
+// fill in when the plain candidates are taken.
+func (s *LinkService) SuggestAliases(ctx context.Context, destination string, count int) ([]string, error) {
+	if err := s.validateURL(destination); err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = DefaultSuggestions
+	}
+	if count > MaxSuggestions {
+		count = MaxSuggestions
+	}
+
+	parsed, _ := url.Parse(destination) // validated above
+	candidates := aliasCandidates(parsed)
+
+	suggestions := make([]string, 0, count)
+	seen := make(map[string]bool)
+	try := func(alias string) error {
+		if seen[alias] {
+			return nil
+		}
+		seen[alias] = true
+
+		result, err := s.CheckAlias(ctx, alias)
+		if err != nil {
+			return err
+		}
+		if result.Available {
+			suggestions = append(suggestions, alias)
+		}
+		return nil
+	}
+
+	for _, candidate := range candidates {
+		if len(suggestions) == count {
+			return suggestions, nil
+		}
+		if err := try(candidate); err != nil {
+			return nil, err
+		}
+	}
+
+	// Plain candidates exhausted; fall back to numbered variants of the best one
+	if len(candidates) > 0 {
+		base := candidates[0]
+		for n := 2; n < 100 && len(suggestions) < count; n++ {
+			if err := try(slugify(fmt.Sprintf("%s-%d", base, n))); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return suggestions, nil
+}
+
+// aliasCandidates lists alias ideas for a destination, best first.
+func aliasCandidates(u *url.URL) []string {
+	brand := brandName(u.Hostname())
+
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(u.Path), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		// Skip file extensions and noise like "index"
+		if word == "html" || word == "htm" || word == "php" || word == "index" {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	var candidates []string
+	add := func(parts ...string) {
+		if slug := slugify(strings.Join(parts, "-")); shortcode.ValidateAlias(slug) == nil {
+			candidates = append(candidates, slug)
+		}
+	}
+
+	if n := len(words); n > 0 {
+		add(brand, words[n-1])
+		if n > 1 {
+			add(brand, words[n-2], words[n-1])
+			add(words[n-2], words[n-1])
+		}
+		add(words[n-1])
+	}
+	add(brand)
+
+	return candidates
+}
+
+// secondLevelSuffixes are registry labels that sit between a brand and
+// a country TLD, as in acme.co.uk or acme.com.au.
+var secondLevelSuffixes = map[string]bool{
+	"co": true, "com": true, "net": true, "org": true, "gov": true, "ac": true, "edu": true,
+}
+
+// brandName picks the most recognizable label of a host: "acme" for
+// www.acme.com, shop.acme.co.uk and acme.io. IP addresses have no brand.
+func brandName(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) == 1 {
+		return labels[0]
+	}
+
+	labels = labels[:len(labels)-1] // TLD
+	if n := len(labels); n > 1 && secondLevelSuffixes[labels[n-1]] {
+		labels = labels[:n-1]
+	}
+	return labels[len(labels)-1]
+}
+
+// slugify lowercases s, turns runs of other characters into single
+// hyphens and trims the result to the maximum alias length.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > shortcode.MaxAliasLength {
+		slug = slug[:shortcode.MaxAliasLength]
+	}
+	return strings.TrimRight(slug, "-")
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_CheckAlias(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	config := DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	if err := linkRepo.Create(ctx, &model.Link{ID: "taken", ShortCode: "taken", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("failed to seed link: %v", err)
+	}
+	if _, err := svc.CreatePrefix(ctx, model.CreatePrefixRequest{Prefix: "eng"}); err != nil {
+		t.Fatalf("failed to allocate prefix: %v", err)
+	}
+
+	tests := []struct {
+		alias      string
+		wantReason string
+	}{
+		{"acme-pricing", ""},
+		{"a", apierror.CodeInvalidAlias},
+		{"has space", apierror.CodeInvalidAlias},
+		{"API", apierror.CodeReservedAlias},
+		{"health", apierror.CodeReservedAlias},
+		{"taken", apierror.CodeAliasTaken},
+		{"eng-roadmap", apierror.CodeReservedAlias},
+		{"engine-docs", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			got, err := svc.CheckAlias(ctx, tt.alias)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Available != (tt.wantReason == "") {
+				t.Errorf("expected available=%v, got %v", tt.wantReason == "", got.Available)
+			}
+			if got.Reason != tt.wantReason {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, got.Reason)
+			}
+		})
+	}
+}
+
+func TestLinkService_CreateLink_CustomCode(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	config := DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	config.NotFoundCacheTTL = time.Minute
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	if _, err := svc.CreatePrefix(ctx, model.CreatePrefixRequest{Prefix: "eng"}); err != nil {
+		t.Fatalf("failed to allocate prefix: %v", err)
+	}
+
+	// A miss cached before the alias is claimed must not outlive the create
+	if _, err := svc.GetStats(ctx, "launch2024"); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("expected ErrLinkNotFound, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		req      model.CreateLinkRequest
+		wantCode string
+		wantErr  error
+	}{
+		{"alias", model.CreateLinkRequest{URL: "https://example.com", CustomCode: "launch2024"}, "launch2024", nil},
+		{"taken", model.CreateLinkRequest{URL: "https://example.com/other", CustomCode: "launch2024"}, "", ErrAliasTaken},
+		{"invalid", model.CreateLinkRequest{URL: "https://example.com", CustomCode: "-x"}, "", ErrInvalidAlias},
+		{"reserved", model.CreateLinkRequest{URL: "https://example.com", CustomCode: "health"}, "", ErrReservedAlias},
+		{"inside a prefix", model.CreateLinkRequest{URL: "https://example.com", CustomCode: "eng-roadmap"}, "", ErrReservedAlias},
+		{"under a prefix", model.CreateLinkRequest{URL: "https://example.com", Prefix: "eng", CustomCode: "roadmap"}, "eng-roadmap", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.CreateLink(ctx, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && resp.ShortCode != tt.wantCode {
+				t.Errorf("expected code %q, got %q", tt.wantCode, resp.ShortCode)
+			}
+		})
+	}
+
+	if _, err := svc.GetStats(ctx, "launch2024"); err != nil {
+		t.Errorf("expected the claimed alias to resolve, got %v", err)
+	}
+}
+
+func TestLinkService_SuggestAliases(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	got, err := svc.SuggestAliases(ctx, "https://www.acme.com/products/pricing.html", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"acme-pricing", "acme-products-pricing", "products-pricing"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Taken candidates are skipped and numbered variants fill the gap
+	for _, alias := range []string{"acme-pricing", "acme-products-pricing", "products-pricing", "pricing", "acme"} {
+		if err := linkRepo.Create(ctx, &model.Link{ID: alias, ShortCode: alias}); err != nil {
+			t.Fatalf("failed to seed link: %v", err)
+		}
+	}
+	got, err = svc.SuggestAliases(ctx, "https://www.acme.com/products/pricing.html", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{"acme-pricing-2", "acme-pricing-3"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := svc.SuggestAliases(ctx, "not-a-url", 3); err != ErrInvalidURL {
+		t.Errorf("expected ErrInvalidURL, got %v", err)
+	}
+}
+
+func TestLinkService_ReservePaths(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	result, err := svc.CheckAlias(ctx, "metrics")
+	if err != nil || !result.Available {
+		t.Fatalf("expected metrics to be available before it's reserved, got %+v (%v)", result, err)
+	}
+
+	svc.ReservePaths("metrics")
+	result, err = svc.CheckAlias(ctx, "Metrics")
+	if err != nil || result.Reason != apierror.CodeReservedAlias {
+		t.Errorf("expected a reserved route segment to be refused, got %+v (%v)", result, err)
+	}
+}
+
+func TestLinkService_CheckBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		wantErr bool
+	}{
+		{"https://snip.io", false},
+		{"https://snip.io/", false},
+		{"https://example.com/go", false}, // needs a stripping proxy, but works
+		{"https://example.com/api", true},
+		{"https://example.com/Health/links", true},
+		{"https://example.com/metrics", true},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.BaseURL = tt.baseURL
+		svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+		svc.ReservePaths("metrics")
+
+		if err := svc.CheckBaseURL(); (err != nil) != tt.wantErr {
+			t.Errorf("CheckBaseURL(%s) error = %v, wantErr %v", tt.baseURL, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBrandName(t *testing.T) {
+	tests := map[string]string{
+		"www.acme.com":    "acme",
+		"shop.acme.co.uk": "acme",
+		"acme.io":         "acme",
+		"www.ibm.com":     "ibm",
+		"localhost":       "localhost",
+		"10.0.0.1":        "",
+	}
+
+	for host, want := range tests {
+		if got := brandName(host); got != want {
+			t.Errorf("brandName(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
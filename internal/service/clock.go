@@ -0,0 +1,24 @@
+package service
+
+import "time"
+
+// Clock tells the service the time. Everything time-dependent in the
+// service, from creation timestamps and click days to delete grace
+// periods and retention cutoffs, reads it, so tests can pin the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by time.Now.
+type SystemClock struct{}
+
+// Now returns the current time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns the clock's time in UTC, the zone the service stores and
+// buckets times in.
+func (s *LinkService) now() time.Time {
+	return s.clock.Now().UTC()
+}
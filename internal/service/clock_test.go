@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLinkService_Clock_DeleteGrace(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	linkRepo := repository.NewMemoryLinkRepository()
+	config := DefaultConfig()
+	config.DeleteGracePeriod = time.Hour
+	config.Clock = clock
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	if !link.CreatedAt.Equal(clock.Now()) {
+		t.Errorf("expected the link to be created at %v, got %v", clock.Now(), link.CreatedAt)
+	}
+
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 0); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+	clock.Advance(time.Hour)
+	if removed, err := svc.PurgeDeletedLinks(ctx); err != nil || removed != 0 {
+		t.Fatalf("expected nothing purged at the end of the grace period, got %d, %v", removed, err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := svc.RestoreLink(ctx, resp.ShortCode); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected restore after the grace period to fail, got %v", err)
+	}
+	if removed, err := svc.PurgeDeletedLinks(ctx); err != nil || removed != 1 {
+		t.Errorf("expected 1 link purged, got %d, %v", removed, err)
+	}
+}
+
+// Clicks land on the UTC day, whatever zone the clock reports in. A
+// Sunday evening in New York, just after the switch to daylight saving
+// time, is already Monday in UTC.
+func TestLinkService_Clock_ClickDays(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	clock := &fakeClock{now: time.Date(2024, 3, 10, 20, 30, 0, 0, newYork)}
+	config := DefaultConfig()
+	config.Rollups = repository.NewMemoryStatsRollupRepository()
+	config.Clock = clock
+	linkRepo := repository.NewMemoryLinkRepository()
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	svc.recordClick(ctx, link, ClickMetadata{})
+
+	series, err := svc.ClickTimeseries(ctx, resp.ShortCode, "2024-03-10", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if series.To != "2024-03-11" {
+		t.Fatalf("expected the range to end on 2024-03-11, got %s", series.To)
+	}
+	if len(series.Days) != 2 || series.Days[0].Clicks != 0 || series.Days[1].Clicks != 1 {
+		t.Errorf("expected the click on 2024-03-11 only, got %+v", series.Days)
+	}
+}
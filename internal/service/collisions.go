@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// DefaultCollisionWarnRate is the collisions-per-create average that
+// triggers a warning. With a healthy keyspace collisions are vanishingly
+// rare, so one every ten creates means the space is filling up.
+const DefaultCollisionWarnRate = 0.1
+
+// MaxCodeLength caps automatic code length growth.
+const MaxCodeLength = 16
+
+// collisionWindow is the number of creates averaged per rate check.
+const collisionWindow = 100
+
+// collisionTracker averages code collisions over fixed windows of creates.
+type collisionTracker struct {
+	mu         sync.Mutex
+	warnRate   float64
+	growRate   float64 // 0 disables growth
+	creates    int
+	collisions int
+}
+
+// observe records one create and reports the window's collision rate once
+// the window is full, resetting it.
+func (t *collisionTracker) observe(collisions int) (rate float64, full bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.creates++
+	t.collisions += collisions
+	if t.creates < collisionWindow {
+		return 0, false
+	}
+
+	rate = float64(t.collisions) / float64(t.creates)
+	t.creates, t.collisions = 0, 0
+	return rate, true
+}
+
+// observeCollisions feeds a create's collision count into the tracker. When
+// a window's rate suggests the code length is too short it warns and, if
+// configured, grows the length.
+func (s *LinkService) observeCollisions(collisions int) {
+	rate, full := s.collisions.observe(collisions)
+	if !full {
+		return
+	}
+
+	gen := s.codeGen.Load()
+	if s.collisions.growRate > 0 && rate > s.collisions.growRate {
+		s.growCodeLength(gen, rate)
+		return
+	}
+
+	if rate > s.collisions.warnRate {
+		s.logger.Warn("short code collision rate is high; consider raising the code length",
+			"collisions_per_create", rate,
+			"threshold", s.collisions.warnRate,
+			"code_length", gen.Length(),
+			"possible_codes", gen.PossibleCombinations(),
+		)
+	}
+}
+
+// growCodeLength replaces the generator with one a character longer and
+// persists the new length. Concurrent callers that observed the same
+// generator grow it only once.
+func (s *LinkService) growCodeLength(current *shortcode.Generator, rate float64) {
+	length := current.Length() + 1
+	if length > MaxCodeLength {
+		s.logger.Warn("short code collision rate is high but code length is at its maximum",
+			"collisions_per_create", rate,
+			"code_length", current.Length(),
+		)
+		return
+	}
+
+	if !s.codeGen.CompareAndSwap(current, current.WithLength(length)) {
+		return
+	}
+	s.logger.Warn("grew short code length after high collision rate",
+		"collisions_per_create", rate,
+		"threshold", s.collisions.growRate,
+		"code_length", length,
+	)
+
+	if s.settings == nil {
+		return
+	}
+	if err := s.settings.SetCodeLength(context.Background(), length); err != nil {
+		s.logger.Error("failed to persist code length; it will reset on restart", "code_length", length, "error", err)
+	}
+}
+
+// initialCodeLength picks the configured length or, when longer, the
+// length persisted by an earlier growth.
+func (s *LinkService) initialCodeLength(configured int) int {
+	if configured <= 0 {
+		configured = shortcode.DefaultLength
+	}
+	if s.settings == nil {
+		return configured
+	}
+
+	stored, err := s.settings.GetCodeLength(context.Background())
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			s.logger.Error("failed to load stored code length", "error", err)
+		}
+		return configured
+	}
+	if stored > configured {
+		return stored
+	}
+	return configured
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestCollisionTracker(t *testing.T) {
+	tracker := collisionTracker{warnRate: DefaultCollisionWarnRate}
+
+	for i := 0; i < collisionWindow-1; i++ {
+		if _, full := tracker.observe(1); full {
+			t.Fatalf("window reported full after %d creates", i+1)
+		}
+	}
+
+	rate, full := tracker.observe(1)
+	if !full {
+		t.Fatal("expected window to be full")
+	}
+	if rate != 1 {
+		t.Errorf("expected rate 1, got %v", rate)
+	}
+
+	// The window resets after each report
+	for i := 0; i < collisionWindow-1; i++ {
+		tracker.observe(0)
+	}
+	if rate, _ := tracker.observe(0); rate != 0 {
+		t.Errorf("expected rate 0 after reset, got %v", rate)
+	}
+}
+
+func TestObserveCollisions_GrowsCodeLength(t *testing.T) {
+	settings := repository.NewMemorySettingsRepository()
+	config := LinkServiceConfig{
+		BaseURL:            "http://localhost:8080",
+		CodeLength:         7,
+		CodeLengthGrowRate: 0.5,
+		Settings:           settings,
+	}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+
+	for i := 0; i < collisionWindow; i++ {
+		svc.observeCollisions(1)
+	}
+
+	if got := svc.codeGen.Load().Length(); got != 8 {
+		t.Fatalf("expected code length 8, got %d", got)
+	}
+	stored, err := settings.GetCodeLength(context.Background())
+	if err != nil || stored != 8 {
+		t.Fatalf("expected stored length 8, got %d (%v)", stored, err)
+	}
+
+	// A restarted service picks up the grown length
+	restarted := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	if got := restarted.codeGen.Load().Length(); got != 8 {
+		t.Errorf("expected restarted code length 8, got %d", got)
+	}
+}
+
+func TestObserveCollisions_NoGrowthBelowRate(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), LinkServiceConfig{
+		CodeLength:         7,
+		CodeLengthGrowRate: 0.5,
+	})
+
+	for i := 0; i < collisionWindow; i++ {
+		svc.observeCollisions(i % 4 / 3) // one collision every four creates
+	}
+
+	if got := svc.codeGen.Load().Length(); got != 7 {
+		t.Errorf("expected code length to stay 7, got %d", got)
+	}
+}
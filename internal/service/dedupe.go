@@ -0,0 +1,52 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// clickDeduper remembers recent clicks by visitor and link, so repeats
+// within a window, such as double-clicks and mail client prefetches, can
+// be left out of counts. State is per process: replicas and Lambda
+// execution environments each dedupe on their own.
+type clickDeduper struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // last click per key
+	lastPrune time.Time
+}
+
+func newClickDeduper(window time.Duration) *clickDeduper {
+	if window <= 0 {
+		return nil
+	}
+	return &clickDeduper{window: window, seen: make(map[string]time.Time)}
+}
+
+// duplicate reports whether the visitor clicked the link within the
+// window before now, and records this click either way. A nil deduper
+// never reports duplicates.
+func (d *clickDeduper) duplicate(linkID string, metadata ClickMetadata, now time.Time) bool {
+	if d == nil || metadata.IPAddress == "" {
+		return false
+	}
+	key := linkID + "\x00" + metadata.IPAddress + "\x00" + metadata.UserAgent
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Expired entries are dropped once per window to bound memory
+	if now.Sub(d.lastPrune) >= d.window {
+		for k, at := range d.seen {
+			if now.Sub(at) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	last, ok := d.seen[key]
+	d.seen[key] = now
+	return ok && now.Sub(last) < d.window
+}
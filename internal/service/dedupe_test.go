@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_ClickDedupe(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	config := DefaultConfig()
+	config.ClickDedupeWindow = time.Minute
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	visitor := ClickMetadata{IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0"}
+	other := ClickMetadata{IPAddress: "203.0.113.8", UserAgent: "Mozilla/5.0"}
+	for _, metadata := range []ClickMetadata{visitor, visitor, other} {
+		if _, err := svc.Redirect(ctx, resp.ShortCode, metadata); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	if link.ClickCount != 2 {
+		t.Errorf("expected click count 2, got %d", link.ClickCount)
+	}
+
+	clicks, _ := clickRepo.GetByLinkID(ctx, link.ID, 0)
+	deduped := 0
+	for _, click := range clicks {
+		if click.Deduped {
+			deduped++
+		}
+	}
+	if len(clicks) != 3 || deduped != 1 {
+		t.Errorf("expected 3 stored clicks with 1 deduped, got %d with %d", len(clicks), deduped)
+	}
+}
+
+func TestClickDeduper_WindowExpires(t *testing.T) {
+	d := newClickDeduper(time.Minute)
+	visitor := ClickMetadata{IPAddress: "203.0.113.7"}
+	start := time.Now()
+
+	if d.duplicate("link-1", visitor, start) {
+		t.Error("first click reported as duplicate")
+	}
+	if !d.duplicate("link-1", visitor, start.Add(30*time.Second)) {
+		t.Error("repeat within the window not reported")
+	}
+	if d.duplicate("link-2", visitor, start.Add(30*time.Second)) {
+		t.Error("click on another link reported as duplicate")
+	}
+	if d.duplicate("link-1", visitor, start.Add(2*time.Minute)) {
+		t.Error("click after the window reported as duplicate")
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_HonorDoNotTrack(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	rollups := repository.NewMemoryStatsRollupRepository()
+	config := DefaultConfig()
+	config.HonorDoNotTrack = true
+	config.Rollups = rollups
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	svc.Redirect(ctx, resp.ShortCode, ClickMetadata{IPAddress: "203.0.113.7", DoNotTrack: true})
+	svc.Redirect(ctx, resp.ShortCode, ClickMetadata{IPAddress: "203.0.113.8"})
+
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	if link.ClickCount != 2 {
+		t.Errorf("expected both clicks counted, got %d", link.ClickCount)
+	}
+	clicks, _ := clickRepo.GetByLinkID(ctx, link.ID, 0)
+	anonymous := 0
+	for _, click := range clicks {
+		if click.IPAddress == "" && click.UserAgent == "" && click.Referrer == "" {
+			anonymous++
+		}
+	}
+	if len(clicks) != 2 || anonymous != 1 {
+		t.Errorf("expected the opted-out click stored anonymously, got %+v", clicks)
+	}
+	today := time.Now().UTC().Format(dayLayout)
+	days, _ := rollups.GetRange(ctx, link.ID, today, today)
+	if len(days) != 1 || days[0].Clicks != 2 {
+		t.Errorf("expected both clicks in today's rollup, got %+v", days)
+	}
+
+	// Rebuilding from stored events keeps the opted-out click
+	recount, err := svc.RecountClicks(ctx, resp.ShortCode)
+	if err != nil || recount.ClickCount != 2 {
+		t.Errorf("expected a recount of 2, got %+v (%v)", recount, err)
+	}
+	if _, err := svc.RebuildRollups(ctx, time.Now()); err != nil {
+		t.Fatalf("failed to rebuild rollups: %v", err)
+	}
+	days, _ = rollups.GetRange(ctx, link.ID, today, today)
+	if len(days) != 1 || days[0].Clicks != 2 {
+		t.Errorf("expected both clicks in the rebuilt rollup, got %+v", days)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// findDuplicate returns the oldest live link to originalURL that behaves
+// as the link req asks for would: same wildcard, interstitial and referrer
+// settings, and in req's prefix namespace when it names one. Notes, titles
+// and other descriptive fields may differ; the existing link keeps its
+// own. Only the caller's own links count. It returns nil when there's no
+// such link.
+func (s *LinkService) findDuplicate(ctx context.Context, req model.CreateLinkRequest, originalURL string, allowedReferrers []string) (*model.Link, error) {
+	links, err := s.linkRepo.GetByDestination(ctx, originalURL)
+	if err != nil {
+		return nil, fmt.Errorf("finding links to destination: %w", err)
+	}
+
+	var found *model.Link
+	for _, link := range links {
+		if link.DeletedAt != nil || link.Disabled || link.OwnerID != ownerOf(ctx) {
+			continue
+		}
+		if link.Wildcard != req.Wildcard || link.Interstitial != req.Interstitial {
+			continue
+		}
+		if !slices.Equal(link.AllowedReferrers, allowedReferrers) {
+			continue
+		}
+		if req.Prefix != "" && !strings.HasPrefix(link.ShortCode, req.Prefix+shortcode.PrefixSeparator) {
+			continue
+		}
+		if found == nil || link.CreatedAt.Before(found.CreatedAt) {
+			found = link
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_CreateLink_Dedupe(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	first, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/page"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	again, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/page", Dedupe: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !again.Existing || again.ShortCode != first.ShortCode {
+		t.Errorf("expected the existing link %s, got %+v", first.ShortCode, again)
+	}
+
+	for _, req := range []model.CreateLinkRequest{
+		{URL: "https://example.com/page"},
+		{URL: "https://example.com/page", Dedupe: true, Wildcard: true},
+		{URL: "https://example.com/page", Dedupe: true, CustomCode: "page"},
+		{URL: "https://example.com/other", Dedupe: true},
+	} {
+		resp, err := svc.CreateLink(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error for %+v: %v", req, err)
+		}
+		if resp.Existing || resp.ShortCode == first.ShortCode {
+			t.Errorf("expected a new link for %+v, got %+v", req, resp)
+		}
+	}
+
+	if err := svc.DeleteLink(ctx, first.ShortCode, 0); err != nil {
+		t.Fatalf("failed to delete link: %v", err)
+	}
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/page", Dedupe: true, Interstitial: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Existing {
+		t.Errorf("expected no link to reuse after deleting the original, got %s", resp.ShortCode)
+	}
+}
+
+func TestLinkService_DedupeDestinations(t *testing.T) {
+	config := DefaultConfig()
+	config.DedupeDestinations = true
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	first, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/page"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if first.Existing {
+		t.Error("expected the first create to make a link")
+	}
+
+	newURL := "https://example.com/moved"
+	if _, err := svc.UpdateLink(ctx, first.ShortCode, LinkPatch{URL: &newURL}, 0); err != nil {
+		t.Fatalf("failed to update link: %v", err)
+	}
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: newURL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Existing || resp.ShortCode != first.ShortCode {
+		t.Errorf("expected the updated link %s to be reused, got %+v", first.ShortCode, resp)
+	}
+	resp, err = svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/page"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Existing {
+		t.Errorf("expected the old destination to be unindexed, got %s", resp.ShortCode)
+	}
+}
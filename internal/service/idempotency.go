@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Idempotency errors.
+var (
+	ErrIdempotencyKeyReused = apierror.New(apierror.CodeIdempotencyReused, "the idempotency key was already used for a different request")
+	ErrIdempotencyKeyInUse  = apierror.New(apierror.CodeIdempotencyInUse, "a request with this idempotency key is still in progress")
+)
+
+// Idempotency key limits.
+const (
+	// IdempotencyKeyTTL is how long a key returns the link its first
+	// request created.
+	IdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyReservation is how long a key stays held by a create
+	// that hasn't finished, so one interrupted by a crash doesn't block
+	// retries for the whole TTL.
+	idempotencyReservation = time.Minute
+
+	MaxIdempotencyKeyLength = 255
+)
+
+// createIdempotent creates a link at most once per idempotency key. A
+// repeat of the same request within IdempotencyKeyTTL returns the first
+// response; the key used with a different request fails with
+// ErrIdempotencyKeyReused, and a repeat while the first is still running
+// with ErrIdempotencyKeyInUse. Failed creates aren't remembered, so they
+// can be retried with the same key.
+func (s *LinkService) createIdempotent(ctx context.Context, req model.CreateLinkRequest) (*model.CreateLinkResponse, error) {
+	key := req.IdempotencyKey
+	if len(key) > MaxIdempotencyKeyLength {
+		return nil, validationError(map[string]string{"idempotency_key": apierror.CodeTooLong})
+	}
+	req.IdempotencyKey = ""
+	if owner := ownerOf(ctx); owner != "" {
+		// Each owner has their own keys, so one can't replay another's create
+		key = owner + "/" + key
+	}
+
+	record := &model.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: requestFingerprint(req),
+		ExpiresAt:   s.now().Add(idempotencyReservation),
+	}
+	existing, err := s.reserveIdempotencyKey(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		switch {
+		case existing.Fingerprint != record.Fingerprint:
+			return nil, ErrIdempotencyKeyReused
+		case existing.Response == nil:
+			return nil, ErrIdempotencyKeyInUse
+		}
+		return existing.Response, nil
+	}
+
+	resp, err := s.CreateLink(ctx, req)
+	if err != nil {
+		if err := s.idempotency.Delete(ctx, key); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			s.logger.WarnContext(ctx, "failed to release idempotency key", "error", err)
+		}
+		return nil, err
+	}
+
+	record.Response = resp
+	record.ExpiresAt = s.now().Add(IdempotencyKeyTTL)
+	if err := s.idempotency.Update(ctx, record); err != nil {
+		// The link exists; retries are refused until the reservation lapses
+		s.logger.WarnContext(ctx, "failed to store idempotency record", "short_code", resp.ShortCode, "error", err)
+	}
+	return resp, nil
+}
+
+// reserveIdempotencyKey holds record.Key for a new create. If the key is
+// already held by an unexpired record, that record is returned instead.
+func (s *LinkService) reserveIdempotencyKey(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	for range 2 {
+		err := s.idempotency.Create(ctx, record)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, fmt.Errorf("reserving idempotency key: %w", err)
+		}
+
+		existing, err := s.idempotency.Get(ctx, record.Key)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue // released in the meantime
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetching idempotency record: %w", err)
+		}
+		if s.now().Before(existing.ExpiresAt) {
+			return existing, nil
+		}
+		// Expired but not yet purged; free it for this request
+		if err := s.idempotency.Delete(ctx, record.Key); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("releasing expired idempotency key: %w", err)
+		}
+	}
+	// Lost a race for the key twice over
+	return nil, ErrIdempotencyKeyInUse
+}
+
+// requestFingerprint identifies a create request's content.
+func requestFingerprint(req model.CreateLinkRequest) string {
+	data, _ := json.Marshal(req) // plain struct; can't fail
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PurgeIdempotencyKeys removes expired idempotency records and returns how
+// many were removed.
+func (s *LinkService) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	if s.idempotency == nil {
+		return 0, ErrSweepUnsupported
+	}
+	removed, err := s.idempotency.DeleteExpired(ctx, s.now())
+	if err != nil {
+		return removed, fmt.Errorf("purging idempotency keys: %w", err)
+	}
+	return removed, nil
+}
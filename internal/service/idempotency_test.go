@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func newIdempotentService(clock Clock) (*LinkService, *repository.MemoryIdempotencyRepository) {
+	records := repository.NewMemoryIdempotencyRepository()
+	config := DefaultConfig()
+	config.Idempotency = records
+	config.Clock = clock
+	return NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config), records
+}
+
+func TestLinkService_CreateLink_Idempotent(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	svc, _ := newIdempotentService(clock)
+	ctx := context.Background()
+
+	req := model.CreateLinkRequest{URL: "https://example.com", IdempotencyKey: "retry-1"}
+	first, err := svc.CreateLink(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := svc.CreateLink(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error on retry: %v", err)
+	}
+	if again.ShortCode != first.ShortCode {
+		t.Errorf("expected the retry to return %s, got %s", first.ShortCode, again.ShortCode)
+	}
+
+	other := model.CreateLinkRequest{URL: "https://example.com/other", IdempotencyKey: "retry-1"}
+	if _, err := svc.CreateLink(ctx, other); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// Without a key, every request creates a link
+	plain, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plain.ShortCode == first.ShortCode {
+		t.Error("expected a request without a key to create a new link")
+	}
+
+	// Once the key expires it creates again
+	clock.Advance(IdempotencyKeyTTL + time.Second)
+	later, err := svc.CreateLink(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if later.ShortCode == first.ShortCode {
+		t.Error("expected an expired key to create a new link")
+	}
+}
+
+func TestLinkService_CreateLink_IdempotentFailure(t *testing.T) {
+	svc, records := newIdempotentService(nil)
+	ctx := context.Background()
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "not a url", IdempotencyKey: "k"}); err == nil {
+		t.Fatal("expected an invalid URL to fail")
+	}
+	if _, err := records.Get(ctx, "k"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected a failed create to release its key, got %v", err)
+	}
+
+	// A create still in progress holds its key
+	inProgress := &model.IdempotencyRecord{
+		Key:         "busy",
+		Fingerprint: requestFingerprint(model.CreateLinkRequest{URL: "https://example.com"}),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	records.Create(ctx, inProgress)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", IdempotencyKey: "busy"}); !errors.Is(err, ErrIdempotencyKeyInUse) {
+		t.Errorf("expected ErrIdempotencyKeyInUse, got %v", err)
+	}
+
+	long := strings.Repeat("k", MaxIdempotencyKeyLength+1)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", IdempotencyKey: long}); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected an overlong key to fail validation, got %v", err)
+	}
+}
+
+func TestLinkService_PurgeIdempotencyKeys(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	svc, records := newIdempotentService(clock)
+	ctx := context.Background()
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", IdempotencyKey: "old"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(IdempotencyKeyTTL + time.Second)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", IdempotencyKey: "new"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removed, err := svc.PurgeIdempotencyKeys(ctx)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 record purged, got %d, %v", removed, err)
+	}
+	if _, err := records.Get(ctx, "new"); err != nil {
+		t.Errorf("expected the live record to be kept, got %v", err)
+	}
+}
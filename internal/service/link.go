@@ -5,37 +5,234 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/metering"
+	"github.com/colby/snip/internal/metrics"
 	"github.com/colby/snip/internal/model"
 	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
 	"github.com/colby/snip/pkg/shortcode"
 )
 
-// Common errors returned by the service layer.
+// Common errors returned by the service layer. Each carries a stable
+// apierror code so transports can report it without a lookup table.
 var (
-	ErrInvalidURL     = errors.New("invalid URL")
-	ErrEmptyURL       = errors.New("URL cannot be empty")
-	ErrLinkNotFound   = errors.New("link not found")
-	ErrCodeGeneration = errors.New("failed to generate unique code after maximum retries")
+	ErrInvalidURL      = apierror.New(apierror.CodeInvalidURL, "invalid URL")
+	ErrEmptyURL        = apierror.New(apierror.CodeURLRequired, "URL cannot be empty")
+	ErrLinkNotFound    = apierror.New(apierror.CodeLinkNotFound, "link not found")
+	ErrLinkDisabled    = apierror.New(apierror.CodeLinkDisabled, "this link has been disabled")
+	ErrCodeGeneration  = apierror.New(apierror.CodeCodeGeneration, "failed to generate unique code after maximum retries")
+	ErrReadOnly        = apierror.New(apierror.CodeReadOnly, "service is in read-only mode")
+	ErrVersionConflict = apierror.New(apierror.CodeVersionConflict, "link version does not match")
+	ErrDeadURL         = apierror.New(apierror.CodeDeadURL, "destination could not be reached")
 )
 
+// errReservedCode marks a generated code that collides with a route.
+var errReservedCode = errors.New("generated code is reserved")
+
+// MaxNotesLength is the longest notes value, in characters, a link may have.
+const MaxNotesLength = 1000
+
+// MaxTitleLength is the longest title, in characters, a link may have.
+const MaxTitleLength = 200
+
 // LinkService handles the business logic for link operations.
 type LinkService struct {
 	linkRepo    repository.LinkRepository
 	clickRepo   repository.ClickRepository
-	codeGen     *shortcode.Generator
+	codeGen     atomic.Pointer[shortcode.Generator] // swapped when the code length grows
 	baseURL     string
 	maxRetries  int
+	events      *events.Bus
+	meter       metering.Meter
+	readOnly    atomic.Bool
+	logger      *slog.Logger
+	collisions  collisionTracker
+	settings    repository.SettingsRepository
+	prefixes    repository.PrefixRepository
+	templates   repository.TemplateRepository
+	aliases     repository.LinkAliasRepository
+	idempotency repository.IdempotencyRepository
+	resolver    DestinationResolver
+	verifier    DestinationVerifier
+
+	shortener atomic.Pointer[shortenerPolicy] // swapped on config reload
+
+	scanner URLScanner
+	scans   chan scanJob
+
+	phishingWarnScore  int
+	phishingBlockScore int
+
+	thumbnailCapturer ThumbnailCapturer
+	thumbnails        repository.ThumbnailRepository
+
+	rollups       repository.StatsRollupRepository
+	clickRecorder ClickRecorder
+	milestones    map[int64]bool
+	deleteGrace   time.Duration
+	dedupe        *clickDeduper
+
+	countPrefetches bool
+	prefetchAgents  []string
+
+	honorDoNotTrack bool
+
+	caseInsensitive bool
+
+	dedupeDestinations bool
+
+	reservedMu    sync.RWMutex
+	reservedPaths map[string]bool // route segments registered by transports
+
+	linkReads flightGroup[*model.Link]
+	misses    *missCache
+
+	sitemap sitemapCache
+
+	clock Clock
 }
 
 // LinkServiceConfig holds configuration for LinkService.
 type LinkServiceConfig struct {
-	BaseURL       string // e.g., "https://snip.io"
-	CodeLength    int    // length of generated short codes
-	MaxRetries    int    // max attempts to generate a unique code
+	BaseURL    string // e.g., "https://snip.io"
+	CodeLength int    // length of generated short codes
+	MaxRetries int    // max attempts to generate a unique code
+	ReadOnly   bool   // reject writes while still serving redirects and stats
+
+	// CollisionWarnRate is the average number of code collisions per create,
+	// measured over a window of creates, above which a warning is logged.
+	// Defaults to DefaultCollisionWarnRate.
+	CollisionWarnRate float64
+
+	// CodeLengthGrowRate, when positive, makes the service lengthen its
+	// codes by one character whenever the windowed collision rate exceeds
+	// it (up to MaxCodeLength). The new length is saved to Settings so it
+	// survives restarts; a stored length longer than CodeLength wins at
+	// startup.
+	CodeLengthGrowRate float64
+	Settings           repository.SettingsRepository // optional
+
+	// Prefixes holds the namespace prefixes codes can be created under.
+	// When nil, prefixes are unsupported.
+	Prefixes repository.PrefixRepository
+
+	// Templates holds saved link templates. When nil, templates are
+	// unsupported.
+	Templates repository.TemplateRepository
+
+	// Aliases holds secondary codes attached to existing links. When nil,
+	// links only resolve by their own code.
+	Aliases repository.LinkAliasRepository
+
+	// Idempotency remembers creates made with an idempotency key, so
+	// retries return the original link. When nil, keys are ignored.
+	Idempotency repository.IdempotencyRepository
+
+	// Resolver, when set, follows each new destination's redirect chain
+	// and stores the final URL instead.
+	Resolver DestinationResolver
+
+	// Verifier checks destinations of links created with verify set.
+	// Without one, the flag is ignored.
+	Verifier DestinationVerifier
+
+	// ShortenerDomains lists URL shortener domains whose links can't be
+	// shortened again; nil uses DefaultShortenerDomains. AllowShorteners
+	// turns the check off. When ShortenerResolver is set, such links are
+	// resolved to their real destination instead of being rejected.
+	ShortenerDomains  []string
+	AllowShorteners   bool
+	ShortenerResolver DestinationResolver
+
+	// Scanner, when set, checks each new or changed destination in the
+	// background; links it flags are disabled. ScanQueueSize bounds the
+	// backlog (default DefaultScanQueueSize).
+	Scanner       URLScanner
+	ScanQueueSize int
+
+	// PhishingWarnScore and PhishingBlockScore are thresholds for the
+	// built-in phishing heuristics (see package phishing). Destinations
+	// scoring at least PhishingBlockScore are rejected with
+	// ErrSuspiciousURL; new links scoring at least PhishingWarnScore are
+	// created with the matched signals as warnings. Zero disables either.
+	PhishingWarnScore  int
+	PhishingBlockScore int
+
+	// ThumbnailCapturer and Thumbnails, when both set, serve images of
+	// link destinations, captured on first request and stored until the
+	// destination changes.
+	ThumbnailCapturer ThumbnailCapturer
+	Thumbnails        repository.ThumbnailRepository
+
+	// Rollups, when set, keeps per-day click aggregates as clicks are
+	// recorded, and stats read them instead of scanning click events.
+	// Clicks recorded before rollups were enabled aren't included.
+	Rollups repository.StatsRollupRepository
+
+	// ClickRecorder decides whether redirects wait for their click to be
+	// stored. Defaults to AsyncClickRecorder; use BoundedClickRecorder
+	// where background goroutines can't be relied on.
+	ClickRecorder ClickRecorder
+
+	// Milestones are the click counts at which links with
+	// NotifyMilestones set publish a TypeMilestoneReached event. Nil uses
+	// DefaultMilestones.
+	Milestones []int64
+
+	// ClickDedupeWindow, when positive, leaves repeat clicks on a link by
+	// the same IP address and user agent within the window out of click
+	// counts and rollups. The events are still stored, marked Deduped.
+	ClickDedupeWindow time.Duration
+
+	// Fetches by link preview bots (PrefetchAgents, which defaults to
+	// DefaultPrefetchAgents) and speculative prefetches are stored marked
+	// Prefetch and left out of click counts, unless CountPrefetches is set.
+	CountPrefetches bool
+	PrefetchAgents  []string
+
+	// HonorDoNotTrack stores anonymous click events for visitors who send
+	// DNT or Sec-GPC: no referrer, user agent or IP address is kept. Their
+	// clicks are still counted, in totals, rollups and recounts.
+	HonorDoNotTrack bool
+
+	// DeleteGracePeriod, when positive, makes DeleteLink mark links
+	// deleted instead of removing them. They stop resolving at once but can
+	// be restored with RestoreLink until the period has passed, after which
+	// PurgeDeletedLinks removes them. Their codes stay taken until then.
+	DeleteGracePeriod time.Duration
+
+	// NotFoundCacheTTL, when positive, answers repeat redirects and stats
+	// requests for codes that don't exist from memory for this long, so
+	// scanners probing random codes don't each cost a storage read.
+	// Creating or restoring a link clears its code on this instance only.
+	NotFoundCacheTTL time.Duration
+
+	// CaseInsensitiveCodes generates single-case codes and resolves codes
+	// regardless of the case they arrive in. Links created before it was
+	// enabled still resolve by their exact code.
+	CaseInsensitiveCodes bool
+
+	// DedupeDestinations makes every create behave as if it set Dedupe,
+	// returning an existing link to the same destination instead of
+	// minting another code.
+	DedupeDestinations bool
+
+	Events *events.Bus    // optional; receives link and click events when set
+	Meter  metering.Meter // optional; counts billable usage when set
+	Logger *slog.Logger   // optional; defaults to discarding output
+	Clock  Clock          // optional; defaults to SystemClock
 }
 
 // DefaultConfig returns sensible default configuration.
@@ -53,41 +250,201 @@ func NewLinkService(
 	clickRepo repository.ClickRepository,
 	config LinkServiceConfig,
 ) *LinkService {
-	return &LinkService{
-		linkRepo:   linkRepo,
-		clickRepo:  clickRepo,
-		codeGen:    shortcode.NewGenerator(config.CodeLength),
-		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
-		maxRetries: config.MaxRetries,
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	warnRate := config.CollisionWarnRate
+	if warnRate <= 0 {
+		warnRate = DefaultCollisionWarnRate
+	}
+
+	s := &LinkService{
+		linkRepo:    linkRepo,
+		clickRepo:   clickRepo,
+		baseURL:     strings.TrimSuffix(config.BaseURL, "/"),
+		maxRetries:  config.MaxRetries,
+		events:      config.Events,
+		meter:       config.Meter,
+		logger:      logger,
+		collisions:  collisionTracker{warnRate: warnRate, growRate: config.CodeLengthGrowRate},
+		settings:    config.Settings,
+		prefixes:    config.Prefixes,
+		templates:   config.Templates,
+		aliases:     config.Aliases,
+		idempotency: config.Idempotency,
+		resolver:    config.Resolver,
+		verifier:    config.Verifier,
+
+		phishingWarnScore:  config.PhishingWarnScore,
+		phishingBlockScore: config.PhishingBlockScore,
+
+		thumbnailCapturer: config.ThumbnailCapturer,
+		thumbnails:        config.Thumbnails,
+
+		rollups:       config.Rollups,
+		clickRecorder: config.ClickRecorder,
+		milestones:    milestoneSet(config.Milestones),
+		deleteGrace:   config.DeleteGracePeriod,
+		dedupe:        newClickDeduper(config.ClickDedupeWindow),
+		misses:        newMissCache(config.NotFoundCacheTTL),
+
+		countPrefetches: config.CountPrefetches,
+		prefetchAgents:  config.PrefetchAgents,
+
+		honorDoNotTrack: config.HonorDoNotTrack,
+
+		caseInsensitive: config.CaseInsensitiveCodes,
+
+		dedupeDestinations: config.DedupeDestinations,
+
+		clock: config.Clock,
+	}
+	if s.clock == nil {
+		s.clock = SystemClock{}
+	}
+	if s.clickRecorder == nil {
+		s.clickRecorder = AsyncClickRecorder{}
+	}
+	if s.meter == nil {
+		s.meter = metering.Nop{}
+	}
+	if s.prefetchAgents == nil {
+		s.prefetchAgents = DefaultPrefetchAgents
 	}
+	s.SetShortenerPolicy(config.ShortenerDomains, config.AllowShorteners, config.ShortenerResolver)
+
+	if config.Scanner != nil {
+		s.startScanner(config.Scanner, config.ScanQueueSize)
+	}
+
+	length := s.initialCodeLength(config.CodeLength)
+	if s.caseInsensitive {
+		s.codeGen.Store(shortcode.NewCaseInsensitiveGenerator(length))
+	} else {
+		s.codeGen.Store(shortcode.NewGenerator(length))
+	}
+	s.readOnly.Store(config.ReadOnly)
+	return s
 }
 
-// CreateLink creates a new shortened URL.
-func (s *LinkService) CreateLink(ctx context.Context, originalURL string) (*model.CreateLinkResponse, error) {
+// SetReadOnly toggles read-only mode at runtime (e.g., on config reload).
+func (s *LinkService) SetReadOnly(readOnly bool) {
+	s.readOnly.Store(readOnly)
+}
+
+// ReadOnly reports whether the service currently rejects writes.
+func (s *LinkService) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// CreateLink creates a new shortened URL. With req.IdempotencyKey set and
+// an idempotency store configured, a repeat of an earlier request returns
+// the link it created (see createIdempotent). With req.Dedupe or
+// DedupeDestinations set, an existing link to the same destination may be
+// returned instead, marked Existing (see findDuplicate).
+func (s *LinkService) CreateLink(ctx context.Context, req model.CreateLinkRequest) (*model.CreateLinkResponse, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+	if req.IdempotencyKey != "" && s.idempotency != nil {
+		return s.createIdempotent(ctx, req)
+	}
+
 	// Validate URL
+	originalURL := req.URL
 	if err := s.validateURL(originalURL); err != nil {
 		return nil, err
 	}
+	if err := validateNotes(req.Notes); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(req.Title); err != nil {
+		return nil, err
+	}
+	allowedReferrers, err := normalizeReferrers(req.AllowedReferrers)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPrefix(ctx, req.Prefix); err != nil {
+		return nil, err
+	}
+	customCode, err := s.customCode(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	originalURL, err = s.checkShortener(ctx, s.resolveDestination(ctx, originalURL))
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := s.checkPhishing(ctx, originalURL)
+	if err != nil {
+		return nil, err
+	}
+	if (req.Dedupe || s.dedupeDestinations) && customCode == "" {
+		existing, err := s.findDuplicate(ctx, req, originalURL, allowedReferrers)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			resp := s.createResponse(existing, warnings)
+			resp.Existing = true
+			return resp, nil
+		}
+	}
+	if req.Verify {
+		if err := s.verifyDestination(ctx, originalURL); err != nil {
+			return nil, err
+		}
+	}
+
+	scanStatus := ""
+	if s.scanner != nil {
+		scanStatus = model.ScanStatusPending
+	}
 
 	// Generate unique short code with retry logic
 	var link *model.Link
-	var err error
+	collisions := 0
+	id := model.NewID()
 
 	for attempt := 0; attempt < s.maxRetries; attempt++ {
-		code, genErr := s.codeGen.Generate()
-		if genErr != nil {
-			return nil, fmt.Errorf("generating code: %w", genErr)
+		code := customCode
+		if code == "" {
+			generated, genErr := s.codeGen.Load().Generate()
+			if genErr != nil {
+				return nil, fmt.Errorf("generating code: %w", genErr)
+			}
+			code = generated
+			if req.Prefix != "" {
+				code = req.Prefix + shortcode.PrefixSeparator + code
+			}
+			if s.isReserved(code) {
+				err = errReservedCode
+				continue
+			}
 		}
 
 		link = &model.Link{
-			ID:          code, // Using short code as ID for simplicity
+			ID:          id,
 			ShortCode:   code,
 			OriginalURL: originalURL,
-			CreatedAt:   time.Now().UTC(),
+			CreatedAt:   s.now(),
 			ClickCount:  0,
+			OwnerID:     ownerOf(ctx),
+			Notes:       req.Notes,
+			Wildcard:    req.Wildcard,
+			ScanStatus:  scanStatus,
+			Version:     1,
+
+			Interstitial:     req.Interstitial,
+			NotifyMilestones: req.NotifyMilestones,
+			AllowedReferrers: allowedReferrers,
+			Public:           req.Public,
+			Title:            req.Title,
 		}
 
-		err = s.linkRepo.Create(ctx, link)
+		err = s.insertLink(ctx, link)
 		if err == nil {
 			break // Success!
 		}
@@ -95,63 +452,557 @@ func (s *LinkService) CreateLink(ctx context.Context, originalURL string) (*mode
 		if !errors.Is(err, repository.ErrAlreadyExists) {
 			return nil, fmt.Errorf("creating link: %w", err)
 		}
+		if customCode != "" {
+			return nil, ErrAliasTaken
+		}
 		// Code collision, retry with new code
+		collisions++
+		metrics.CodeCollisions.Add(1)
 	}
 
+	s.observeCollisions(collisions)
+
 	if err != nil {
+		metrics.CodeGenerationFailures.Add(1)
+		s.logger.Error("short code generation exhausted retries",
+			"code_length", s.codeGen.Load().Length(),
+			"max_retries", s.maxRetries,
+		)
 		return nil, ErrCodeGeneration
 	}
+	metrics.LinksCreated.Add(1)
+	s.meter.Record(ctx, metering.MetricLinksCreated, 1)
+	s.misses.forget(link.ShortCode)
+	s.enqueueScan(link)
 
+	s.events.Publish(events.Event{
+		Type:      events.TypeLinkCreated,
+		Timestamp: link.CreatedAt,
+		ShortCode: link.ShortCode,
+		Link:      link,
+	})
+
+	return s.createResponse(link, warnings), nil
+}
+
+// createResponse describes a link to the client that asked for it.
+func (s *LinkService) createResponse(link *model.Link, warnings []string) *model.CreateLinkResponse {
 	return &model.CreateLinkResponse{
 		ShortCode:   link.ShortCode,
 		ShortURL:    fmt.Sprintf("%s/%s", s.baseURL, link.ShortCode),
 		OriginalURL: link.OriginalURL,
-	}, nil
+		Warnings:    warnings,
+		Links:       s.resourceLinks(link.ShortCode),
+	}
 }
 
 // Redirect retrieves the original URL for a short code and records the click.
 func (s *LinkService) Redirect(ctx context.Context, shortCode string, metadata ClickMetadata) (string, error) {
-	link, err := s.linkRepo.GetByShortCode(ctx, shortCode)
+	return s.RedirectPath(ctx, shortCode, "", metadata)
+}
+
+// RedirectPath is Redirect for requests with path segments after the code
+// (/{code}/{rest}). Only wildcard links accept them: rest is appended to
+// the destination's path. Other links, and rest paths that try to climb
+// out of the destination with "..", are reported as ErrLinkNotFound.
+func (s *LinkService) RedirectPath(ctx context.Context, shortCode, rest string, metadata ClickMetadata) (string, error) {
+	target, err := s.ResolveRedirect(ctx, shortCode, rest, metadata)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return "", ErrLinkNotFound
+		return "", err
+	}
+	return target.URL, nil
+}
+
+// RedirectTarget is where a short link sends its visitor, and how.
+type RedirectTarget struct {
+	URL          string
+	Interstitial bool // show a countdown page instead of redirecting straight away
+	Private      bool // depends on the request, e.g. its referrer, so mustn't be cached
+}
+
+// ResolveRedirect is RedirectPath returning the whole redirect target, for
+// transports that honor per-link redirect options.
+func (s *LinkService) ResolveRedirect(ctx context.Context, shortCode, rest string, metadata ClickMetadata) (*RedirectTarget, error) {
+	link, err := s.readLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.Disabled {
+		return nil, ErrLinkDisabled
+	}
+	if !referrerAllowed(link, metadata.Referrer) {
+		return nil, ErrReferrerBlocked
+	}
+
+	destination := link.OriginalURL
+	if rest != "" {
+		if !link.Wildcard {
+			return nil, ErrLinkNotFound
+		}
+		destination, err = appendPath(link.OriginalURL, rest)
+		if err != nil {
+			return nil, ErrLinkNotFound
 		}
-		return "", fmt.Errorf("fetching link: %w", err)
 	}
 
-	// Record click asynchronously to not block redirect
-	go s.recordClick(context.Background(), link, metadata)
+	s.clickRecorder.Record(ctx, func(ctx context.Context) {
+		s.recordClick(ctx, link, metadata)
+	})
+	s.meter.Record(ctx, metering.MetricRedirects, 1)
 
-	return link.OriginalURL, nil
+	return &RedirectTarget{
+		URL:          destination,
+		Interstitial: link.Interstitial,
+		Private:      len(link.AllowedReferrers) > 0,
+	}, nil
+}
+
+// appendPath joins rest onto the path of destination, keeping its query
+// and fragment.
+func appendPath(destination, rest string) (string, error) {
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == ".." {
+			return "", errors.New("path escapes destination")
+		}
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+	return u.JoinPath(rest).String(), nil
+}
+
+// GetLink retrieves the full record for a short code.
+func (s *LinkService) GetLink(ctx context.Context, shortCode string) (*model.LinkDetails, error) {
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.linkDetails(link), nil
 }
 
 // GetStats retrieves statistics for a short code.
 func (s *LinkService) GetStats(ctx context.Context, shortCode string) (*model.LinkStats, error) {
-	link, err := s.linkRepo.GetByShortCode(ctx, shortCode)
+	link, err := s.readLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ownsLink(ctx, link) {
+		return nil, ErrLinkNotFound
+	}
+
+	stats := linkStats(link)
+
+	// Every day since creation; rollups make this cheap
+	days, err := s.dailyClicks(ctx, link, link.CreatedAt.UTC().Format(dayLayout), s.now().Format(dayLayout), time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		if stats.ClicksBySource == nil {
+			stats.ClicksBySource = make(map[string]int64)
+		}
+		for source, clicks := range day.BySource {
+			stats.ClicksBySource[source] += clicks
+		}
+	}
+
+	return stats, nil
+}
+
+// MaxBatchStats caps the number of codes in one GetStatsBatch call.
+const MaxBatchStats = 100
+
+// GetStatsBatch retrieves statistics for several links with a single
+// repository lookup. Per-source click counts are left out, since they
+// would need one click query per link.
+func (s *LinkService) GetStatsBatch(ctx context.Context, shortCodes []string) (*model.BatchStatsResponse, error) {
+	switch {
+	case len(shortCodes) == 0:
+		return nil, validationError(map[string]string{"short_codes": apierror.CodeShortCodeRequired})
+	case len(shortCodes) > MaxBatchStats:
+		return nil, validationError(map[string]string{"short_codes": apierror.CodeTooLong})
+	}
+
+	links, err := s.findLinks(ctx, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.BatchStatsResponse{Stats: []model.LinkStats{}}
+	seen := make(map[string]bool, len(shortCodes))
+	for _, code := range shortCodes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		if link, ok := links[code]; ok && ownsLink(ctx, link) {
+			resp.Stats = append(resp.Stats, *linkStats(link))
+		} else {
+			resp.NotFound = append(resp.NotFound, code)
+		}
+	}
+	return resp, nil
+}
+
+// findLinks is the batch form of findLink: it returns the links found,
+// keyed by the code as given.
+func (s *LinkService) findLinks(ctx context.Context, shortCodes []string) (map[string]*model.Link, error) {
+	lookups := make([]string, len(shortCodes))
+	for i, code := range shortCodes {
+		lookups[i] = code
+		if s.caseInsensitive {
+			lookups[i] = shortcode.Canonicalize(code)
+		}
+	}
+
+	found, err := s.linkRepo.GetByShortCodes(ctx, lookups)
+	if err != nil {
+		return nil, fmt.Errorf("fetching links: %w", err)
+	}
+
+	result := make(map[string]*model.Link, len(shortCodes))
+	var fallback []string
+	for i, code := range shortCodes {
+		if link, ok := found[lookups[i]]; ok {
+			result[code] = link
+		} else if lookups[i] != code {
+			fallback = append(fallback, code)
+		}
+	}
+
+	// Mixed-case codes from before case-insensitivity was enabled
+	if len(fallback) > 0 {
+		exact, err := s.linkRepo.GetByShortCodes(ctx, fallback)
+		if err != nil {
+			return nil, fmt.Errorf("fetching links: %w", err)
+		}
+		for code, link := range exact {
+			result[code] = link
+		}
+	}
+
+	// Secondary aliases, one lookup each
+	if s.aliases != nil {
+		for i, code := range shortCodes {
+			if _, ok := result[code]; ok {
+				continue
+			}
+			link, err := s.aliasedLink(ctx, lookups[i])
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("fetching links: %w", err)
+			}
+			result[code] = link
+		}
+	}
+
+	for code, link := range result {
+		if link.DeletedAt != nil {
+			delete(result, code)
+		}
+	}
+	return result, nil
+}
+
+// DestinationResolver follows a destination's redirects to its final URL.
+// outbound.Resolver implements it.
+type DestinationResolver interface {
+	Resolve(ctx context.Context, rawURL string) (string, error)
+}
+
+// resolveDestination returns where destination finally lands, so short
+// links don't add extra hops and stats reflect the real landing page.
+// Resolution is best effort: on failure the destination is kept as given.
+func (s *LinkService) resolveDestination(ctx context.Context, destination string) string {
+	if s.resolver == nil {
+		return destination
+	}
+
+	final, err := s.resolver.Resolve(ctx, destination)
+	if err != nil {
+		s.logger.WarnContext(ctx, "could not resolve destination; keeping it as given", "url", destination, "error", err)
+		return destination
+	}
+	if s.validateURL(final) != nil {
+		return destination
+	}
+	return final
+}
+
+// DestinationVerifier checks that a destination is live. outbound.Resolver
+// implements it.
+type DestinationVerifier interface {
+	Verify(ctx context.Context, rawURL string) error
+}
+
+// verifyDestination reports ErrDeadURL when destination can't be reached
+// or answers that there's nothing there.
+func (s *LinkService) verifyDestination(ctx context.Context, destination string) error {
+	if s.verifier == nil {
+		return nil
+	}
+	if err := s.verifier.Verify(ctx, destination); err != nil {
+		s.logger.InfoContext(ctx, "destination failed verification", "url", destination, "error", err)
+		return ErrDeadURL
+	}
+	return nil
+}
+
+// findLink fetches a link by code, canonicalizing the code first when codes
+// are case-insensitive. The exact code is tried as a fallback so mixed-case
+// codes created before the option was enabled keep working. Soft-deleted
+// links are reported as ErrLinkNotFound.
+func (s *LinkService) findLink(ctx context.Context, shortCode string) (*model.Link, error) {
+	link, err := s.findStoredLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.DeletedAt != nil {
+		return nil, ErrLinkNotFound
+	}
+	return link, nil
+}
+
+// findStoredLink is findLink including soft-deleted links. Secondary
+// aliases resolve to the link they point at.
+func (s *LinkService) findStoredLink(ctx context.Context, shortCode string) (*model.Link, error) {
+	lookup := shortCode
+	if s.caseInsensitive {
+		lookup = shortcode.Canonicalize(shortCode)
+	}
+
+	link, err := s.linkRepo.GetByShortCode(ctx, lookup)
+	if errors.Is(err, repository.ErrNotFound) && lookup != shortCode {
+		link, err = s.linkRepo.GetByShortCode(ctx, shortCode)
+	}
+	if errors.Is(err, repository.ErrNotFound) && s.aliases != nil {
+		link, err = s.aliasedLink(ctx, lookup)
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrLinkNotFound
 		}
 		return nil, fmt.Errorf("fetching link: %w", err)
 	}
+	return link, nil
+}
+
+// linkDetails builds the public record of a link.
+func (s *LinkService) linkDetails(link *model.Link) *model.LinkDetails {
+	return &model.LinkDetails{
+		ShortCode:    link.ShortCode,
+		ShortURL:     fmt.Sprintf("%s/%s", s.baseURL, link.ShortCode),
+		OriginalURL:  link.OriginalURL,
+		CreatedAt:    link.CreatedAt,
+		Pinned:       link.Pinned,
+		Notes:        link.Notes,
+		Wildcard:     link.Wildcard,
+		Interstitial: link.Interstitial,
+		Disabled:     link.Disabled,
+		ScanStatus:   link.ScanStatus,
+		Version:      link.Version,
+		Links:        s.resourceLinks(link.ShortCode),
+
+		NotifyMilestones: link.NotifyMilestones,
+		AllowedReferrers: link.AllowedReferrers,
+		Public:           link.Public,
+		Title:            link.Title,
+		OwnerID:          link.OwnerID,
+	}
+}
+
+// resourceLinks returns the API routes related to a link, so clients
+// don't have to hard-code route templates.
+func (s *LinkService) resourceLinks(shortCode string) map[string]model.HALLink {
+	self := fmt.Sprintf("%s/api/links/%s", s.baseURL, url.PathEscape(shortCode))
+	links := map[string]model.HALLink{
+		"self":   {Href: self},
+		"stats":  {Href: self + "/stats"},
+		"qr":     {Href: self + "/qr"},
+		"pin":    {Href: self + "/pin", Method: http.MethodPost},
+		"update": {Href: self, Method: http.MethodPatch},
+		"delete": {Href: self, Method: http.MethodDelete},
+	}
+	if s.ThumbnailsEnabled() {
+		links["thumbnail"] = model.HALLink{Href: self + "/thumbnail"}
+	}
+	return links
+}
 
+// linkStats builds the analytics view of a link.
+func linkStats(link *model.Link) *model.LinkStats {
 	return &model.LinkStats{
 		ShortCode:   link.ShortCode,
 		OriginalURL: link.OriginalURL,
 		ClickCount:  link.ClickCount,
 		CreatedAt:   link.CreatedAt,
-	}, nil
+		Version:     link.Version,
+	}
+}
+
+// UpdateLink applies a partial update to a link and returns its new state.
+// Invalid fields are reported together as an apierror validation error.
+// A non-zero expectedVersion makes the update conditional: if the link has
+// changed since the caller read it, ErrVersionConflict is returned. Only
+// an operator, acting without an owner, can re-enable a link the scanner
+// flagged.
+func (s *LinkService) UpdateLink(ctx context.Context, shortCode string, patch LinkPatch, expectedVersion int64) (*model.LinkDetails, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	if patch.URL != nil {
+		if err := s.validateURL(*patch.URL); err != nil {
+			return nil, validationError(map[string]string{"url": apierror.CodeOf(err)})
+		}
+		final, err := s.checkShortener(ctx, *patch.URL)
+		if err != nil {
+			return nil, validationError(map[string]string{"url": apierror.CodeOf(err)})
+		}
+		if _, err := s.checkPhishing(ctx, final); err != nil {
+			return nil, validationError(map[string]string{"url": apierror.CodeOf(err)})
+		}
+		patch.URL = &final
+	}
+	if patch.Notes != nil {
+		if err := validateNotes(*patch.Notes); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Title != nil {
+		if err := validateTitle(*patch.Title); err != nil {
+			return nil, err
+		}
+	}
+	if patch.AllowedReferrers != nil {
+		hosts, err := normalizeReferrers(*patch.AllowedReferrers)
+		if err != nil {
+			return nil, err
+		}
+		patch.AllowedReferrers = &hosts
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && link.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	// A link the scanner flagged stays disabled until an operator has
+	// reviewed it; its owner can't turn it back on
+	if patch.Disabled != nil && !*patch.Disabled && link.ScanStatus == model.ScanStatusFlagged && ownerOf(ctx) != "" {
+		return nil, validationError(map[string]string{"disabled": apierror.CodeImmutableField})
+	}
+
+	changed, rescan := false, false
+	if patch.URL != nil && *patch.URL != link.OriginalURL {
+		link.OriginalURL = *patch.URL
+		changed = true
+		if s.scanner != nil {
+			link.ScanStatus = model.ScanStatusPending
+			rescan = true
+		}
+	}
+	if patch.Pinned != nil && *patch.Pinned != link.Pinned {
+		link.Pinned = *patch.Pinned
+		changed = true
+	}
+	if patch.Notes != nil && *patch.Notes != link.Notes {
+		link.Notes = *patch.Notes
+		changed = true
+	}
+	if patch.Disabled != nil && *patch.Disabled != link.Disabled {
+		link.Disabled = *patch.Disabled
+		changed = true
+	}
+	if patch.Interstitial != nil && *patch.Interstitial != link.Interstitial {
+		link.Interstitial = *patch.Interstitial
+		changed = true
+	}
+	if patch.NotifyMilestones != nil && *patch.NotifyMilestones != link.NotifyMilestones {
+		link.NotifyMilestones = *patch.NotifyMilestones
+		changed = true
+	}
+	if patch.AllowedReferrers != nil && !slices.Equal(*patch.AllowedReferrers, link.AllowedReferrers) {
+		link.AllowedReferrers = *patch.AllowedReferrers
+		changed = true
+	}
+	if patch.Public != nil && *patch.Public != link.Public {
+		link.Public = *patch.Public
+		changed = true
+	}
+	if patch.Title != nil && *patch.Title != link.Title {
+		link.Title = *patch.Title
+		changed = true
+	}
+
+	if changed {
+		if err := s.linkRepo.Update(ctx, link); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrLinkNotFound
+			}
+			// Someone else wrote between our read and write
+			if errors.Is(err, repository.ErrConflict) {
+				return nil, ErrVersionConflict
+			}
+			return nil, fmt.Errorf("updating link: %w", err)
+		}
+
+		s.events.Publish(events.Event{
+			Type:      events.TypeLinkUpdated,
+			Timestamp: s.now(),
+			ShortCode: link.ShortCode,
+			Link:      link,
+		})
+		if rescan {
+			s.enqueueScan(link)
+		}
+	}
+
+	return s.linkDetails(link), nil
 }
 
-// DeleteLink removes a link by its short code.
-func (s *LinkService) DeleteLink(ctx context.Context, shortCode string) error {
-	err := s.linkRepo.Delete(ctx, shortCode)
+// DeleteLink removes a link by its short code. A non-zero expectedVersion
+// makes the delete conditional, as in UpdateLink. With a delete grace
+// period the link is only marked deleted; see RestoreLink.
+func (s *LinkService) DeleteLink(ctx context.Context, shortCode string, expectedVersion int64) error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+	if s.deleteGrace > 0 {
+		return s.softDelete(ctx, shortCode, expectedVersion)
+	}
+
+	// The stored link is needed to find its code, its thumbnail or its
+	// owner
+	var linkID string
+	if s.caseInsensitive || s.thumbnails != nil || s.aliases != nil || ownerOf(ctx) != "" {
+		link, err := s.ownedLink(ctx, shortCode)
+		if err != nil {
+			return err
+		}
+		shortCode, linkID = link.ShortCode, link.ID
+	}
+
+	err := s.linkRepo.Delete(ctx, shortCode, expectedVersion)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return ErrLinkNotFound
 		}
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrVersionConflict
+		}
 		return fmt.Errorf("deleting link: %w", err)
 	}
+	s.deleteThumbnail(ctx, linkID)
+	s.deleteLinkAliases(ctx, shortCode)
 	return nil
 }
 
@@ -160,25 +1011,98 @@ type ClickMetadata struct {
 	Referrer  string
 	UserAgent string
 	IPAddress string
+	Source    string // raw ?src= value; see ClickSource
+	Purpose   string // Sec-Purpose or Purpose header, e.g. "prefetch"
+
+	DoNotTrack bool // DNT: 1 or Sec-GPC: 1 was sent
 }
 
-// recordClick records a click event and increments the counter.
-// This runs asynchronously to not block redirects.
-func (s *LinkService) recordClick(ctx context.Context, link *model.Link, metadata ClickMetadata) {
-	// Increment click count
-	_ = s.linkRepo.IncrementClickCount(ctx, link.ShortCode)
+// ClickSource normalizes a ?src= value to a known click source. Unknown
+// values count as ordinary link clicks so arbitrary strings can't inflate
+// the set of sources.
+func ClickSource(src string) string {
+	if strings.EqualFold(src, model.ClickSourceQR) {
+		return model.ClickSourceQR
+	}
+	return model.ClickSourceLink
+}
 
-	// Record detailed click event
+// recordClick records a click event and increments the counter. The
+// configured ClickRecorder decides whether redirects wait for it, and for
+// how long. The event write is retried once, since rewriting an event
+// under the same ID is harmless; the increment isn't, since a failure
+// may still have counted the click, and a recount repairs a missed one.
+// Failures are logged and counted in metrics rather than returned: a
+// redirect never fails because its click couldn't be stored. Duplicate
+// clicks within the dedupe window, and prefetches, are stored but not
+// counted. Visitors opting out of tracking are stored anonymously, when
+// that's honored.
+func (s *LinkService) recordClick(ctx context.Context, link *model.Link, metadata ClickMetadata) {
+	now := s.now()
+	prefetch := s.isPrefetch(metadata)
 	event := &model.ClickEvent{
-		ID:        fmt.Sprintf("%s-%d", link.ShortCode, time.Now().UnixNano()),
+		ID:        model.NewID(),
 		LinkID:    link.ID,
-		ClickedAt: time.Now().UTC(),
+		ClickedAt: now,
 		Referrer:  metadata.Referrer,
 		UserAgent: metadata.UserAgent,
 		IPAddress: metadata.IPAddress,
+		Source:    ClickSource(metadata.Source),
+		Prefetch:  prefetch,
+		Deduped:   !prefetch && s.dedupe.duplicate(link.ID, metadata, now),
 	}
+	counted := s.counts(event)
 
-	_ = s.clickRepo.Record(ctx, event)
+	// Increment click count
+	var count int64
+	var err error
+	if counted {
+		count, err = s.linkRepo.IncrementClickCount(ctx, link.ShortCode)
+		if err != nil {
+			metrics.ClickCountFailures.Add(1)
+			s.logger.WarnContext(ctx, "failed to increment click count", "short_code", link.ShortCode, "error", err)
+		}
+	}
+
+	// Record the click event. A visitor who opted out gets one without
+	// their details, so recounts and rebuilt rollups still include it.
+	if s.honorDoNotTrack && metadata.DoNotTrack {
+		event.Referrer, event.UserAgent, event.IPAddress = "", "", ""
+	}
+	if err := retryOnce(ctx, func(ctx context.Context) error { return s.clickRepo.Record(ctx, event) }); err != nil {
+		metrics.ClickEventFailures.Add(1)
+		s.logger.WarnContext(ctx, "failed to record click event", "short_code", link.ShortCode, "error", err)
+	}
+
+	s.events.Publish(events.Event{
+		Type:      events.TypeClickRecorded,
+		Timestamp: event.ClickedAt,
+		ShortCode: link.ShortCode,
+		Click:     event,
+	})
+	if !counted {
+		return
+	}
+	s.recordRollup(ctx, event)
+	if err == nil {
+		s.checkMilestone(link, count, event.ClickedAt)
+	}
+}
+
+// validateNotes checks that notes fit within MaxNotesLength.
+func validateNotes(notes string) error {
+	if utf8.RuneCountInString(notes) > MaxNotesLength {
+		return validationError(map[string]string{"notes": apierror.CodeTooLong})
+	}
+	return nil
+}
+
+// validateTitle checks that a title fits within MaxTitleLength.
+func validateTitle(title string) error {
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return validationError(map[string]string{"title": apierror.CodeTooLong})
+	}
+	return nil
 }
 
 // validateURL checks if the provided URL is valid.
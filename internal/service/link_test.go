@@ -2,10 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/colby/snip/internal/metering"
+	"github.com/colby/snip/internal/model"
 	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
 )
 
 func TestLinkService_CreateLink(t *testing.T) {
@@ -57,7 +62,7 @@ func TestLinkService_CreateLink(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp, err := svc.CreateLink(context.Background(), tt.url)
+			resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: tt.url})
 
 			if tt.wantErr != nil {
 				if err == nil {
@@ -95,7 +100,7 @@ func TestLinkService_Redirect(t *testing.T) {
 
 	// Create a link first
 	originalURL := "https://example.com/test"
-	resp, err := svc.CreateLink(ctx, originalURL)
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: originalURL})
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
@@ -128,6 +133,101 @@ func TestLinkService_Redirect_NotFound(t *testing.T) {
 	}
 }
 
+func TestLinkService_RedirectPath(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	wildcard, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://real.site/documentation/?lang=en", Wildcard: true})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	plain, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	got, err := svc.RedirectPath(ctx, wildcard.ShortCode, "guides/setup", ClickMetadata{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://real.site/documentation/guides/setup?lang=en"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if _, err := svc.RedirectPath(ctx, wildcard.ShortCode, "../admin", ClickMetadata{}); err != ErrLinkNotFound {
+		t.Errorf("expected ErrLinkNotFound for an escaping path, got %v", err)
+	}
+	if _, err := svc.RedirectPath(ctx, plain.ShortCode, "extra", ClickMetadata{}); err != ErrLinkNotFound {
+		t.Errorf("expected ErrLinkNotFound for a non-wildcard link, got %v", err)
+	}
+}
+
+// stubResolver resolves URLs from a fixed table.
+type stubResolver map[string]string
+
+func (r stubResolver) Resolve(ctx context.Context, rawURL string) (string, error) {
+	if final, ok := r[rawURL]; ok {
+		return final, nil
+	}
+	return "", errors.New("unreachable")
+}
+
+// Verify treats the URLs in the table as live.
+func (r stubResolver) Verify(ctx context.Context, rawURL string) error {
+	if _, ok := r[rawURL]; ok {
+		return nil
+	}
+	return errors.New("unreachable")
+}
+
+func TestLinkService_ResolveDestination(t *testing.T) {
+	config := DefaultConfig()
+	config.Resolver = stubResolver{
+		"https://bit.example/x": "https://example.com/landing",
+		"https://ftp.example/y": "ftp://example.com/file",
+	}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://bit.example/x", "https://example.com/landing"},
+		{"https://ftp.example/y", "https://ftp.example/y"},   // unsupported final scheme
+		{"https://down.example/z", "https://down.example/z"}, // resolution failed
+	}
+
+	for _, tt := range tests {
+		resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: tt.url})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.OriginalURL != tt.want {
+			t.Errorf("CreateLink(%s) stored %s, want %s", tt.url, resp.OriginalURL, tt.want)
+		}
+	}
+}
+
+func TestLinkService_VerifyDestination(t *testing.T) {
+	config := DefaultConfig()
+	config.Verifier = stubResolver{"https://example.com/live": ""}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/live", Verify: true}); err != nil {
+		t.Errorf("unexpected error for a live destination: %v", err)
+	}
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://exmaple.com/live", Verify: true}); err != ErrDeadURL {
+		t.Errorf("expected ErrDeadURL, got %v", err)
+	}
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://exmaple.com/live"}); err != nil {
+		t.Errorf("expected unverified creates to skip the check, got %v", err)
+	}
+}
+
 func TestLinkService_GetStats(t *testing.T) {
 	linkRepo := repository.NewMemoryLinkRepository()
 	clickRepo := repository.NewMemoryClickRepository()
@@ -136,7 +236,7 @@ func TestLinkService_GetStats(t *testing.T) {
 
 	// Create a link
 	originalURL := "https://example.com/stats-test"
-	resp, err := svc.CreateLink(ctx, originalURL)
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: originalURL})
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
@@ -171,6 +271,41 @@ func TestLinkService_GetStats_NotFound(t *testing.T) {
 	}
 }
 
+func TestLinkService_GetStatsBatch(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	first, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/one"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	second, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/two"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	resp, err := svc.GetStatsBatch(ctx, []string{second.ShortCode, "missing", first.ShortCode, second.ShortCode})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.Stats) != 2 || resp.Stats[0].ShortCode != second.ShortCode || resp.Stats[1].ShortCode != first.ShortCode {
+		t.Errorf("expected stats for [%s %s] in request order, got %+v", second.ShortCode, first.ShortCode, resp.Stats)
+	}
+	if len(resp.NotFound) != 1 || resp.NotFound[0] != "missing" {
+		t.Errorf("expected not_found [missing], got %v", resp.NotFound)
+	}
+
+	if _, err := svc.GetStatsBatch(ctx, nil); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected validation_failed for an empty batch, got %v", err)
+	}
+	if _, err := svc.GetStatsBatch(ctx, make([]string, MaxBatchStats+1)); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected validation_failed for an oversized batch, got %v", err)
+	}
+}
+
 func TestLinkService_DeleteLink(t *testing.T) {
 	linkRepo := repository.NewMemoryLinkRepository()
 	clickRepo := repository.NewMemoryClickRepository()
@@ -178,13 +313,13 @@ func TestLinkService_DeleteLink(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a link
-	resp, err := svc.CreateLink(ctx, "https://example.com/delete-test")
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/delete-test"})
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
 
 	// Delete it
-	err = svc.DeleteLink(ctx, resp.ShortCode)
+	err = svc.DeleteLink(ctx, resp.ShortCode, 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -201,12 +336,195 @@ func TestLinkService_DeleteLink_NotFound(t *testing.T) {
 	clickRepo := repository.NewMemoryClickRepository()
 	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
 
-	err := svc.DeleteLink(context.Background(), "nonexistent")
+	err := svc.DeleteLink(context.Background(), "nonexistent", 0)
 	if err != ErrLinkNotFound {
 		t.Errorf("expected ErrLinkNotFound, got %v", err)
 	}
 }
 
+func TestLinkService_UpdateLink(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/old"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	newURL := "https://example.com/new"
+	stats, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &newURL}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.OriginalURL != newURL {
+		t.Errorf("expected %s, got %s", newURL, stats.OriginalURL)
+	}
+
+	redirect, err := svc.Redirect(ctx, resp.ShortCode, ClickMetadata{})
+	if err != nil {
+		t.Fatalf("redirect failed: %v", err)
+	}
+	if redirect != newURL {
+		t.Errorf("expected redirect to %s, got %s", newURL, redirect)
+	}
+
+	badURL := "ftp://example.com"
+	_, err = svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &badURL}, 0)
+	if apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	if _, err := svc.UpdateLink(ctx, "nonexistent", LinkPatch{URL: &newURL}, 0); err != ErrLinkNotFound {
+		t.Errorf("expected ErrLinkNotFound, got %v", err)
+	}
+}
+
+func TestLinkService_ClicksBySource(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	link, err := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("failed to fetch link: %v", err)
+	}
+
+	// Record synchronously rather than through Redirect's goroutine
+	for _, src := range []string{"qr", "QR", "", "newsletter"} {
+		svc.recordClick(ctx, link, ClickMetadata{Source: src})
+	}
+
+	stats, err := svc.GetStats(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stats.ClicksBySource[model.ClickSourceQR]; got != 2 {
+		t.Errorf("expected 2 QR scans, got %d", got)
+	}
+	if got := stats.ClicksBySource[model.ClickSourceLink]; got != 2 {
+		t.Errorf("expected 2 link clicks, got %d", got)
+	}
+}
+
+func TestLinkService_Notes(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", Notes: "used in Q3 newsletter"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	link, err := svc.GetLink(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("failed to get link: %v", err)
+	}
+	if link.Notes != "used in Q3 newsletter" {
+		t.Errorf("expected notes to be stored, got %q", link.Notes)
+	}
+
+	// A merge patch null clears notes
+	patch, err := ParseLinkPatch([]byte(`{"notes": null}`))
+	if err != nil {
+		t.Fatalf("failed to parse patch: %v", err)
+	}
+	link, err = svc.UpdateLink(ctx, resp.ShortCode, patch, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.Notes != "" {
+		t.Errorf("expected notes to be cleared, got %q", link.Notes)
+	}
+
+	long := strings.Repeat("x", MaxNotesLength+1)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", Notes: long}); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected validation error for long notes on create, got %v", err)
+	}
+	if _, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{Notes: &long}, 0); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected validation error for long notes on update, got %v", err)
+	}
+}
+
+func TestLinkService_VersionConflict(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/v1"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	// Two editors read version 1; the first write wins
+	first, second := "https://example.com/first", "https://example.com/second"
+	stats, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &first}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Version != 2 {
+		t.Errorf("expected version 2, got %d", stats.Version)
+	}
+
+	if _, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &second}, 1); err != ErrVersionConflict {
+		t.Errorf("expected ErrVersionConflict on stale update, got %v", err)
+	}
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 1); err != ErrVersionConflict {
+		t.Errorf("expected ErrVersionConflict on stale delete, got %v", err)
+	}
+
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 2); err != nil {
+		t.Errorf("expected delete at current version to succeed, got %v", err)
+	}
+}
+
+func TestLinkService_CaseInsensitiveCodes(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	ctx := context.Background()
+
+	// A mixed-case link from before the option was enabled
+	legacy := &model.Link{ID: "legacy", ShortCode: "AbC2345", OriginalURL: "https://example.com/legacy", Version: 1}
+	if err := linkRepo.Create(ctx, legacy); err != nil {
+		t.Fatalf("failed to seed link: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.CaseInsensitiveCodes = true
+	svc := NewLinkService(linkRepo, clickRepo, config)
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/test"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if resp.ShortCode != strings.ToLower(resp.ShortCode) {
+		t.Errorf("expected a single-case code, got %s", resp.ShortCode)
+	}
+
+	if _, err := svc.Redirect(ctx, strings.ToUpper(resp.ShortCode), ClickMetadata{}); err != nil {
+		t.Errorf("expected upper-cased code to resolve, got %v", err)
+	}
+	if _, err := svc.GetLink(ctx, "AbC2345"); err != nil {
+		t.Errorf("expected legacy code to resolve, got %v", err)
+	}
+
+	if err := svc.DeleteLink(ctx, strings.ToUpper(resp.ShortCode), 0); err != nil {
+		t.Fatalf("expected upper-cased delete to succeed, got %v", err)
+	}
+	if _, err := svc.GetLink(ctx, resp.ShortCode); err != ErrLinkNotFound {
+		t.Errorf("expected ErrLinkNotFound after delete, got %v", err)
+	}
+}
+
 func TestLinkService_CustomBaseURL(t *testing.T) {
 	linkRepo := repository.NewMemoryLinkRepository()
 	clickRepo := repository.NewMemoryClickRepository()
@@ -216,7 +534,7 @@ func TestLinkService_CustomBaseURL(t *testing.T) {
 
 	svc := NewLinkService(linkRepo, clickRepo, config)
 
-	resp, err := svc.CreateLink(context.Background(), "https://example.com")
+	resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: "https://example.com"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -230,3 +548,79 @@ func TestLinkService_CustomBaseURL(t *testing.T) {
 		t.Errorf("short URL has double slashes: %s", resp.ShortURL)
 	}
 }
+
+func TestLinkService_ReadOnly(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/read-only"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	svc.SetReadOnly(true)
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/other"}); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on create, got %v", err)
+	}
+
+	newURL := "https://example.com/new"
+	if _, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &newURL}, 0); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on update, got %v", err)
+	}
+
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 0); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on delete, got %v", err)
+	}
+
+	// Reads keep working
+	if _, err := svc.Redirect(ctx, resp.ShortCode, ClickMetadata{}); err != nil {
+		t.Errorf("expected redirect to work in read-only mode, got %v", err)
+	}
+	if _, err := svc.GetStats(ctx, resp.ShortCode); err != nil {
+		t.Errorf("expected stats to work in read-only mode, got %v", err)
+	}
+
+	svc.SetReadOnly(false)
+
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 0); err != nil {
+		t.Errorf("expected delete to work after leaving read-only mode, got %v", err)
+	}
+}
+
+// countingMeter sums recorded usage per metric.
+type countingMeter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *countingMeter) Record(_ context.Context, metric string, quantity int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[metric] += quantity
+}
+
+func TestLinkService_Meter(t *testing.T) {
+	meter := &countingMeter{counts: make(map[string]int64)}
+	config := DefaultConfig()
+	config.Meter = meter
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	for range 2 {
+		if _, err := svc.ResolveRedirect(ctx, resp.ShortCode, "", ClickMetadata{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	svc.ResolveRedirect(ctx, "missing", "", ClickMetadata{})
+
+	if meter.counts[metering.MetricLinksCreated] != 1 || meter.counts[metering.MetricRedirects] != 2 {
+		t.Errorf("expected 1 link created and 2 redirects, got %v", meter.counts)
+	}
+}
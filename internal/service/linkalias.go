@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// ErrLinkAliasNotFound is returned when a link has no alias with the given
+// code.
+var ErrLinkAliasNotFound = apierror.New(apierror.CodeAliasNotFound, "alias not found for this link")
+
+// LinkAliasesEnabled reports whether the service was configured with an
+// alias store, and so whether links can have secondary aliases.
+func (s *LinkService) LinkAliasesEnabled() bool {
+	return s.aliases != nil
+}
+
+// AddLinkAlias attaches a secondary code to the link at shortCode, which
+// may itself be an alias. The alias redirects to the same link and its
+// clicks count toward the link's stats. It follows the rules for custom
+// codes, failing with ErrInvalidAlias, ErrReservedAlias or ErrAliasTaken.
+func (s *LinkService) AddLinkAlias(ctx context.Context, shortCode string, req model.AddLinkAliasRequest) (*model.LinkAlias, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.customCode(ctx, model.CreateLinkRequest{CustomCode: req.Alias})
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, ErrInvalidAlias
+	}
+
+	// The alias repository only knows about other aliases
+	switch _, err := s.findStoredLink(ctx, code); {
+	case err == nil:
+		return nil, ErrAliasTaken
+	case !errors.Is(err, ErrLinkNotFound):
+		return nil, fmt.Errorf("checking alias: %w", err)
+	}
+
+	alias := &model.LinkAlias{
+		Alias:     code,
+		ShortCode: link.ShortCode,
+		CreatedAt: s.now(),
+	}
+	if err := s.aliases.Create(ctx, alias); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrAliasTaken
+		}
+		return nil, fmt.Errorf("creating alias: %w", err)
+	}
+	s.misses.forget(code)
+
+	alias.ShortURL = fmt.Sprintf("%s/%s", s.baseURL, alias.Alias)
+	return alias, nil
+}
+
+// ListLinkAliases returns the secondary aliases of the link at shortCode,
+// ordered by code.
+func (s *LinkService) ListLinkAliases(ctx context.Context, shortCode string) (*model.ListLinkAliasesResponse, error) {
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := s.aliases.ListByShortCode(ctx, link.ShortCode)
+	if err != nil {
+		return nil, fmt.Errorf("listing aliases: %w", err)
+	}
+	for i := range aliases {
+		aliases[i].ShortURL = fmt.Sprintf("%s/%s", s.baseURL, aliases[i].Alias)
+	}
+	return &model.ListLinkAliasesResponse{ShortCode: link.ShortCode, Aliases: aliases}, nil
+}
+
+// RemoveLinkAlias detaches alias from the link at shortCode. The link and
+// its stats are unaffected, and the code becomes free again.
+func (s *LinkService) RemoveLinkAlias(ctx context.Context, shortCode, alias string) error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if s.caseInsensitive {
+		alias = shortcode.Canonicalize(alias)
+	}
+
+	stored, err := s.aliases.Get(ctx, alias)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && stored.ShortCode != link.ShortCode {
+		return ErrLinkAliasNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fetching alias: %w", err)
+	}
+
+	if err := s.aliases.Delete(ctx, alias); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrLinkAliasNotFound
+		}
+		return fmt.Errorf("deleting alias: %w", err)
+	}
+	return nil
+}
+
+// aliasedLink returns the link an alias points at.
+func (s *LinkService) aliasedLink(ctx context.Context, code string) (*model.Link, error) {
+	alias, err := s.aliases.Get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkRepo.GetByShortCode(ctx, alias.ShortCode)
+}
+
+// insertLink stores a new link, reporting repository.ErrAlreadyExists
+// when its code is already used as an alias.
+func (s *LinkService) insertLink(ctx context.Context, link *model.Link) error {
+	if s.aliases != nil {
+		_, err := s.aliases.Get(ctx, link.ShortCode)
+		if err == nil {
+			return repository.ErrAlreadyExists
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("checking aliases: %w", err)
+		}
+	}
+	return s.linkRepo.Create(ctx, link)
+}
+
+// deleteLinkAliases removes the aliases of a deleted link, freeing their
+// codes. Failures are logged: the aliases no longer resolve either way.
+func (s *LinkService) deleteLinkAliases(ctx context.Context, shortCode string) {
+	if s.aliases == nil {
+		return
+	}
+	aliases, err := s.aliases.ListByShortCode(ctx, shortCode)
+	if err != nil {
+		s.logger.WarnContext(ctx, "listing aliases of deleted link failed", "short_code", shortCode, "error", err)
+		return
+	}
+	for _, alias := range aliases {
+		if err := s.aliases.Delete(ctx, alias.Alias); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			s.logger.WarnContext(ctx, "deleting alias failed", "alias", alias.Alias, "short_code", shortCode, "error", err)
+		}
+	}
+}
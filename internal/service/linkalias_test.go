@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_LinkAliases(t *testing.T) {
+	config := DefaultConfig()
+	config.Aliases = repository.NewMemoryLinkAliasRepository()
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	link, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", CustomCode: "oldbrand"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	other, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.org"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	alias, err := svc.AddLinkAlias(ctx, link.ShortCode, model.AddLinkAliasRequest{Alias: "newbrand"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alias.ShortCode != "oldbrand" || alias.ShortURL != "http://localhost:8080/newbrand" {
+		t.Errorf("unexpected alias %+v", alias)
+	}
+
+	for _, tt := range []struct {
+		alias string
+		want  error
+	}{
+		{"newbrand", ErrAliasTaken},
+		{other.ShortCode, ErrAliasTaken},
+		{"api", ErrReservedAlias},
+		{"a", ErrInvalidAlias},
+		{"", ErrInvalidAlias},
+	} {
+		if _, err := svc.AddLinkAlias(ctx, link.ShortCode, model.AddLinkAliasRequest{Alias: tt.alias}); !errors.Is(err, tt.want) {
+			t.Errorf("alias %q: expected %v, got %v", tt.alias, tt.want, err)
+		}
+	}
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.net", CustomCode: "newbrand"}); !errors.Is(err, ErrAliasTaken) {
+		t.Errorf("expected a new link to be refused the alias's code, got %v", err)
+	}
+
+	// Clicks through the alias count toward the link
+	if _, err := svc.Redirect(ctx, "newbrand", ClickMetadata{}); err != nil {
+		t.Fatalf("unexpected error redirecting through alias: %v", err)
+	}
+	stats, err := svc.GetStats(ctx, "oldbrand")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.ClickCount != 1 {
+		t.Errorf("expected 1 click on the link, got %d", stats.ClickCount)
+	}
+
+	list, err := svc.ListLinkAliases(ctx, "newbrand")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list.ShortCode != "oldbrand" || len(list.Aliases) != 1 || list.Aliases[0].Alias != "newbrand" {
+		t.Errorf("unexpected aliases %+v", list)
+	}
+
+	if err := svc.RemoveLinkAlias(ctx, other.ShortCode, "newbrand"); !errors.Is(err, ErrLinkAliasNotFound) {
+		t.Errorf("expected ErrLinkAliasNotFound removing another link's alias, got %v", err)
+	}
+	if err := svc.RemoveLinkAlias(ctx, "oldbrand", "newbrand"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Redirect(ctx, "newbrand", ClickMetadata{}); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected a removed alias to stop resolving, got %v", err)
+	}
+}
+
+func TestLinkService_DeleteLink_RemovesAliases(t *testing.T) {
+	config := DefaultConfig()
+	aliases := repository.NewMemoryLinkAliasRepository()
+	config.Aliases = aliases
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	link, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if _, err := svc.AddLinkAlias(ctx, link.ShortCode, model.AddLinkAliasRequest{Alias: "launch"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Deleting through the alias deletes the link itself
+	if err := svc.DeleteLink(ctx, "launch", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetLink(ctx, link.ShortCode); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected the link to be deleted, got %v", err)
+	}
+	if _, err := aliases.Get(ctx, "launch"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the alias to be removed with its link, got %v", err)
+	}
+}
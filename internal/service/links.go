@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// Links is the API the transports serve: the HTTP handler and the Lambda
+// router depend on it rather than on LinkService, so they can be tested
+// against fakes, and wrappers such as a read-only proxy can stand in for
+// the real service. Startup and maintenance jobs, which only the binaries
+// run, stay on LinkService.
+type Links interface {
+	CreateLink(ctx context.Context, req model.CreateLinkRequest) (*model.CreateLinkResponse, error)
+	GetLink(ctx context.Context, shortCode string) (*model.LinkDetails, error)
+	UpdateLink(ctx context.Context, shortCode string, patch LinkPatch, expectedVersion int64) (*model.LinkDetails, error)
+	DeleteLink(ctx context.Context, shortCode string, expectedVersion int64) error
+	RestoreLink(ctx context.Context, shortCode string) (*model.LinkDetails, error)
+	ListLinks(ctx context.Context, cursor, limit, pinned string) (*model.ListLinksResponse, error)
+	PublicLinks(ctx context.Context, cursor, limit string) (*model.DirectoryPage, error)
+	SitemapURLs(ctx context.Context) ([]model.SitemapURL, error)
+
+	ResolveRedirect(ctx context.Context, shortCode, rest string, metadata ClickMetadata) (*RedirectTarget, error)
+
+	GetStats(ctx context.Context, shortCode string) (*model.LinkStats, error)
+	GetStatsBatch(ctx context.Context, shortCodes []string) (*model.BatchStatsResponse, error)
+	ClickTimeseries(ctx context.Context, shortCode, from, to, tz string) (*model.ClickTimeseries, error)
+	RecountClicks(ctx context.Context, shortCode string) (*model.ClickRecount, error)
+	MergeLinks(ctx context.Context, duplicateCode, canonicalCode string) (*model.LinkMerge, error)
+
+	CheckAlias(ctx context.Context, alias string) (*model.AliasAvailability, error)
+	SuggestAliases(ctx context.Context, destination string, count int) ([]string, error)
+
+	// Prefixes, templates and thumbnails are optional; transports only
+	// expose their routes when enabled.
+	PrefixesEnabled() bool
+	CreatePrefix(ctx context.Context, req model.CreatePrefixRequest) (*model.Prefix, error)
+	ListPrefixes(ctx context.Context) ([]model.Prefix, error)
+	DeletePrefix(ctx context.Context, name string) error
+	TemplatesEnabled() bool
+	CreateTemplate(ctx context.Context, req model.CreateTemplateRequest) (*model.Template, error)
+	ListTemplates(ctx context.Context) ([]model.Template, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	CreateLinkFromTemplate(ctx context.Context, id string, req model.CreateFromTemplateRequest) (*model.CreateLinkResponse, error)
+	LinkAliasesEnabled() bool
+	AddLinkAlias(ctx context.Context, shortCode string, req model.AddLinkAliasRequest) (*model.LinkAlias, error)
+	ListLinkAliases(ctx context.Context, shortCode string) (*model.ListLinkAliasesResponse, error)
+	RemoveLinkAlias(ctx context.Context, shortCode, alias string) error
+	ThumbnailsEnabled() bool
+	Thumbnail(ctx context.Context, shortCode string) (*model.Thumbnail, error)
+	QRCode(ctx context.Context, shortCode, format, size, level string) (*model.QRCode, error)
+
+	// ReservePaths keeps generated codes and aliases off the transport's
+	// own routes.
+	ReservePaths(segments ...string)
+}
+
+var _ Links = (*LinkService)(nil)
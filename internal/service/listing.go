@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Page sizes for ListLinks and PublicLinks.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// pinnedCursor starts the ListLinks cursors that point into the pinned
+// links, which are listed first. restCursor starts the unpinned links
+// from the beginning, for a page that ended with the last pinned one.
+// Short codes can't contain a '.'.
+const (
+	pinnedCursor = "pinned."
+	restCursor   = "rest."
+)
+
+// ListLinks returns a page of links, starting after cursor, the
+// next_cursor of the previous page ("" for the first). Pinned links come
+// first, then the others. Within each, the order is the repository's: by
+// short code in memory and on disk, unordered in DynamoDB. pinned, when
+// "true" or "false", lists only pinned or unpinned links. limit is the
+// page size as given in the query string: empty means DefaultListLimit,
+// and larger values are capped at MaxListLimit. Deleted links are
+// skipped, and so are other owners' when ctx acts for one. A response
+// without a next cursor is the last page.
+func (s *LinkService) ListLinks(ctx context.Context, cursor, limit, pinned string) (*model.ListLinksResponse, error) {
+	size, err := pageSize(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := ownerOf(ctx)
+	list := s.linkRepo.List
+	if owner != "" {
+		list = func(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+			return s.linkRepo.ListByOwner(ctx, owner, cursor, limit)
+		}
+	}
+	listPinned := func(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+		return s.linkRepo.ListPinned(ctx, owner, cursor, limit)
+	}
+	live := func(link *model.Link) bool { return link.DeletedAt == nil }
+	unpinned := func(link *model.Link) bool { return link.DeletedAt == nil && !link.Pinned }
+
+	var links []*model.Link
+	var next string
+	switch pinned {
+	case "":
+		links, next, err = s.pinnedFirst(ctx, listPinned, list, cursor, size)
+	case "true":
+		links, next, err = s.pageLinks(ctx, listPinned, cursor, size, live)
+	case "false":
+		links, next, err = s.pageLinks(ctx, list, cursor, size, unpinned)
+	default:
+		return nil, validationError(map[string]string{"pinned": apierror.CodeInvalidRequest})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.ListLinksResponse{Links: make([]model.LinkSummary, len(links)), NextCursor: next}
+	for i, link := range links {
+		resp.Links[i] = model.LinkSummary{
+			ShortCode:   link.ShortCode,
+			ShortURL:    fmt.Sprintf("%s/%s", s.baseURL, link.ShortCode),
+			OriginalURL: link.OriginalURL,
+			ClickCount:  link.ClickCount,
+			CreatedAt:   link.CreatedAt,
+			Pinned:      link.Pinned,
+			Notes:       link.Notes,
+		}
+	}
+	return resp, nil
+}
+
+// pinnedFirst pages through the live links from listPinned and then the
+// unpinned ones from list, filling a page across the boundary. Cursors
+// into the pinned links carry pinnedCursor, so the next page knows which
+// list to go on with. Repository cursors are passed through untouched,
+// so they needn't be short codes.
+func (s *LinkService) pinnedFirst(ctx context.Context, listPinned, list listFunc, cursor string, size int) ([]*model.Link, string, error) {
+	var page []*model.Link
+	if cursor == restCursor {
+		cursor = ""
+	} else if rest, ok := strings.CutPrefix(cursor, pinnedCursor); ok || cursor == "" {
+		links, next, err := s.pageLinks(ctx, listPinned, rest, size, func(link *model.Link) bool {
+			return link.DeletedAt == nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		switch {
+		case next != "":
+			return links, pinnedCursor + next, nil
+		case len(links) == size:
+			// The page ends with the last pinned link; the next one
+			// starts on the others
+			return links, restCursor, nil
+		}
+		page, cursor = links, ""
+	}
+
+	links, next, err := s.pageLinks(ctx, list, cursor, size-len(page), func(link *model.Link) bool {
+		return link.DeletedAt == nil && !link.Pinned
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return append(page, links...), next, nil
+}
+
+// PublicLinks returns a page of the public directory: the links listed
+// publicly (see listedPublicly), paged as by ListLinks.
+func (s *LinkService) PublicLinks(ctx context.Context, cursor, limit string) (*model.DirectoryPage, error) {
+	size, err := pageSize(limit)
+	if err != nil {
+		return nil, err
+	}
+	links, next, err := s.pageLinks(ctx, s.linkRepo.List, cursor, size, listedPublicly)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.DirectoryPage{Links: make([]model.DirectoryEntry, len(links)), NextCursor: next}
+	for i, link := range links {
+		page.Links[i] = model.DirectoryEntry{
+			ShortCode:   link.ShortCode,
+			ShortURL:    fmt.Sprintf("%s/%s", s.baseURL, link.ShortCode),
+			OriginalURL: link.OriginalURL,
+			Title:       link.Title,
+			CreatedAt:   link.CreatedAt,
+		}
+	}
+	return page, nil
+}
+
+// listedPublicly reports whether a link belongs in listings meant for
+// anyone: it's marked public and currently redirects every visitor, so
+// deleted, disabled and referrer-locked links are left out.
+func listedPublicly(link *model.Link) bool {
+	return link.Public && link.DeletedAt == nil && !link.Disabled && len(link.AllowedReferrers) == 0
+}
+
+// listFunc reads a page of links, as repository.LinkRepository.List does.
+type listFunc func(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error)
+
+// pageSize parses limit, a page size as given in the query string: empty
+// means DefaultListLimit, and larger values are capped at MaxListLimit.
+func pageSize(limit string) (int, error) {
+	if limit == "" {
+		return DefaultListLimit, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return 0, validationError(map[string]string{"limit": apierror.CodeInvalidRequest})
+	}
+	return min(n, MaxListLimit), nil
+}
+
+// pageLinks reads links from list after cursor until it has a page of
+// size links passing keep, so filtered-out links don't leave pages short.
+// It returns the cursor for the next page, "" after the last.
+func (s *LinkService) pageLinks(ctx context.Context, list listFunc, cursor string, size int, keep func(*model.Link) bool) ([]*model.Link, string, error) {
+	var page []*model.Link
+	for {
+		links, next, err := list(ctx, cursor, size-len(page))
+		if err != nil {
+			return nil, "", fmt.Errorf("listing links: %w", err)
+		}
+		for _, link := range links {
+			if keep(link) {
+				page = append(page, link)
+			}
+		}
+		if next == "" || len(page) == size {
+			return page, next, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_PublicLinks(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	for _, req := range []model.CreateLinkRequest{
+		{URL: "https://example.com/a", CustomCode: "aaa", Public: true, Title: "First"},
+		{URL: "https://example.com/b", CustomCode: "bbb"},
+		{URL: "https://example.com/c", CustomCode: "ccc", Public: true, AllowedReferrers: []string{"intranet.example.com"}},
+		{URL: "https://example.com/d", CustomCode: "ddd", Public: true},
+	} {
+		if _, err := svc.CreateLink(ctx, req); err != nil {
+			t.Fatalf("failed to create %s: %v", req.CustomCode, err)
+		}
+	}
+
+	page, err := svc.PublicLinks(ctx, "", "2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Links) != 2 || page.Links[0].Title != "First" || page.Links[1].ShortCode != "ddd" {
+		t.Errorf("expected aaa and ddd, got %+v", page.Links)
+	}
+
+	long := strings.Repeat("x", MaxTitleLength+1)
+	if _, err := svc.UpdateLink(ctx, "aaa", LinkPatch{Title: &long}, 0); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected an overlong title to fail validation, got %v", err)
+	}
+}
+
+func TestLinkService_ListLinks(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	for _, code := range []string{"aaa", "bbb", "ccc", "ddd", "eee"} {
+		if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/" + code, CustomCode: code}); err != nil {
+			t.Fatalf("failed to create link: %v", err)
+		}
+	}
+	if err := svc.DeleteLink(ctx, "bbb", 0); err != nil {
+		t.Fatalf("failed to delete link: %v", err)
+	}
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages == 5 {
+			t.Fatal("paging didn't finish")
+		}
+		page, err := svc.ListLinks(ctx, cursor, "2", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Links) > 2 {
+			t.Errorf("expected at most 2 links per page, got %d", len(page.Links))
+		}
+		for _, link := range page.Links {
+			got = append(got, link.ShortCode)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	// The deleted link is skipped without leaving a short page
+	want := []string{"aaa", "ccc", "ddd", "eee"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	first, err := svc.ListLinks(ctx, "", "2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Links) != 2 || first.Links[1].ShortCode != "ccc" {
+		t.Errorf("expected the first page to skip the deleted link, got %+v", first.Links)
+	}
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		if _, err := svc.ListLinks(ctx, "", limit, ""); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+			t.Errorf("limit %q: expected a validation error, got %v", limit, err)
+		}
+	}
+}
+
+func TestLinkService_ListLinksPinned(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	for _, code := range []string{"aaa", "bbb", "ccc", "ddd", "eee"} {
+		if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/" + code, CustomCode: code, Notes: "note " + code}); err != nil {
+			t.Fatalf("failed to create link: %v", err)
+		}
+	}
+	pinned := true
+	for _, code := range []string{"ccc", "eee"} {
+		if _, err := svc.UpdateLink(ctx, code, LinkPatch{Pinned: &pinned}, 0); err != nil {
+			t.Fatalf("failed to pin %s: %v", code, err)
+		}
+	}
+
+	walk := func(limit, filter string) string {
+		var pages [][]string
+		cursor := ""
+		for len(pages) < 10 {
+			page, err := svc.ListLinks(ctx, cursor, limit, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var codes []string
+			for _, link := range page.Links {
+				codes = append(codes, link.ShortCode)
+			}
+			pages = append(pages, codes)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		return fmt.Sprint(pages)
+	}
+
+	// Pinned links come first, and pages fill across the boundary
+	tests := []struct {
+		limit, filter, want string
+	}{
+		{"2", "", "[[ccc eee] [aaa bbb] [ddd]]"},
+		{"3", "", "[[ccc eee aaa] [bbb ddd]]"},
+		{"4", "", "[[ccc eee aaa bbb] [ddd]]"},
+		{"2", "true", "[[ccc eee]]"},
+		{"2", "false", "[[aaa bbb] [ddd]]"},
+	}
+	for _, tt := range tests {
+		if got := walk(tt.limit, tt.filter); got != tt.want {
+			t.Errorf("limit %s, pinned %q: expected %s, got %s", tt.limit, tt.filter, tt.want, got)
+		}
+	}
+
+	page, err := svc.ListLinks(ctx, "", "1", "")
+	if err != nil || !page.Links[0].Pinned || page.Links[0].Notes != "note ccc" {
+		t.Errorf("expected pinned and notes in the summary, got %+v (%v)", page, err)
+	}
+	if _, err := svc.ListLinks(ctx, "", "", "yes"); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+		t.Errorf("expected a validation error for pinned=yes, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// ErrSweepUnsupported is returned by maintenance sweeps the configured
+// repositories can't run.
+var ErrSweepUnsupported = errors.New("sweep not supported by the configured repositories")
+
+// PurgeClicks deletes click events older than retention and returns how
+// many were removed. Click counts and rollups are kept, so totals and
+// daily stats still cover purged clicks when rollups are configured.
+func (s *LinkService) PurgeClicks(ctx context.Context, retention time.Duration) (int, error) {
+	sweeper, ok := s.clickRepo.(repository.ClickSweeper)
+	if !ok {
+		return 0, ErrSweepUnsupported
+	}
+
+	cutoff := s.now().Add(-retention)
+	removed, err := sweeper.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		return removed, fmt.Errorf("purging clicks: %w", err)
+	}
+	return removed, nil
+}
+
+// RebuildRollups recomputes every link's rollup for the UTC day containing
+// day from raw click events and returns how many links were updated.
+// Rollups are updated best-effort as clicks are recorded; rebuilding a
+// finished day corrects counts lost to failed updates. Rebuilding the
+// current day would race with clicks still arriving.
+func (s *LinkService) RebuildRollups(ctx context.Context, day time.Time) (int, error) {
+	sweeper, ok := s.clickRepo.(repository.ClickSweeper)
+	if s.rollups == nil || !ok {
+		return 0, ErrSweepUnsupported
+	}
+
+	start := day.UTC().Truncate(24 * time.Hour)
+	clicks, err := sweeper.GetBetween(ctx, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, fmt.Errorf("fetching clicks: %w", err)
+	}
+
+	date := start.Format(dayLayout)
+	byLink := make(map[string]*model.DailyClicks)
+	for _, click := range clicks {
+		if !s.counts(&click) {
+			continue
+		}
+		rollup, ok := byLink[click.LinkID]
+		if !ok {
+			rollup = &model.DailyClicks{Date: date, BySource: make(map[string]int64)}
+			byLink[click.LinkID] = rollup
+		}
+		rollup.Clicks++
+		rollup.BySource[ClickSource(click.Source)]++
+	}
+
+	updated := 0
+	for linkID, rollup := range byLink {
+		if err := s.rollups.SetDay(ctx, linkID, *rollup); err != nil {
+			return updated, fmt.Errorf("writing rollup for link %s: %w", linkID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// RecountClicks recomputes a link's click count from its stored click
+// events, leaving out uncounted clicks, and writes the correction back, for
+// drift after outages or migrations. The count is adjusted by the difference rather than
+// overwritten, so clicks arriving during the recount aren't lost. Only
+// stored events are counted: with a click store that doesn't keep events,
+// or after a retention purge, the recount would undercount.
+func (s *LinkService) RecountClicks(ctx context.Context, shortCode string) (*model.ClickRecount, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	clicks, err := s.clickRepo.GetByLinkID(ctx, link.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetching clicks: %w", err)
+	}
+
+	recount := &model.ClickRecount{
+		ShortCode:          link.ShortCode,
+		PreviousClickCount: link.ClickCount,
+	}
+	for _, click := range clicks {
+		if s.counts(&click) {
+			recount.ClickCount++
+		}
+	}
+	if delta := recount.ClickCount - link.ClickCount; delta != 0 {
+		if err := s.linkRepo.AddClickCount(ctx, link.ShortCode, delta); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrLinkNotFound
+			}
+			return nil, fmt.Errorf("correcting click count: %w", err)
+		}
+		s.logger.InfoContext(ctx, "recounted clicks", "short_code", link.ShortCode, "from", link.ClickCount, "to", recount.ClickCount)
+	}
+	return recount, nil
+}
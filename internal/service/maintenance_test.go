@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_PurgeClicks(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	svc := NewLinkService(linkRepo, clickRepo, DefaultConfig())
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for i, age := range []time.Duration{time.Hour, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+		clickRepo.Record(ctx, &model.ClickEvent{ID: string(rune('a' + i)), LinkID: "link-1", ClickedAt: now.Add(-age)})
+	}
+
+	removed, err := svc.PurgeClicks(ctx, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 click removed, got %d", removed)
+	}
+
+	remaining, _ := clickRepo.GetByLinkID(ctx, "link-1", 0)
+	if len(remaining) != 2 {
+		t.Errorf("expected 2 clicks left, got %d", len(remaining))
+	}
+}
+
+func TestLinkService_RebuildRollups(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	rollups := repository.NewMemoryStatsRollupRepository()
+	config := DefaultConfig()
+	config.Rollups = rollups
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	clicks := []model.ClickEvent{
+		{ID: "1", LinkID: "link-1", ClickedAt: day.Add(time.Hour), Source: model.ClickSourceQR},
+		{ID: "2", LinkID: "link-1", ClickedAt: day.Add(23 * time.Hour)},
+		{ID: "3", LinkID: "link-2", ClickedAt: day.Add(2 * time.Hour)},
+		{ID: "4", LinkID: "link-1", ClickedAt: day.AddDate(0, 0, 1)}, // next day
+	}
+	for _, click := range clicks {
+		clickRepo.Record(ctx, &click)
+	}
+	// A rollup that missed a click
+	rollups.AddClick(ctx, "link-1", "2024-06-01", model.ClickSourceQR)
+
+	updated, err := svc.RebuildRollups(ctx, day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("expected 2 links updated, got %d", updated)
+	}
+
+	days, _ := rollups.GetRange(ctx, "link-1", "2024-06-01", "2024-06-02")
+	if len(days) != 1 {
+		t.Fatalf("expected 1 rollup day, got %d", len(days))
+	}
+	if days[0].Clicks != 2 || days[0].BySource[model.ClickSourceQR] != 1 || days[0].BySource[model.ClickSourceLink] != 1 {
+		t.Errorf("unexpected rollup %+v", days[0])
+	}
+}
+
+func TestLinkService_RebuildRollups_Unsupported(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+
+	if _, err := svc.RebuildRollups(context.Background(), time.Now()); !errors.Is(err, ErrSweepUnsupported) {
+		t.Errorf("expected ErrSweepUnsupported without rollups, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ErrMergeUnsupported is returned by MergeLinks when the service has no
+// alias store to keep the duplicate's code in.
+var ErrMergeUnsupported = errors.New("merging links requires link aliases")
+
+// MergeLinks folds the duplicate link at duplicateCode into the canonical
+// link at canonicalCode: the duplicate's click events, daily rollups and
+// click count move to the canonical link, its code and aliases become
+// aliases of the canonical link, and the duplicate is deleted. Existing
+// short URLs keep working and reporting covers both links' history.
+//
+// The steps aren't atomic. Clicks on the duplicate while it runs may be
+// left out of the canonical link's count, and a failure part way leaves
+// the history split between the two; the duplicate is only deleted once
+// everything else has moved.
+func (s *LinkService) MergeLinks(ctx context.Context, duplicateCode, canonicalCode string) (*model.LinkMerge, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+	if s.aliases == nil {
+		return nil, ErrMergeUnsupported
+	}
+	if canonicalCode == "" {
+		return nil, validationError(map[string]string{"into": apierror.CodeShortCodeRequired})
+	}
+
+	duplicate, err := s.findLink(ctx, duplicateCode)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := s.findLink(ctx, canonicalCode)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate.ID == canonical.ID {
+		return nil, validationError(map[string]string{"into": apierror.CodeInvalidRequest})
+	}
+
+	merge := &model.LinkMerge{
+		ShortCode:  canonical.ShortCode,
+		MergedCode: duplicate.ShortCode,
+	}
+
+	// Events keep their IDs and times; only the link changes
+	events, err := s.clickRepo.GetByLinkID(ctx, duplicate.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetching clicks: %w", err)
+	}
+	for _, event := range events {
+		event.LinkID = canonical.ID
+		if err := s.clickRepo.Record(ctx, &event); err != nil {
+			return nil, fmt.Errorf("moving click %s: %w", event.ID, err)
+		}
+		merge.EventsMoved++
+	}
+
+	if err := s.mergeRollups(ctx, duplicate, canonical); err != nil {
+		return nil, err
+	}
+
+	if duplicate.ClickCount != 0 {
+		if err := s.linkRepo.AddClickCount(ctx, canonical.ShortCode, duplicate.ClickCount); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrLinkNotFound
+			}
+			return nil, fmt.Errorf("adding click count: %w", err)
+		}
+	}
+	merge.ClickCount = canonical.ClickCount + duplicate.ClickCount
+
+	// The duplicate's own aliases now point at the canonical link
+	aliases, err := s.aliases.ListByShortCode(ctx, duplicate.ShortCode)
+	if err != nil {
+		return nil, fmt.Errorf("listing aliases: %w", err)
+	}
+	for _, alias := range aliases {
+		if err := s.aliases.Delete(ctx, alias.Alias); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("moving alias %s: %w", alias.Alias, err)
+		}
+		alias.ShortCode = canonical.ShortCode
+		if err := s.aliases.Create(ctx, &alias); err != nil {
+			return nil, fmt.Errorf("moving alias %s: %w", alias.Alias, err)
+		}
+		merge.AliasesMoved++
+	}
+
+	// Its code resolves through the new alias once the link is gone
+	err = s.aliases.Create(ctx, &model.LinkAlias{
+		Alias:     duplicate.ShortCode,
+		ShortCode: canonical.ShortCode,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aliasing %s: %w", duplicate.ShortCode, err)
+	}
+	if err := s.linkRepo.Delete(ctx, duplicate.ShortCode, 0); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("deleting merged link: %w", err)
+	}
+	s.deleteThumbnail(ctx, duplicate.ID)
+
+	s.logger.InfoContext(ctx, "merged links",
+		"short_code", canonical.ShortCode,
+		"merged_code", duplicate.ShortCode,
+		"events_moved", merge.EventsMoved,
+	)
+	return merge, nil
+}
+
+// mergeRollups adds the duplicate's daily click aggregates to the
+// canonical link's.
+func (s *LinkService) mergeRollups(ctx context.Context, duplicate, canonical *model.Link) error {
+	if s.rollups == nil {
+		return nil
+	}
+
+	from, to := duplicate.CreatedAt.UTC().Format(dayLayout), s.now().Format(dayLayout)
+	days, err := s.rollups.GetRange(ctx, duplicate.ID, from, to)
+	if err != nil {
+		return fmt.Errorf("fetching rollups: %w", err)
+	}
+	for _, day := range days {
+		existing, err := s.rollups.GetRange(ctx, canonical.ID, day.Date, day.Date)
+		if err != nil {
+			return fmt.Errorf("fetching rollups: %w", err)
+		}
+		merged := model.DailyClicks{Date: day.Date, BySource: make(map[string]int64)}
+		for _, rollup := range append(existing, day) {
+			merged.Clicks += rollup.Clicks
+			for source, clicks := range rollup.BySource {
+				merged.BySource[source] += clicks
+			}
+		}
+		if err := s.rollups.SetDay(ctx, canonical.ID, merged); err != nil {
+			return fmt.Errorf("merging rollup for %s: %w", day.Date, err)
+		}
+	}
+	return nil
+}
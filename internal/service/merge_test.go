@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_MergeLinks(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	rollups := repository.NewMemoryStatsRollupRepository()
+	config := DefaultConfig()
+	config.Aliases = repository.NewMemoryLinkAliasRepository()
+	config.Rollups = rollups
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(linkRepo, clickRepo, config)
+	ctx := context.Background()
+
+	for _, code := range []string{"canonical", "duplicate"} {
+		if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", CustomCode: code}); err != nil {
+			t.Fatalf("failed to create link: %v", err)
+		}
+	}
+	if _, err := svc.AddLinkAlias(ctx, "duplicate", model.AddLinkAliasRequest{Alias: "dupe-alias"}); err != nil {
+		t.Fatalf("failed to add alias: %v", err)
+	}
+	svc.Redirect(ctx, "canonical", ClickMetadata{})
+	svc.Redirect(ctx, "duplicate", ClickMetadata{})
+	svc.Redirect(ctx, "duplicate", ClickMetadata{Source: model.ClickSourceQR})
+
+	merge, err := svc.MergeLinks(ctx, "duplicate", "canonical")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := model.LinkMerge{ShortCode: "canonical", MergedCode: "duplicate", ClickCount: 3, EventsMoved: 2, AliasesMoved: 1}
+	if *merge != want {
+		t.Errorf("expected %+v, got %+v", want, *merge)
+	}
+
+	stats, err := svc.GetStats(ctx, "canonical")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.ClickCount != 3 || stats.ClicksBySource[model.ClickSourceLink] != 2 || stats.ClicksBySource[model.ClickSourceQR] != 1 {
+		t.Errorf("expected merged stats, got %+v", stats)
+	}
+	canonical, _ := linkRepo.GetByShortCode(ctx, "canonical")
+	if events, _ := clickRepo.GetByLinkID(ctx, canonical.ID, 0); len(events) != 3 {
+		t.Errorf("expected 3 click events on the canonical link, got %d", len(events))
+	}
+
+	// Both of the duplicate's codes now lead to the canonical link
+	for _, code := range []string{"duplicate", "dupe-alias"} {
+		link, err := svc.GetLink(ctx, code)
+		if err != nil || link.ShortCode != "canonical" {
+			t.Errorf("expected %s to resolve to the canonical link, got %+v, %v", code, link, err)
+		}
+	}
+	if _, err := linkRepo.GetByShortCode(ctx, "duplicate"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the duplicate link to be deleted, got %v", err)
+	}
+
+	// A merged code is an alias now, so merging it again is a self-merge
+	_, err = svc.MergeLinks(ctx, "duplicate", "canonical")
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Fields["into"] != apierror.CodeInvalidRequest {
+		t.Errorf("expected a validation error on into, got %v", err)
+	}
+	if _, err := svc.MergeLinks(ctx, "missing", "canonical"); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected ErrLinkNotFound, got %v", err)
+	}
+}
+
+func TestLinkService_MergeLinks_RequiresAliases(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	if _, err := svc.MergeLinks(context.Background(), "a", "b"); !errors.Is(err, ErrMergeUnsupported) {
+		t.Errorf("expected ErrMergeUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/model"
+)
+
+// DefaultMilestones are the click counts links are notified about when no
+// milestones are configured.
+var DefaultMilestones = []int64{100, 1000, 10000}
+
+// milestoneSet indexes milestones for lookup on every click.
+func milestoneSet(milestones []int64) map[int64]bool {
+	if milestones == nil {
+		milestones = DefaultMilestones
+	}
+	set := make(map[int64]bool, len(milestones))
+	for _, m := range milestones {
+		if m > 0 {
+			set[m] = true
+		}
+	}
+	return set
+}
+
+// checkMilestone publishes a TypeMilestoneReached event when count, the
+// click count just written, is a milestone. Counts come from atomic
+// increments, so exactly one click reaches each milestone even when
+// clicks race. A milestone skipped by a recount isn't reported.
+func (s *LinkService) checkMilestone(link *model.Link, count int64, at time.Time) {
+	if !link.NotifyMilestones || !s.milestones[count] {
+		return
+	}
+	snapshot := *link
+	snapshot.ClickCount = count
+	s.events.Publish(events.Event{
+		Type:      events.TypeMilestoneReached,
+		Timestamp: at,
+		ShortCode: link.ShortCode,
+		Link:      &snapshot,
+		Milestone: count,
+	})
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_MilestoneEvents(t *testing.T) {
+	bus := events.NewBus()
+	reached, cancel := bus.Subscribe(0, func(e events.Event) bool {
+		return e.Type == events.TypeMilestoneReached
+	})
+	defer cancel()
+
+	config := DefaultConfig()
+	config.Events = bus
+	config.Milestones = []int64{2, 3}
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	notified, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/a", NotifyMilestones: true})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	quiet, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/b"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		svc.Redirect(ctx, notified.ShortCode, ClickMetadata{})
+		svc.Redirect(ctx, quiet.ShortCode, ClickMetadata{})
+	}
+
+	for _, want := range []int64{2, 3} {
+		select {
+		case e := <-reached:
+			if e.ShortCode != notified.ShortCode || e.Milestone != want || e.Link.ClickCount != want {
+				t.Errorf("expected milestone %d for %s, got %d for %s", want, notified.ShortCode, e.Milestone, e.ShortCode)
+			}
+		default:
+			t.Fatalf("expected milestone %d to be published", want)
+		}
+	}
+	select {
+	case e := <-reached:
+		t.Errorf("unexpected milestone event %+v", e)
+	default:
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// maxMisses caps how many codes a missCache holds, so a scan trying
+// millions of random codes can't grow it without bound. Codes beyond it
+// simply aren't cached.
+const maxMisses = 100_000
+
+// missCache remembers codes that recently resolved to no link, so
+// scanners probing random codes are answered without a storage read.
+// Creating or restoring a link forgets its code. State is per process: a
+// link created on another replica or Lambda execution environment can
+// keep 404ing here until the entry expires.
+type missCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	misses    map[string]time.Time // when each miss expires
+	lastPrune time.Time
+}
+
+func newMissCache(ttl time.Duration) *missCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &missCache{ttl: ttl, misses: make(map[string]time.Time)}
+}
+
+// missed reports whether code is cached as not found at now. A nil cache
+// never is.
+func (c *missCache) missed(code string, now time.Time) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires, ok := c.misses[code]
+	return ok && now.Before(expires)
+}
+
+// add caches code as not found until the TTL after now.
+func (c *missCache) add(code string, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Expired entries are dropped once per TTL to bound memory
+	if now.Sub(c.lastPrune) >= c.ttl {
+		for k, expires := range c.misses {
+			if !now.Before(expires) {
+				delete(c.misses, k)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if len(c.misses) < maxMisses {
+		c.misses[code] = now.Add(c.ttl)
+	}
+}
+
+// forget drops code from the cache, for a link that now exists.
+func (c *missCache) forget(code string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.misses, code)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// countingLinkRepository counts GetByShortCode and List calls.
+type countingLinkRepository struct {
+	*repository.MemoryLinkRepository
+	reads int
+	lists int
+}
+
+func (r *countingLinkRepository) List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+	r.lists++
+	return r.MemoryLinkRepository.List(ctx, cursor, limit)
+}
+
+func (r *countingLinkRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.Link, error) {
+	r.reads++
+	return r.MemoryLinkRepository.GetByShortCode(ctx, shortCode)
+}
+
+func TestLinkService_NotFoundCache(t *testing.T) {
+	linkRepo := &countingLinkRepository{MemoryLinkRepository: repository.NewMemoryLinkRepository()}
+	config := DefaultConfig()
+	config.NotFoundCacheTTL = time.Minute
+	config.DeleteGracePeriod = time.Hour
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := svc.ResolveRedirect(ctx, "nope123", "", ClickMetadata{}); !errors.Is(err, ErrLinkNotFound) {
+			t.Fatalf("expected ErrLinkNotFound, got %v", err)
+		}
+	}
+	if _, err := svc.GetStats(ctx, "nope123"); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("expected ErrLinkNotFound, got %v", err)
+	}
+	if linkRepo.reads != 1 {
+		t.Errorf("expected 1 repository read, got %d", linkRepo.reads)
+	}
+
+	// A deleted link is cached as missing until it's restored
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 0); err != nil {
+		t.Fatalf("failed to delete link: %v", err)
+	}
+	if _, err := svc.GetStats(ctx, resp.ShortCode); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("expected ErrLinkNotFound, got %v", err)
+	}
+	if _, err := svc.RestoreLink(ctx, resp.ShortCode); err != nil {
+		t.Fatalf("failed to restore link: %v", err)
+	}
+	if _, err := svc.GetStats(ctx, resp.ShortCode); err != nil {
+		t.Errorf("expected the restored link to resolve, got %v", err)
+	}
+}
+
+func TestMissCache_Expires(t *testing.T) {
+	cache := newMissCache(time.Minute)
+	now := time.Now()
+
+	cache.add("abc123", now)
+	if !cache.missed("abc123", now.Add(59*time.Second)) {
+		t.Error("expected a miss within the TTL")
+	}
+	if cache.missed("abc123", now.Add(time.Minute)) {
+		t.Error("expected the miss to expire after the TTL")
+	}
+
+	cache.add("abc123", now)
+	cache.forget("abc123")
+	if cache.missed("abc123", now) {
+		t.Error("expected a forgotten code not to be cached")
+	}
+
+	var disabled *missCache
+	disabled.add("abc123", now)
+	if disabled.missed("abc123", now) {
+		t.Error("expected a nil cache to never report misses")
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"github.com/colby/snip/internal/model"
+)
+
+type ownerContextKey struct{}
+
+// WithOwner returns ctx acting for the user with the given ID. Links,
+// prefixes and templates created with it are theirs, and the management
+// operations (get, list, update, delete, restore, stats and the link's
+// aliases, thumbnail and QR code, and listing, deleting and using
+// prefixes and templates) only see those they own. Without an owner, as
+// for API keys or an open API, everything is visible. Redirects ignore
+// owners.
+func WithOwner(ctx context.Context, ownerID string) context.Context {
+	return context.WithValue(ctx, ownerContextKey{}, ownerID)
+}
+
+// ownerOf returns the ID of the owner ctx acts for, or "".
+func ownerOf(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerContextKey{}).(string)
+	return owner
+}
+
+// owns reports whether the caller in ctx may manage something owned by
+// ownerID.
+func owns(ctx context.Context, ownerID string) bool {
+	owner := ownerOf(ctx)
+	return owner == "" || ownerID == owner
+}
+
+// ownsLink reports whether the caller in ctx may manage link.
+func ownsLink(ctx context.Context, link *model.Link) bool {
+	return owns(ctx, link.OwnerID)
+}
+
+// ownedLink is findLink limited to links the caller in ctx may manage.
+// Other owners' links are reported as ErrLinkNotFound, so their codes
+// don't leak.
+func (s *LinkService) ownedLink(ctx context.Context, shortCode string) (*model.Link, error) {
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ownsLink(ctx, link) {
+		return nil, ErrLinkNotFound
+	}
+	return link, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_Owners(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ada := WithOwner(context.Background(), "ada")
+	grace := WithOwner(context.Background(), "grace")
+
+	link, err := svc.CreateLink(ada, model.CreateLinkRequest{URL: "https://example.com/page", CustomCode: "adas"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if _, err := svc.CreateLink(grace, model.CreateLinkRequest{URL: "https://example.com/other", CustomCode: "graces"}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	details, err := svc.GetLink(ada, link.ShortCode)
+	if err != nil || details.OwnerID != "ada" {
+		t.Fatalf("expected ada's link, got %+v (%v)", details, err)
+	}
+
+	// Another owner's link is reported missing, whatever the operation
+	url := "https://example.com/changed"
+	if _, err := svc.GetLink(grace, link.ShortCode); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("get: expected ErrLinkNotFound, got %v", err)
+	}
+	if _, err := svc.GetStats(grace, link.ShortCode); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("stats: expected ErrLinkNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateLink(grace, link.ShortCode, LinkPatch{URL: &url}, 0); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("update: expected ErrLinkNotFound, got %v", err)
+	}
+	if err := svc.DeleteLink(grace, link.ShortCode, 0); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("delete: expected ErrLinkNotFound, got %v", err)
+	}
+	batch, err := svc.GetStatsBatch(grace, []string{link.ShortCode})
+	if err != nil || len(batch.Stats) != 0 || len(batch.NotFound) != 1 {
+		t.Errorf("expected ada's link among grace's not found, got %+v (%v)", batch, err)
+	}
+
+	list, err := svc.ListLinks(grace, "", "", "")
+	if err != nil || len(list.Links) != 1 || list.Links[0].ShortCode != "graces" {
+		t.Errorf("expected only grace's link, got %+v (%v)", list, err)
+	}
+	list, err = svc.ListLinks(context.Background(), "", "", "")
+	if err != nil || len(list.Links) != 2 {
+		t.Errorf("expected both links without an owner, got %+v (%v)", list, err)
+	}
+
+	// Dedupe only reuses the caller's own links
+	resp, err := svc.CreateLink(grace, model.CreateLinkRequest{URL: "https://example.com/page", Dedupe: true})
+	if err != nil || resp.Existing {
+		t.Errorf("expected a new link for grace, got %+v (%v)", resp, err)
+	}
+
+	if err := svc.DeleteLink(ada, link.ShortCode, 0); err != nil {
+		t.Errorf("expected ada to delete the link, got %v", err)
+	}
+}
+
+func TestLinkService_OwnerIdempotencyKeys(t *testing.T) {
+	config := DefaultConfig()
+	config.Idempotency = repository.NewMemoryIdempotencyRepository()
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+
+	req := model.CreateLinkRequest{URL: "https://example.com/page", IdempotencyKey: "retry-1"}
+	first, err := svc.CreateLink(WithOwner(context.Background(), "ada"), req)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	second, err := svc.CreateLink(WithOwner(context.Background(), "grace"), req)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if second.ShortCode == first.ShortCode {
+		t.Errorf("expected grace's key not to replay ada's create, got %s twice", first.ShortCode)
+	}
+}
+
+func TestLinkService_OwnerPrefixesAndTemplates(t *testing.T) {
+	config := DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	config.Templates = repository.NewMemoryTemplateRepository()
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ada := WithOwner(context.Background(), "ada")
+	grace := WithOwner(context.Background(), "grace")
+
+	if _, err := svc.CreatePrefix(ada, model.CreatePrefixRequest{Prefix: "eng"}); err != nil {
+		t.Fatalf("failed to create prefix: %v", err)
+	}
+	if _, err := svc.CreateTemplate(ada, model.CreateTemplateRequest{ID: "product", URL: "https://example.com/p/{sku}", Prefix: "eng"}); err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+
+	if prefixes, err := svc.ListPrefixes(grace); err != nil || len(prefixes) != 0 {
+		t.Errorf("expected no prefixes for grace, got %+v (%v)", prefixes, err)
+	}
+	if templates, err := svc.ListTemplates(grace); err != nil || len(templates) != 0 {
+		t.Errorf("expected no templates for grace, got %+v (%v)", templates, err)
+	}
+	if err := svc.DeletePrefix(grace, "eng"); !errors.Is(err, ErrPrefixNotFound) {
+		t.Errorf("delete prefix: expected ErrPrefixNotFound, got %v", err)
+	}
+	if err := svc.DeleteTemplate(grace, "product"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("delete template: expected ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := svc.CreateLinkFromTemplate(grace, "product", model.CreateFromTemplateRequest{Params: map[string]string{"sku": "1"}}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("create from template: expected ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := svc.CreateLink(grace, model.CreateLinkRequest{URL: "https://example.com", Prefix: "eng"}); err == nil {
+		t.Error("expected grace not to create links under ada's prefix")
+	}
+
+	if prefixes, err := svc.ListPrefixes(context.Background()); err != nil || len(prefixes) != 1 {
+		t.Errorf("expected the prefix without an owner, got %+v (%v)", prefixes, err)
+	}
+	if _, err := svc.CreateLinkFromTemplate(ada, "product", model.CreateFromTemplateRequest{Params: map[string]string{"sku": "1"}}); err != nil {
+		t.Errorf("expected ada to use the template, got %v", err)
+	}
+	if err := svc.DeleteTemplate(ada, "product"); err != nil {
+		t.Errorf("expected ada to delete the template, got %v", err)
+	}
+	if err := svc.DeletePrefix(ada, "eng"); err != nil {
+		t.Errorf("expected ada to delete the prefix, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// LinkPatch describes a partial update to a link. Nil fields are left
+// unchanged.
+type LinkPatch struct {
+	URL    *string
+	Pinned *bool
+	Notes  *string // null in the patch clears notes, leaving ""
+
+	Disabled *bool // re-enable a link after reviewing a flagged scan, or disable it by hand
+
+	Interstitial *bool
+
+	NotifyMilestones *bool
+
+	AllowedReferrers *[]string // null or [] in the patch unlocks the link
+
+	Public *bool
+	Title  *string // null in the patch clears the title
+}
+
+// immutableLinkFields are link fields clients can see but not patch.
+var immutableLinkFields = map[string]bool{
+	"id":           true,
+	"short_code":   true,
+	"click_count":  true,
+	"created_at":   true,
+	"wildcard":     true,
+	"scan_status":  true,
+	"original_url": true, // patched via "url", matching CreateLinkRequest
+}
+
+// ParseLinkPatch decodes an RFC 7386 JSON Merge Patch document into a
+// LinkPatch. Members that are absent are left unchanged. null clears
+// "notes", "title" and "allowed_referrers"; the other members can't be
+// removed, and null for them is reported as invalid. Every offending
+// member is reported in the returned validation error, not just the
+// first.
+func ParseLinkPatch(data []byte) (LinkPatch, error) {
+	var patch LinkPatch
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		return patch, apierror.New(apierror.CodeInvalidRequest, "merge patch must be a JSON object")
+	}
+
+	fields := make(map[string]string)
+	for name, raw := range members {
+		switch {
+		case name == "url":
+			if isJSONNull(raw) {
+				fields[name] = apierror.CodeURLRequired
+				continue
+			}
+			var u string
+			if err := json.Unmarshal(raw, &u); err != nil {
+				fields[name] = apierror.CodeInvalidURL
+				continue
+			}
+			patch.URL = &u
+		case name == "pinned":
+			var pinned bool
+			if isJSONNull(raw) || json.Unmarshal(raw, &pinned) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Pinned = &pinned
+		case name == "disabled":
+			var disabled bool
+			if isJSONNull(raw) || json.Unmarshal(raw, &disabled) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Disabled = &disabled
+		case name == "interstitial":
+			var interstitial bool
+			if isJSONNull(raw) || json.Unmarshal(raw, &interstitial) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Interstitial = &interstitial
+		case name == "notify_milestones":
+			var notify bool
+			if isJSONNull(raw) || json.Unmarshal(raw, &notify) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.NotifyMilestones = &notify
+		case name == "public":
+			var public bool
+			if isJSONNull(raw) || json.Unmarshal(raw, &public) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Public = &public
+		case name == "allowed_referrers":
+			var hosts []string
+			if !isJSONNull(raw) && json.Unmarshal(raw, &hosts) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.AllowedReferrers = &hosts
+		case name == "notes":
+			var notes string
+			if !isJSONNull(raw) && json.Unmarshal(raw, &notes) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Notes = &notes
+		case name == "title":
+			var title string
+			if !isJSONNull(raw) && json.Unmarshal(raw, &title) != nil {
+				fields[name] = apierror.CodeInvalidRequest
+				continue
+			}
+			patch.Title = &title
+		case immutableLinkFields[name]:
+			fields[name] = apierror.CodeImmutableField
+		default:
+			fields[name] = apierror.CodeUnknownField
+		}
+	}
+
+	if len(fields) > 0 {
+		return patch, validationError(fields)
+	}
+	return patch, nil
+}
+
+// validationError builds a validation failure listing offending fields.
+func validationError(fields map[string]string) *apierror.Error {
+	err := apierror.New(apierror.CodeValidationFailed, "one or more fields are invalid")
+	err.Fields = fields
+	return err
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
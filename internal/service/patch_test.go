@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestParseLinkPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantURL    string
+		wantCode   string
+		wantFields map[string]string
+	}{
+		{
+			name:    "url",
+			body:    `{"url": "https://example.com/new"}`,
+			wantURL: "https://example.com/new",
+		},
+		{
+			name: "empty patch",
+			body: `{}`,
+		},
+		{
+			name:       "bad pinned",
+			body:       `{"pinned": "yes"}`,
+			wantCode:   apierror.CodeValidationFailed,
+			wantFields: map[string]string{"pinned": apierror.CodeInvalidRequest},
+		},
+		{
+			name:       "bad title",
+			body:       `{"title": 7}`,
+			wantCode:   apierror.CodeValidationFailed,
+			wantFields: map[string]string{"title": apierror.CodeInvalidRequest},
+		},
+		{
+			name:     "not an object",
+			body:     `["url"]`,
+			wantCode: apierror.CodeInvalidRequest,
+		},
+		{
+			name:     "null document",
+			body:     `null`,
+			wantCode: apierror.CodeInvalidRequest,
+		},
+		{
+			name:       "null url",
+			body:       `{"url": null}`,
+			wantCode:   apierror.CodeValidationFailed,
+			wantFields: map[string]string{"url": apierror.CodeURLRequired},
+		},
+		{
+			name:     "every bad field reported",
+			body:     `{"url": 42, "short_code": "abc", "color": "red"}`,
+			wantCode: apierror.CodeValidationFailed,
+			wantFields: map[string]string{
+				"url":        apierror.CodeInvalidURL,
+				"short_code": apierror.CodeImmutableField,
+				"color":      apierror.CodeUnknownField,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := ParseLinkPatch([]byte(tt.body))
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got := ""
+				if patch.URL != nil {
+					got = *patch.URL
+				}
+				if got != tt.wantURL {
+					t.Errorf("expected url %q, got %q", tt.wantURL, got)
+				}
+				return
+			}
+
+			var apiErr *apierror.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected apierror, got %v", err)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("expected code %s, got %s", tt.wantCode, apiErr.Code)
+			}
+			if len(apiErr.Fields) != len(tt.wantFields) {
+				t.Fatalf("expected fields %v, got %v", tt.wantFields, apiErr.Fields)
+			}
+			for field, code := range tt.wantFields {
+				if apiErr.Fields[field] != code {
+					t.Errorf("expected %s for %s, got %s", code, field, apiErr.Fields[field])
+				}
+			}
+		})
+	}
+}
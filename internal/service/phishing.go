@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/colby/snip/internal/phishing"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ErrSuspiciousURL is returned for destinations whose phishing score
+// reaches the block threshold.
+var ErrSuspiciousURL = apierror.New(apierror.CodeSuspiciousURL, "destination looks like a phishing link")
+
+// checkPhishing scores a destination with the built-in phishing
+// heuristics. It returns ErrSuspiciousURL at or above the block threshold,
+// and the matched signals as warnings at or above the warn threshold.
+func (s *LinkService) checkPhishing(ctx context.Context, destination string) ([]string, error) {
+	if s.phishingWarnScore <= 0 && s.phishingBlockScore <= 0 {
+		return nil, nil
+	}
+
+	assessment := phishing.Assess(destination)
+	if assessment.Score == 0 {
+		return nil, nil
+	}
+	if s.phishingBlockScore > 0 && assessment.Score >= s.phishingBlockScore {
+		s.logger.WarnContext(ctx, "blocked suspicious destination",
+			"url", destination, "score", assessment.Score, "signals", assessment.Signals)
+		return nil, ErrSuspiciousURL
+	}
+	if s.phishingWarnScore <= 0 || assessment.Score < s.phishingWarnScore {
+		return nil, nil
+	}
+
+	s.logger.WarnContext(ctx, "suspicious destination",
+		"url", destination, "score", assessment.Score, "signals", assessment.Signals)
+	warnings := make([]string, len(assessment.Signals))
+	for i, signal := range assessment.Signals {
+		warnings[i] = string(signal)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_PhishingThresholds(t *testing.T) {
+	config := DefaultConfig()
+	config.PhishingWarnScore = 3
+	config.PhishingBlockScore = 6
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/?pwd=x"})
+	if err != nil {
+		t.Fatalf("unexpected error below the warn threshold: %v", err)
+	}
+	if resp.Warnings != nil {
+		t.Errorf("expected no warnings, got %v", resp.Warnings)
+	}
+
+	resp, err = svc.CreateLink(ctx, model.CreateLinkRequest{URL: "http://10.0.0.1/"})
+	if err != nil {
+		t.Fatalf("unexpected error below the block threshold: %v", err)
+	}
+	if want := []string{"ip_literal_host"}; !reflect.DeepEqual(resp.Warnings, want) {
+		t.Errorf("expected warnings %v, got %v", want, resp.Warnings)
+	}
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "http://10.0.0.1/?password=x#data:text/html,hi"}); err != ErrSuspiciousURL {
+		t.Errorf("expected ErrSuspiciousURL, got %v", err)
+	}
+
+	suspicious := "https://bank.example@xn--pypal-4ve.com/"
+	_, err = svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &suspicious}, 0)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Fields["url"] != apierror.CodeSuspiciousURL {
+		t.Errorf("expected a suspicious_url field error, got %v", err)
+	}
+}
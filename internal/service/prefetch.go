@@ -0,0 +1,70 @@
+package service
+
+import "github.com/colby/snip/internal/model"
+
+// DefaultPrefetchAgents are user agent fragments, lowercase, of link
+// preview fetchers in chat apps and mail clients. They fetch a link as
+// soon as it's shared, before anyone clicks it. iMessage identifies
+// itself as facebookexternalhit with Facebot and Twitterbot; Outlook
+// and Safe Links previews use Microsoft Office agents.
+var DefaultPrefetchAgents = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"whatsapp",
+	"slackbot",
+	"slack-imgproxy",
+	"discordbot",
+	"telegrambot",
+	"linkedinbot",
+	"skypeuripreview",
+	"microsoftpreview",
+	"bingpreview",
+	"ms-office",
+	"microsoft office",
+}
+
+// isPrefetch reports whether a redirect request was made by a link preview
+// fetcher, or announced itself as a speculative fetch with a Purpose or
+// Sec-Purpose header.
+func (s *LinkService) isPrefetch(metadata ClickMetadata) bool {
+	if containsFold(metadata.Purpose, "prefetch") || containsFold(metadata.Purpose, "preview") {
+		return true
+	}
+	for _, fragment := range s.prefetchAgents {
+		if containsFold(metadata.UserAgent, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether s contains the lowercase ASCII substr,
+// ignoring ASCII case in s. Unlike lowercasing s first, it doesn't
+// allocate, which matters on every redirect.
+func containsFold(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		match := true
+		for j := 0; j < len(substr); j++ {
+			c := s[i+j]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if c != substr[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// counts reports whether a click event counts towards click totals.
+// Deduped repeats, and prefetches unless CountPrefetches is set, are
+// stored but not counted.
+func (s *LinkService) counts(click *model.ClickEvent) bool {
+	return !click.Deduped && (!click.Prefetch || s.countPrefetches)
+}
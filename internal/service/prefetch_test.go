@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_PrefetchesNotCounted(t *testing.T) {
+	tests := []struct {
+		name            string
+		countPrefetches bool
+		wantCount       int64
+	}{
+		{"excluded by default", false, 1},
+		{"counted when enabled", true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkRepo := repository.NewMemoryLinkRepository()
+			clickRepo := repository.NewMemoryClickRepository()
+			config := DefaultConfig()
+			config.CountPrefetches = tt.countPrefetches
+			config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+			svc := NewLinkService(linkRepo, clickRepo, config)
+			ctx := context.Background()
+
+			resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+			if err != nil {
+				t.Fatalf("failed to create link: %v", err)
+			}
+
+			for _, metadata := range []ClickMetadata{
+				{UserAgent: "WhatsApp/2.23.20.0 A"},
+				{UserAgent: "facebookexternalhit/1.1 Facebot Twitterbot/1.0"}, // iMessage
+				{UserAgent: "Mozilla/5.0", Purpose: "prefetch"},
+				{UserAgent: "Mozilla/5.0"},
+			} {
+				svc.Redirect(ctx, resp.ShortCode, metadata)
+			}
+
+			link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+			if link.ClickCount != tt.wantCount {
+				t.Errorf("expected click count %d, got %d", tt.wantCount, link.ClickCount)
+			}
+			clicks, _ := clickRepo.GetByLinkID(ctx, link.ID, 0)
+			prefetches := 0
+			for _, click := range clicks {
+				if click.Prefetch {
+					prefetches++
+				}
+			}
+			if len(clicks) != 4 || prefetches != 3 {
+				t.Errorf("expected 4 stored clicks with 3 prefetches, got %d with %d", len(clicks), prefetches)
+			}
+		})
+	}
+}
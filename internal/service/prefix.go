@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// Prefix errors.
+var (
+	ErrPrefixNotFound = apierror.New(apierror.CodePrefixNotFound, "namespace prefix not found")
+	ErrPrefixTaken    = apierror.New(apierror.CodePrefixTaken, "namespace prefix is already allocated")
+)
+
+// PrefixesEnabled reports whether the service was configured with a prefix
+// store, and so whether prefixes can be allocated and used.
+func (s *LinkService) PrefixesEnabled() bool {
+	return s.prefixes != nil
+}
+
+// CreatePrefix allocates a namespace prefix. Invalid input is reported as
+// an apierror validation error.
+func (s *LinkService) CreatePrefix(ctx context.Context, req model.CreatePrefixRequest) (*model.Prefix, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	fields := make(map[string]string)
+	if shortcode.ValidatePrefix(req.Prefix) != nil {
+		fields["prefix"] = apierror.CodeInvalidPrefix
+	}
+	if len(req.Description) > MaxNotesLength {
+		fields["description"] = apierror.CodeTooLong
+	}
+	if len(fields) > 0 {
+		return nil, validationError(fields)
+	}
+
+	prefix := &model.Prefix{
+		Name:        req.Prefix,
+		Description: req.Description,
+		CreatedAt:   s.now(),
+		OwnerID:     ownerOf(ctx),
+	}
+	if err := s.prefixes.Create(ctx, prefix); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrPrefixTaken
+		}
+		return nil, fmt.Errorf("creating prefix: %w", err)
+	}
+
+	return prefix, nil
+}
+
+// ListPrefixes returns the allocated prefixes the caller in ctx owns,
+// ordered by name.
+func (s *LinkService) ListPrefixes(ctx context.Context) ([]model.Prefix, error) {
+	prefixes, err := s.prefixes.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing prefixes: %w", err)
+	}
+
+	owned := prefixes[:0]
+	for _, prefix := range prefixes {
+		if owns(ctx, prefix.OwnerID) {
+			owned = append(owned, prefix)
+		}
+	}
+	return owned, nil
+}
+
+// DeletePrefix releases a prefix. Links already created under it keep
+// their codes. Other owners' prefixes are reported as ErrPrefixNotFound.
+func (s *LinkService) DeletePrefix(ctx context.Context, name string) error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	if _, err := s.ownedPrefix(ctx, name); err != nil {
+		return err
+	}
+	if err := s.prefixes.Delete(ctx, name); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPrefixNotFound
+		}
+		return fmt.Errorf("deleting prefix: %w", err)
+	}
+	return nil
+}
+
+// ownedPrefix fetches the prefix called name, if the caller in ctx owns
+// it. Other owners' prefixes are reported as ErrPrefixNotFound.
+func (s *LinkService) ownedPrefix(ctx context.Context, name string) (*model.Prefix, error) {
+	prefix, err := s.prefixes.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrPrefixNotFound
+		}
+		return nil, fmt.Errorf("fetching prefix: %w", err)
+	}
+	if !owns(ctx, prefix.OwnerID) {
+		return nil, ErrPrefixNotFound
+	}
+	return prefix, nil
+}
+
+// checkPrefix verifies that a prefix requested for a new link has been
+// allocated, to the caller in ctx. An empty prefix is always fine.
+func (s *LinkService) checkPrefix(ctx context.Context, name string) error {
+	if name == "" {
+		return nil
+	}
+	if s.prefixes == nil {
+		return validationError(map[string]string{"prefix": apierror.CodePrefixNotFound})
+	}
+
+	if _, err := s.ownedPrefix(ctx, name); err != nil {
+		if errors.Is(err, ErrPrefixNotFound) {
+			return validationError(map[string]string{"prefix": apierror.CodePrefixNotFound})
+		}
+		return err
+	}
+	return nil
+}
+
+// prefixAllocated reports whether code starts with an allocated prefix,
+// in which case it belongs to that prefix's namespace.
+func (s *LinkService) prefixAllocated(ctx context.Context, code string) (bool, error) {
+	name, _, ok := strings.Cut(code, shortcode.PrefixSeparator)
+	if !ok || s.prefixes == nil {
+		return false, nil
+	}
+
+	_, err := s.prefixes.Get(ctx, name)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, repository.ErrNotFound):
+		return false, nil
+	default:
+		return false, fmt.Errorf("fetching prefix: %w", err)
+	}
+}
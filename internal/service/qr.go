@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/qr"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// QRCode renders a QR code for a link's short URL, tagged ?src=qr so
+// scans are counted as model.ClickSourceQR. format, size and level are
+// taken as given in a query string; empty ones get the qr package
+// defaults, and anything else invalid is reported as a validation error on
+// its name.
+func (s *LinkService) QRCode(ctx context.Context, shortCode, format, size, level string) (*model.QRCode, error) {
+	fields := make(map[string]string)
+	if format == "" {
+		format = qr.FormatPNG
+	}
+	format = strings.ToLower(format)
+	if !qr.ValidFormat(format) {
+		fields["format"] = apierror.CodeInvalidRequest
+	}
+	pixels := qr.DefaultSize
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < qr.MinSize || n > qr.MaxSize {
+			fields["size"] = apierror.CodeInvalidRequest
+		}
+		pixels = n
+	}
+	if level == "" {
+		level = qr.DefaultLevel
+	}
+	level = strings.ToUpper(level)
+	if !qr.ValidLevel(level) {
+		fields["level"] = apierror.CodeInvalidRequest
+	}
+	if len(fields) > 0 {
+		return nil, validationError(fields)
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("%s/%s?src=%s", s.baseURL, link.ShortCode, model.ClickSourceQR)
+	data, contentType, err := qr.Encode(content, format, level, pixels)
+	if err != nil {
+		return nil, fmt.Errorf("rendering QR code for %s: %w", link.ShortCode, err)
+	}
+	return &model.QRCode{ContentType: contentType, Data: data}, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_QRCode(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com", CustomCode: "poster"}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	code, err := svc.QRCode(ctx, "poster", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code.ContentType != "image/png" || len(code.Data) == 0 {
+		t.Errorf("expected a PNG by default, got %s (%d bytes)", code.ContentType, len(code.Data))
+	}
+
+	code, err = svc.QRCode(ctx, "poster", "SVG", "512", "h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code.ContentType != "image/svg+xml" {
+		t.Errorf("expected an SVG, got %s", code.ContentType)
+	}
+
+	_, err = svc.QRCode(ctx, "poster", "gif", "10", "Z")
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || len(apiErr.Fields) != 3 {
+		t.Errorf("expected format, size and level to fail validation, got %v", err)
+	}
+
+	if _, err := svc.QRCode(ctx, "missing", "", "", ""); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected ErrLinkNotFound, got %v", err)
+	}
+}
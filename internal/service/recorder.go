@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// ClickRecorder decides when a redirect's click is written relative to
+// the response. record persists one click and is called exactly once.
+type ClickRecorder interface {
+	Record(ctx context.Context, record func(ctx context.Context))
+}
+
+// AsyncClickRecorder records clicks in a background goroutine so redirects
+// never wait on storage. This suits long-running servers; on platforms
+// that freeze the process after the response, such as AWS Lambda, the
+// goroutine may never finish.
+type AsyncClickRecorder struct {
+	Timeout time.Duration // defaults to DefaultAsyncClickTimeout
+}
+
+// DefaultAsyncClickTimeout bounds an AsyncClickRecorder without a timeout.
+// It's generous, since no visitor waits on it; it only stops stuck
+// storage from piling up goroutines.
+const DefaultAsyncClickTimeout = 5 * time.Second
+
+// Record starts record in a new goroutine, giving up after Timeout. The
+// request's values, such as its trace, are kept but not its cancellation.
+func (r AsyncClickRecorder) Record(ctx context.Context, record func(ctx context.Context)) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAsyncClickTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	go func() {
+		defer cancel()
+		record(ctx)
+	}()
+}
+
+// BoundedClickRecorder records clicks before the redirect is returned,
+// giving up after Timeout so slow storage can only delay a redirect by
+// that much. Clicks that time out are logged by the service.
+type BoundedClickRecorder struct {
+	Timeout time.Duration // defaults to DefaultClickRecordTimeout
+}
+
+// DefaultClickRecordTimeout bounds a BoundedClickRecorder without a timeout.
+const DefaultClickRecordTimeout = 250 * time.Millisecond
+
+// Record runs record and waits for it, up to Timeout. The request's
+// cancellation is ignored: a client hanging up mid-redirect still clicked.
+func (r BoundedClickRecorder) Record(ctx context.Context, record func(ctx context.Context)) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultClickRecordTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	record(ctx)
+}
+
+// clickRetryDelay is the pause before a failed click write is retried.
+const clickRetryDelay = 50 * time.Millisecond
+
+// retryOnce runs op, and runs it again after clickRetryDelay if it fails
+// while ctx still has time left. A write that fails once because of a
+// blip usually goes through the second time. op must be safe to repeat,
+// since a failed attempt may still have been applied.
+func retryOnce(ctx context.Context, op func(ctx context.Context) error) error {
+	err := op(ctx)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	timer := time.NewTimer(clickRetryDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return err
+	}
+	return op(ctx)
+}
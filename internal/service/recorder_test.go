@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/metrics"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestBoundedClickRecorder_RecordsBeforeRedirect(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	clickRepo := repository.NewMemoryClickRepository()
+	config := DefaultConfig()
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(linkRepo, clickRepo, config)
+
+	resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	// A canceled request context must not cost the click
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := svc.Redirect(ctx, resp.ShortCode, ClickMetadata{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// No waiting: the click is stored by the time Redirect returns
+	link, _ := linkRepo.GetByShortCode(context.Background(), resp.ShortCode)
+	if link.ClickCount != 1 {
+		t.Errorf("expected click count 1, got %d", link.ClickCount)
+	}
+	clicks, _ := clickRepo.GetByLinkID(context.Background(), link.ID, 0)
+	if len(clicks) != 1 {
+		t.Errorf("expected 1 click event, got %d", len(clicks))
+	}
+}
+
+func TestBoundedClickRecorder_Timeout(t *testing.T) {
+	recorder := BoundedClickRecorder{Timeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	var recordErr error
+	recorder.Record(context.Background(), func(ctx context.Context) {
+		<-ctx.Done() // storage that never answers
+		recordErr = ctx.Err()
+	})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the record to be cut off after the timeout, took %v", elapsed)
+	}
+	if recordErr != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", recordErr)
+	}
+}
+
+func TestAsyncClickRecorder_Timeout(t *testing.T) {
+	recorder := AsyncClickRecorder{Timeout: 20 * time.Millisecond}
+
+	// The request is already over, but the click still gets its own time
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	recorder.Record(ctx, func(ctx context.Context) {
+		if _, ok := ctx.Deadline(); !ok {
+			done <- errors.New("expected a deadline")
+			return
+		}
+		<-ctx.Done() // storage that never answers
+		done <- ctx.Err()
+	})
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the record to be cut off after the timeout")
+	}
+}
+
+// flakyClickRepository fails the first failures calls to Record.
+type flakyClickRepository struct {
+	*repository.MemoryClickRepository
+	failures int
+	calls    int
+}
+
+func (r *flakyClickRepository) Record(ctx context.Context, event *model.ClickEvent) error {
+	r.calls++
+	if r.calls <= r.failures {
+		return errors.New("throttled")
+	}
+	return r.MemoryClickRepository.Record(ctx, event)
+}
+
+func TestRecordClick_RetriesOnce(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantEvents  int
+		wantMetrics int64
+	}{
+		{"succeeds", 0, 1, 0},
+		{"recovers on retry", 1, 1, 0},
+		{"fails twice", 2, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clickRepo := &flakyClickRepository{MemoryClickRepository: repository.NewMemoryClickRepository(), failures: tt.failures}
+			config := DefaultConfig()
+			config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+			linkRepo := repository.NewMemoryLinkRepository()
+			svc := NewLinkService(linkRepo, clickRepo, config)
+
+			resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: "https://example.com"})
+			if err != nil {
+				t.Fatalf("failed to create link: %v", err)
+			}
+
+			before := metrics.ClickEventFailures.Value()
+			if _, err := svc.Redirect(context.Background(), resp.ShortCode, ClickMetadata{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			link, _ := linkRepo.GetByShortCode(context.Background(), resp.ShortCode)
+			clicks, _ := clickRepo.GetByLinkID(context.Background(), link.ID, 0)
+			if len(clicks) != tt.wantEvents {
+				t.Errorf("expected %d click events, got %d", tt.wantEvents, len(clicks))
+			}
+			if got := metrics.ClickEventFailures.Value() - before; got != tt.wantMetrics {
+				t.Errorf("expected %d counted failures, got %d", tt.wantMetrics, got)
+			}
+		})
+	}
+}
+
+// appliedThenFailedLinkRepository counts each click but reports an error,
+// like a write whose response was lost.
+type appliedThenFailedLinkRepository struct {
+	*repository.MemoryLinkRepository
+	calls int
+}
+
+func (r *appliedThenFailedLinkRepository) IncrementClickCount(ctx context.Context, shortCode string) (int64, error) {
+	r.calls++
+	r.MemoryLinkRepository.IncrementClickCount(ctx, shortCode)
+	return 0, errors.New("connection reset")
+}
+
+func TestRecordClick_IncrementNotRetried(t *testing.T) {
+	linkRepo := &appliedThenFailedLinkRepository{MemoryLinkRepository: repository.NewMemoryLinkRepository()}
+	config := DefaultConfig()
+	config.ClickRecorder = BoundedClickRecorder{Timeout: time.Second}
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+
+	resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	before := metrics.ClickCountFailures.Value()
+	if _, err := svc.Redirect(context.Background(), resp.ShortCode, ClickMetadata{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	link, _ := linkRepo.GetByShortCode(context.Background(), resp.ShortCode)
+	if linkRepo.calls != 1 || link.ClickCount != 1 {
+		t.Errorf("expected one increment and a count of 1, got %d and %d", linkRepo.calls, link.ClickCount)
+	}
+	if got := metrics.ClickCountFailures.Value() - before; got != 1 {
+		t.Errorf("expected 1 counted failure, got %d", got)
+	}
+}
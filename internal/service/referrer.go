@@ -0,0 +1,58 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ErrReferrerBlocked is returned when a link locked to allowed referrers
+// is opened from anywhere else.
+var ErrReferrerBlocked = apierror.New(apierror.CodeReferrerBlocked, "link can't be opened from this referrer")
+
+// MaxAllowedReferrers caps the number of hosts a link may be locked to.
+const MaxAllowedReferrers = 50
+
+// normalizeReferrers validates an allowed-referrer list, returning its
+// hosts lowercased. Entries are bare hostnames, without scheme or path.
+func normalizeReferrers(hosts []string) ([]string, error) {
+	if len(hosts) > MaxAllowedReferrers {
+		return nil, validationError(map[string]string{"allowed_referrers": apierror.CodeTooLong})
+	}
+
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || strings.ContainsAny(host, "/:@?# ") {
+			return nil, validationError(map[string]string{"allowed_referrers": apierror.CodeInvalidRequest})
+		}
+		normalized = append(normalized, host)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// referrerAllowed reports whether a visitor coming from referrer may use
+// link. Links without allowed referrers accept everyone; locked links
+// reject visitors whose browser sent no Referer at all.
+func referrerAllowed(link *model.Link, referrer string) bool {
+	if len(link.AllowedReferrers) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range link.AllowedReferrers {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
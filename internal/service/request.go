@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ParseCreateLinkRequest decodes a create body according to its
+// Content-Type. Besides JSON, HTML forms (application/x-www-form-urlencoded,
+// with the same field names) and text/plain (the body is the URL) are
+// accepted so forms and minimal clients can create links directly. Other
+// or absent content types are read as JSON.
+func ParseCreateLinkRequest(contentType string, body []byte) (model.CreateLinkRequest, error) {
+	var req model.CreateLinkRequest
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return parseCreateForm(body)
+	case "text/plain":
+		req.URL = strings.TrimSpace(string(body))
+		return req, nil
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return req, apierror.New(apierror.CodeInvalidRequest, "request body must be a JSON object")
+	}
+	return req, nil
+}
+
+// parseCreateForm decodes a form-encoded create body. Unknown fields, such
+// as a form's submit button, are ignored.
+func parseCreateForm(body []byte) (model.CreateLinkRequest, error) {
+	var req model.CreateLinkRequest
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return req, apierror.New(apierror.CodeInvalidRequest, "malformed form body")
+	}
+	req.URL = form.Get("url")
+	req.Notes = form.Get("notes")
+	req.Title = form.Get("title")
+	req.Prefix = form.Get("prefix")
+	req.CustomCode = form.Get("custom_code")
+	req.AllowedReferrers = splitHosts(form.Get("allowed_referrers"))
+
+	fields := make(map[string]string)
+	for name, dst := range map[string]*bool{
+		"wildcard":          &req.Wildcard,
+		"verify":            &req.Verify,
+		"interstitial":      &req.Interstitial,
+		"notify_milestones": &req.NotifyMilestones,
+		"public":            &req.Public,
+		"dedupe":            &req.Dedupe,
+	} {
+		if !form.Has(name) {
+			continue
+		}
+		value, ok := parseFormBool(form.Get(name))
+		if !ok {
+			fields[name] = apierror.CodeInvalidRequest
+			continue
+		}
+		*dst = value
+	}
+
+	if len(fields) > 0 {
+		return req, validationError(fields)
+	}
+	return req, nil
+}
+
+// parseFormBool parses a form boolean. "on" is what a checked checkbox
+// without a value attribute sends.
+func parseFormBool(value string) (bool, bool) {
+	if strings.EqualFold(value, "on") {
+		return true, true
+	}
+	b, err := strconv.ParseBool(value)
+	return b, err == nil
+}
+
+// splitHosts parses a comma- or whitespace-separated form list of hosts.
+func splitHosts(value string) []string {
+	hosts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(hosts) == 0 {
+		return nil
+	}
+	return hosts
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestParseCreateLinkRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        model.CreateLinkRequest
+		wantCode    string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"url": "https://example.com", "wildcard": true}`,
+			want:        model.CreateLinkRequest{URL: "https://example.com", Wildcard: true},
+		},
+		{
+			name: "no content type",
+			body: `{"url": "https://example.com"}`,
+			want: model.CreateLinkRequest{URL: "https://example.com"},
+		},
+		{
+			name:        "bad json",
+			contentType: "application/json",
+			body:        `{url}`,
+			wantCode:    apierror.CodeInvalidRequest,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fexample.com%2F%3Fa%3D1&prefix=mkt&verify=true&interstitial=on&public=on&go=Shorten",
+			want:        model.CreateLinkRequest{URL: "https://example.com/?a=1", Prefix: "mkt", Verify: true, Interstitial: true, Public: true},
+		},
+		{
+			name:        "form with allowed referrers",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fexample.com&allowed_referrers=intranet.example.com%2C+wiki.example.com",
+			want:        model.CreateLinkRequest{URL: "https://example.com", AllowedReferrers: []string{"intranet.example.com", "wiki.example.com"}},
+		},
+		{
+			name:        "form with a bad flag",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fexample.com&verify=sure",
+			wantCode:    apierror.CodeValidationFailed,
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain; charset=utf-8",
+			body:        "  https://example.com/text\r\n",
+			want:        model.CreateLinkRequest{URL: "https://example.com/text"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCreateLinkRequest(tt.contentType, []byte(tt.body))
+			if tt.wantCode != "" {
+				if code := apierror.CodeOf(err); code != tt.wantCode {
+					t.Errorf("expected code %s, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// URLScanner checks a destination's reputation, e.g. against VirusTotal.
+// urlscan.VirusTotal implements it.
+type URLScanner interface {
+	// Scan reports whether rawURL is known to be malicious.
+	Scan(ctx context.Context, rawURL string) (malicious bool, err error)
+}
+
+// DefaultScanQueueSize is the number of pending scans buffered before new
+// ones are dropped (and left pending).
+const DefaultScanQueueSize = 256
+
+// Scan pipeline tuning.
+const (
+	scanTimeout        = 30 * time.Second
+	scanUpdateAttempts = 3
+)
+
+// scanJob is a destination waiting to be scanned.
+type scanJob struct {
+	shortCode string
+	url       string
+}
+
+// startScanner starts the background worker that drains scan jobs.
+func (s *LinkService) startScanner(scanner URLScanner, queueSize int) {
+	if queueSize <= 0 {
+		queueSize = DefaultScanQueueSize
+	}
+	s.scanner = scanner
+	s.scans = make(chan scanJob, queueSize)
+	go s.runScans()
+}
+
+// enqueueScan queues link's destination for scanning. It never blocks: when
+// the queue is full the link stays pending and a warning is logged.
+func (s *LinkService) enqueueScan(link *model.Link) {
+	if s.scanner == nil {
+		return
+	}
+
+	select {
+	case s.scans <- scanJob{shortCode: link.ShortCode, url: link.OriginalURL}:
+	default:
+		s.logger.Warn("url scan queue full; link left pending", "code", link.ShortCode)
+	}
+}
+
+// runScans scans queued destinations one at a time.
+func (s *LinkService) runScans() {
+	for job := range s.scans {
+		ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+		malicious, err := s.scanner.Scan(ctx, job.url)
+
+		status := model.ScanStatusClean
+		switch {
+		case err != nil:
+			s.logger.Warn("url scan failed", "code", job.shortCode, "error", err)
+			status = model.ScanStatusFailed
+		case malicious:
+			status = model.ScanStatusFlagged
+		}
+
+		s.applyScanResult(ctx, job, status)
+		cancel()
+	}
+}
+
+// applyScanResult records a scan outcome on the link, disabling it when
+// flagged. Results for a destination the link no longer points to are
+// discarded; the newer destination has its own scan queued.
+func (s *LinkService) applyScanResult(ctx context.Context, job scanJob, status string) {
+	for attempt := 0; attempt < scanUpdateAttempts; attempt++ {
+		link, err := s.linkRepo.GetByShortCode(ctx, job.shortCode)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				s.logger.Error("failed to load scanned link", "code", job.shortCode, "error", err)
+			}
+			return
+		}
+		if link.OriginalURL != job.url {
+			return
+		}
+
+		link.ScanStatus = status
+		if status == model.ScanStatusFlagged {
+			link.Disabled = true
+		}
+
+		err = s.linkRepo.Update(ctx, link)
+		if errors.Is(err, repository.ErrConflict) {
+			continue // the link changed under us; re-read and try again
+		}
+		if err != nil {
+			s.logger.Error("failed to save scan result", "code", job.shortCode, "error", err)
+			return
+		}
+
+		if status == model.ScanStatusFlagged {
+			s.logger.Warn("url scan flagged destination; link disabled", "code", link.ShortCode, "url", link.OriginalURL)
+			s.events.Publish(events.Event{
+				Type:      events.TypeLinkFlagged,
+				Timestamp: s.now(),
+				ShortCode: link.ShortCode,
+				Link:      link,
+			})
+		}
+		return
+	}
+
+	s.logger.Error("gave up saving scan result after repeated conflicts", "code", job.shortCode)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// stubScanner flags the URLs in its set.
+type stubScanner map[string]bool
+
+func (s stubScanner) Scan(ctx context.Context, rawURL string) (bool, error) {
+	return s[rawURL], nil
+}
+
+// waitForScan polls until the link's scan leaves the pending state.
+func waitForScan(t *testing.T, svc *LinkService, code string) *model.LinkDetails {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		link, err := svc.GetLink(context.Background(), code)
+		if err != nil {
+			t.Fatalf("failed to get link: %v", err)
+		}
+		if link.ScanStatus != model.ScanStatusPending {
+			return link
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scan of %s never finished", code)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLinkService_URLScanning(t *testing.T) {
+	bus := events.NewBus()
+	flagged, cancel := bus.Subscribe(0, func(e events.Event) bool { return e.Type == events.TypeLinkFlagged })
+	defer cancel()
+
+	config := DefaultConfig()
+	config.Events = bus
+	config.Scanner = stubScanner{"https://evil.example/": true}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	good, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if link := waitForScan(t, svc, good.ShortCode); link.ScanStatus != model.ScanStatusClean || link.Disabled {
+		t.Errorf("expected a clean, enabled link, got %+v", link)
+	}
+
+	bad, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://evil.example/"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if link := waitForScan(t, svc, bad.ShortCode); link.ScanStatus != model.ScanStatusFlagged || !link.Disabled {
+		t.Errorf("expected a flagged, disabled link, got %+v", link)
+	}
+	if _, err := svc.Redirect(ctx, bad.ShortCode, ClickMetadata{}); err != ErrLinkDisabled {
+		t.Errorf("expected ErrLinkDisabled, got %v", err)
+	}
+
+	select {
+	case e := <-flagged:
+		if e.ShortCode != bad.ShortCode {
+			t.Errorf("expected a flag event for %s, got %s", bad.ShortCode, e.ShortCode)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a link.flagged event")
+	}
+
+	// An operator can re-enable a link after review
+	enabled := false
+	if _, err := svc.UpdateLink(ctx, bad.ShortCode, LinkPatch{Disabled: &enabled}, 0); err != nil {
+		t.Fatalf("failed to re-enable link: %v", err)
+	}
+	if _, err := svc.Redirect(ctx, bad.ShortCode, ClickMetadata{}); err != nil {
+		t.Errorf("expected re-enabled link to redirect, got %v", err)
+	}
+}
+
+func TestLinkService_OwnerCannotReenableFlagged(t *testing.T) {
+	config := DefaultConfig()
+	config.Scanner = stubScanner{"https://evil.example/": true, "https://evil.example/again": true}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ada := WithOwner(context.Background(), "ada")
+
+	bad, err := svc.CreateLink(ada, model.CreateLinkRequest{URL: "https://evil.example/"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	waitForScan(t, svc, bad.ShortCode)
+
+	enabled, url := false, "https://evil.example/again"
+	for _, patch := range []LinkPatch{
+		{Disabled: &enabled},
+		{Disabled: &enabled, URL: &url}, // changing the URL in the same patch doesn't count as a review
+	} {
+		_, err := svc.UpdateLink(ada, bad.ShortCode, patch, 0)
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) || apiErr.Fields["disabled"] != apierror.CodeImmutableField {
+			t.Errorf("expected the owner to be refused with immutable_field, got %v", err)
+		}
+	}
+	if _, err := svc.Redirect(context.Background(), bad.ShortCode, ClickMetadata{}); err != ErrLinkDisabled {
+		t.Errorf("expected the link to stay disabled, got %v", err)
+	}
+
+	// Disabling by hand is still up to the owner
+	disabled := true
+	if _, err := svc.UpdateLink(ada, bad.ShortCode, LinkPatch{Disabled: &disabled}, 0); err != nil {
+		t.Errorf("expected the owner to disable the link, got %v", err)
+	}
+}
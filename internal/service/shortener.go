@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ErrShortenerURL is returned for destinations on another URL shortener.
+var ErrShortenerURL = apierror.New(apierror.CodeShortenerURL, "links to other URL shorteners are not allowed")
+
+// DefaultShortenerDomains lists well-known URL shorteners. Shortening
+// their links hides the real destination behind a chain of redirects.
+var DefaultShortenerDomains = []string{
+	"bit.ly", "bitly.com", "buff.ly", "cutt.ly", "goo.gl", "is.gd",
+	"ow.ly", "rb.gy", "rebrand.ly", "shorturl.at", "t.co", "t.ly",
+	"tiny.cc", "tinyurl.com", "v.gd",
+}
+
+// shortenerPolicy is how links to other shorteners are handled.
+type shortenerPolicy struct {
+	domains  []string // empty allows all shorteners
+	resolver DestinationResolver
+}
+
+// SetShortenerPolicy replaces the shortener settings at runtime (e.g., on
+// config reload), with the meaning of the ShortenerDomains,
+// AllowShorteners and ShortenerResolver config fields.
+func (s *LinkService) SetShortenerPolicy(domains []string, allow bool, resolver DestinationResolver) {
+	policy := &shortenerPolicy{resolver: resolver}
+	if !allow {
+		policy.domains = domains
+		if policy.domains == nil {
+			policy.domains = DefaultShortenerDomains
+		}
+	}
+	s.shortener.Store(policy)
+}
+
+// isShortener reports whether rawURL's host is, or is a subdomain of, one
+// of the domains in policy.
+func isShortener(policy *shortenerPolicy, rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	for _, domain := range policy.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkShortener vets a destination against the shortener list. Links to
+// a shortener are resolved to where they really go when a shortener
+// resolver is configured, and rejected with ErrShortenerURL otherwise (or
+// when they can't be resolved to somewhere else).
+func (s *LinkService) checkShortener(ctx context.Context, destination string) (string, error) {
+	policy := s.shortener.Load()
+	if !isShortener(policy, destination) {
+		return destination, nil
+	}
+	if policy.resolver == nil {
+		return "", ErrShortenerURL
+	}
+
+	final, err := policy.resolver.Resolve(ctx, destination)
+	if err != nil {
+		s.logger.WarnContext(ctx, "could not resolve shortened destination", "url", destination, "error", err)
+		return "", ErrShortenerURL
+	}
+	if isShortener(policy, final) || s.validateURL(final) != nil {
+		return "", ErrShortenerURL
+	}
+	return final, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_RejectsShorteners(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	for _, url := range []string{"https://bit.ly/abc", "http://T.CO/xyz", "https://www.tinyurl.com/q"} {
+		if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: url}); err != ErrShortenerURL {
+			t.Errorf("CreateLink(%s) error = %v, want ErrShortenerURL", url, err)
+		}
+	}
+
+	// Lookalike domains are fine
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://notbit.ly/abc"}); err != nil {
+		t.Errorf("unexpected error for a lookalike domain: %v", err)
+	}
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	shortener := "https://bit.ly/abc"
+	_, err = svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &shortener}, 0)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Fields["url"] != apierror.CodeShortenerURL {
+		t.Errorf("expected a shortener_url field error, got %v", err)
+	}
+}
+
+func TestLinkService_ResolvesShorteners(t *testing.T) {
+	config := DefaultConfig()
+	config.ShortenerDomains = []string{"sho.rt"}
+	config.ShortenerResolver = stubResolver{
+		"https://sho.rt/real": "https://example.com/landing",
+		"https://sho.rt/loop": "https://sho.rt/other",
+	}
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://sho.rt/real"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OriginalURL != "https://example.com/landing" {
+		t.Errorf("expected the resolved destination, got %s", resp.OriginalURL)
+	}
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://sho.rt/loop"}); err != ErrShortenerURL {
+		t.Errorf("expected ErrShortenerURL when resolution ends on a shortener, got %v", err)
+	}
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://bit.ly/abc"}); err != nil {
+		t.Errorf("expected a custom list to replace the defaults, got %v", err)
+	}
+}
+
+func TestLinkService_SetShortenerPolicy(t *testing.T) {
+	svc := NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), DefaultConfig())
+	ctx := context.Background()
+
+	svc.SetShortenerPolicy([]string{"sho.rt"}, false, nil)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://sho.rt/abc"}); err != ErrShortenerURL {
+		t.Errorf("expected ErrShortenerURL for a newly listed domain, got %v", err)
+	}
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://bit.ly/abc"}); err != nil {
+		t.Errorf("expected the new list to replace the defaults, got %v", err)
+	}
+
+	svc.SetShortenerPolicy([]string{"sho.rt"}, true, nil)
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://sho.rt/abc"}); err != nil {
+		t.Errorf("expected shorteners to be allowed, got %v", err)
+	}
+}
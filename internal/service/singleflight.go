@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/colby/snip/internal/metrics"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// flightGroup collapses concurrent calls for the same key into one, so a
+// link going viral costs one backend read per round trip rather than one
+// per visitor. It's a small stand-in for x/sync/singleflight that lets
+// each waiter give up on its own context.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flight[T]
+}
+
+// flight is one in-progress call.
+type flight[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// do runs fn for key, or waits for the call already running for it and
+// shares its result. The first caller runs fn without its ctx's
+// cancellation, since the result is shared: that caller going away
+// mustn't fail the others. Callers joining it stop waiting when their
+// ctx ends.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight[T])
+	}
+	if f, running := g.calls[key]; running {
+		g.mu.Unlock()
+		metrics.CoalescedReads.Add(1)
+		select {
+		case <-f.done:
+			return f.val, f.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	f := &flight[T]{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.val, f.err = fn(context.WithoutCancel(ctx))
+	return f.val, f.err
+}
+
+// readLink is findLink for read-only paths, redirects and stats, with
+// concurrent lookups of a code sharing one repository read and recent
+// misses answered from the miss cache. Each caller gets its own copy of
+// the link.
+func (s *LinkService) readLink(ctx context.Context, shortCode string) (*model.Link, error) {
+	if s.misses.missed(shortCode, s.now()) {
+		metrics.CachedNotFound.Add(1)
+		return nil, ErrLinkNotFound
+	}
+	link, err := s.linkReads.do(ctx, shortCode, func(ctx context.Context) (*model.Link, error) {
+		link, err := s.findLink(ctx, shortCode)
+		if errors.Is(err, ErrLinkNotFound) && s.cacheableMiss(shortCode) {
+			s.misses.add(shortCode, s.now())
+		}
+		return link, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *link
+	return &copied, nil
+}
+
+// cacheableMiss reports whether a miss for shortCode can be cached. With
+// case-insensitive codes, only canonical spellings are: a new link clears
+// its own code, not every spelling that would have reached it.
+func (s *LinkService) cacheableMiss(shortCode string) bool {
+	return !s.caseInsensitive || shortcode.Canonicalize(shortCode) == shortCode
+}
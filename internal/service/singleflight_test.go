@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/colby/snip/internal/metrics"
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// gatedLinkRepository holds every GetByShortCode until release is closed
+// or its ctx ends, counting the calls that reach it.
+type gatedLinkRepository struct {
+	*repository.MemoryLinkRepository
+	release chan struct{}
+	reads   atomic.Int32
+}
+
+func (r *gatedLinkRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.Link, error) {
+	r.reads.Add(1)
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.MemoryLinkRepository.GetByShortCode(ctx, shortCode)
+}
+
+func TestReadLink_CoalescesConcurrentReads(t *testing.T) {
+	linkRepo := &gatedLinkRepository{MemoryLinkRepository: repository.NewMemoryLinkRepository(), release: make(chan struct{})}
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), DefaultConfig())
+	if err := linkRepo.Create(context.Background(), &model.Link{ID: "1", ShortCode: "abc123", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	const readers = 20
+	before := metrics.CoalescedReads.Value()
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for i := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err = svc.ResolveRedirect(context.Background(), "abc123", "", ClickMetadata{})
+			} else {
+				_, err = svc.GetStats(context.Background(), "abc123")
+			}
+			errs <- err
+		}()
+	}
+
+	// Hold the first read until every other reader has joined it
+	for metrics.CoalescedReads.Value()-before < readers-1 {
+		runtime.Gosched()
+	}
+	close(linkRepo.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if got := linkRepo.reads.Load(); got != 1 {
+		t.Errorf("expected 1 repository read, got %d", got)
+	}
+}
+
+func TestReadLink_LeaderCanceled(t *testing.T) {
+	linkRepo := &gatedLinkRepository{MemoryLinkRepository: repository.NewMemoryLinkRepository(), release: make(chan struct{})}
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), DefaultConfig())
+	if err := linkRepo.Create(context.Background(), &model.Link{ID: "1", ShortCode: "abc123", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := svc.GetStats(ctx, "abc123")
+		leader <- err
+	}()
+	for linkRepo.reads.Load() == 0 {
+		runtime.Gosched()
+	}
+
+	before := metrics.CoalescedReads.Value()
+	joiner := make(chan error, 1)
+	go func() {
+		_, err := svc.GetStats(context.Background(), "abc123")
+		joiner <- err
+	}()
+	for metrics.CoalescedReads.Value() == before {
+		runtime.Gosched()
+	}
+
+	// The leader's request ends, but the read it started carries on
+	cancel()
+	close(linkRepo.release)
+	if err := <-joiner; err != nil {
+		t.Errorf("expected the joiner to get the link, got %v", err)
+	}
+	<-leader
+	if got := linkRepo.reads.Load(); got != 1 {
+		t.Errorf("expected 1 repository read, got %d", got)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+)
+
+// MaxSitemapURLs is the most URLs a sitemap may hold, per the sitemaps.org
+// protocol. Public links beyond it are left out.
+const MaxSitemapURLs = 50_000
+
+// sitemapPageSize is how many links SitemapURLs reads per repository call.
+const sitemapPageSize = 1000
+
+// SitemapCacheTTL is how long SitemapURLs reuses a sitemap it built.
+const SitemapCacheTTL = 5 * time.Minute
+
+// sitemapCache holds the last sitemap built, so the public export costs
+// one read of every link per SitemapCacheTTL however often it's fetched.
+// Concurrent rebuilds are collapsed into one.
+type sitemapCache struct {
+	builds flightGroup[[]model.SitemapURL]
+
+	mu      sync.Mutex
+	urls    []model.SitemapURL
+	expires time.Time
+}
+
+// SitemapURLs lists the short URLs of the links listed publicly (see
+// listedPublicly). Building the list reads every stored link, so it's
+// cached for SitemapCacheTTL: links created or changed since show up once
+// it expires. Callers must not modify the returned slice, which is
+// shared.
+func (s *LinkService) SitemapURLs(ctx context.Context) ([]model.SitemapURL, error) {
+	c := &s.sitemap
+	c.mu.Lock()
+	urls, fresh := c.urls, s.now().Before(c.expires)
+	c.mu.Unlock()
+	if fresh {
+		return urls, nil
+	}
+
+	return c.builds.do(ctx, "", func(ctx context.Context) ([]model.SitemapURL, error) {
+		urls, err := s.buildSitemap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.urls, c.expires = urls, s.now().Add(SitemapCacheTTL)
+		c.mu.Unlock()
+		return urls, nil
+	})
+}
+
+// buildSitemap reads every link for SitemapURLs.
+func (s *LinkService) buildSitemap(ctx context.Context) ([]model.SitemapURL, error) {
+	urls := []model.SitemapURL{}
+	cursor := ""
+	for {
+		links, next, err := s.linkRepo.List(ctx, cursor, sitemapPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("listing links: %w", err)
+		}
+		for _, link := range links {
+			if !listedPublicly(link) {
+				continue
+			}
+			if len(urls) == MaxSitemapURLs {
+				s.logger.WarnContext(ctx, "sitemap truncated", "max_urls", MaxSitemapURLs)
+				return urls, nil
+			}
+			urls = append(urls, model.SitemapURL{
+				Loc:     fmt.Sprintf("%s/%s", s.baseURL, link.ShortCode),
+				LastMod: link.CreatedAt.UTC().Format(time.DateOnly),
+			})
+		}
+		if next == "" {
+			return urls, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func TestLinkService_SitemapURLs(t *testing.T) {
+	linkRepo := repository.NewMemoryLinkRepository()
+	config := DefaultConfig()
+	config.BaseURL = "https://snip.io"
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	for _, req := range []model.CreateLinkRequest{
+		{URL: "https://example.com/a", CustomCode: "listed", Public: true},
+		{URL: "https://example.com/b", CustomCode: "private"},
+		{URL: "https://example.com/c", CustomCode: "disabled", Public: true},
+		{URL: "https://example.com/d", CustomCode: "locked", Public: true, AllowedReferrers: []string{"intranet.example.com"}},
+		{URL: "https://example.com/e", CustomCode: "deleted", Public: true},
+	} {
+		if _, err := svc.CreateLink(ctx, req); err != nil {
+			t.Fatalf("failed to create %s: %v", req.CustomCode, err)
+		}
+	}
+	disabled := true
+	if _, err := svc.UpdateLink(ctx, "disabled", LinkPatch{Disabled: &disabled}, 0); err != nil {
+		t.Fatalf("failed to disable link: %v", err)
+	}
+	if err := svc.DeleteLink(ctx, "deleted", 0); err != nil {
+		t.Fatalf("failed to delete link: %v", err)
+	}
+
+	urls, err := svc.SitemapURLs(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(urls) != 1 || urls[0].Loc != "https://snip.io/listed" {
+		t.Fatalf("expected only https://snip.io/listed, got %+v", urls)
+	}
+	if want := svc.now().UTC().Format("2006-01-02"); urls[0].LastMod != want {
+		t.Errorf("expected lastmod %s, got %s", want, urls[0].LastMod)
+	}
+}
+
+func TestLinkService_SitemapURLsCached(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	linkRepo := &countingLinkRepository{MemoryLinkRepository: repository.NewMemoryLinkRepository()}
+	config := DefaultConfig()
+	config.Clock = clock
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/a", CustomCode: "first", Public: true}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	count := func() int {
+		urls, err := svc.SitemapURLs(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return len(urls)
+	}
+
+	count()
+	if _, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/b", CustomCode: "second", Public: true}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	lists := linkRepo.lists
+	if n := count(); n != 1 || linkRepo.lists != lists {
+		t.Errorf("expected the cached sitemap of 1 URL without listing links, got %d URLs and %d lists", n, linkRepo.lists-lists)
+	}
+
+	clock.Advance(SitemapCacheTTL)
+	if n := count(); n != 2 {
+		t.Errorf("expected the sitemap rebuilt with 2 URLs after the TTL, got %d", n)
+	}
+}
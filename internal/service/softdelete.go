@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// softDelete marks a link deleted, keeping it for the grace period.
+func (s *LinkService) softDelete(ctx context.Context, shortCode string, expectedVersion int64) error {
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if expectedVersion != 0 && link.Version != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	now := s.now()
+	link.DeletedAt = &now
+	if err := s.updateLink(ctx, link); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "link deleted; restorable until purged", "short_code", link.ShortCode, "grace", s.deleteGrace)
+	return nil
+}
+
+// RestoreLink undoes a delete made within the delete grace period.
+// Restoring a link that isn't deleted returns it unchanged. Links whose
+// grace period has passed are reported as ErrLinkNotFound, even before
+// they are purged.
+func (s *LinkService) RestoreLink(ctx context.Context, shortCode string) (*model.LinkDetails, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	link, err := s.findStoredLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ownsLink(ctx, link) {
+		return nil, ErrLinkNotFound
+	}
+	if link.DeletedAt == nil {
+		return s.linkDetails(link), nil
+	}
+	if s.now().Sub(*link.DeletedAt) > s.deleteGrace {
+		return nil, ErrLinkNotFound
+	}
+
+	link.DeletedAt = nil
+	if err := s.updateLink(ctx, link); err != nil {
+		return nil, err
+	}
+	s.misses.forget(link.ShortCode)
+	s.logger.InfoContext(ctx, "link restored", "short_code", link.ShortCode)
+	return s.linkDetails(link), nil
+}
+
+// updateLink writes link, mapping repository errors to service errors.
+func (s *LinkService) updateLink(ctx context.Context, link *model.Link) error {
+	if err := s.linkRepo.Update(ctx, link); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrLinkNotFound
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("updating link: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeletedLinks removes links whose delete grace period has passed and
+// returns how many were removed. Links restored while the purge runs are
+// left alone.
+func (s *LinkService) PurgeDeletedLinks(ctx context.Context) (int, error) {
+	sweeper, ok := s.linkRepo.(repository.LinkSweeper)
+	if !ok {
+		return 0, ErrSweepUnsupported
+	}
+
+	links, err := sweeper.DeletedBefore(ctx, s.now().Add(-s.deleteGrace))
+	if err != nil {
+		return 0, fmt.Errorf("finding deleted links: %w", err)
+	}
+
+	removed := 0
+	for _, link := range links {
+		// Conditional on the version, so a concurrent restore wins
+		err := s.linkRepo.Delete(ctx, link.ShortCode, link.Version)
+		if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("purging link %s: %w", link.ShortCode, err)
+		}
+		s.deleteThumbnail(ctx, link.ID)
+		s.deleteLinkAliases(ctx, link.ShortCode)
+		removed++
+	}
+	return removed, nil
+}
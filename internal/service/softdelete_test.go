@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+func newSoftDeleteService(t *testing.T) (*LinkService, *repository.MemoryLinkRepository, string) {
+	t.Helper()
+	linkRepo := repository.NewMemoryLinkRepository()
+	config := DefaultConfig()
+	config.DeleteGracePeriod = time.Hour
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+
+	resp, err := svc.CreateLink(context.Background(), model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	return svc, linkRepo, resp.ShortCode
+}
+
+func TestLinkService_DeleteAndRestore(t *testing.T) {
+	svc, _, code := newSoftDeleteService(t)
+	ctx := context.Background()
+
+	if err := svc.DeleteLink(ctx, code, 0); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+	if _, err := svc.Redirect(ctx, code, ClickMetadata{}); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected deleted link to stop resolving, got %v", err)
+	}
+	if err := svc.DeleteLink(ctx, code, 0); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected second delete to report not found, got %v", err)
+	}
+
+	details, err := svc.RestoreLink(ctx, code)
+	if err != nil {
+		t.Fatalf("unexpected error restoring: %v", err)
+	}
+	if details.ShortCode != code {
+		t.Errorf("expected restored link %s, got %s", code, details.ShortCode)
+	}
+	if _, err := svc.Redirect(ctx, code, ClickMetadata{}); err != nil {
+		t.Errorf("expected restored link to resolve, got %v", err)
+	}
+}
+
+func TestLinkService_PurgeDeletedLinks(t *testing.T) {
+	svc, linkRepo, code := newSoftDeleteService(t)
+	ctx := context.Background()
+
+	if err := svc.DeleteLink(ctx, code, 0); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+
+	// Still within the grace period
+	if removed, err := svc.PurgeDeletedLinks(ctx); err != nil || removed != 0 {
+		t.Fatalf("expected nothing purged, got %d, %v", removed, err)
+	}
+
+	// Backdate the delete past the grace period
+	link, _ := linkRepo.GetByShortCode(ctx, code)
+	past := time.Now().UTC().Add(-2 * time.Hour)
+	link.DeletedAt = &past
+	if err := linkRepo.Update(ctx, link); err != nil {
+		t.Fatalf("failed to backdate delete: %v", err)
+	}
+
+	if _, err := svc.RestoreLink(ctx, code); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("expected restore after the grace period to fail, got %v", err)
+	}
+	removed, err := svc.PurgeDeletedLinks(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 link purged, got %d", removed)
+	}
+	if _, err := linkRepo.GetByShortCode(ctx, code); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected link to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+	"github.com/colby/snip/pkg/shortcode"
+)
+
+// Template errors.
+var (
+	ErrTemplateNotFound = apierror.New(apierror.CodeTemplateNotFound, "link template not found")
+	ErrTemplateTaken    = apierror.New(apierror.CodeTemplateTaken, "a link template with this id already exists")
+)
+
+// utmKeys are the UTM parameters a template may set, without their
+// "utm_" prefix.
+var utmKeys = map[string]bool{
+	"source":   true,
+	"medium":   true,
+	"campaign": true,
+	"term":     true,
+	"content":  true,
+}
+
+// TemplatesEnabled reports whether the service was configured with a
+// template store, and so whether templates can be saved and used.
+func (s *LinkService) TemplatesEnabled() bool {
+	return s.templates != nil
+}
+
+// CreateTemplate saves a link template. Invalid input is reported as an
+// apierror validation error.
+func (s *LinkService) CreateTemplate(ctx context.Context, req model.CreateTemplateRequest) (*model.Template, error) {
+	if s.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	fields := make(map[string]string)
+	if shortcode.ValidateAlias(req.ID) != nil {
+		fields["id"] = apierror.CodeInvalidRequest
+	}
+	// Placeholders are checked by filling each with a sample value
+	sample, err := fillPlaceholders(req.URL, func(string) string { return "x" })
+	if err == nil {
+		err = s.validateURL(sample)
+	}
+	if err != nil {
+		fields["url"] = apierror.CodeInvalidURL
+	}
+	for key := range req.UTM {
+		if !utmKeys[key] {
+			fields["utm."+key] = apierror.CodeUnknownField
+		}
+	}
+	if len(req.Notes) > MaxNotesLength {
+		fields["notes"] = apierror.CodeTooLong
+	}
+	if len(fields) > 0 {
+		return nil, validationError(fields)
+	}
+	if err := s.checkPrefix(ctx, req.Prefix); err != nil {
+		return nil, err
+	}
+
+	template := &model.Template{
+		ID:        req.ID,
+		URL:       req.URL,
+		UTM:       req.UTM,
+		Notes:     req.Notes,
+		Prefix:    req.Prefix,
+		CreatedAt: s.now(),
+		OwnerID:   ownerOf(ctx),
+	}
+	if err := s.templates.Create(ctx, template); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrTemplateTaken
+		}
+		return nil, fmt.Errorf("creating template: %w", err)
+	}
+
+	return template, nil
+}
+
+// ListTemplates returns the saved templates the caller in ctx owns,
+// ordered by ID.
+func (s *LinkService) ListTemplates(ctx context.Context) ([]model.Template, error) {
+	templates, err := s.templates.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing templates: %w", err)
+	}
+
+	owned := templates[:0]
+	for _, template := range templates {
+		if owns(ctx, template.OwnerID) {
+			owned = append(owned, template)
+		}
+	}
+	return owned, nil
+}
+
+// DeleteTemplate removes a template. Links already created from it are
+// left alone. Other owners' templates are reported as ErrTemplateNotFound.
+func (s *LinkService) DeleteTemplate(ctx context.Context, id string) error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	if _, err := s.ownedTemplate(ctx, id); err != nil {
+		return err
+	}
+	if err := s.templates.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrTemplateNotFound
+		}
+		return fmt.Errorf("deleting template: %w", err)
+	}
+	return nil
+}
+
+// CreateLinkFromTemplate creates a link whose destination is the
+// template's URL with its placeholders filled from req.Params and its UTM
+// parameters added. UTM parameters already in the filled URL are kept.
+// Every placeholder needs a param, and every param a placeholder;
+// mismatches are reported as validation errors on "params.<name>". The
+// link is then created as by CreateLink, with the same errors.
+func (s *LinkService) CreateLinkFromTemplate(ctx context.Context, id string, req model.CreateFromTemplateRequest) (*model.CreateLinkResponse, error) {
+	template, err := s.ownedTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	used := make(map[string]bool)
+	destination, err := fillPlaceholders(template.URL, func(name string) string {
+		used[name] = true
+		if req.Params[name] == "" {
+			fields["params."+name] = apierror.CodeInvalidRequest
+		}
+		return req.Params[name]
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filling template %s: %w", id, err)
+	}
+	for name := range req.Params {
+		if !used[name] {
+			fields["params."+name] = apierror.CodeUnknownField
+		}
+	}
+	for key := range req.UTM {
+		if !utmKeys[key] {
+			fields["utm."+key] = apierror.CodeUnknownField
+		}
+	}
+	if len(fields) > 0 {
+		return nil, validationError(fields)
+	}
+
+	utm := make(map[string]string, len(template.UTM)+len(req.UTM))
+	for key, value := range template.UTM {
+		utm[key] = value
+	}
+	for key, value := range req.UTM {
+		utm[key] = value
+	}
+
+	notes := req.Notes
+	if notes == "" {
+		notes = template.Notes
+	}
+	return s.CreateLink(ctx, model.CreateLinkRequest{
+		URL:        addUTM(destination, utm),
+		Notes:      notes,
+		Prefix:     template.Prefix,
+		CustomCode: req.CustomCode,
+	})
+}
+
+// ownedTemplate fetches the template with the given ID, if the caller in
+// ctx owns it. Other owners' templates are reported as
+// ErrTemplateNotFound.
+func (s *LinkService) ownedTemplate(ctx context.Context, id string) (*model.Template, error) {
+	template, err := s.templates.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("fetching template: %w", err)
+	}
+	if !owns(ctx, template.OwnerID) {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// fillPlaceholders replaces each {name} in pattern with value(name),
+// escaped for the part of the URL it appears in.
+func fillPlaceholders(pattern string, value func(name string) string) (string, error) {
+	var b strings.Builder
+	inQuery := false
+	for rest := pattern; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed placeholder in %q", pattern)
+		}
+		literal := rest[:open]
+		b.WriteString(literal)
+		inQuery = inQuery || strings.ContainsAny(literal, "?#")
+
+		v := value(rest[open+1 : open+end])
+		if inQuery {
+			b.WriteString(url.QueryEscape(v))
+		} else {
+			b.WriteString(url.PathEscape(v))
+		}
+		rest = rest[open+end+1:]
+	}
+	return b.String(), nil
+}
+
+// addUTM appends utm_<key>=<value> to rawURL's query for each key it
+// doesn't already carry, keeping the existing query as written.
+func addUTM(rawURL string, utm map[string]string) string {
+	if len(utm) == 0 {
+		return rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL // CreateLink reports it
+	}
+
+	keys := make([]string, 0, len(utm))
+	for key := range utm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	existing := parsed.Query()
+	query := parsed.RawQuery
+	for _, key := range keys {
+		name := "utm_" + key
+		if existing.Has(name) || utm[key] == "" {
+			continue
+		}
+		if query != "" {
+			query += "&"
+		}
+		query += name + "=" + url.QueryEscape(utm[key])
+	}
+	parsed.RawQuery = query
+	return parsed.String()
+}
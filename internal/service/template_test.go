@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func newTemplateService(t *testing.T) *LinkService {
+	t.Helper()
+	config := DefaultConfig()
+	config.Prefixes = repository.NewMemoryPrefixRepository()
+	config.Templates = repository.NewMemoryTemplateRepository()
+	return NewLinkService(repository.NewMemoryLinkRepository(), repository.NewMemoryClickRepository(), config)
+}
+
+func TestLinkService_CreateTemplate(t *testing.T) {
+	svc := newTemplateService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		req       model.CreateTemplateRequest
+		wantField string
+	}{
+		{"valid", model.CreateTemplateRequest{ID: "docs", URL: "https://example.com/docs/{page}"}, ""},
+		{"bad id", model.CreateTemplateRequest{ID: "a", URL: "https://example.com/{page}"}, "id"},
+		{"bad url", model.CreateTemplateRequest{ID: "bad-url", URL: "{host}/path"}, "url"},
+		{"unclosed placeholder", model.CreateTemplateRequest{ID: "unclosed", URL: "https://example.com/{page"}, "url"},
+		{"unknown utm", model.CreateTemplateRequest{ID: "utm", URL: "https://example.com", UTM: map[string]string{"id": "x"}}, "utm.id"},
+		{"unallocated prefix", model.CreateTemplateRequest{ID: "prefixed", URL: "https://example.com", Prefix: "ops"}, "prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateTemplate(ctx, tt.req)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var apiErr *apierror.Error
+			if !errors.As(err, &apiErr) || apiErr.Fields[tt.wantField] == "" {
+				t.Errorf("expected a validation error on %s, got %v", tt.wantField, err)
+			}
+		})
+	}
+
+	if _, err := svc.CreateTemplate(ctx, model.CreateTemplateRequest{ID: "docs", URL: "https://example.com"}); !errors.Is(err, ErrTemplateTaken) {
+		t.Errorf("expected ErrTemplateTaken, got %v", err)
+	}
+}
+
+func TestLinkService_CreateLinkFromTemplate(t *testing.T) {
+	svc := newTemplateService(t)
+	ctx := context.Background()
+
+	if _, err := svc.CreatePrefix(ctx, model.CreatePrefixRequest{Prefix: "shop"}); err != nil {
+		t.Fatalf("failed to allocate prefix: %v", err)
+	}
+	_, err := svc.CreateTemplate(ctx, model.CreateTemplateRequest{
+		ID:     "product",
+		URL:    "https://shop.example.com/{category}/{sku}?ref={ref}",
+		UTM:    map[string]string{"source": "newsletter", "medium": "email"},
+		Notes:  "product links",
+		Prefix: "shop",
+	})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+
+	resp, err := svc.CreateLinkFromTemplate(ctx, "product", model.CreateFromTemplateRequest{
+		Params: map[string]string{"category": "home & garden", "sku": "A/100", "ref": "a&b"},
+		UTM:    map[string]string{"medium": "sms"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "https://shop.example.com/home%20&%20garden/A%2F100?ref=a%26b&utm_medium=sms&utm_source=newsletter"
+	if resp.OriginalURL != want {
+		t.Errorf("expected destination\n%s\ngot\n%s", want, resp.OriginalURL)
+	}
+	details, err := svc.GetLink(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("failed to fetch link: %v", err)
+	}
+	if details.Notes != "product links" {
+		t.Errorf("expected the template's notes, got %q", details.Notes)
+	}
+	if len(resp.ShortCode) < 5 || resp.ShortCode[:5] != "shop-" {
+		t.Errorf("expected a code under shop-, got %s", resp.ShortCode)
+	}
+
+	_, err = svc.CreateLinkFromTemplate(ctx, "product", model.CreateFromTemplateRequest{
+		Params: map[string]string{"category": "toys", "color": "red"},
+	})
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	wantFields := map[string]string{
+		"params.sku":   apierror.CodeInvalidRequest,
+		"params.ref":   apierror.CodeInvalidRequest,
+		"params.color": apierror.CodeUnknownField,
+	}
+	for field, code := range wantFields {
+		if apiErr.Fields[field] != code {
+			t.Errorf("expected %s on %s, got %q", code, field, apiErr.Fields[field])
+		}
+	}
+
+	if _, err := svc.CreateLinkFromTemplate(ctx, "missing", model.CreateFromTemplateRequest{}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// ErrThumbnailUnavailable is returned when a destination's thumbnail
+// isn't stored and couldn't be captured.
+var ErrThumbnailUnavailable = apierror.New(apierror.CodeNoThumbnail, "thumbnail could not be captured")
+
+// thumbnailTimeout bounds a single capture.
+const thumbnailTimeout = 30 * time.Second
+
+// ThumbnailCapturer renders an image of a destination, e.g. through an
+// external screenshot service. thumbnail.HTTP implements it.
+type ThumbnailCapturer interface {
+	Capture(ctx context.Context, rawURL string) (data []byte, contentType string, err error)
+}
+
+// ThumbnailsEnabled reports whether the service was configured to capture
+// and store destination thumbnails.
+func (s *LinkService) ThumbnailsEnabled() bool {
+	return s.thumbnailCapturer != nil && s.thumbnails != nil
+}
+
+// Thumbnail returns an image of a link's destination. Thumbnails are
+// captured on first request and kept until the destination changes.
+func (s *LinkService) Thumbnail(ctx context.Context, shortCode string) (*model.Thumbnail, error) {
+	if !s.ThumbnailsEnabled() {
+		return nil, ErrThumbnailUnavailable
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail, err := s.thumbnails.Get(ctx, link.ID)
+	switch {
+	case err == nil && thumbnail.SourceURL == link.OriginalURL:
+		return thumbnail, nil
+	case err != nil && !errors.Is(err, repository.ErrNotFound):
+		return nil, fmt.Errorf("fetching thumbnail: %w", err)
+	}
+
+	captureCtx, cancel := context.WithTimeout(ctx, thumbnailTimeout)
+	defer cancel()
+	data, contentType, err := s.thumbnailCapturer.Capture(captureCtx, link.OriginalURL)
+	if err != nil {
+		s.logger.WarnContext(ctx, "thumbnail capture failed", "code", link.ShortCode, "error", err)
+		return nil, ErrThumbnailUnavailable
+	}
+
+	thumbnail = &model.Thumbnail{ContentType: contentType, SourceURL: link.OriginalURL, Data: data}
+	if err := s.thumbnails.Put(ctx, link.ID, thumbnail); err != nil {
+		// Still worth serving; the next request captures again
+		s.logger.WarnContext(ctx, "storing thumbnail failed", "code", link.ShortCode, "error", err)
+	}
+	return thumbnail, nil
+}
+
+// deleteThumbnail removes a deleted link's thumbnail, best effort.
+func (s *LinkService) deleteThumbnail(ctx context.Context, linkID string) {
+	if s.thumbnails == nil || linkID == "" {
+		return
+	}
+	if err := s.thumbnails.Delete(ctx, linkID); err != nil {
+		s.logger.WarnContext(ctx, "deleting thumbnail failed", "link_id", linkID, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// stubCapturer renders a destination as its own URL, counting captures.
+type stubCapturer struct {
+	captures int
+}
+
+func (c *stubCapturer) Capture(ctx context.Context, rawURL string) ([]byte, string, error) {
+	c.captures++
+	if rawURL == "https://example.com/broken" {
+		return nil, "", errors.New("render failed")
+	}
+	return []byte(rawURL), "image/png", nil
+}
+
+func TestLinkService_Thumbnail(t *testing.T) {
+	capturer := &stubCapturer{}
+	thumbnails := repository.NewMemoryThumbnailRepository()
+	config := DefaultConfig()
+	config.ThumbnailCapturer = capturer
+	config.Thumbnails = thumbnails
+	linkRepo := repository.NewMemoryLinkRepository()
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if _, ok := resp.Links["thumbnail"]; !ok {
+		t.Error("expected a thumbnail link when thumbnails are enabled")
+	}
+
+	for i := 0; i < 2; i++ {
+		thumbnail, err := svc.Thumbnail(ctx, resp.ShortCode)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(thumbnail.Data) != "https://example.com/a" || thumbnail.ContentType != "image/png" {
+			t.Errorf("unexpected thumbnail %q (%s)", thumbnail.Data, thumbnail.ContentType)
+		}
+	}
+	if capturer.captures != 1 {
+		t.Errorf("expected the stored thumbnail to be reused, got %d captures", capturer.captures)
+	}
+
+	// A new destination is captured again
+	newURL := "https://example.com/b"
+	if _, err := svc.UpdateLink(ctx, resp.ShortCode, LinkPatch{URL: &newURL}, 0); err != nil {
+		t.Fatalf("failed to update link: %v", err)
+	}
+	thumbnail, err := svc.Thumbnail(ctx, resp.ShortCode)
+	if err != nil || string(thumbnail.Data) != newURL {
+		t.Errorf("expected a fresh thumbnail of %s, got %v (%v)", newURL, thumbnail, err)
+	}
+
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	if err := svc.DeleteLink(ctx, resp.ShortCode, 0); err != nil {
+		t.Fatalf("failed to delete link: %v", err)
+	}
+	if _, err := thumbnails.Get(ctx, link.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the thumbnail to be deleted with its link, got %v", err)
+	}
+
+	broken, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com/broken"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if _, err := svc.Thumbnail(ctx, broken.ShortCode); err != ErrThumbnailUnavailable {
+		t.Errorf("expected ErrThumbnailUnavailable, got %v", err)
+	}
+	if _, err := svc.Thumbnail(ctx, "missing"); err != ErrLinkNotFound {
+		t.Errorf("expected ErrLinkNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+// Timeseries ranges.
+const (
+	DefaultTimeseriesDays = 30
+	MaxTimeseriesDays     = 366
+)
+
+// dayLayout formats rollup days.
+const dayLayout = "2006-01-02"
+
+// ClickTimeseries returns a link's clicks per day between from and to
+// (YYYY-MM-DD, inclusive), with days running midnight to midnight in the
+// IANA time zone tz, or UTC when tz is empty. to defaults to today and
+// from to the DefaultTimeseriesDays days ending at to. Every day in the
+// range is present, with zero counts where there were no clicks. Rollups
+// are read when configured and tz is UTC; otherwise raw click events are
+// aggregated, since rollups only hold UTC days.
+func (s *LinkService) ClickTimeseries(ctx context.Context, shortCode, from, to, tz string) (*model.ClickTimeseries, error) {
+	loc, err := timeZone(tz)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := timeseriesRange(from, to, s.now().In(loc))
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.dailyClicks(ctx, link, start.Format(dayLayout), end.Format(dayLayout), loc)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]model.DailyClicks, len(days))
+	for _, day := range days {
+		byDate[day.Date] = day
+	}
+
+	series := &model.ClickTimeseries{
+		ShortCode: link.ShortCode,
+		From:      start.Format(dayLayout),
+		To:        end.Format(dayLayout),
+		TimeZone:  loc.String(),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dayLayout)
+		if rollup, ok := byDate[date]; ok {
+			series.Days = append(series.Days, rollup)
+		} else {
+			series.Days = append(series.Days, model.DailyClicks{Date: date})
+		}
+	}
+	return series, nil
+}
+
+// timeZone loads a requested IANA time zone, defaulting to UTC. "Local"
+// is refused: it would be the server's zone, not the caller's.
+func timeZone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, validationError(map[string]string{"tz": apierror.CodeInvalidRequest})
+	}
+	return loc, nil
+}
+
+// civilDate returns the calendar date of t in its own zone, as midnight
+// UTC, so dates can be stepped through a day at a time regardless of
+// daylight saving changes.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// timeseriesRange parses and bounds a requested date range, as civil
+// dates. to defaults to the date of now in now's zone.
+func timeseriesRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	fields := make(map[string]string)
+
+	end := civilDate(now)
+	if to != "" {
+		parsed, err := time.Parse(dayLayout, to)
+		if err != nil {
+			fields["to"] = apierror.CodeInvalidRequest
+		}
+		end = parsed
+	}
+	start := end.AddDate(0, 0, -(DefaultTimeseriesDays - 1))
+	if from != "" {
+		parsed, err := time.Parse(dayLayout, from)
+		if err != nil {
+			fields["from"] = apierror.CodeInvalidRequest
+		}
+		start = parsed
+	}
+
+	switch {
+	case len(fields) > 0:
+	case start.After(end):
+		fields["from"] = apierror.CodeInvalidRequest
+	case end.Sub(start) >= MaxTimeseriesDays*24*time.Hour:
+		fields["from"] = apierror.CodeTooLong
+	}
+	if len(fields) > 0 {
+		return start, end, validationError(fields)
+	}
+	return start, end, nil
+}
+
+// dailyClicks returns a link's per-day aggregates between from and to,
+// with days in loc. UTC days come from rollups when configured; other
+// zones, or no rollups, scan click events.
+func (s *LinkService) dailyClicks(ctx context.Context, link *model.Link, from, to string, loc *time.Location) ([]model.DailyClicks, error) {
+	if s.rollups != nil && loc == time.UTC {
+		days, err := s.rollups.GetRange(ctx, link.ID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("fetching rollups: %w", err)
+		}
+		return days, nil
+	}
+
+	clicks, err := s.clickRepo.GetByLinkID(ctx, link.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetching clicks: %w", err)
+	}
+
+	var days []model.DailyClicks
+	index := make(map[string]int)
+	for _, click := range clicks {
+		if !s.counts(&click) {
+			continue
+		}
+		date := click.ClickedAt.In(loc).Format(dayLayout)
+		if date < from || date > to {
+			continue
+		}
+		i, ok := index[date]
+		if !ok {
+			i = len(days)
+			index[date] = i
+			days = append(days, model.DailyClicks{Date: date, BySource: make(map[string]int64)})
+		}
+		days[i].Clicks++
+		days[i].BySource[ClickSource(click.Source)]++
+	}
+	return days, nil
+}
+
+// recordRollup counts a click in the day's rollup, if rollups are kept.
+func (s *LinkService) recordRollup(ctx context.Context, event *model.ClickEvent) {
+	if s.rollups == nil {
+		return
+	}
+	if err := s.rollups.AddClick(ctx, event.LinkID, event.ClickedAt.Format(dayLayout), event.Source); err != nil {
+		s.logger.WarnContext(ctx, "failed to update click rollup", "link_id", event.LinkID, "error", err)
+	}
+}
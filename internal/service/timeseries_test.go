@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+	"github.com/colby/snip/pkg/apierror"
+)
+
+func TestLinkService_ClickTimeseries(t *testing.T) {
+	for _, withRollups := range []bool{true, false} {
+		name := "events"
+		if withRollups {
+			name = "rollups"
+		}
+		t.Run(name, func(t *testing.T) {
+			linkRepo := repository.NewMemoryLinkRepository()
+			clickRepo := repository.NewMemoryClickRepository()
+			config := DefaultConfig()
+			if withRollups {
+				config.Rollups = repository.NewMemoryStatsRollupRepository()
+			}
+			svc := NewLinkService(linkRepo, clickRepo, config)
+			ctx := context.Background()
+
+			resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+			if err != nil {
+				t.Fatalf("failed to create link: %v", err)
+			}
+			link, err := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+			if err != nil {
+				t.Fatalf("failed to fetch link: %v", err)
+			}
+			for _, src := range []string{"qr", "", ""} {
+				svc.recordClick(ctx, link, ClickMetadata{Source: src})
+			}
+
+			today := time.Now().UTC().Format(dayLayout)
+			series, err := svc.ClickTimeseries(ctx, resp.ShortCode, "", "", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(series.Days) != DefaultTimeseriesDays {
+				t.Fatalf("expected %d days, got %d", DefaultTimeseriesDays, len(series.Days))
+			}
+			if series.To != today {
+				t.Errorf("expected range to end %s, got %s", today, series.To)
+			}
+
+			last := series.Days[len(series.Days)-1]
+			if last.Date != today || last.Clicks != 3 {
+				t.Errorf("expected 3 clicks on %s, got %d on %s", today, last.Clicks, last.Date)
+			}
+			if got := last.BySource[model.ClickSourceQR]; got != 1 {
+				t.Errorf("expected 1 QR scan, got %d", got)
+			}
+			if first := series.Days[0]; first.Clicks != 0 {
+				t.Errorf("expected no clicks on %s, got %d", first.Date, first.Clicks)
+			}
+
+			stats, err := svc.GetStats(ctx, resp.ShortCode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := stats.ClicksBySource[model.ClickSourceLink]; got != 2 {
+				t.Errorf("expected 2 link clicks in stats, got %d", got)
+			}
+		})
+	}
+}
+
+func TestLinkService_ClickTimeseries_TimeZone(t *testing.T) {
+	// 00:30 UTC on March 11 is still the evening of March 10 in New York
+	clock := &fakeClock{now: time.Date(2024, 3, 11, 0, 30, 0, 0, time.UTC)}
+	config := DefaultConfig()
+	config.Rollups = repository.NewMemoryStatsRollupRepository()
+	config.Clock = clock
+	linkRepo := repository.NewMemoryLinkRepository()
+	svc := NewLinkService(linkRepo, repository.NewMemoryClickRepository(), config)
+	ctx := context.Background()
+
+	resp, err := svc.CreateLink(ctx, model.CreateLinkRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	link, _ := linkRepo.GetByShortCode(ctx, resp.ShortCode)
+	svc.recordClick(ctx, link, ClickMetadata{})
+
+	tests := []struct {
+		tz       string
+		wantTo   string
+		wantDate string
+	}{
+		{"", "2024-03-11", "2024-03-11"},
+		{"UTC", "2024-03-11", "2024-03-11"},
+		{"America/New_York", "2024-03-10", "2024-03-10"},
+		{"Asia/Tokyo", "2024-03-11", "2024-03-11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tz, func(t *testing.T) {
+			series, err := svc.ClickTimeseries(ctx, resp.ShortCode, "2024-03-09", "", tt.tz)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if series.To != tt.wantTo {
+				t.Errorf("expected the range to end on %s, got %s", tt.wantTo, series.To)
+			}
+			for _, day := range series.Days {
+				want := int64(0)
+				if day.Date == tt.wantDate {
+					want = 1
+				}
+				if day.Clicks != want {
+					t.Errorf("expected %d clicks on %s, got %d", want, day.Date, day.Clicks)
+				}
+			}
+		})
+	}
+
+	for _, tz := range []string{"Mars/Olympus_Mons", "Local"} {
+		if _, err := svc.ClickTimeseries(ctx, resp.ShortCode, "", "", tz); apierror.CodeOf(err) != apierror.CodeValidationFailed {
+			t.Errorf("expected a validation error for %q, got %v", tz, err)
+		}
+	}
+}
+
+func TestTimeseriesRange(t *testing.T) {
+	now := time.Date(2024, 6, 15, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		from, to  string
+		wantFrom  string
+		wantTo    string
+		wantField string
+	}{
+		{"defaults", "", "", "2024-05-17", "2024-06-15", ""},
+		{"explicit", "2024-01-01", "2024-01-31", "2024-01-01", "2024-01-31", ""},
+		{"from only", "2024-06-01", "", "2024-06-01", "2024-06-15", ""},
+		{"malformed", "June 1", "", "", "", "from"},
+		{"reversed", "2024-02-01", "2024-01-01", "", "", "from"},
+		{"too long", "2022-01-01", "2024-01-01", "", "", "from"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := timeseriesRange(tt.from, tt.to, now)
+			if tt.wantField != "" {
+				if apierror.CodeOf(err) != apierror.CodeValidationFailed {
+					t.Fatalf("expected validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := start.Format(dayLayout); got != tt.wantFrom {
+				t.Errorf("expected from %s, got %s", tt.wantFrom, got)
+			}
+			if got := end.Format(dayLayout); got != tt.wantTo {
+				t.Errorf("expected to %s, got %s", tt.wantTo, got)
+			}
+		})
+	}
+}
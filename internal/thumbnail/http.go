@@ -0,0 +1,104 @@
+// Package thumbnail captures images of link destinations for dashboards.
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTP capture defaults.
+const (
+	DefaultMaxBytes = 5 << 20
+	httpTimeout     = 30 * time.Second
+)
+
+// URLPlaceholder is replaced with the query-escaped destination in
+// HTTPConfig.Endpoint.
+const URLPlaceholder = "{url}"
+
+// ErrNotImage is returned when the screenshot service answers with
+// something other than a raster image.
+var ErrNotImage = errors.New("screenshot service did not return an image")
+
+// imageTypes are the content types accepted from screenshot services.
+// SVG is left out: served from Snip's origin it could carry script.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// HTTPConfig configures an HTTP capturer.
+type HTTPConfig struct {
+	// Endpoint is the screenshot service URL, with URLPlaceholder where the
+	// destination goes, e.g.
+	// "https://shots.example.com/capture?key=...&width=640&url={url}".
+	Endpoint string
+
+	MaxBytes int64        // largest accepted image; defaults to DefaultMaxBytes
+	Client   *http.Client // defaults to a client with a 30s timeout
+}
+
+// HTTP captures thumbnails with an external screenshot service that
+// renders a URL given in its query string and answers with the image.
+type HTTP struct {
+	endpoint string
+	maxBytes int64
+	client   *http.Client
+}
+
+// NewHTTP creates an HTTP capturer.
+func NewHTTP(config HTTPConfig) *HTTP {
+	h := &HTTP{
+		endpoint: config.Endpoint,
+		maxBytes: config.MaxBytes,
+		client:   config.Client,
+	}
+	if h.maxBytes <= 0 {
+		h.maxBytes = DefaultMaxBytes
+	}
+	if h.client == nil {
+		h.client = &http.Client{Timeout: httpTimeout}
+	}
+	return h
+}
+
+// Capture returns an image of rawURL and its content type.
+func (h *HTTP) Capture(ctx context.Context, rawURL string) ([]byte, string, error) {
+	endpoint := strings.ReplaceAll(h.endpoint, URLPlaceholder, url.QueryEscape(rawURL))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("building screenshot request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("screenshot request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("screenshot service answered %s", resp.Status)
+	}
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !imageTypes[contentType] {
+		return nil, "", ErrNotImage
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading screenshot: %w", err)
+	}
+	if int64(len(data)) > h.maxBytes {
+		return nil, "", fmt.Errorf("screenshot larger than %d bytes", h.maxBytes)
+	}
+	return data, contentType, nil
+}
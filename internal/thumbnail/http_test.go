@@ -0,0 +1,48 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP_Capture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "https://example.com/a?b=c":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png-bytes"))
+		case "https://example.com/svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte("<svg/>"))
+		default:
+			http.Error(w, "capture failed", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	capturer := NewHTTP(HTTPConfig{Endpoint: srv.URL + "/shot?key=k&url=" + URLPlaceholder})
+	ctx := context.Background()
+
+	data, contentType, err := capturer.Capture(ctx, "https://example.com/a?b=c")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "png-bytes" || contentType != "image/png" {
+		t.Errorf("got %q (%s), want png-bytes (image/png)", data, contentType)
+	}
+
+	if _, _, err := capturer.Capture(ctx, "https://example.com/svg"); !errors.Is(err, ErrNotImage) {
+		t.Errorf("expected ErrNotImage for SVG, got %v", err)
+	}
+	if _, _, err := capturer.Capture(ctx, "https://example.com/broken"); err == nil {
+		t.Error("expected an error when the service fails")
+	}
+
+	small := NewHTTP(HTTPConfig{Endpoint: srv.URL + "/?url=" + URLPlaceholder, MaxBytes: 4})
+	if _, _, err := small.Capture(ctx, "https://example.com/a?b=c"); err == nil {
+		t.Error("expected an error for an oversized image")
+	}
+}
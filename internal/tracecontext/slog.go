@@ -0,0 +1,38 @@
+package tracecontext
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogHandler decorates a slog.Handler so records logged with a context
+// carrying a trace include trace_id and span_id attributes.
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps next with trace-aware attribute injection.
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{Handler: next}
+}
+
+// Handle adds trace attributes before delegating to the wrapped handler.
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if tc, ok := FromContext(ctx); ok {
+		record.AddAttrs(
+			slog.String("trace_id", tc.TraceID),
+			slog.String("span_id", tc.SpanID),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs preserves the trace-aware wrapper.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup preserves the trace-aware wrapper.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
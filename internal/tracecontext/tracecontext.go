@@ -0,0 +1,169 @@
+// Package tracecontext implements W3C Trace Context propagation
+// (https://www.w3.org/TR/trace-context/) for incoming and outgoing requests.
+package tracecontext
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Header names defined by the specification.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateLen caps the tracestate we are willing to forward.
+const maxTracestateLen = 512
+
+// TraceContext identifies the trace a request belongs to and the span
+// (parent-id) that issued it.
+type TraceContext struct {
+	TraceID string // 32 lowercase hex characters
+	SpanID  string // 16 lowercase hex characters
+	Flags   string // 2 lowercase hex characters; "01" means sampled
+	State   string // opaque vendor-specific tracestate, forwarded as-is
+}
+
+// Parse validates a traceparent header (and accompanying tracestate).
+// It returns false if the traceparent is missing or malformed, in which
+// case the caller should start a new trace.
+func Parse(traceparent, tracestate string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// Version ff is forbidden; version 00 must have exactly four fields.
+	// Higher versions may append fields we don't understand.
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+	if !isHex(traceID, 32) || isZero(traceID) {
+		return TraceContext{}, false
+	}
+	if !isHex(spanID, 16) || isZero(spanID) {
+		return TraceContext{}, false
+	}
+	if !isHex(flags, 2) {
+		return TraceContext{}, false
+	}
+
+	tc := TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Flags:   flags,
+	}
+	if len(tracestate) <= maxTracestateLen {
+		tc.State = strings.TrimSpace(tracestate)
+	}
+
+	return tc, true
+}
+
+// New starts a fresh, sampled trace.
+func New() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   "01",
+	}
+}
+
+// FromRequest extracts the trace context from an HTTP request, starting a
+// new trace when none (or an invalid one) was supplied.
+func FromRequest(r *http.Request) TraceContext {
+	return FromHeaders(r.Header.Get(TraceparentHeader), r.Header.Get(TracestateHeader))
+}
+
+// FromHeaders is FromRequest for transports that expose raw header values,
+// such as API Gateway events.
+func FromHeaders(traceparent, tracestate string) TraceContext {
+	if tc, ok := Parse(traceparent, tracestate); ok {
+		return tc
+	}
+	return New()
+}
+
+// Child returns a context for an outgoing call: same trace, new span.
+func (tc TraceContext) Child() TraceContext {
+	child := tc
+	child.SpanID = randomHex(8)
+	return child
+}
+
+// Traceparent renders the version-00 traceparent header value.
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// IsValid reports whether tc carries a trace.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != ""
+}
+
+type contextKey struct{}
+
+// WithContext attaches tc to ctx.
+func WithContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, tc)
+}
+
+// FromContext returns the trace context attached to ctx, if any.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(contextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// Inject writes traceparent/tracestate headers for an outgoing request made
+// on behalf of ctx. It is a no-op when ctx carries no trace.
+func Inject(ctx context.Context, header http.Header) {
+	tc, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	child := tc.Child()
+	header.Set(TraceparentHeader, child.Traceparent())
+	if child.State != "" {
+		header.Set(TracestateHeader, child.State)
+	}
+}
+
+// Middleware attaches the incoming (or a newly started) trace context to
+// each request's context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc := FromRequest(r)
+		next.ServeHTTP(w, r.WithContext(WithContext(r.Context(), tc)))
+	})
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
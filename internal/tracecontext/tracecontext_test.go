@@ -0,0 +1,115 @@
+package tracecontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantOK      bool
+	}{
+		{"valid", validTraceparent, true},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"empty", "", false},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"version 00 with extra field", validTraceparent + "-extra", false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"short trace id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, ok := Parse(tt.traceparent, "vendor=value")
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if ok && tc.State != "vendor=value" {
+				t.Errorf("expected tracestate to be kept, got %q", tc.State)
+			}
+		})
+	}
+}
+
+func TestFromHeaders_StartsNewTrace(t *testing.T) {
+	tc := FromHeaders("garbage", "")
+	if !tc.IsValid() {
+		t.Fatal("expected a new trace to be started")
+	}
+	if _, ok := Parse(tc.Traceparent(), ""); !ok {
+		t.Errorf("generated traceparent %q does not parse", tc.Traceparent())
+	}
+}
+
+func TestInject(t *testing.T) {
+	tc, _ := Parse(validTraceparent, "vendor=value")
+	ctx := WithContext(context.Background(), tc)
+
+	header := http.Header{}
+	Inject(ctx, header)
+
+	child, ok := Parse(header.Get(TraceparentHeader), header.Get(TracestateHeader))
+	if !ok {
+		t.Fatalf("injected traceparent %q does not parse", header.Get(TraceparentHeader))
+	}
+	if child.TraceID != tc.TraceID {
+		t.Errorf("expected trace ID %s, got %s", tc.TraceID, child.TraceID)
+	}
+	if child.SpanID == tc.SpanID {
+		t.Error("expected a new span ID for the outgoing call")
+	}
+	if child.State != "vendor=value" {
+		t.Errorf("expected tracestate to be forwarded, got %q", child.State)
+	}
+
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("expected no headers without a trace, got %v", empty)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got TraceContext
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, validTraceparent)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected incoming trace ID, got %q", got.TraceID)
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	tc, _ := Parse(validTraceparent, "")
+	logger.InfoContext(WithContext(context.Background(), tc), "hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log line: %v", err)
+	}
+	if record["trace_id"] != tc.TraceID {
+		t.Errorf("expected trace_id %s, got %v", tc.TraceID, record["trace_id"])
+	}
+	if record["component"] != "test" {
+		t.Errorf("expected attributes from With to be kept, got %v", record["component"])
+	}
+}
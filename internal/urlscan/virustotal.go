@@ -0,0 +1,103 @@
+// Package urlscan checks link destinations against URL reputation services.
+package urlscan
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// VirusTotal defaults.
+const (
+	DefaultVirusTotalURL  = "https://www.virustotal.com"
+	DefaultMinDetections  = 2
+	virusTotalHTTPTimeout = 10 * time.Second
+)
+
+// VirusTotalConfig configures the VirusTotal scanner.
+type VirusTotalConfig struct {
+	APIKey string
+
+	// MinDetections is how many engines must call a URL malicious before
+	// it's flagged; a single engine is often a false positive. Defaults to
+	// DefaultMinDetections.
+	MinDetections int
+
+	BaseURL string       // defaults to DefaultVirusTotalURL; overridable for tests
+	Client  *http.Client // defaults to a client with a 10s timeout
+}
+
+// VirusTotal looks URLs up in VirusTotal's v3 API. URLs VirusTotal has
+// never analyzed are reported as not malicious.
+type VirusTotal struct {
+	apiKey        string
+	minDetections int
+	baseURL       string
+	client        *http.Client
+}
+
+// NewVirusTotal creates a VirusTotal scanner.
+func NewVirusTotal(config VirusTotalConfig) *VirusTotal {
+	v := &VirusTotal{
+		apiKey:        config.APIKey,
+		minDetections: config.MinDetections,
+		baseURL:       config.BaseURL,
+		client:        config.Client,
+	}
+	if v.minDetections <= 0 {
+		v.minDetections = DefaultMinDetections
+	}
+	if v.baseURL == "" {
+		v.baseURL = DefaultVirusTotalURL
+	}
+	if v.client == nil {
+		v.client = &http.Client{Timeout: virusTotalHTTPTimeout}
+	}
+	return v
+}
+
+// urlReport is the part of a VirusTotal URL object we read.
+type urlReport struct {
+	Data struct {
+		Attributes struct {
+			LastAnalysisStats struct {
+				Malicious int `json:"malicious"`
+			} `json:"last_analysis_stats"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Scan reports whether enough VirusTotal engines flag rawURL as malicious.
+func (v *VirusTotal) Scan(ctx context.Context, rawURL string) (bool, error) {
+	// VirusTotal identifies URLs by their unpadded base64url encoding
+	id := base64.RawURLEncoding.EncodeToString([]byte(rawURL))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/api/v3/urls/"+id, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("x-apikey", v.apiKey)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("virustotal request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("virustotal returned %s", resp.Status)
+	}
+
+	var report urlReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return false, fmt.Errorf("decoding virustotal report: %w", err)
+	}
+	return report.Data.Attributes.LastAnalysisStats.Malicious >= v.minDetections, nil
+}
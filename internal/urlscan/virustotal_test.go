@@ -0,0 +1,63 @@
+package urlscan
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVirusTotal_Scan(t *testing.T) {
+	detections := map[string]int{
+		"https://evil.example/":  5,
+		"https://fluke.example/": 1,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-apikey") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/api/v3/urls/"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n, ok := detections[string(raw)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"data": {"attributes": {"last_analysis_stats": {"malicious": %d, "harmless": 60}}}}`, n)
+	}))
+	defer srv.Close()
+
+	scanner := NewVirusTotal(VirusTotalConfig{APIKey: "test-key", BaseURL: srv.URL})
+	ctx := context.Background()
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://evil.example/", true},
+		{"https://fluke.example/", false}, // below MinDetections
+		{"https://unknown.example/", false},
+	}
+	for _, tt := range tests {
+		got, err := scanner.Scan(ctx, tt.url)
+		if err != nil {
+			t.Fatalf("Scan(%s) unexpected error: %v", tt.url, err)
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	bad := NewVirusTotal(VirusTotalConfig{APIKey: "wrong", BaseURL: srv.URL})
+	if _, err := bad.Scan(ctx, "https://evil.example/"); err == nil {
+		t.Error("expected an error for a rejected API key")
+	}
+}
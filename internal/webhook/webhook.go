@@ -0,0 +1,184 @@
+// Package webhook delivers domain events from the event bus to an HTTP
+// endpoint, such as a chat integration or an email relay.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/internal/outbound"
+	"github.com/colby/snip/pkg/snipclient"
+)
+
+// Notifier defaults.
+const (
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = time.Second
+	httpTimeout        = 10 * time.Second
+)
+
+// DefaultEvents are the event types delivered when none are configured.
+var DefaultEvents = []string{events.TypeMilestoneReached}
+
+// bufferSize is how many events may wait for delivery before the bus
+// starts dropping them for this notifier.
+const bufferSize = 256
+
+// Config configures a Notifier.
+type Config struct {
+	URL    string   // endpoint events are POSTed to
+	Secret string   // signs each body when set; receivers verify with snipclient.VerifyWebhook
+	Events []string // event types to deliver; defaults to DefaultEvents
+
+	// Failed deliveries (network errors and non-2xx responses) are tried
+	// up to MaxAttempts times, waiting RetryDelay, then twice as long, and
+	// so on between attempts. Defaults to DefaultMaxAttempts and
+	// DefaultRetryDelay.
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	// AllowPrivate lets deliveries reach loopback and private addresses,
+	// for endpoints on the internal network. Ignored when Client is set.
+	AllowPrivate bool
+
+	Client *http.Client // defaults to an outbound client with a 10s timeout
+	Logger *slog.Logger
+}
+
+// Notifier POSTs events as JSON, one request per event, in the order they
+// were published. Deliveries happen in the background; events that still
+// fail after every attempt are logged and dropped.
+type Notifier struct {
+	url         string
+	secret      []byte
+	events      []string
+	maxAttempts int
+	retryDelay  time.Duration
+	client      *http.Client
+	logger      *slog.Logger
+
+	cancel func()
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New creates a Notifier. Call Subscribe to start delivering events.
+func New(config Config) *Notifier {
+	n := &Notifier{
+		url:         config.URL,
+		secret:      []byte(config.Secret),
+		events:      config.Events,
+		maxAttempts: config.MaxAttempts,
+		retryDelay:  config.RetryDelay,
+		client:      config.Client,
+		logger:      config.Logger,
+		done:        make(chan struct{}),
+	}
+	if len(n.events) == 0 {
+		n.events = DefaultEvents
+	}
+	if n.maxAttempts <= 0 {
+		n.maxAttempts = DefaultMaxAttempts
+	}
+	if n.retryDelay <= 0 {
+		n.retryDelay = DefaultRetryDelay
+	}
+	if n.client == nil {
+		n.client = outbound.NewClient(outbound.ClientConfig{Timeout: httpTimeout, AllowPrivate: config.AllowPrivate})
+	}
+	if n.logger == nil {
+		n.logger = slog.Default()
+	}
+	return n
+}
+
+// Subscribe starts delivering the configured event types published on
+// bus. It must be called at most once.
+func (n *Notifier) Subscribe(bus *events.Bus) {
+	ch, cancel := bus.Subscribe(bufferSize, func(e events.Event) bool {
+		return slices.Contains(n.events, e.Type)
+	})
+	n.cancel = cancel
+
+	go func() {
+		defer close(n.done)
+		for event := range ch {
+			if err := n.Deliver(context.Background(), event); err != nil {
+				n.logger.Error("webhook delivery failed", "type", event.Type, "short_code", event.ShortCode, "error", err)
+			}
+		}
+	}()
+}
+
+// Close stops receiving events and waits for those already received to be
+// delivered, until ctx is done.
+func (n *Notifier) Close(ctx context.Context) error {
+	if n.cancel == nil {
+		return nil
+	}
+	n.once.Do(n.cancel)
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Deliver POSTs one event, retrying failures.
+func (n *Notifier) Deliver(ctx context.Context, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, event.Type, body)
+		if err == nil || attempt == n.maxAttempts {
+			return err
+		}
+		n.logger.Warn("webhook delivery attempt failed", "type", event.Type, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// post sends a single delivery attempt.
+func (n *Notifier) post(ctx context.Context, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(snipclient.HeaderEvent, eventType)
+	if len(n.secret) > 0 {
+		// Each attempt is signed afresh, so retries aren't rejected as stale
+		timestamp, signature := snipclient.Sign(n.secret, time.Now(), body)
+		req.Header.Set(snipclient.HeaderTimestamp, timestamp)
+		req.Header.Set(snipclient.HeaderSignature, signature)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/events"
+	"github.com/colby/snip/pkg/snipclient"
+)
+
+func TestNotifier_DeliversSignedEvents(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		event  *snipclient.WebhookEvent
+		err    error
+	}
+	received := make(chan delivery, 1)
+	var deliveries int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries++
+		event, err := snipclient.VerifyWebhook(r, []byte("s3cret"))
+		received <- delivery{r.Header, event, err}
+	}))
+	defer server.Close()
+
+	bus := events.NewBus()
+	n := New(Config{URL: server.URL, AllowPrivate: true, Secret: "s3cret"})
+	n.Subscribe(bus)
+
+	bus.Publish(events.Event{Type: events.TypeClickRecorded, ShortCode: "abc"}) // not subscribed
+	bus.Publish(events.Event{Type: events.TypeMilestoneReached, ShortCode: "abc", Milestone: 100})
+
+	select {
+	case d := <-received:
+		if got := d.header.Get(snipclient.HeaderEvent); got != events.TypeMilestoneReached {
+			t.Errorf("expected event header %q, got %q", events.TypeMilestoneReached, got)
+		}
+		if d.err != nil {
+			t.Fatalf("expected a valid signature, got %v", d.err)
+		}
+		if d.event.ShortCode != "abc" || d.event.Milestone != 100 {
+			t.Errorf("unexpected event %+v", d.event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if deliveries != 1 {
+		t.Errorf("expected 1 delivery, got %d", deliveries)
+	}
+}
+
+func TestNotifier_RetriesFailures(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	n := New(Config{URL: server.URL, AllowPrivate: true, RetryDelay: time.Millisecond})
+	if err := n.Deliver(context.Background(), events.Event{Type: events.TypeMilestoneReached}); err != nil {
+		t.Fatalf("expected delivery to succeed on the third attempt, got %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	attempts.Store(-10)
+	if err := n.Deliver(context.Background(), events.Event{Type: events.TypeMilestoneReached}); err == nil {
+		t.Error("expected an error after exhausting attempts")
+	}
+	if got := attempts.Load(); got != -7 {
+		t.Errorf("expected %d attempts, got %d", DefaultMaxAttempts, got+10)
+	}
+}
@@ -0,0 +1,88 @@
+// Package apierror defines Snip's machine-readable error codes. Codes are
+// part of the public API contract: they are returned in the "code" field of
+// every JSON error body and never change meaning once published, so clients
+// can branch on them instead of parsing messages.
+package apierror
+
+import "errors"
+
+// Error codes. Add new codes rather than repurposing existing ones.
+const (
+	CodeInvalidRequest    = "invalid_request"        // malformed request body or parameters
+	CodeURLRequired       = "url_required"           // destination URL missing
+	CodeInvalidURL        = "invalid_url"            // destination URL malformed or unsupported
+	CodeShortCodeRequired = "short_code_required"    // short code missing from the path
+	CodeLinkNotFound      = "link_not_found"         // no link with that short code
+	CodeLinkDisabled      = "link_disabled"          // link exists but its redirect is disabled
+	CodeReadOnly          = "read_only"              // instance rejects writes
+	CodeCodeGeneration    = "code_generation_failed" // no unique short code could be allocated
+	CodeUnauthorized      = "unauthorized"           // missing or invalid credentials
+	CodeNotFound          = "not_found"              // no such route
+	CodeValidationFailed  = "validation_failed"      // one or more fields are invalid; see Fields
+	CodeUnsupportedMedia  = "unsupported_media_type" // request Content-Type not accepted
+	CodeVersionConflict   = "version_conflict"       // If-Match version is stale
+	CodePrefixNotFound    = "prefix_not_found"       // no namespace prefix with that name
+	CodePrefixTaken       = "prefix_taken"           // namespace prefix already allocated
+	CodeShortenerURL      = "shortener_url"          // destination is on another URL shortener
+	CodeSuspiciousURL     = "suspicious_url"         // destination scored as a likely phishing link
+	CodeDeadURL           = "dead_url"               // destination failed the reachability check
+	CodeNoThumbnail       = "thumbnail_unavailable"  // destination thumbnail couldn't be captured
+	CodeReferrerBlocked   = "referrer_not_allowed"   // link only redirects visitors from allowed referrers
+	CodeForbidden         = "forbidden"              // route is restricted to other networks or client certificates
+	CodeTemplateNotFound  = "template_not_found"     // no link template with that ID
+	CodeTemplateTaken     = "template_taken"         // a link template already uses that ID
+	CodeAliasNotFound     = "alias_not_found"        // the link has no secondary alias with that code
+	CodeIdempotencyReused = "idempotency_key_reused" // the Idempotency-Key was already used for a different request
+	CodeIdempotencyInUse  = "idempotency_key_in_use" // a request with the same Idempotency-Key is still in progress
+	CodeAPIKeyNotFound    = "api_key_not_found"      // no API key with the given ID
+	CodeEmailTaken        = "email_taken"            // an account already uses the email
+	CodeLoginFailed       = "invalid_credentials"    // email and password don't match an account
+	CodeInternal          = "internal_error"         // unexpected server-side failure
+)
+
+// Alias availability codes, reported as the reason an alias can't be used.
+const (
+	CodeInvalidAlias  = "invalid_alias"  // alias has the wrong length or characters
+	CodeReservedAlias = "reserved_alias" // alias collides with a route or well-known path
+	CodeAliasTaken    = "alias_taken"    // a link already uses the alias
+)
+
+// Field-level codes, used as values in Error.Fields alongside the request
+// codes above (e.g. {"url": "invalid_url"}).
+const (
+	CodeUnknownField   = "unknown_field"   // field does not exist on the resource
+	CodeImmutableField = "immutable_field" // field exists but cannot be changed
+	CodeTooLong        = "too_long"        // value exceeds the field's maximum length
+	CodeInvalidPrefix  = "invalid_prefix"  // prefix has the wrong length or characters
+	CodeTooShort       = "too_short"       // value is below the field's minimum length
+	CodeInvalidEmail   = "invalid_email"   // value isn't a plain email address
+)
+
+// Error is an error carrying a stable code. Its JSON form is the error body
+// returned by the API: {"error": "<message>", "code": "<code>"}. Validation
+// failures also list the offending fields, mapped to field-level codes.
+type Error struct {
+	Code    string            `json:"code"`
+	Message string            `json:"error"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// New creates an Error.
+func New(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or
+// CodeInternal if there is none.
+func CodeOf(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return CodeInternal
+}
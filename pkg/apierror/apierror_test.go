@@ -0,0 +1,43 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	notFound := New(CodeLinkNotFound, "link not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"direct", notFound, CodeLinkNotFound},
+		{"wrapped", fmt.Errorf("fetching: %w", notFound), CodeLinkNotFound},
+		{"plain error", errors.New("boom"), CodeInternal},
+		{"nil", nil, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestError_JSON(t *testing.T) {
+	body, err := json.Marshal(New(CodeInvalidURL, "invalid url format"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"code":"invalid_url","error":"invalid url format"}`
+	if string(body) != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+}
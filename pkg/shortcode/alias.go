@@ -0,0 +1,63 @@
+package shortcode
+
+import "errors"
+
+// Alias length bounds for user-chosen short codes.
+const (
+	MinAliasLength = 3
+	MaxAliasLength = 64
+)
+
+// Prefix length bounds for namespace prefixes.
+const (
+	MinPrefixLength = 2
+	MaxPrefixLength = 16
+)
+
+// PrefixSeparator joins a namespace prefix to the rest of a code.
+const PrefixSeparator = "-"
+
+// ErrInvalidAlias is returned by ValidateAlias for malformed aliases.
+var ErrInvalidAlias = errors.New("alias must be 3-64 letters, digits, '-' or '_', starting and ending with a letter or digit")
+
+// ErrInvalidPrefix is returned by ValidatePrefix for malformed prefixes.
+var ErrInvalidPrefix = errors.New("prefix must be 2-16 lowercase letters or digits")
+
+// ValidateAlias checks that a user-chosen alias is safe to use as a short
+// code. Unlike generated codes, aliases may use the full alphanumeric range
+// plus '-' and '_' so they can be readable ("acme-pricing").
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return ErrInvalidAlias
+	}
+
+	for i := 0; i < len(alias); i++ {
+		c := alias[i]
+		switch {
+		case isAlphanumeric(c):
+		case (c == '-' || c == '_') && i > 0 && i < len(alias)-1:
+		default:
+			return ErrInvalidAlias
+		}
+	}
+
+	return nil
+}
+
+// ValidatePrefix checks a namespace prefix such as "eng". Prefixes are
+// lowercase so they read the same whether or not codes are case-sensitive.
+func ValidatePrefix(prefix string) error {
+	if len(prefix) < MinPrefixLength || len(prefix) > MaxPrefixLength {
+		return ErrInvalidPrefix
+	}
+	for i := 0; i < len(prefix); i++ {
+		if c := prefix[i]; !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') {
+			return ErrInvalidPrefix
+		}
+	}
+	return nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
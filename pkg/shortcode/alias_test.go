@@ -0,0 +1,58 @@
+package shortcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias   string
+		wantErr bool
+	}{
+		{"acme-pricing", false},
+		{"Q3_report", false},
+		{"abc", false},
+		{strings.Repeat("a", MaxAliasLength), false},
+		{"ab", true},
+		{strings.Repeat("a", MaxAliasLength+1), true},
+		{"-leading", true},
+		{"trailing_", true},
+		{"has space", true},
+		{"slash/path", true},
+		{"ünïcode", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAlias(%q) error = %v, wantErr %v", tt.alias, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		wantErr bool
+	}{
+		{"eng", false},
+		{"q3", false},
+		{strings.Repeat("a", MaxPrefixLength), false},
+		{"e", true},
+		{strings.Repeat("a", MaxPrefixLength+1), true},
+		{"Eng", true},
+		{"eng-x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			err := ValidatePrefix(tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+			}
+		})
+	}
+}
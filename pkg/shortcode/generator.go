@@ -3,19 +3,25 @@ package shortcode
 
 import (
 	"crypto/rand"
-	"math/big"
+	"strings"
 )
 
 // alphabet contains characters used for short codes.
 // Excludes ambiguous characters (0, O, l, 1, I) for readability.
 const alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghjkmnpqrstuvwxyz"
 
+// caseInsensitiveAlphabet is a single-case alphabet for codes that must
+// survive being read aloud or retyped. It also drops i, l and o, which are
+// easily confused with 1 and 0.
+const caseInsensitiveAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
 // DefaultLength is the default length for generated short codes.
 const DefaultLength = 7
 
 // Generator creates unique short codes.
 type Generator struct {
-	length int
+	length   int
+	alphabet string
 }
 
 // NewGenerator creates a new Generator with the specified code length.
@@ -23,21 +29,47 @@ func NewGenerator(length int) *Generator {
 	if length <= 0 {
 		length = DefaultLength
 	}
-	return &Generator{length: length}
+	return &Generator{length: length, alphabet: alphabet}
+}
+
+// NewCaseInsensitiveGenerator creates a Generator whose codes use a single
+// case, so they can be looked up after passing through Canonicalize.
+func NewCaseInsensitiveGenerator(length int) *Generator {
+	g := NewGenerator(length)
+	g.alphabet = caseInsensitiveAlphabet
+	return g
+}
+
+// Canonicalize maps a code to the case used by case-insensitive generators.
+func Canonicalize(code string) string {
+	return strings.ToLower(code)
 }
 
 // Generate creates a new random short code.
 // Uses crypto/rand for secure randomness.
 func (g *Generator) Generate() (string, error) {
-	result := make([]byte, g.length)
-	alphabetLen := big.NewInt(int64(len(alphabet)))
+	// Random bytes are mapped onto the alphabet, rejecting those at or above
+	// the largest multiple of its length so every character is equally
+	// likely. One read usually covers the whole code.
+	n := len(g.alphabet)
+	limit := 256 - 256%n
 
-	for i := 0; i < g.length; i++ {
-		num, err := rand.Int(rand.Reader, alphabetLen)
-		if err != nil {
+	result := make([]byte, g.length)
+	var buf [32]byte
+	for i := 0; i < g.length; {
+		want := min(2*(g.length-i), len(buf))
+		if _, err := rand.Read(buf[:want]); err != nil {
 			return "", err
 		}
-		result[i] = alphabet[num.Int64()]
+		for _, b := range buf[:want] {
+			if int(b) >= limit {
+				continue
+			}
+			result[i] = g.alphabet[int(b)%n]
+			if i++; i == g.length {
+				break
+			}
+		}
 	}
 
 	return string(result), nil
@@ -48,12 +80,19 @@ func (g *Generator) Length() int {
 	return g.length
 }
 
+// WithLength returns a Generator with the same alphabet and a new length.
+func (g *Generator) WithLength(length int) *Generator {
+	next := NewGenerator(length)
+	next.alphabet = g.alphabet
+	return next
+}
+
 // PossibleCombinations returns the number of possible unique codes.
 // With default settings (7 chars, 55 char alphabet): ~1.1 trillion combinations
 func (g *Generator) PossibleCombinations() int64 {
 	result := int64(1)
 	for i := 0; i < g.length; i++ {
-		result *= int64(len(alphabet))
+		result *= int64(len(g.alphabet))
 	}
 	return result
 }
@@ -1,6 +1,7 @@
 package shortcode
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -106,3 +107,45 @@ func BenchmarkGenerator_Generate(b *testing.B) {
 		_, _ = g.Generate()
 	}
 }
+
+func TestCaseInsensitiveGenerator(t *testing.T) {
+	g := NewCaseInsensitiveGenerator(DefaultLength)
+
+	for i := 0; i < 100; i++ {
+		code, err := g.Generate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if Canonicalize(code) != code {
+			t.Fatalf("code %q is not canonical", code)
+		}
+	}
+
+	if got := g.WithLength(8).PossibleCombinations(); got != g.PossibleCombinations()*int64(len(caseInsensitiveAlphabet)) {
+		t.Errorf("WithLength changed the alphabet: %d combinations", got)
+	}
+}
+
+func TestGenerator_UsesWholeAlphabet(t *testing.T) {
+	g := NewGenerator(DefaultLength)
+	seen := make(map[rune]int)
+	for i := 0; i < 2000; i++ {
+		code, err := g.Generate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(alphabet, c) {
+				t.Fatalf("code %q has a character outside the alphabet", code)
+			}
+			seen[c]++
+		}
+	}
+
+	// 14,000 characters over 55 symbols: about 255 each
+	for _, c := range alphabet {
+		if seen[c] < 150 {
+			t.Errorf("character %q drawn only %d times", c, seen[c])
+		}
+	}
+}
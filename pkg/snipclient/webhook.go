@@ -0,0 +1,111 @@
+// Package snipclient has helpers for programs that integrate with a Snip
+// server, such as endpoints receiving its webhook deliveries.
+package snipclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook request headers. The timestamp is the Unix time, in seconds,
+// the delivery was sent. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of the timestamp, a '.' and the body, keyed with the
+// endpoint's secret.
+const (
+	HeaderEvent     = "X-Snip-Event"
+	HeaderTimestamp = "X-Snip-Timestamp"
+	HeaderSignature = "X-Snip-Signature"
+)
+
+// MaxWebhookBody is the largest delivery VerifyWebhook reads.
+const MaxWebhookBody = 1 << 20
+
+// WebhookTolerance is how far a delivery's timestamp may be from the
+// receiver's clock before VerifyWebhook rejects it as a replay.
+const WebhookTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when a delivery's signature or
+	// timestamp is missing or doesn't match its body.
+	ErrInvalidSignature = errors.New("snipclient: invalid webhook signature")
+
+	// ErrStaleWebhook is returned when a delivery is correctly signed but
+	// its timestamp is more than WebhookTolerance away, as when an old
+	// delivery is replayed.
+	ErrStaleWebhook = errors.New("snipclient: webhook timestamp outside tolerance")
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// WebhookEvent is the JSON body of a webhook delivery. Link and Click
+// hold the link and click as the API returns them, for events that carry
+// one; decode them into your own types as needed.
+type WebhookEvent struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	ShortCode string          `json:"short_code"`
+	Link      json.RawMessage `json:"link,omitempty"`
+	Click     json.RawMessage `json:"click,omitempty"`
+	Milestone int64           `json:"milestone,omitempty"` // click count reached, for link.milestone
+}
+
+// Sign returns the timestamp and signature header values for a delivery
+// of body sent at t.
+func Sign(secret []byte, t time.Time, body []byte) (timestamp, signature string) {
+	timestamp = strconv.FormatInt(t.Unix(), 10)
+	return timestamp, sign(secret, timestamp, body)
+}
+
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature, the X-Snip-Signature header
+// of a delivery, was made from timestamp, its X-Snip-Timestamp header,
+// and body with secret. The comparison takes constant time. It doesn't
+// check how old the timestamp is; VerifyWebhook does.
+func VerifySignature(secret, body []byte, timestamp, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(sign(secret, timestamp, body)))
+}
+
+// VerifyWebhook reads and authenticates a webhook delivery and decodes its
+// event. It returns ErrInvalidSignature when the signature doesn't match
+// and ErrStaleWebhook when the delivery was sent more than
+// WebhookTolerance ago (or ahead). In either case the request should be
+// rejected.
+func VerifyWebhook(r *http.Request, secret []byte) (*WebhookEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxWebhookBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("snipclient: reading webhook body: %w", err)
+	}
+	if len(body) > MaxWebhookBody {
+		return nil, fmt.Errorf("snipclient: webhook body exceeds %d bytes", MaxWebhookBody)
+	}
+	timestamp := r.Header.Get(HeaderTimestamp)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !VerifySignature(secret, body, timestamp, r.Header.Get(HeaderSignature)) {
+		return nil, ErrInvalidSignature
+	}
+	if age := now().Sub(time.Unix(sent, 0)); age > WebhookTolerance || age < -WebhookTolerance {
+		return nil, ErrStaleWebhook
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("snipclient: decoding webhook event: %w", err)
+	}
+	return &event, nil
+}
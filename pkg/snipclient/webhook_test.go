@@ -0,0 +1,77 @@
+package snipclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"type":"link.milestone","short_code":"abc1234","link":{"short_code":"abc1234"},"milestone":100}`)
+	sent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return sent.Add(time.Minute) }
+	t.Cleanup(func() { now = time.Now })
+
+	timestamp, signature := Sign(secret, sent, body)
+	_, otherSecret := Sign([]byte("other"), sent, body)
+	staleTimestamp, staleSignature := Sign(secret, sent.Add(-WebhookTolerance), body)
+	futureTimestamp, futureSignature := Sign(secret, sent.Add(WebhookTolerance+2*time.Minute), body)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		wantErr   error
+	}{
+		{"valid", timestamp, signature, nil},
+		{"missing signature", timestamp, "", ErrInvalidSignature},
+		{"missing timestamp", "", signature, ErrInvalidSignature},
+		{"wrong secret", timestamp, otherSecret, ErrInvalidSignature},
+		{"unprefixed", timestamp, signature[len("sha256="):], ErrInvalidSignature},
+		{"other timestamp", futureTimestamp, signature, ErrInvalidSignature},
+		{"stale", staleTimestamp, staleSignature, ErrStaleWebhook},
+		{"future", futureTimestamp, futureSignature, ErrStaleWebhook},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hooks/snip", bytes.NewReader(body))
+			if tt.timestamp != "" {
+				req.Header.Set(HeaderTimestamp, tt.timestamp)
+			}
+			if tt.signature != "" {
+				req.Header.Set(HeaderSignature, tt.signature)
+			}
+
+			event, err := VerifyWebhook(req, secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			var link struct {
+				ShortCode string `json:"short_code"`
+			}
+			if event.ShortCode != "abc1234" || event.Milestone != 100 || json.Unmarshal(event.Link, &link) != nil || link.ShortCode != "abc1234" {
+				t.Errorf("unexpected event %+v", event)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhook_TamperedBody(t *testing.T) {
+	secret := []byte("s3cret")
+	timestamp, signature := Sign(secret, time.Now(), []byte(`{"milestone":100}`))
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/snip", bytes.NewReader([]byte(`{"milestone":1000}`)))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, signature)
+	if _, err := VerifyWebhook(req, secret); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
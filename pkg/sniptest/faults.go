@@ -0,0 +1,99 @@
+// Package sniptest provides test doubles for code that talks to Snip:
+// failure-injecting repositories and an in-process fake server running the
+// real API over in-memory storage.
+package sniptest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Faults records failures and latency to inject per repository method.
+// Methods are identified by their interface method name (e.g., "Create",
+// "GetByShortCode"). A Faults value is safe for concurrent use.
+type Faults struct {
+	mu     sync.Mutex
+	errs   map[string]error
+	times  map[string]int // remaining failures for FailTimes; absent fails forever
+	delays map[string]time.Duration
+	calls  map[string]int
+}
+
+func newFaults() *Faults {
+	return &Faults{
+		errs:   make(map[string]error),
+		times:  make(map[string]int),
+		delays: make(map[string]time.Duration),
+		calls:  make(map[string]int),
+	}
+}
+
+// FailOn makes every call to method return err until cleared.
+func (f *Faults) FailOn(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[method] = err
+	delete(f.times, method)
+}
+
+// FailTimes makes the next n calls to method return err, and later calls
+// succeed. Use it to exercise retries.
+func (f *Faults) FailTimes(method string, err error, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[method] = err
+	f.times[method] = n
+}
+
+// Delay makes every call to method sleep for d (or until its context is
+// cancelled) before proceeding.
+func (f *Faults) Delay(method string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delays[method] = d
+}
+
+// Clear removes all injected failures and delays. Call counts are kept.
+func (f *Faults) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs = make(map[string]error)
+	f.times = make(map[string]int)
+	f.delays = make(map[string]time.Duration)
+}
+
+// Calls returns how many times method has been invoked.
+func (f *Faults) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// before records the call and applies any injected delay and error.
+func (f *Faults) before(ctx context.Context, method string) error {
+	f.mu.Lock()
+	f.calls[method]++
+	err := f.errs[method]
+	if n, limited := f.times[method]; limited {
+		if n <= 0 {
+			err = nil
+		} else {
+			f.times[method] = n - 1
+		}
+	}
+	delay := f.delays[method]
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
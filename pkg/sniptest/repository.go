@@ -0,0 +1,142 @@
+package sniptest
+
+import (
+	"context"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/repository"
+)
+
+// LinkRepository wraps a repository.LinkRepository with fault injection.
+// Methods without injection support pass straight through to the wrapped
+// repository.
+type LinkRepository struct {
+	repository.LinkRepository
+	*Faults
+}
+
+// NewLinkRepository wraps next; a nil next uses a fresh in-memory repository.
+func NewLinkRepository(next repository.LinkRepository) *LinkRepository {
+	if next == nil {
+		next = repository.NewMemoryLinkRepository()
+	}
+	return &LinkRepository{LinkRepository: next, Faults: newFaults()}
+}
+
+// Create implements repository.LinkRepository.
+func (r *LinkRepository) Create(ctx context.Context, link *model.Link) error {
+	if err := r.before(ctx, "Create"); err != nil {
+		return err
+	}
+	return r.LinkRepository.Create(ctx, link)
+}
+
+// GetByShortCode implements repository.LinkRepository.
+func (r *LinkRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.Link, error) {
+	if err := r.before(ctx, "GetByShortCode"); err != nil {
+		return nil, err
+	}
+	return r.LinkRepository.GetByShortCode(ctx, shortCode)
+}
+
+// GetByShortCodes implements repository.LinkRepository.
+func (r *LinkRepository) GetByShortCodes(ctx context.Context, shortCodes []string) (map[string]*model.Link, error) {
+	if err := r.before(ctx, "GetByShortCodes"); err != nil {
+		return nil, err
+	}
+	return r.LinkRepository.GetByShortCodes(ctx, shortCodes)
+}
+
+// Update implements repository.LinkRepository.
+func (r *LinkRepository) Update(ctx context.Context, link *model.Link) error {
+	if err := r.before(ctx, "Update"); err != nil {
+		return err
+	}
+	return r.LinkRepository.Update(ctx, link)
+}
+
+// List implements repository.LinkRepository.
+func (r *LinkRepository) List(ctx context.Context, cursor string, limit int) ([]*model.Link, string, error) {
+	if err := r.before(ctx, "List"); err != nil {
+		return nil, "", err
+	}
+	return r.LinkRepository.List(ctx, cursor, limit)
+}
+
+// ListByOwner implements repository.LinkRepository.
+func (r *LinkRepository) ListByOwner(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	if err := r.before(ctx, "ListByOwner"); err != nil {
+		return nil, "", err
+	}
+	return r.LinkRepository.ListByOwner(ctx, ownerID, cursor, limit)
+}
+
+// ListPinned implements repository.LinkRepository.
+func (r *LinkRepository) ListPinned(ctx context.Context, ownerID, cursor string, limit int) ([]*model.Link, string, error) {
+	if err := r.before(ctx, "ListPinned"); err != nil {
+		return nil, "", err
+	}
+	return r.LinkRepository.ListPinned(ctx, ownerID, cursor, limit)
+}
+
+// IncrementClickCount implements repository.LinkRepository.
+func (r *LinkRepository) IncrementClickCount(ctx context.Context, shortCode string) (int64, error) {
+	if err := r.before(ctx, "IncrementClickCount"); err != nil {
+		return 0, err
+	}
+	return r.LinkRepository.IncrementClickCount(ctx, shortCode)
+}
+
+// AddClickCount implements repository.LinkRepository.
+func (r *LinkRepository) AddClickCount(ctx context.Context, shortCode string, delta int64) error {
+	if err := r.before(ctx, "AddClickCount"); err != nil {
+		return err
+	}
+	return r.LinkRepository.AddClickCount(ctx, shortCode, delta)
+}
+
+// Delete implements repository.LinkRepository.
+func (r *LinkRepository) Delete(ctx context.Context, shortCode string, expectedVersion int64) error {
+	if err := r.before(ctx, "Delete"); err != nil {
+		return err
+	}
+	return r.LinkRepository.Delete(ctx, shortCode, expectedVersion)
+}
+
+// GetByDestination implements repository.LinkRepository.
+func (r *LinkRepository) GetByDestination(ctx context.Context, originalURL string) ([]*model.Link, error) {
+	if err := r.before(ctx, "GetByDestination"); err != nil {
+		return nil, err
+	}
+	return r.LinkRepository.GetByDestination(ctx, originalURL)
+}
+
+// ClickRepository wraps a repository.ClickRepository with fault injection.
+type ClickRepository struct {
+	repository.ClickRepository
+	*Faults
+}
+
+// NewClickRepository wraps next; a nil next uses a fresh in-memory repository.
+func NewClickRepository(next repository.ClickRepository) *ClickRepository {
+	if next == nil {
+		next = repository.NewMemoryClickRepository()
+	}
+	return &ClickRepository{ClickRepository: next, Faults: newFaults()}
+}
+
+// Record implements repository.ClickRepository.
+func (r *ClickRepository) Record(ctx context.Context, event *model.ClickEvent) error {
+	if err := r.before(ctx, "Record"); err != nil {
+		return err
+	}
+	return r.ClickRepository.Record(ctx, event)
+}
+
+// GetByLinkID implements repository.ClickRepository.
+func (r *ClickRepository) GetByLinkID(ctx context.Context, linkID string, limit int) ([]model.ClickEvent, error) {
+	if err := r.before(ctx, "GetByLinkID"); err != nil {
+		return nil, err
+	}
+	return r.ClickRepository.GetByLinkID(ctx, linkID, limit)
+}
@@ -0,0 +1,77 @@
+package sniptest
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/colby/snip/internal/handler"
+	"github.com/colby/snip/internal/service"
+)
+
+// Server is an in-process Snip API backed by in-memory, fault-injectable
+// repositories. Point an HTTP client (or SDK) at URL to exercise real API
+// behavior without deploying anything.
+type Server struct {
+	*httptest.Server
+
+	Links   *LinkRepository
+	Clicks  *ClickRepository
+	Service *service.LinkService
+}
+
+// ServerConfig configures NewServerWithConfig. The zero value gives the
+// same server as NewServer.
+type ServerConfig struct {
+	// Service configures the link service. A zero value means
+	// service.DefaultConfig(); BaseURL is always the server's own URL.
+	Service *service.LinkServiceConfig
+
+	// Handler configures the HTTP handler, e.g. an AdminToken to exercise
+	// the admin endpoints.
+	Handler handler.Config
+
+	Logger *slog.Logger // defaults to discarding everything
+}
+
+// NewServer starts a fake server. Short URLs returned by the API use the
+// server's own URL as base. Call Close when done.
+func NewServer() *Server {
+	return NewServerWithConfig(ServerConfig{})
+}
+
+// NewServerWithConfig starts a fake server with the given service and
+// handler settings. Call Close when done.
+func NewServerWithConfig(config ServerConfig) *Server {
+	links := NewLinkRepository(nil)
+	clicks := NewClickRepository(nil)
+
+	s := &Server{Links: links, Clicks: clicks}
+
+	// The URL is needed before the handler exists, so the listener is
+	// opened first and the server started once everything is wired
+	mux := http.NewServeMux()
+	s.Server = httptest.NewUnstartedServer(nil)
+	s.URL = "http://" + s.Listener.Addr().String()
+
+	serviceConfig := service.DefaultConfig()
+	if config.Service != nil {
+		serviceConfig = *config.Service
+	}
+	serviceConfig.BaseURL = s.URL
+	s.Service = service.NewLinkService(links, clicks, serviceConfig)
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	h := handler.New(s.Service, logger, config.Handler)
+	h.RegisterRoutes(mux)
+
+	// Recover turns handler panics into 500s, as in the real server
+	s.Config.Handler = h.Recover(mux)
+	s.Start()
+
+	return s
+}
@@ -0,0 +1,181 @@
+package sniptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colby/snip/internal/model"
+	"github.com/colby/snip/internal/service"
+)
+
+func TestLinkRepository_FailOn(t *testing.T) {
+	repo := NewLinkRepository(nil)
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	repo.FailOn("Create", boom)
+	if err := repo.Create(ctx, &model.Link{ShortCode: "abc"}); !errors.Is(err, boom) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+
+	repo.Clear()
+	if err := repo.Create(ctx, &model.Link{ShortCode: "abc"}); err != nil {
+		t.Fatalf("expected success after Clear, got %v", err)
+	}
+
+	if got := repo.Calls("Create"); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
+
+func TestLinkRepository_DelayHonorsContext(t *testing.T) {
+	repo := NewLinkRepository(nil)
+	repo.Delay("GetByShortCode", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := repo.GetByShortCode(ctx, "abc"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/links", "application/json", strings.NewReader(`{"url": "https://example.com"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var created model.CreateLinkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(created.ShortURL, srv.URL+"/") {
+		t.Errorf("expected short URL under %s, got %s", srv.URL, created.ShortURL)
+	}
+
+	// Injected repository failures surface as API errors
+	srv.Links.FailOn("GetByShortCode", errors.New("datastore down"))
+
+	statsResp, err := http.Get(srv.URL + "/api/links/" + created.ShortCode + "/stats")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	statsResp.Body.Close()
+
+	if statsResp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, statsResp.StatusCode)
+	}
+}
+
+func TestFaults_FailTimes(t *testing.T) {
+	repo := NewClickRepository(nil)
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	repo.FailTimes("Record", boom, 2)
+	for i := 0; i < 2; i++ {
+		if err := repo.Record(ctx, &model.ClickEvent{ID: "c1", LinkID: "l1"}); !errors.Is(err, boom) {
+			t.Fatalf("call %d: expected injected error, got %v", i+1, err)
+		}
+	}
+	if err := repo.Record(ctx, &model.ClickEvent{ID: "c1", LinkID: "l1"}); err != nil {
+		t.Errorf("expected success once the failures ran out, got %v", err)
+	}
+}
+
+func TestServer_StorageFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string // repository method to fail
+		req    func(srv *Server, code string) (*http.Response, error)
+	}{
+		{"create", "Create", func(srv *Server, _ string) (*http.Response, error) {
+			return http.Post(srv.URL+"/api/links", "application/json", strings.NewReader(`{"url": "https://example.com/other"}`))
+		}},
+		{"get", "GetByShortCode", func(srv *Server, code string) (*http.Response, error) {
+			return http.Get(srv.URL + "/api/links/" + code)
+		}},
+		{"update", "Update", func(srv *Server, code string) (*http.Response, error) {
+			req, _ := http.NewRequest(http.MethodPatch, srv.URL+"/api/links/"+code, strings.NewReader(`{"notes": "x"}`))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			return http.DefaultClient.Do(req)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer()
+			defer srv.Close()
+			created := createLink(t, srv)
+
+			srv.Links.FailOn(tt.method, errors.New("datastore down"))
+			resp, err := tt.req(srv, created.ShortCode)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusInternalServerError {
+				t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code != "internal_error" {
+				t.Errorf("expected code internal_error, got %q (%v)", body.Code, err)
+			}
+		})
+	}
+}
+
+func TestServer_ClickWriteRetried(t *testing.T) {
+	config := service.DefaultConfig()
+	config.ClickRecorder = service.BoundedClickRecorder{Timeout: time.Second}
+	srv := NewServerWithConfig(ServerConfig{Service: &config})
+	defer srv.Close()
+	created := createLink(t, srv)
+
+	srv.Clicks.FailTimes("Record", errors.New("throttled"), 1)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(created.ShortURL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("expected status %d, got %d", http.StatusMovedPermanently, resp.StatusCode)
+	}
+	if got := srv.Clicks.Calls("Record"); got != 2 {
+		t.Errorf("expected the click write to be retried once, got %d calls", got)
+	}
+}
+
+func createLink(t *testing.T, srv *Server) model.CreateLinkResponse {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/api/links", "application/json", strings.NewReader(`{"url": "https://example.com"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var created model.CreateLinkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return created
+}
@@ -0,0 +1,115 @@
+// Package ulid generates Universally Unique Lexicographically Sortable
+// Identifiers (https://github.com/ulid/spec): 26-character strings made of
+// a 48-bit millisecond timestamp and 80 random bits, Crockford base32
+// encoded so that string order matches creation order.
+package ulid
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"time"
+)
+
+// Length is the length of an encoded ULID.
+const Length = 26
+
+// encoding is Crockford's base32 alphabet.
+const encoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// maxTime is the largest timestamp a ULID can hold.
+const maxTime = 1<<48 - 1
+
+// ErrInvalid is returned by Time for strings that aren't ULIDs.
+var ErrInvalid = errors.New("invalid ULID")
+
+// New returns a ULID for the current time using crypto/rand entropy. It's
+// called for every click, so unlike Make it reads crypto/rand directly,
+// which keeps the ID off the heap until it's encoded.
+func New() string {
+	var id [16]byte
+	putTime(&id, time.Now().UnixMilli())
+	if _, err := rand.Read(id[6:]); err != nil {
+		// crypto/rand only fails if the OS entropy source is broken
+		panic("ulid: " + err.Error())
+	}
+	return encode(id)
+}
+
+// Make returns a ULID for t, reading its random part from entropy. A
+// deterministic entropy source yields reproducible IDs, which is useful
+// for fixtures.
+func Make(t time.Time, entropy io.Reader) (string, error) {
+	ms := t.UnixMilli()
+	if ms < 0 || ms > maxTime {
+		return "", errors.New("timestamp out of range")
+	}
+
+	var id [16]byte
+	putTime(&id, ms)
+	if _, err := io.ReadFull(entropy, id[6:]); err != nil {
+		return "", err
+	}
+
+	return encode(id), nil
+}
+
+// putTime writes the 48-bit millisecond timestamp ms into id.
+func putTime(id *[16]byte, ms int64) {
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// Time returns the timestamp encoded in id.
+func Time(id string) (time.Time, error) {
+	if len(id) != Length || id[0] > '7' {
+		return time.Time{}, ErrInvalid
+	}
+
+	var ms int64
+	for i := 0; i < 10; i++ {
+		v := decodeChar(id[i])
+		if v < 0 {
+			return time.Time{}, ErrInvalid
+		}
+		ms = ms<<5 | int64(v)
+	}
+	for i := 10; i < Length; i++ {
+		if decodeChar(id[i]) < 0 {
+			return time.Time{}, ErrInvalid
+		}
+	}
+
+	return time.UnixMilli(ms).UTC(), nil
+}
+
+// encode renders 128 bits as 26 base32 characters; the first character
+// carries only the top 3 bits.
+func encode(id [16]byte) string {
+	out := make([]byte, Length)
+	var acc uint
+	bits := 2 // 130 bits of output for 128 bits of input
+	pos := 0
+	for _, b := range id {
+		acc = acc<<8 | uint(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = encoding[(acc>>bits)&31]
+			pos++
+		}
+	}
+	return string(out)
+}
+
+// decodeChar returns the value of an upper-case base32 character, or -1.
+func decodeChar(c byte) int {
+	for i := 0; i < len(encoding); i++ {
+		if encoding[i] == c {
+			return i
+		}
+	}
+	return -1
+}
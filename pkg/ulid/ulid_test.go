@@ -0,0 +1,57 @@
+package ulid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMake(t *testing.T) {
+	ts := time.UnixMilli(1469918176385)
+	id, err := Make(ts, bytes.NewReader(make([]byte, 10)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Timestamp from the ULID spec's example
+	if want := "01ARYZ6S41" + strings.Repeat("0", 16); id != want {
+		t.Errorf("expected %s, got %s", want, id)
+	}
+
+	got, err := Time(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(ts) {
+		t.Errorf("expected time %v, got %v", ts, got)
+	}
+}
+
+func TestNew_Sortable(t *testing.T) {
+	first := New()
+	time.Sleep(2 * time.Millisecond)
+	second := New()
+
+	if len(first) != Length || len(second) != Length {
+		t.Fatalf("unexpected lengths: %q, %q", first, second)
+	}
+	if first >= second {
+		t.Errorf("expected %s to sort before %s", first, second)
+	}
+}
+
+func TestTime_Invalid(t *testing.T) {
+	for _, id := range []string{"", "abc", "8" + strings.Repeat("0", 25), "01ARYZ6S41" + strings.Repeat("U", 16)} {
+		if _, err := Time(id); err != ErrInvalid {
+			t.Errorf("Time(%q) error = %v, want ErrInvalid", id, err)
+		}
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New()
+	}
+}
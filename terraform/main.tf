@@ -36,8 +36,17 @@ module "lambda" {
   lambda_zip_path     = var.lambda_zip_path
   dynamodb_table_name = module.dynamodb.table_name
   dynamodb_table_arn  = module.dynamodb.table_arn
+  clicks_table_name   = module.dynamodb.clicks_table_name
+  clicks_table_arn    = module.dynamodb.clicks_table_arn
+  rollups_table_name  = module.dynamodb.rollups_table_name
+  rollups_table_arn   = module.dynamodb.rollups_table_arn
   base_url            = var.base_url
   log_level           = var.log_level
+
+  provisioned_concurrency = var.lambda_provisioned_concurrency
+  click_retention_days    = var.click_retention_days
+  delete_grace_days       = var.delete_grace_days
+  secret_arns             = var.secret_arns
 }
 
 module "api_gateway" {
@@ -47,4 +56,5 @@ module "api_gateway" {
   environment          = var.environment
   lambda_function_name = module.lambda.function_name
   lambda_invoke_arn    = module.lambda.invoke_arn
+  lambda_qualifier     = module.lambda.qualifier
 }
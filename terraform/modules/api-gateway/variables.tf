@@ -18,6 +18,12 @@ variable "lambda_invoke_arn" {
   type        = string
 }
 
+variable "lambda_qualifier" {
+  description = "Lambda alias being invoked, if any"
+  type        = string
+  default     = null
+}
+
 variable "base_url" {
   description = "Base URL for generated short links"
   type        = string
@@ -8,9 +8,83 @@ resource "aws_dynamodb_table" "links" {
     type = "S"
   }
 
+  attribute {
+    name = "url_hash"
+    type = "S"
+  }
+
+  attribute {
+    name = "owner_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "created_at"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "url_hash-index"
+    hash_key        = "url_hash"
+    projection_type = "ALL"
+  }
+
+  global_secondary_index {
+    name            = "owner_id-index"
+    hash_key        = "owner_id"
+    range_key       = "created_at"
+    projection_type = "ALL"
+  }
+
   tags = {
     Name        = "${var.app_name}-${var.environment}-links"
     Environment = var.environment
     Project     = var.app_name
   }
 }
+
+resource "aws_dynamodb_table" "clicks" {
+  name         = "${var.app_name}-${var.environment}-clicks"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "link_id"
+  range_key    = "id"
+
+  attribute {
+    name = "link_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "${var.app_name}-${var.environment}-clicks"
+    Environment = var.environment
+    Project     = var.app_name
+  }
+}
+
+resource "aws_dynamodb_table" "rollups" {
+  name         = "${var.app_name}-${var.environment}-rollups"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "link_id"
+  range_key    = "day"
+
+  attribute {
+    name = "link_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "day"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "${var.app_name}-${var.environment}-rollups"
+    Environment = var.environment
+    Project     = var.app_name
+  }
+}
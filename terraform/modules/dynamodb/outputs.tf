@@ -7,3 +7,23 @@ output "table_arn" {
   description = "ARN of the DynamoDB table"
   value       = aws_dynamodb_table.links.arn
 }
+
+output "clicks_table_name" {
+  description = "Name of the click events table"
+  value       = aws_dynamodb_table.clicks.name
+}
+
+output "clicks_table_arn" {
+  description = "ARN of the click events table"
+  value       = aws_dynamodb_table.clicks.arn
+}
+
+output "rollups_table_name" {
+  description = "Name of the daily click rollups table"
+  value       = aws_dynamodb_table.rollups.name
+}
+
+output "rollups_table_arn" {
+  description = "ARN of the daily click rollups table"
+  value       = aws_dynamodb_table.rollups.arn
+}
@@ -13,11 +13,17 @@ resource "aws_lambda_function" "api" {
   memory_size = 128
   timeout     = 10
 
+  # Provisioned concurrency applies to published versions only
+  publish = var.provisioned_concurrency > 0
+
   environment {
     variables = {
-      DYNAMODB_TABLE = var.dynamodb_table_name
-      BASE_URL       = var.base_url
-      LOG_LEVEL      = var.log_level
+      DYNAMODB_TABLE    = var.dynamodb_table_name
+      CLICKS_TABLE      = var.clicks_table_name
+      ROLLUPS_TABLE     = var.rollups_table_name
+      DELETE_GRACE_DAYS = var.delete_grace_days
+      BASE_URL          = var.base_url
+      LOG_LEVEL         = var.log_level
     }
   }
 
@@ -28,6 +34,82 @@ resource "aws_lambda_function" "api" {
   }
 }
 
+# Provisioned concurrency runs init (including the DynamoDB client preload)
+# ahead of traffic, taking cold starts off the redirect path
+
+resource "aws_lambda_alias" "live" {
+  count = var.provisioned_concurrency > 0 ? 1 : 0
+
+  name             = "live"
+  function_name    = aws_lambda_function.api.function_name
+  function_version = aws_lambda_function.api.version
+}
+
+resource "aws_lambda_provisioned_concurrency_config" "live" {
+  count = var.provisioned_concurrency > 0 ? 1 : 0
+
+  function_name                     = aws_lambda_function.api.function_name
+  qualifier                         = aws_lambda_alias.live[0].name
+  provisioned_concurrent_executions = var.provisioned_concurrency
+}
+
+# Maintenance Function
+#
+# The same binary with HANDLER_MODE=maintenance, run on a schedule to
+# rebuild rollups and purge expired click events and deleted links
+
+resource "aws_lambda_function" "maintenance" {
+  function_name = "${var.app_name}-${var.environment}-maintenance"
+  role          = aws_iam_role.lambda_exec.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  architectures = ["arm64"]
+
+  filename         = var.lambda_zip_path
+  source_code_hash = filebase64sha256(var.lambda_zip_path)
+
+  memory_size = 256
+  timeout     = 300
+
+  environment {
+    variables = {
+      HANDLER_MODE         = "maintenance"
+      DYNAMODB_TABLE       = var.dynamodb_table_name
+      CLICKS_TABLE         = var.clicks_table_name
+      ROLLUPS_TABLE        = var.rollups_table_name
+      CLICK_RETENTION_DAYS = var.click_retention_days
+      DELETE_GRACE_DAYS    = var.delete_grace_days
+      BASE_URL             = var.base_url
+      LOG_LEVEL            = var.log_level
+    }
+  }
+
+  tags = {
+    Name        = "${var.app_name}-${var.environment}-maintenance"
+    Environment = var.environment
+    Project     = var.app_name
+  }
+}
+
+resource "aws_cloudwatch_event_rule" "maintenance" {
+  name                = "${var.app_name}-${var.environment}-maintenance"
+  description         = "Runs the maintenance sweeps"
+  schedule_expression = var.maintenance_schedule
+}
+
+resource "aws_cloudwatch_event_target" "maintenance" {
+  rule = aws_cloudwatch_event_rule.maintenance.name
+  arn  = aws_lambda_function.maintenance.arn
+}
+
+resource "aws_lambda_permission" "maintenance_schedule" {
+  statement_id  = "AllowEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.maintenance.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.maintenance.arn
+}
+
 # Permissions
 
 resource "aws_iam_role" "lambda_exec" {
@@ -62,10 +144,12 @@ resource "aws_iam_policy" "dynamodb_access" {
         "dynamodb:PutItem",
         "dynamodb:UpdateItem",
         "dynamodb:DeleteItem",
+        "dynamodb:BatchGetItem",
+        "dynamodb:BatchWriteItem",
         "dynamodb:Query",
         "dynamodb:Scan"
       ]
-      Resource = var.dynamodb_table_arn
+      Resource = [var.dynamodb_table_arn, "${var.dynamodb_table_arn}/index/*", var.clicks_table_arn, var.rollups_table_arn]
     }]
   })
 }
@@ -74,3 +158,30 @@ resource "aws_iam_role_policy_attachment" "dynamodb_access" {
   role       = aws_iam_role.lambda_exec.name
   policy_arn = aws_iam_policy.dynamodb_access.arn
 }
+
+# Settings given as ssm:/secretsmanager: references are resolved at init
+
+resource "aws_iam_policy" "secrets_access" {
+  count = length(var.secret_arns) > 0 ? 1 : 0
+
+  name = "${var.app_name}-${var.environment}-secrets-access"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect = "Allow"
+      Action = [
+        "ssm:GetParameter",
+        "secretsmanager:GetSecretValue"
+      ]
+      Resource = var.secret_arns
+    }]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "secrets_access" {
+  count = length(var.secret_arns) > 0 ? 1 : 0
+
+  role       = aws_iam_role.lambda_exec.name
+  policy_arn = aws_iam_policy.secrets_access[0].arn
+}
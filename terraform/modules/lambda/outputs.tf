@@ -9,6 +9,16 @@ output "function_arn" {
 }
 
 output "invoke_arn" {
-  description = "Invoke ARN for API Gateway"
-  value       = aws_lambda_function.api.invoke_arn
+  description = "Invoke ARN for API Gateway (the live alias when provisioned concurrency is on)"
+  value       = var.provisioned_concurrency > 0 ? aws_lambda_alias.live[0].invoke_arn : aws_lambda_function.api.invoke_arn
+}
+
+output "qualifier" {
+  description = "Alias API Gateway invokes, or null for the unqualified function"
+  value       = var.provisioned_concurrency > 0 ? aws_lambda_alias.live[0].name : null
+}
+
+output "maintenance_function_name" {
+  description = "Name of the scheduled maintenance Lambda function"
+  value       = aws_lambda_function.maintenance.function_name
 }
@@ -23,6 +23,26 @@ variable "dynamodb_table_arn" {
   type        = string
 }
 
+variable "clicks_table_name" {
+  description = "Name of the click events table"
+  type        = string
+}
+
+variable "clicks_table_arn" {
+  description = "ARN of the click events table (for IAM permissions)"
+  type        = string
+}
+
+variable "rollups_table_name" {
+  description = "Name of the daily click rollups table"
+  type        = string
+}
+
+variable "rollups_table_arn" {
+  description = "ARN of the daily click rollups table (for IAM permissions)"
+  type        = string
+}
+
 variable "base_url" {
   description = "Base URL for generated short links"
   type        = string
@@ -33,3 +53,33 @@ variable "log_level" {
   type        = string
   default     = "info"
 }
+
+variable "provisioned_concurrency" {
+  description = "Pre-initialized execution environments kept warm behind the live alias; 0 disables provisioned concurrency"
+  type        = number
+  default     = 0
+}
+
+variable "maintenance_schedule" {
+  description = "EventBridge schedule expression for the maintenance sweeps"
+  type        = string
+  default     = "cron(15 0 * * ? *)"
+}
+
+variable "click_retention_days" {
+  description = "Days click events are kept before the maintenance sweep purges them; 0 keeps them forever"
+  type        = number
+  default     = 0
+}
+
+variable "delete_grace_days" {
+  description = "Days deleted links stay restorable before the maintenance sweep purges them; 0 deletes them at once"
+  type        = number
+  default     = 0
+}
+
+variable "secret_arns" {
+  description = "ARNs of SSM parameters and Secrets Manager secrets the functions may read"
+  type        = list(string)
+  default     = []
+}
@@ -33,3 +33,27 @@ variable "log_level" {
   type        = string
   default     = "info"
 }
+
+variable "lambda_provisioned_concurrency" {
+  description = "Warm Lambda execution environments to keep provisioned (0 disables)"
+  type        = number
+  default     = 0
+}
+
+variable "click_retention_days" {
+  description = "Days click events are kept before the maintenance sweep purges them (0 keeps them forever)"
+  type        = number
+  default     = 0
+}
+
+variable "delete_grace_days" {
+  description = "Days deleted links stay restorable before the maintenance sweep purges them (0 deletes them at once)"
+  type        = number
+  default     = 0
+}
+
+variable "secret_arns" {
+  description = "ARNs of SSM parameters and Secrets Manager secrets referenced by settings"
+  type        = list(string)
+  default     = []
+}